@@ -19,15 +19,18 @@ func main() {
 	fmt.Println("⚡ Initializing Lattice Agentic TUI...")
 	fmt.Println("🤖 Loading autonomous code intelligence...")
 
-	a, err := BuildAgent(ctx)
+	cfg := DefaultConfig()
+	cfg.StateDir = DefaultStateDir()
+	cfg.RegisterFlags(flag.CommandLine)
 	flag.Parse()
+	a, err := BuildAgent(ctx, cfg)
 	if err != nil {
 		fmt.Println("❌ Failed to build agent:", err)
 		os.Exit(1)
 	}
 
 	// Create agentic model instead of base model
-	m := NewAgenticModel(ctx, a, startDir)
+	m := NewAgenticModel(ctx, a, startDir, cfg)
 
 	// Add welcome activity
 	m.AddActivity("system", "Agent initialized", "success", "Ready for autonomous operation")
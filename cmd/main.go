@@ -11,24 +11,61 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 )
 
+var (
+	prompt = flag.String("prompt", "", "run this goal through the planner without the TUI, streaming JSON-lines events, and exit")
+	events = flag.String("events", "-", "with -prompt, where the events go: - for stdout, or a file or named pipe")
+)
+
 func main() {
 	startDir, _ := os.Getwd()
 	ctx := context.Background()
 	var p *tea.Program
 
+	cfg := DefaultConfig()
+	cfg.StateDir = DefaultStateDir()
+	cfg.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	if *prompt != "" {
+		os.Exit(runHeadless(ctx, cfg, startDir))
+	}
+
 	fmt.Println("🚀 Initializing Lattice Code Agent + UTCP...")
 
-	a, err := BuildAgent(ctx)
-	flag.Parse() // Parse flags for qdrant-url etc.
+	a, err := BuildAgent(ctx, cfg)
 	if err != nil {
 		fmt.Println("❌ Failed to build agent:", err)
 		os.Exit(1)
 	}
 
-	m := NewModel(ctx, a, startDir)
+	m := NewModel(ctx, a, startDir, cfg)
 	p = tea.NewProgram(m, tea.WithAltScreen())
 	m.Program = p // Give the model a reference to the program.
 	if _, err := p.Run(); err != nil {
 		fmt.Println("Error:", err)
 	}
 }
+
+// runHeadless runs -prompt in dir, writing events to -events, and returns
+// the exit code. Messages go to stderr so stdout carries only events.
+func runHeadless(ctx context.Context, cfg *Config, dir string) int {
+	out := os.Stdout
+	if *events != "-" {
+		f, err := os.OpenFile(*events, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "❌ Failed to open event stream:", err)
+			return 1
+		}
+		defer f.Close()
+		out = f
+	}
+	a, err := BuildAgent(ctx, cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌ Failed to build agent:", err)
+		return 1
+	}
+	if err := RunPlannerHeadless(ctx, a, cfg, dir, *prompt, out); err != nil {
+		fmt.Fprintln(os.Stderr, "❌", err)
+		return 1
+	}
+	return 0
+}
@@ -1,15 +1,24 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"flag"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/Protocol-Lattice/lattice-code/src/atomicfile"
+	"github.com/Protocol-Lattice/lattice-code/src/logging"
+	"github.com/Protocol-Lattice/lattice-code/src/outline"
 )
 
 const (
@@ -19,9 +28,60 @@ const (
 	toolRefactorFile   = "refactor_file"
 	toolListFiles      = "list_files"
 	toolGetFileOutline = "get_file_outline"
+	toolApplyPatch     = "apply_patch"
+)
+
+var logger = logging.Discard()
+
+// maxSearchResults caps search_codebase output so a common term cannot flood the client.
+const maxSearchResults = 200
+
+// search_codebase reads whole files, so it skips files over maxSearchFileSize
+// and stops after maxSearchFiles files to bound memory and time on large trees.
+const (
+	maxSearchFileSize = 1 << 20
+	maxSearchFiles    = 20000
 )
 
+// ignoredDirs are never descended into by search_codebase.
+var ignoredDirs = map[string]bool{
+	".git": true, "node_modules": true, "vendor": true, "dist": true, "build": true,
+	"target": true, ".venv": true, "__pycache__": true, ".idea": true, ".lattice": true,
+}
+
+var binaryExts = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".ico": true, ".pdf": true,
+	".zip": true, ".gz": true, ".tar": true, ".exe": true, ".dll": true, ".so": true,
+	".dylib": true, ".a": true, ".o": true, ".class": true, ".jar": true, ".wasm": true,
+	".woff": true, ".woff2": true, ".ttf": true, ".mp3": true, ".mp4": true,
+}
+
+func isBinaryExt(path string) bool {
+	return binaryExts[strings.ToLower(filepath.Ext(path))]
+}
+
+// looksBinary reports whether content has a NUL byte in its first 8 KB, the
+// same heuristic git uses.
+func looksBinary(content []byte) bool {
+	if len(content) > 8000 {
+		content = content[:8000]
+	}
+	return bytes.IndexByte(content, 0) >= 0
+}
+
 func main() {
+	logLevel := flag.String("log-level", "info", "level for .lattice/lattice.log: debug, info, warn or error")
+	transport := flag.String("transport", "stdio", "how to serve MCP: stdio or http (streamable HTTP)")
+	addr := flag.String("addr", ":8080", "listen address for -transport http")
+	flag.Parse()
+
+	if cwd, err := os.Getwd(); err == nil {
+		if l, closer, err := logging.Open(cwd, *logLevel); err == nil {
+			logger = l.With("server", "mcp-server")
+			defer closer.Close()
+		}
+	}
+
 	// Create MCP server
 	s := server.NewMCPServer(
 		"Lattice Code MCP Server",
@@ -33,11 +93,49 @@ func main() {
 	registerTools(s)
 
 	// Start server
-	if err := server.ServeStdio(s); err != nil {
+	if err := serve(s, *transport, *addr); err != nil {
+		logger.Error("server error", "err", err)
 		log.Fatalf("Server error: %v", err)
 	}
 }
 
+// Server start functions, replaced in tests.
+var (
+	serveStdio = func(s *server.MCPServer) error { return server.ServeStdio(s) }
+	serveHTTP  = func(s *server.MCPServer, addr string) error { return server.NewStreamableHTTPServer(s).Start(addr) }
+)
+
+// serve starts s on the named transport.
+func serve(s *server.MCPServer, transport, addr string) error {
+	switch transport {
+	case "stdio":
+		logger.Info("serving over stdio")
+		return serveStdio(s)
+	case "http":
+		logger.Info("serving over http", "addr", addr)
+		return serveHTTP(s, addr)
+	default:
+		return fmt.Errorf("unknown transport %q, want stdio or http", transport)
+	}
+}
+
+// logged wraps a tool handler so every call and failure is written to the log.
+func logged(name string, h server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		start := time.Now()
+		res, err := h(ctx, request)
+		switch {
+		case err != nil:
+			logger.Error("tool failed", "tool", name, "err", err)
+		case res != nil && res.IsError:
+			logger.Warn("tool returned error", "tool", name, "duration", time.Since(start))
+		default:
+			logger.Debug("tool call", "tool", name, "duration", time.Since(start))
+		}
+		return res, err
+	}
+}
+
 func registerTools(s *server.MCPServer) {
 	// Tool 1: Search codebase
 	s.AddTool(mcp.Tool{
@@ -63,10 +161,15 @@ func registerTools(s *server.MCPServer) {
 					"description": "Whether search should be case sensitive",
 					"default":     false,
 				},
+				"regex": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Treat query as a Go regular expression; matches report the column span",
+					"default":     false,
+				},
 			},
 			Required: []string{"query"},
 		},
-	}, handleSearchCodebase)
+	}, logged(toolSearchCodebase, handleSearchCodebase))
 
 	// Tool 2: Read file
 	s.AddTool(mcp.Tool{
@@ -87,10 +190,15 @@ func registerTools(s *server.MCPServer) {
 					"type":        "integer",
 					"description": "Optional ending line number (1-indexed)",
 				},
+				"with_line_numbers": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Prefix each line with its 1-indexed line number, e.g. for targeting refactor_file ranges",
+					"default":     false,
+				},
 			},
 			Required: []string{"path"},
 		},
-	}, handleReadFile)
+	}, logged(toolReadFile, handleReadFile))
 
 	// Tool 3: Write file
 	s.AddTool(mcp.Tool{
@@ -115,7 +223,7 @@ func registerTools(s *server.MCPServer) {
 			},
 			Required: []string{"path", "content"},
 		},
-	}, handleWriteFile)
+	}, logged(toolWriteFile, handleWriteFile))
 
 	// Tool 4: Refactor file
 	s.AddTool(mcp.Tool{
@@ -147,7 +255,7 @@ func registerTools(s *server.MCPServer) {
 			},
 			Required: []string{"path", "find", "replace"},
 		},
-	}, handleRefactorFile)
+	}, logged(toolRefactorFile, handleRefactorFile))
 
 	// Tool 5: List files
 	s.AddTool(mcp.Tool{
@@ -171,7 +279,7 @@ func registerTools(s *server.MCPServer) {
 				},
 			},
 		},
-	}, handleListFiles)
+	}, logged(toolListFiles, handleListFiles))
 
 	// Tool 6: Get file outline
 	s.AddTool(mcp.Tool{
@@ -187,7 +295,31 @@ func registerTools(s *server.MCPServer) {
 			},
 			Required: []string{"path"},
 		},
-	}, handleGetFileOutline)
+	}, logged(toolGetFileOutline, handleGetFileOutline))
+
+	// Tool 7: Apply patch
+	s.AddTool(mcp.Tool{
+		Name:        toolApplyPatch,
+		Description: "Apply a unified diff to one or more files, reporting which hunks applied and which were rejected. A /dev/null side creates or deletes the file",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"patch": map[string]interface{}{
+					"type":        "string",
+					"description": "Unified diff with ---/+++ file headers and @@ hunks",
+				},
+				"path": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional file to patch, overriding the path in a single-file patch",
+				},
+				"root": map[string]interface{}{
+					"type":        "string",
+					"description": "Directory the patch's file paths are relative to (defaults to current directory)",
+				},
+			},
+			Required: []string{"patch"},
+		},
+	}, logged(toolApplyPatch, handleApplyPatch))
 }
 
 // Tool handlers
@@ -196,17 +328,34 @@ func handleSearchCodebase(ctx context.Context, request mcp.CallToolRequest) (*mc
 	searchPath := request.GetString("path", ".")
 	filePattern := request.GetString("file_pattern", "")
 	caseSensitive := request.GetBool("case_sensitive", false)
+	useRegex := request.GetBool("regex", false)
+
+	var re *regexp.Regexp
+	if useRegex {
+		pattern := query
+		if !caseSensitive {
+			pattern = "(?i)" + pattern
+		}
+		var err error
+		if re, err = regexp.Compile(pattern); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid regex %q: %v", query, err)), nil
+		}
+	}
 
 	results := []string{}
+	truncated := false
+	var scanned, skippedBinary, skippedLarge int
 	err := filepath.Walk(searchPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return nil // Skip files with errors
 		}
 
 		if info.IsDir() {
+			if path != searchPath && ignoredDirs[info.Name()] {
+				return filepath.SkipDir
+			}
 			return nil
 		}
-
 		// Filter by file pattern if provided
 		if filePattern != "" {
 			matched, _ := filepath.Match(filePattern, filepath.Base(path))
@@ -214,15 +363,43 @@ func handleSearchCodebase(ctx context.Context, request mcp.CallToolRequest) (*mc
 				return nil
 			}
 		}
+		if isBinaryExt(path) {
+			skippedBinary++
+			return nil
+		}
+		if info.Size() > maxSearchFileSize {
+			skippedLarge++
+			return nil
+		}
+		if scanned >= maxSearchFiles {
+			truncated = true
+			return filepath.SkipAll
+		}
+		scanned++
 
 		// Read and search file
 		content, err := os.ReadFile(path)
 		if err != nil {
 			return nil
 		}
+		if looksBinary(content) {
+			skippedBinary++
+			return nil
+		}
 
 		lines := strings.Split(string(content), "\n")
 		for i, line := range lines {
+			if re != nil {
+				if loc := re.FindStringIndex(line); loc != nil {
+					results = append(results, fmt.Sprintf("%s:%d:%d-%d: %s", path, i+1, loc[0]+1, loc[1], strings.TrimSpace(line)))
+					if len(results) >= maxSearchResults {
+						truncated = true
+						return filepath.SkipAll
+					}
+				}
+				continue
+			}
+
 			var found bool
 			if caseSensitive {
 				found = strings.Contains(line, query)
@@ -232,6 +409,10 @@ func handleSearchCodebase(ctx context.Context, request mcp.CallToolRequest) (*mc
 
 			if found {
 				results = append(results, fmt.Sprintf("%s:%d: %s", path, i+1, strings.TrimSpace(line)))
+				if len(results) >= maxSearchResults {
+					truncated = true
+					return filepath.SkipAll
+				}
 			}
 		}
 
@@ -246,6 +427,15 @@ func handleSearchCodebase(ctx context.Context, request mcp.CallToolRequest) (*mc
 	if output == "" {
 		output = "No results found"
 	}
+	switch {
+	case truncated && scanned >= maxSearchFiles:
+		output += fmt.Sprintf("\n... stopped after scanning %d files; narrow the path or file_pattern", maxSearchFiles)
+	case truncated:
+		output += fmt.Sprintf("\n... stopped after %d results; narrow the query or file_pattern", maxSearchResults)
+	}
+	if skippedBinary > 0 || skippedLarge > 0 {
+		output += fmt.Sprintf("\n(skipped %d binary and %d files over %d KB)", skippedBinary, skippedLarge, maxSearchFileSize>>10)
+	}
 
 	return mcp.NewToolResultText(output), nil
 }
@@ -254,6 +444,7 @@ func handleReadFile(ctx context.Context, request mcp.CallToolRequest) (*mcp.Call
 	path := request.GetString("path", "")
 	startLine := request.GetFloat("start_line", 0)
 	endLine := request.GetFloat("end_line", 0)
+	withLineNumbers := request.GetBool("with_line_numbers", false)
 
 	content, err := os.ReadFile(path)
 	if err != nil {
@@ -261,6 +452,7 @@ func handleReadFile(ctx context.Context, request mcp.CallToolRequest) (*mcp.Call
 	}
 
 	lines := strings.Split(string(content), "\n")
+	total, first := len(lines), 1
 
 	// Apply line range if specified
 	if startLine > 0 && endLine > 0 {
@@ -272,13 +464,37 @@ func handleReadFile(ctx context.Context, request mcp.CallToolRequest) (*mcp.Call
 		if end > len(lines) {
 			end = len(lines)
 		}
+		if start > end {
+			start = end
+		}
 		lines = lines[start:end]
+		first = start + 1
 	}
 
+	if withLineNumbers {
+		// The empty string after a final newline is not a line of the file.
+		last := first + len(lines) - 1
+		if len(lines) > 0 && last == total && lines[len(lines)-1] == "" {
+			lines = append(numberLines(lines[:len(lines)-1], first), "")
+		} else {
+			lines = numberLines(lines, first)
+		}
+	}
 	output := strings.Join(lines, "\n")
 	return mcp.NewToolResultText(output), nil
 }
 
+// numberLines prefixes lines with their line numbers, starting at first, in
+// the same "N | text" form the TUI uses for numbered context.
+func numberLines(lines []string, first int) []string {
+	width := len(strconv.Itoa(first + len(lines) - 1))
+	out := make([]string, len(lines))
+	for i, l := range lines {
+		out[i] = fmt.Sprintf("%*d | %s", width, first+i, l)
+	}
+	return out
+}
+
 func handleWriteFile(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	path := request.GetString("path", "")
 	content := request.GetString("content", "")
@@ -291,7 +507,7 @@ func handleWriteFile(ctx context.Context, request mcp.CallToolRequest) (*mcp.Cal
 		}
 	}
 
-	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+	if err := atomicfile.WriteFile(path, []byte(content), 0644); err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("Failed to write file: %v", err)), nil
 	}
 
@@ -337,7 +553,7 @@ func handleRefactorFile(ctx context.Context, request mcp.CallToolRequest) (*mcp.
 
 	// Write back
 	newContent := strings.Join(lines, "\n")
-	if err := os.WriteFile(path, []byte(newContent), 0644); err != nil {
+	if err := atomicfile.WriteFile(path, []byte(newContent), 0644); err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("Failed to write file: %v", err)), nil
 	}
 
@@ -356,6 +572,9 @@ func handleListFiles(ctx context.Context, request mcp.CallToolRequest) (*mcp.Cal
 			if err != nil {
 				return nil
 			}
+			if info.IsDir() && p != path && ignoredDirs[info.Name()] {
+				return filepath.SkipDir
+			}
 
 			if pattern != "" {
 				matched, _ := filepath.Match(pattern, filepath.Base(p))
@@ -416,34 +635,7 @@ func handleGetFileOutline(ctx context.Context, request mcp.CallToolRequest) (*mc
 		return mcp.NewToolResultError(fmt.Sprintf("Failed to read file: %v", err)), nil
 	}
 
-	lines := strings.Split(string(content), "\n")
-	outline := []string{}
-
-	// Simple outline extraction for Go files
-	ext := filepath.Ext(path)
-	if ext == ".go" {
-		for i, line := range lines {
-			trimmed := strings.TrimSpace(line)
-			// Find functions, types, interfaces
-			if strings.HasPrefix(trimmed, "func ") ||
-				strings.HasPrefix(trimmed, "type ") ||
-				strings.HasPrefix(trimmed, "const ") ||
-				strings.HasPrefix(trimmed, "var ") {
-				outline = append(outline, fmt.Sprintf("Line %d: %s", i+1, trimmed))
-			}
-		}
-	} else {
-		// Generic outline for other files - show first 20 lines
-		maxLines := 20
-		if len(lines) < maxLines {
-			maxLines = len(lines)
-		}
-		for i := 0; i < maxLines; i++ {
-			outline = append(outline, fmt.Sprintf("Line %d: %s", i+1, strings.TrimSpace(lines[i])))
-		}
-	}
-
-	output := strings.Join(outline, "\n")
+	output := outline.Format(outline.Extract(path, content))
 	if output == "" {
 		output = "No outline available"
 	}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func searchRequest(args map[string]any) mcp.CallToolRequest {
+	var req mcp.CallToolRequest
+	req.Params.Name = toolSearchCodebase
+	req.Params.Arguments = args
+	return req
+}
+
+func resultText(t *testing.T, res *mcp.CallToolResult) string {
+	t.Helper()
+	if len(res.Content) == 0 {
+		t.Fatal("empty result")
+	}
+	text, ok := res.Content[0].(mcp.TextContent)
+	if !ok {
+		t.Fatalf("unexpected content %T", res.Content[0])
+	}
+	return text.Text
+}
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for rel, body := range files {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSearchCodebaseSkipsBinariesAndIgnoredDirs(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"main.go":             "needle in source\n",
+		"node_modules/a/x.js": "needle in deps\n",
+		".git/HEAD":           "needle in git\n",
+		"logo.png":            "needle in image\n",
+		"blob.dat":            "needle\x00in binary\n",
+	})
+
+	res, err := handleSearchCodebase(context.Background(), searchRequest(map[string]any{"query": "needle", "path": root}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := resultText(t, res)
+	if !strings.Contains(out, "main.go:1: needle in source") {
+		t.Errorf("missing source match:\n%s", out)
+	}
+	for _, skipped := range []string{"node_modules", ".git", "logo.png", "blob.dat"} {
+		if strings.Contains(out, skipped) {
+			t.Errorf("search should skip %s:\n%s", skipped, out)
+		}
+	}
+}
+
+func TestSearchCodebaseSkipsOversizedFiles(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"small.go": "needle\n",
+		"huge.log": "needle\n" + strings.Repeat("x", maxSearchFileSize),
+		"logo.png": "needle\n",
+		"blob.dat": "needle\x00\n",
+	})
+
+	res, _ := handleSearchCodebase(context.Background(), searchRequest(map[string]any{"query": "needle", "path": root}))
+	out := resultText(t, res)
+	if !strings.Contains(out, "small.go:1: needle") || strings.Contains(out, "huge.log") {
+		t.Errorf("want only the small match:\n%s", out)
+	}
+	if !strings.Contains(out, "(skipped 2 binary and 1 files over 1024 KB)") {
+		t.Errorf("missing skip summary:\n%s", out)
+	}
+}
+
+func TestListFilesSkipsIgnoredDirs(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"main.go": "", "node_modules/a/x.js": "", ".git/HEAD": ""})
+
+	var req mcp.CallToolRequest
+	req.Params.Name = toolListFiles
+	req.Params.Arguments = map[string]any{"path": root, "recursive": true}
+	res, _ := handleListFiles(context.Background(), req)
+	out := resultText(t, res)
+	if !strings.Contains(out, "main.go") || strings.Contains(out, "x.js") || strings.Contains(out, "HEAD") {
+		t.Errorf("recursive listing should skip ignored dirs:\n%s", out)
+	}
+}
+
+func TestSearchCodebaseCapsResults(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"many.txt": strings.Repeat("hit\n", maxSearchResults+50)})
+
+	res, _ := handleSearchCodebase(context.Background(), searchRequest(map[string]any{"query": "hit", "path": root}))
+	out := resultText(t, res)
+	if got := strings.Count(out, "many.txt:"); got != maxSearchResults {
+		t.Errorf("got %d results; want %d", got, maxSearchResults)
+	}
+	if !strings.Contains(out, "stopped after") {
+		t.Errorf("missing truncation note")
+	}
+}
+
+func TestSearchCodebaseRegexMode(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"h.go": "func userHandler() {}\nfunc helper() {}\n// func \\w+Handler\n",
+	})
+	search := func(args map[string]any) *mcp.CallToolResult {
+		args["path"] = root
+		res, err := handleSearchCodebase(context.Background(), searchRequest(args))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	out := resultText(t, search(map[string]any{"query": `func \w+Handler`}))
+	if !strings.Contains(out, `h.go:3: // func \w+Handler`) || strings.Contains(out, "userHandler") {
+		t.Errorf("substring mode should match the literal text only:\n%s", out)
+	}
+
+	out = resultText(t, search(map[string]any{"query": `func \w+Handler`, "regex": true}))
+	if !strings.Contains(out, "h.go:1:1-16: func userHandler() {}") || strings.Contains(out, "helper") {
+		t.Errorf("regex mode should report the matched span:\n%s", out)
+	}
+
+	res := search(map[string]any{"query": "func (", "regex": true})
+	if !res.IsError || !strings.Contains(resultText(t, res), "Invalid regex") {
+		t.Errorf("invalid pattern should return a tool error")
+	}
+}
+
+func TestServeSelectsTransport(t *testing.T) {
+	origStdio, origHTTP := serveStdio, serveHTTP
+	defer func() { serveStdio, serveHTTP = origStdio, origHTTP }()
+
+	var started string
+	serveStdio = func(*server.MCPServer) error { started = "stdio"; return nil }
+	serveHTTP = func(_ *server.MCPServer, addr string) error { started = "http " + addr; return nil }
+
+	s := server.NewMCPServer("test", "0")
+	for transport, want := range map[string]string{"stdio": "stdio", "http": "http :9000"} {
+		started = ""
+		if err := serve(s, transport, ":9000"); err != nil {
+			t.Fatalf("%s: %v", transport, err)
+		}
+		if started != want {
+			t.Errorf("%s started %q, want %q", transport, started, want)
+		}
+	}
+	if err := serve(s, "grpc", ""); err == nil {
+		t.Error("expected an error for an unknown transport")
+	}
+}
+
+func TestReadFileWithLineNumbers(t *testing.T) {
+	root := t.TempDir()
+	var body strings.Builder
+	for i := 1; i <= 12; i++ {
+		fmt.Fprintf(&body, "line %d\n", i)
+	}
+	writeFiles(t, root, map[string]string{"f.txt": body.String()})
+	path := filepath.Join(root, "f.txt")
+
+	read := func(args map[string]any) string {
+		var req mcp.CallToolRequest
+		req.Params.Name = toolReadFile
+		req.Params.Arguments = args
+		res, _ := handleReadFile(context.Background(), req)
+		return resultText(t, res)
+	}
+
+	got := read(map[string]any{"path": path, "start_line": 9, "end_line": 10, "with_line_numbers": true})
+	if want := " 9 | line 9\n10 | line 10"; got != want {
+		t.Errorf("ranged read = %q, want %q", got, want)
+	}
+	got = read(map[string]any{"path": path, "with_line_numbers": true})
+	if !strings.HasPrefix(got, " 1 | line 1\n") || !strings.HasSuffix(got, "12 | line 12\n") {
+		t.Errorf("full read = %q", got)
+	}
+	if got := read(map[string]any{"path": path, "start_line": 2, "end_line": 2}); got != "line 2" {
+		t.Errorf("plain read = %q", got)
+	}
+	// Out-of-range and fractional bounds are clamped rather than panicking.
+	for _, r := range [][2]any{{0.5, 0.5}, {20, 3}, {1.5, 99.9}} {
+		read(map[string]any{"path": path, "start_line": r[0], "end_line": r[1]})
+	}
+	if got := read(map[string]any{"path": path, "start_line": 0.5, "end_line": 1}); got != "line 1" {
+		t.Errorf("fractional start = %q", got)
+	}
+	if got := read(map[string]any{"path": path, "start_line": 12, "end_line": 99}); got != "line 12\n" {
+		t.Errorf("range past the end = %q", got)
+	}
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/Protocol-Lattice/lattice-code/src/atomicfile"
+)
+
+// filePatch is the part of a unified diff that targets one file.
+type filePatch struct {
+	Path   string
+	Hunks  []hunk
+	Create bool // the old side is /dev/null
+	Delete bool // the new side is /dev/null
+}
+
+// hunk is one "@@ -a,b +c,d @@" section. Lines keep their leading ' ', '-'
+// or '+' marker.
+type hunk struct {
+	Header   string
+	OldStart int
+	Lines    []string
+}
+
+// before and after return the lines the hunk expects and the lines it leaves.
+func (h hunk) before() []string { return h.side('+') }
+func (h hunk) after() []string  { return h.side('-') }
+
+func (h hunk) side(skip byte) []string {
+	var out []string
+	for _, l := range h.Lines {
+		if l[0] != skip {
+			out = append(out, l[1:])
+		}
+	}
+	return out
+}
+
+var (
+	ansiRe     = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+	hunkHeadRe = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+)
+
+// parsePatch reads a unified diff, as produced by git or by the TUI's
+// ChangeTracker.DiffPretty (whose colour codes are ignored).
+func parsePatch(text string) ([]filePatch, error) {
+	var (
+		files   []filePatch
+		cur     *filePatch
+		oldPath string
+		oldNull bool // the last --- header was /dev/null
+	)
+	for _, line := range strings.Split(strings.ReplaceAll(ansiRe.ReplaceAllString(text, ""), "\r\n", "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "--- ") && (cur == nil || !inHunkBody(cur)):
+			oldPath = patchPath(line[4:])
+			oldNull = oldPath == ""
+		case strings.HasPrefix(line, "+++ ") && (cur == nil || !inHunkBody(cur)):
+			path := patchPath(line[4:])
+			f := filePatch{Path: path, Create: oldNull, Delete: path == ""}
+			if f.Delete {
+				f.Path = oldPath
+			}
+			oldPath, oldNull = "", false
+			if f.Path == "" {
+				return nil, fmt.Errorf("no file name in %q", line)
+			}
+			files = append(files, f)
+			cur = &files[len(files)-1]
+		case strings.HasPrefix(line, "@@"):
+			if cur == nil {
+				return nil, fmt.Errorf("hunk %q before a +++ file header", line)
+			}
+			m := hunkHeadRe.FindStringSubmatch(line)
+			if m == nil {
+				return nil, fmt.Errorf("invalid hunk header %q", line)
+			}
+			start, _ := strconv.Atoi(m[1])
+			cur.Hunks = append(cur.Hunks, hunk{Header: line, OldStart: start})
+		case cur != nil && inHunkBody(cur):
+			h := &cur.Hunks[len(cur.Hunks)-1]
+			switch {
+			case line == "":
+				h.Lines = append(h.Lines, " ") // context line whose space was trimmed
+			case line[0] == ' ' || line[0] == '-' || line[0] == '+':
+				h.Lines = append(h.Lines, line)
+			}
+			// Anything else ("\ No newline at end of file", diff --git,
+			// index lines) carries nothing to apply.
+		}
+	}
+	if len(files) == 0 {
+		return nil, errors.New("no file headers (--- / +++) found in patch")
+	}
+	return files, nil
+}
+
+// inHunkBody reports whether the last hunk of f still expects lines, so a
+// "--- x" line inside it is a removed line rather than a new file header.
+func inHunkBody(f *filePatch) bool {
+	if len(f.Hunks) == 0 {
+		return false
+	}
+	h := f.Hunks[len(f.Hunks)-1]
+	m := hunkHeadRe.FindStringSubmatch(h.Header)
+	count := func(s string) int {
+		if s == "" {
+			return 1
+		}
+		n, _ := strconv.Atoi(s)
+		return n
+	}
+	oldWant, newWant := count(m[2]), count(m[4])
+	oldHave, newHave := len(h.before()), len(h.after())
+	return oldHave < oldWant || newHave < newWant
+}
+
+// patchPath strips the a/ or b/ prefix and any trailing timestamp; /dev/null
+// yields "".
+func patchPath(s string) string {
+	if i := strings.IndexByte(s, '\t'); i >= 0 {
+		s = s[:i]
+	}
+	s = strings.TrimSpace(s)
+	if s == "/dev/null" {
+		return ""
+	}
+	if strings.HasPrefix(s, "a/") || strings.HasPrefix(s, "b/") {
+		s = s[2:]
+	}
+	return s
+}
+
+// applyHunks applies hunks to content. Each hunk is tried at its stated line
+// (adjusted by the drift of earlier hunks) and then at the nearest position
+// where its context matches; hunks whose context is not found are rejected.
+func applyHunks(content string, hunks []hunk) (string, []hunk, []hunk) {
+	trailingNL := strings.HasSuffix(content, "\n")
+	var lines []string
+	if content != "" {
+		lines = strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	}
+	var applied, rejected []hunk
+	offset := 0
+	for _, h := range hunks {
+		old, repl := h.before(), h.after()
+		orig := h.OldStart - 1
+		if len(old) == 0 {
+			orig = h.OldStart // a pure insertion goes after line OldStart
+		}
+		at := findLines(lines, old, orig+offset)
+		if at < 0 {
+			rejected = append(rejected, h)
+			continue
+		}
+		next := make([]string, 0, len(lines)-len(old)+len(repl))
+		next = append(next, lines[:at]...)
+		next = append(next, repl...)
+		next = append(next, lines[at+len(old):]...)
+		lines = next
+		offset = at - orig + len(repl) - len(old)
+		applied = append(applied, h)
+	}
+	out := strings.Join(lines, "\n")
+	if len(lines) > 0 && (trailingNL || content == "") {
+		out += "\n"
+	}
+	return out, applied, rejected
+}
+
+// findLines returns the index where want occurs in lines, preferring the
+// occurrence closest to hint, or -1.
+func findLines(lines, want []string, hint int) int {
+	matches := func(at int) bool {
+		if at < 0 || at+len(want) > len(lines) {
+			return false
+		}
+		for i, w := range want {
+			if lines[at+i] != w {
+				return false
+			}
+		}
+		return true
+	}
+	hint = min(max(hint, 0), len(lines))
+	for d := 0; d <= len(lines); d++ {
+		if matches(hint - d) {
+			return hint - d
+		}
+		if d > 0 && matches(hint+d) {
+			return hint + d
+		}
+	}
+	return -1
+}
+
+func handleApplyPatch(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	patch := request.GetString("patch", "")
+	target := request.GetString("path", "")
+	root := request.GetString("root", ".")
+
+	files, err := parsePatch(patch)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid patch: %v", err)), nil
+	}
+	if target != "" && len(files) > 1 {
+		return mcp.NewToolResultError(fmt.Sprintf("path is set but the patch touches %d files", len(files))), nil
+	}
+
+	var report []string
+	total, totalRejected := 0, 0
+	for _, f := range files {
+		path := filepath.Join(root, filepath.FromSlash(f.Path))
+		if target != "" {
+			path = target
+		}
+		content, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to read %s: %v", path, err)), nil
+		}
+		total += len(f.Hunks)
+		// A creation must not land on an existing file, and a deletion must
+		// account for all of the file; either is applied whole or not at all.
+		switch {
+		case f.Create && err == nil:
+			totalRejected += len(f.Hunks)
+			report = append(report, fmt.Sprintf("%s: rejected, the patch creates it but it already exists", path))
+			continue
+		case f.Delete:
+			if out, _, rejected := applyHunks(string(content), f.Hunks); err != nil || len(rejected) > 0 || out != "" {
+				totalRejected += len(f.Hunks)
+				report = append(report, fmt.Sprintf("%s: rejected, the patch deletes it but does not match its content", path))
+				continue
+			}
+			if err := os.Remove(path); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("Failed to delete %s: %v", path, err)), nil
+			}
+			report = append(report, fmt.Sprintf("%s: deleted", path))
+			continue
+		}
+		out, applied, rejected := applyHunks(string(content), f.Hunks)
+		totalRejected += len(rejected)
+		if len(applied) > 0 {
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("Failed to create directories: %v", err)), nil
+			}
+			if err := atomicfile.WriteFile(path, []byte(out), 0644); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("Failed to write %s: %v", path, err)), nil
+			}
+		}
+		report = append(report, fmt.Sprintf("%s: %d/%d hunks applied", path, len(applied), len(f.Hunks)))
+		for _, h := range rejected {
+			report = append(report, fmt.Sprintf("  rejected %s (context not found)", h.Header))
+		}
+	}
+
+	output := strings.Join(report, "\n")
+	if totalRejected == total {
+		return mcp.NewToolResultError(output), nil
+	}
+	return mcp.NewToolResultText(output), nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func patchRequest(args map[string]any) mcp.CallToolRequest {
+	var req mcp.CallToolRequest
+	req.Params.Name = toolApplyPatch
+	req.Params.Arguments = args
+	return req
+}
+
+const patchOriginal = "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n\nfunc helper() int {\n\treturn 1\n}\n"
+
+func TestApplyPatch(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"main.go": patchOriginal})
+
+	patch := "diff --git a/main.go b/main.go\n" +
+		"--- a/main.go\n" +
+		"+++ b/main.go\n" +
+		"@@ -5,3 +5,3 @@\n" +
+		" func main() {\n" +
+		"-\tfmt.Println(\"hi\")\n" +
+		"+\tfmt.Println(\"hello\")\n" +
+		" }\n" +
+		"@@ -9,3 +9,3 @@\n" +
+		" func helper() int {\n" +
+		"-\treturn 1\n" +
+		"+\treturn 2\n" +
+		" }\n"
+	res, _ := handleApplyPatch(context.Background(), patchRequest(map[string]any{"patch": patch, "root": root}))
+	out := resultText(t, res)
+	if res.IsError || !strings.Contains(out, "2/2 hunks applied") {
+		t.Fatalf("unexpected result: %s", out)
+	}
+	got, _ := os.ReadFile(filepath.Join(root, "main.go"))
+	want := strings.NewReplacer(`"hi"`, `"hello"`, "return 1", "return 2").Replace(patchOriginal)
+	if string(got) != want {
+		t.Fatalf("patched file:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestApplyPatchReportsRejectedHunk(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"main.go": patchOriginal})
+
+	// The first hunk's context drifted by two lines; the second no longer matches.
+	patch := "--- a/main.go\n+++ b/main.go\n" +
+		"@@ -3,3 +3,3 @@\n func main() {\n-\tfmt.Println(\"hi\")\n+\tfmt.Println(\"hey\")\n }\n" +
+		"@@ -9,2 +9,2 @@\n func other() {\n-\treturn 1\n+\treturn 3\n"
+	res, _ := handleApplyPatch(context.Background(), patchRequest(map[string]any{"patch": patch, "path": filepath.Join(root, "main.go")}))
+	out := resultText(t, res)
+	if res.IsError || !strings.Contains(out, "1/2 hunks applied") || !strings.Contains(out, "rejected @@ -9,2 +9,2 @@") {
+		t.Fatalf("unexpected result: %s", out)
+	}
+	got, _ := os.ReadFile(filepath.Join(root, "main.go"))
+	if !strings.Contains(string(got), `"hey"`) || !strings.Contains(string(got), "return 1") {
+		t.Fatalf("patched file:\n%s", got)
+	}
+}
+
+func TestApplyPatchCreatesAndDeletesFiles(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"old.txt": "one\ntwo\n", "keep.txt": "mine\n"})
+	apply := func(patch string) (string, bool) {
+		t.Helper()
+		res, _ := handleApplyPatch(context.Background(), patchRequest(map[string]any{"patch": patch, "root": root}))
+		return resultText(t, res), res.IsError
+	}
+
+	out, isErr := apply("--- a/old.txt\n+++ /dev/null\n@@ -1,2 +0,0 @@\n-one\n-two\n" +
+		"--- /dev/null\n+++ b/new.txt\n@@ -0,0 +1,1 @@\n+fresh\n")
+	if isErr || !strings.Contains(out, "old.txt: deleted") {
+		t.Fatalf("unexpected result: %s", out)
+	}
+	if _, err := os.Stat(filepath.Join(root, "old.txt")); !os.IsNotExist(err) {
+		t.Errorf("old.txt should be removed: %v", err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(root, "new.txt")); string(got) != "fresh\n" {
+		t.Errorf("new.txt = %q", got)
+	}
+
+	// A creation over an existing file and a deletion that leaves lines
+	// behind are both refused.
+	out, isErr = apply("--- /dev/null\n+++ b/keep.txt\n@@ -0,0 +1,1 @@\n+theirs\n")
+	if !isErr || !strings.Contains(out, "already exists") {
+		t.Errorf("creation over an existing file: %s", out)
+	}
+	writeFiles(t, root, map[string]string{"long.txt": "one\ntwo\nthree\n"})
+	out, isErr = apply("--- a/long.txt\n+++ /dev/null\n@@ -1,2 +0,0 @@\n-one\n-two\n")
+	if !isErr || !strings.Contains(out, "does not match") {
+		t.Errorf("partial deletion: %s", out)
+	}
+	for name, want := range map[string]string{"keep.txt": "mine\n", "long.txt": "one\ntwo\nthree\n"} {
+		if got, _ := os.ReadFile(filepath.Join(root, name)); string(got) != want {
+			t.Errorf("%s = %q, want it untouched", name, got)
+		}
+	}
+}
+
+func TestParsePatchAcceptsColouredDiff(t *testing.T) {
+	patch := "\x1b[1m\x1b[36mdiff --git a/x.txt b/x.txt\x1b[0m\n\x1b[36m--- a/x.txt\x1b[0m\n\x1b[36m+++ b/x.txt\x1b[0m\n" +
+		"\x1b[36m@@ -1,1 +1,1 @@\x1b[0m\n\x1b[31m--- old\x1b[0m\n\x1b[32m+new\x1b[0m\n"
+	files, err := parsePatch(patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Path != "x.txt" || len(files[0].Hunks) != 1 {
+		t.Fatalf("parsed %+v", files)
+	}
+	out, applied, _ := applyHunks("-- old\n", files[0].Hunks)
+	if len(applied) != 1 || out != "new\n" {
+		t.Fatalf("applied %d hunks, got %q", len(applied), out)
+	}
+}
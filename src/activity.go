@@ -0,0 +1,63 @@
+package src
+
+import (
+	"context"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	agent "github.com/Protocol-Lattice/go-agent"
+	"github.com/Protocol-Lattice/lattice-code/src/ui"
+)
+
+// maxActivity bounds the timeline kept for the activity panel.
+const maxActivity = 200
+
+// AgenticModel is the chat model with an activity panel beside the
+// transcript: a timeline of what the agent did and what it is working on.
+type AgenticModel struct {
+	*model
+}
+
+// NewAgenticModel returns a model like NewModel's that shows the activity
+// panel.
+func NewAgenticModel(ctx context.Context, a *agent.Agent, startDir string, cfg *Config) *AgenticModel {
+	m := NewModel(ctx, a, startDir, cfg)
+	m.showActivity = true
+	return &AgenticModel{m}
+}
+
+// Update lets the chat model handle msg while keeping the program pointed at
+// the AgenticModel.
+func (a *AgenticModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	_, cmd := a.model.Update(msg)
+	return a, cmd
+}
+
+// AddActivity appends an action to the timeline. status "success", "error"
+// and "running" get their own marker; kind and detail are shown beneath the
+// title. Like the rest of the model it must be called from Update or before
+// the program starts.
+func (m *model) AddActivity(kind, title, status, detail string) {
+	m.addActivity(ui.ActivityEntry{At: time.Now(), Kind: kind, Title: title, Status: status, Detail: detail})
+}
+
+// AddThought appends a note on what the agent is working on to the timeline.
+func (m *model) AddThought(text string) {
+	m.addActivity(ui.ActivityEntry{At: time.Now(), Title: text, Thought: true})
+}
+
+func (m *model) addActivity(e ui.ActivityEntry) {
+	m.activity = append(m.activity, e)
+	if len(m.activity) > maxActivity {
+		m.activity = append(m.activity[:0], m.activity[len(m.activity)-maxActivity:]...)
+	}
+}
+
+// activityWidth is the width the activity panel takes from the chat.
+func (m *model) activityWidth() int {
+	if !m.showActivity {
+		return 0
+	}
+	return ui.ActivityPanelWidth(m.width)
+}
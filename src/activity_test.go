@@ -0,0 +1,57 @@
+package src
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/Protocol-Lattice/lattice-code/src/ui"
+)
+
+func TestAgenticModelActivityFeed(t *testing.T) {
+	a := NewAgenticModel(context.Background(), nil, t.TempDir(), nil)
+	a.AddActivity("system", "Agent initialized", "success", "Ready for autonomous operation")
+	a.AddThought("Analyzing workspace structure...")
+
+	next, _ := a.Update(tea.WindowSizeMsg{Width: 140, Height: 40})
+	if next != a {
+		t.Fatalf("Update returned %T, want the AgenticModel", next)
+	}
+	a.mode = ui.ModeChat
+	a.Update(fileActionMsg{FileAction{Path: "src/server.go", Action: "saved", Message: "created"}})
+	a.Update(generateMsg{err: errors.New("model unavailable")})
+
+	if len(a.activity) != 4 || !a.activity[1].Thought {
+		t.Fatalf("activity = %+v", a.activity)
+	}
+	view := ansi.Strip(a.View())
+	for _, want := range []string{"Activity", "✓ Agent initialized", "system · Ready", "💭 Analyzing workspace", "✓ src/server.go", "✗ Run failed"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view lacks %q:\n%s", want, view)
+		}
+	}
+	if a.viewport.Width >= 140-ui.ActivityPanelWidth(140) {
+		t.Errorf("viewport width %d leaves no room for the panel", a.viewport.Width)
+	}
+
+	for i := 0; i < maxActivity+10; i++ {
+		a.AddThought("step")
+	}
+	if len(a.activity) != maxActivity {
+		t.Errorf("timeline holds %d entries, want %d", len(a.activity), maxActivity)
+	}
+}
+
+func TestPlainModelHidesActivityPanel(t *testing.T) {
+	m := NewModel(context.Background(), nil, t.TempDir(), nil)
+	m.Update(tea.WindowSizeMsg{Width: 140, Height: 40})
+	m.mode = ui.ModeChat
+	m.AddThought("hidden")
+	if strings.Contains(ansi.Strip(m.View()), "hidden") {
+		t.Error("NewModel should not render the activity panel")
+	}
+}
@@ -2,7 +2,12 @@ package src
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
 
 	agent "github.com/Protocol-Lattice/go-agent"
 	adk "github.com/Protocol-Lattice/go-agent/src/adk"
@@ -13,19 +18,74 @@ import (
 	"github.com/Protocol-Lattice/go-agent/src/tools"
 )
 
-func BuildAgent(ctx context.Context) (*agent.Agent, error) {
-	utcp, err := BuildUTCP(ctx)
+// systemPrompt returns the system prompt for an agent started in dir: the
+// -system-prompt file, or dir's .lattice/system.md when the flag is unset,
+// in place of VibeSystemPrompt or after it with -system-prompt-append. When
+// there is no such file the built-in prompt is used; a -system-prompt file
+// that cannot be read is an error.
+func systemPrompt(cfg *Config, dir string) (string, error) {
+	path := cfg.SystemPrompt
+	if path == "" {
+		path = filepath.Join(dir, latticeDir, "system.md")
+	}
+	data, err := os.ReadFile(path)
+	switch {
+	case err != nil && cfg.SystemPrompt == "" && errors.Is(err, fs.ErrNotExist):
+		return VibeSystemPrompt, nil
+	case err != nil:
+		return "", fmt.Errorf("system prompt: %w", err)
+	}
+	custom := strings.TrimSpace(string(data))
+	switch {
+	case custom == "":
+		return VibeSystemPrompt, nil
+	case cfg.SystemPromptAppend:
+		return VibeSystemPrompt + "\n\n" + custom, nil
+	}
+	return custom, nil
+}
+
+// BuildAgent assembles the coding agent for cfg; opts are forwarded to
+// BuildUTCP so embedders can register extra tool providers. The system
+// prompt comes from systemPrompt for the launch directory; the TUI rebuilds
+// the agent with BuildAgentFor when the workspace confirmed there has a
+// different one.
+func BuildAgent(ctx context.Context, cfg *Config, opts ...UTCPOption) (*agent.Agent, error) {
+	wd, _ := os.Getwd()
+	return BuildAgentFor(ctx, cfg, wd, opts...)
+}
+
+// BuildAgentFor is BuildAgent with the system prompt taken for the
+// workspace dir.
+func BuildAgentFor(ctx context.Context, cfg *Config, dir string, opts ...UTCPOption) (*agent.Agent, error) {
+	cfg = cfg.orDefault()
+	sysPrompt, err := systemPrompt(cfg, dir)
 	if err != nil {
-		fmt.Println("⚠️ UTCP unavailable:", err)
+		return nil, err
+	}
+	utcp, err := BuildUTCP(ctx, opts...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "⚠️ UTCP unavailable:", err)
+	}
+	specs, err := parseModelChain(cfg.Models)
+	if err != nil {
+		return nil, err
 	}
 	memOpts := memory.DefaultOptions()
 	builder, err := adk.New(
 		ctx,
-		adk.WithDefaultSystemPrompt(VibeSystemPrompt),
+		adk.WithDefaultSystemPrompt(sysPrompt),
 		adk.WithModules(
 			modules.InMemoryMemoryModule(10000, memory.AutoEmbedder(), &memOpts),
-			adkmodules.NewModelModule("gemini", func(_ context.Context) (models.Agent, error) {
-				return models.NewGeminiLLM(ctx, "gemini-2.5-pro", "Universal code generator")
+			adkmodules.NewModelModule(specs[0].Provider, func(_ context.Context) (models.Agent, error) {
+				if cfg.Mock {
+					return recordingModel{mockLLM{}}, nil
+				}
+				chain, err := buildModelChain(ctx, specs, models.NewLLMProvider)
+				if err != nil {
+					return nil, err
+				}
+				return recordingModel{chain}, nil
 			}),
 			adkmodules.NewToolModule("essentials",
 				adkmodules.StaticToolProvider([]agent.Tool{&tools.EchoTool{}}, nil),
@@ -0,0 +1,93 @@
+package src
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestSystemPromptFileReachesAgent(t *testing.T) {
+	dir := t.TempDir()
+	writeFixture(t, dir, map[string]string{latticeDir + "/system.md": "HOUSE STYLE: wrap errors with %w.\n"})
+	t.Chdir(dir)
+	cfg := DefaultConfig()
+	cfg.Mock = true
+	lastPrompt = promptLog{}
+
+	ag, err := BuildAgent(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ag.Generate(context.Background(), "s", "add a health check"); err != nil {
+		t.Fatal(err)
+	}
+	p := lastPrompt.get()
+	if p == nil || !strings.Contains(p.prompt, "HOUSE STYLE: wrap errors with %w.") {
+		t.Fatalf("prompt sent to the model lacks the workspace system prompt: %+v", p)
+	}
+	if strings.Contains(p.prompt, "world-class coding assistant") {
+		t.Error("the built-in system prompt should be replaced")
+	}
+}
+
+func TestSystemPromptSources(t *testing.T) {
+	dir := t.TempDir()
+	cfg := DefaultConfig()
+
+	if got, err := systemPrompt(cfg, dir); err != nil || got != VibeSystemPrompt {
+		t.Errorf("without a file: %q, %v; want the built-in prompt", got, err)
+	}
+
+	writeFixture(t, dir, map[string]string{"team.md": "Use tabs."})
+	cfg.SystemPrompt = filepath.Join(dir, "team.md")
+	if got, _ := systemPrompt(cfg, dir); got != "Use tabs." {
+		t.Errorf("-system-prompt: %q", got)
+	}
+	cfg.SystemPromptAppend = true
+	if got, _ := systemPrompt(cfg, dir); got != VibeSystemPrompt+"\n\nUse tabs." {
+		t.Errorf("-system-prompt-append: %q", got)
+	}
+
+	cfg.SystemPrompt = filepath.Join(dir, "missing.md")
+	if _, err := systemPrompt(cfg, dir); err == nil {
+		t.Error("a missing -system-prompt file should be an error")
+	}
+}
+
+func TestConfirmedWorkspaceSystemPromptReachesAgent(t *testing.T) {
+	launch, root := t.TempDir(), t.TempDir()
+	writeFixture(t, root, map[string]string{latticeDir + "/system.md": "HOUSE STYLE: table-driven tests.\n"})
+	cfg := DefaultConfig()
+	cfg.Mock = true
+	ag, err := BuildAgentFor(context.Background(), cfg, launch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewModel(context.Background(), ag, launch, cfg)
+	m.syncInterval = 0
+	m.working = root
+	m.reloadDirs()
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("confirming a workspace with its own system prompt should rebuild the agent")
+	}
+	msg := cmd()
+	if batch, ok := msg.(tea.BatchMsg); ok && len(batch) == 1 {
+		msg = batch[0]()
+	}
+	m.Update(msg)
+	if m.agent == ag || m.agentDir != root {
+		t.Fatalf("agent not rebuilt for %s: agentDir = %q", root, m.agentDir)
+	}
+
+	lastPrompt = promptLog{}
+	if _, err := m.agent.Generate(context.Background(), "s", "add a test"); err != nil {
+		t.Fatal(err)
+	}
+	if p := lastPrompt.get(); p == nil || !strings.Contains(p.prompt, "HOUSE STYLE: table-driven tests.") {
+		t.Errorf("prompt sent to the model lacks the workspace system prompt: %+v", p)
+	}
+}
@@ -0,0 +1,49 @@
+// Package atomicfile replaces files without ever leaving a partial write
+// behind: data goes to a temporary file in the target's directory, which is
+// then renamed over the target.
+package atomicfile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// write copies data into f; tests replace it to simulate an interrupted write.
+var write = func(f *os.File, data []byte) error {
+	_, err := f.Write(data)
+	return err
+}
+
+// WriteFile is a drop-in replacement for os.WriteFile. If path already
+// exists its permissions are kept; otherwise perm is used. On any error the
+// original file is left untouched.
+func WriteFile(path string, data []byte, perm os.FileMode) (err error) {
+	if info, statErr := os.Stat(path); statErr == nil {
+		perm = info.Mode().Perm()
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmp)
+		}
+	}()
+
+	if err := write(f, data); err != nil {
+		return errors.Join(err, f.Close())
+	}
+	if err := f.Sync(); err != nil {
+		return errors.Join(err, f.Close())
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
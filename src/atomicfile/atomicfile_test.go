@@ -0,0 +1,52 @@
+package atomicfile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileReplacesContent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.go")
+	if err := os.WriteFile(path, []byte("old"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFile(path, []byte("new"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := os.ReadFile(path)
+	if string(got) != "new" {
+		t.Fatalf("content = %q", got)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o600 {
+		t.Fatalf("mode = %v, want the original 0600", info.Mode().Perm())
+	}
+}
+
+func TestInterruptedWriteKeepsOriginal(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(path, []byte("package main // original\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	orig := write
+	defer func() { write = orig }()
+	write = func(f *os.File, data []byte) error {
+		_, _ = f.Write(data[:len(data)/2]) // the process "dies" half-way
+		return errors.New("interrupted")
+	}
+
+	if err := WriteFile(path, []byte("package main // a much longer replacement\n"), 0o644); err == nil {
+		t.Fatal("expected the simulated interruption to fail the write")
+	}
+	got, _ := os.ReadFile(path)
+	if string(got) != "package main // original\n" {
+		t.Fatalf("original damaged: %q", got)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Fatalf("temporary file left behind: %v", entries)
+	}
+}
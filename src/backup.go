@@ -0,0 +1,78 @@
+package src
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// backupStamp sorts lexically in time order, so pruning can sort names.
+const backupStamp = "20060102-150405.000"
+
+func backupDir(root string) string {
+	return filepath.Join(root, latticeDir, "backups")
+}
+
+// backupFile copies the current content of rel, if any, to
+// .lattice/backups/<rel>.<timestamp> and prunes all but the newest keep
+// backups of that file. It is a no-op when -backup is off.
+func backupFile(cfg *Config, root, rel string, now time.Time) error {
+	if !cfg.Backup {
+		return nil
+	}
+	data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(rel)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	base := filepath.Join(backupDir(root), filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(base), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(base+"."+now.Format(backupStamp), data, 0o644); err != nil {
+		return err
+	}
+	return pruneBackups(base, cfg.BackupKeep)
+}
+
+// pruneBackups removes all but the newest keep backups of base.
+func pruneBackups(base string, keep int) error {
+	backups := fileBackups(base)
+	if keep < 1 {
+		keep = 1
+	}
+	for len(backups) > keep {
+		if err := os.Remove(backups[0]); err != nil {
+			return err
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
+
+// fileBackups lists the backups of base, oldest first.
+func fileBackups(base string) []string {
+	entries, err := os.ReadDir(filepath.Dir(base))
+	if err != nil {
+		return nil
+	}
+	prefix := filepath.Base(base) + "."
+	var out []string
+	for _, e := range entries {
+		name := e.Name()
+		stamp, ok := strings.CutPrefix(name, prefix)
+		if !ok || e.IsDir() {
+			continue
+		}
+		if _, err := time.Parse(backupStamp, stamp); err != nil {
+			continue // e.g. main.go.orig next to main.go's backups
+		}
+		out = append(out, filepath.Join(filepath.Dir(base), name))
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,50 @@
+package src
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestOverwriteCreatesBackup(t *testing.T) {
+	GlobalChanges = NewChangeTracker()
+	root := t.TempDir()
+	if _, err := WriteCodeBlocks(root, fence("app.go", "package app // v1"), WriteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := fileBackups(filepath.Join(backupDir(root), "app.go")); len(got) != 0 {
+		t.Fatalf("creating a file should not back anything up: %v", got)
+	}
+	if _, err := WriteCodeBlocks(root, fence("app.go", "package app // v2"), WriteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	backups := fileBackups(filepath.Join(backupDir(root), "app.go"))
+	if len(backups) != 1 {
+		t.Fatalf("backups = %v, want one", backups)
+	}
+	if got := readFile(t, backups[0]); got != "package app // v1" {
+		t.Fatalf("backup holds %q, want the prior content", got)
+	}
+}
+
+func TestBackupsArePruned(t *testing.T) {
+	root := t.TempDir()
+	writeFixture(t, root, map[string]string{"pkg/a.go": "v0", ".lattice/backups/pkg/a.go.orig": "not a backup"})
+	cfg := DefaultConfig()
+	cfg.BackupKeep = 2
+
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i := 0; i < 4; i++ {
+		writeFixture(t, root, map[string]string{"pkg/a.go": string(rune('a' + i))})
+		if err := backupFile(cfg, root, "pkg/a.go", start.Add(time.Duration(i)*time.Second)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	backups := fileBackups(filepath.Join(backupDir(root), "pkg", "a.go"))
+	if len(backups) != 2 {
+		t.Fatalf("backups = %v, want the newest two", backups)
+	}
+	if readFile(t, backups[0]) != "c" || readFile(t, backups[1]) != "d" {
+		t.Fatalf("kept the wrong backups: %v", backups)
+	}
+}
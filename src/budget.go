@@ -0,0 +1,137 @@
+package src
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Protocol-Lattice/go-agent/src/models"
+
+	"github.com/Protocol-Lattice/lattice-code/src/ui"
+)
+
+// requestSize estimates the bytes a generate call sends.
+func requestSize(prompt string, files []models.File) int64 {
+	n := int64(len(prompt))
+	for _, f := range files {
+		n += int64(len(f.Name) + len(f.Data))
+	}
+	return n
+}
+
+// attachmentRelevance scores how directly the prompt refers to a file: the
+// full path counts most, then its base name, then each directory on the way.
+func attachmentRelevance(prompt string, name string) int {
+	prompt = strings.ToLower(prompt)
+	rel := strings.ToLower(filepath.ToSlash(name))
+	score := 0
+	if strings.Contains(prompt, rel) {
+		score += 4
+	}
+	base := filepath.Base(rel)
+	if strings.Contains(prompt, base) {
+		score += 2
+	} else if stem := strings.TrimSuffix(base, filepath.Ext(base)); len(stem) >= 3 && strings.Contains(prompt, stem) {
+		score++
+	}
+	for _, dir := range strings.Split(filepath.Dir(rel), "/") {
+		if len(dir) >= 3 && dir != "." && strings.Contains(prompt, dir) {
+			score++
+		}
+	}
+	return score
+}
+
+// pruneAttachments drops the least relevant files (largest first among equals)
+// until the request fits limit. Kept files stay in their original order.
+// Files named in keep (pinned ones) are never dropped, even if the request
+// then stays over limit.
+func pruneAttachments(prompt string, files []models.File, limit int64, keep map[string]bool) ([]models.File, []string) {
+	size := requestSize(prompt, files)
+	if limit <= 0 || size <= limit {
+		return files, nil
+	}
+
+	order := make([]int, len(files))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		fa, fb := files[order[a]], files[order[b]]
+		ra, rb := attachmentRelevance(prompt, fa.Name), attachmentRelevance(prompt, fb.Name)
+		if ra != rb {
+			return ra < rb
+		}
+		return len(fa.Data) > len(fb.Data)
+	})
+
+	drop := map[int]bool{}
+	var dropped []string
+	for _, i := range order {
+		if size <= limit {
+			break
+		}
+		if keep[files[i].Name] {
+			continue
+		}
+		drop[i] = true
+		dropped = append(dropped, files[i].Name)
+		size -= int64(len(files[i].Name) + len(files[i].Data))
+	}
+
+	kept := make([]models.File, 0, len(files)-len(drop))
+	for i, f := range files {
+		if !drop[i] {
+			kept = append(kept, f)
+		}
+	}
+	return kept, dropped
+}
+
+// isRequestTooLarge guesses whether a provider rejected a call for its size.
+func isRequestTooLarge(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, hint := range []string{"too large", "too long", "exceeds", "maximum context", "413", "token limit", "request payload size"} {
+		if strings.Contains(msg, hint) {
+			return true
+		}
+	}
+	return false
+}
+
+// nearLimit is the share of a cap past which a request is reported as
+// close to it.
+const nearLimit = 0.9
+
+// requestPreview describes a request before it is sent: its files, size and
+// estimated prompt tokens, and a warning naming each cap it is at or near,
+// so a big, slow call does not come as a surprise.
+func (c *Config) requestPreview(prompt string, files []models.File) string {
+	lim := c.contextLimits()
+	size := requestSize(prompt, files)
+	var fileBytes int64
+	for _, f := range files {
+		fileBytes += int64(len(f.Data))
+	}
+	preview := fmt.Sprintf("📤 Sending %d file(s) (%s, ~%s tokens) to the model…",
+		len(files), HumanSize(size), ui.HumanCount(estimateUsage(prompt, files, "").PromptTokens))
+
+	var near []string
+	if lim.maxFiles > 0 && float64(len(files)) >= nearLimit*float64(lim.maxFiles) {
+		near = append(near, fmt.Sprintf("%d of %d files", len(files), lim.maxFiles))
+	}
+	if lim.maxTotalBytes > 0 && float64(fileBytes) >= nearLimit*float64(lim.maxTotalBytes) {
+		near = append(near, fmt.Sprintf("%s of %s context", HumanSize(fileBytes), HumanSize(lim.maxTotalBytes)))
+	}
+	if c.MaxRequestBytes > 0 && float64(size) >= nearLimit*float64(c.MaxRequestBytes) {
+		near = append(near, fmt.Sprintf("%s of %s per request", HumanSize(size), HumanSize(c.MaxRequestBytes)))
+	}
+	if len(near) > 0 {
+		preview += " ⚠️ near the limits: " + strings.Join(near, ", ")
+	}
+	return preview
+}
@@ -13,15 +13,16 @@ import (
 
 // ChangeTracker tracks file contents between prompts and computes unified diffs.
 type ChangeTracker struct {
-	mu    sync.Mutex
-	prev  map[string][]byte
-	seqno uint64
+	mu      sync.Mutex
+	prev    map[string][]byte
+	written map[string]bool // paths recorded since the tracker was created
+	seqno   uint64
 }
 
 var GlobalChanges = NewChangeTracker()
 
 func NewChangeTracker() *ChangeTracker {
-	return &ChangeTracker{prev: make(map[string][]byte)}
+	return &ChangeTracker{prev: make(map[string][]byte), written: make(map[string]bool)}
 }
 
 // BeginPrompt marks a new generation turn.
@@ -53,6 +54,7 @@ func (t *ChangeTracker) Snapshot(root, rel string) []byte {
 func (t *ChangeTracker) Record(rel string, data []byte) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
+	t.written[filepath.ToSlash(rel)] = true
 	if data == nil {
 		delete(t.prev, rel)
 		return
@@ -60,6 +62,18 @@ func (t *ChangeTracker) Record(rel string, data []byte) {
 	t.prev[filepath.ToSlash(rel)] = append([]byte(nil), data...)
 }
 
+// Written returns the slash paths recorded this session, i.e. the files the
+// tool wrote, restored or removed.
+func (t *ChangeTracker) Written() map[string]bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make(map[string]bool, len(t.written))
+	for p := range t.written {
+		out[p] = true
+	}
+	return out
+}
+
 // edit represents a single line change in a diff.
 type edit struct {
 	tag string // " " same, "+" add, "-" del
@@ -98,56 +112,14 @@ const (
 	colorBold  = "\033[1m"
 )
 
-// DiffPretty prints a colorized git-style unified diff.
-func (t *ChangeTracker) DiffPretty(rel string, oldB, newB []byte) string {
+// DiffPretty prints a colorized git-style unified diff. When oldB and newB
+// together exceed maxBytes (and maxBytes is positive) it only reports how
+// much changed.
+func (t *ChangeTracker) DiffPretty(rel string, oldB, newB []byte, maxBytes int64) string {
 	if bytes.Equal(oldB, newB) {
 		return ""
 	}
 
-	oldLines := splitLines(oldB)
-	newLines := splitLines(newB)
-	n, m := len(oldLines), len(newLines)
-
-	// Build LCS table.
-	lcs := make([][]int, n+1)
-	for i := range lcs {
-		lcs[i] = make([]int, m+1)
-	}
-	for i := n - 1; i >= 0; i-- {
-		for j := m - 1; j >= 0; j-- {
-			if oldLines[i] == newLines[j] {
-				lcs[i][j] = lcs[i+1][j+1] + 1
-			} else if lcs[i+1][j] >= lcs[i][j+1] {
-				lcs[i][j] = lcs[i+1][j]
-			} else {
-				lcs[i][j] = lcs[i][j+1]
-			}
-		}
-	}
-
-	// Collect edits.
-	var seq []edit
-	i, j := 0, 0
-	for i < n && j < m {
-		if oldLines[i] == newLines[j] {
-			seq = append(seq, edit{" ", oldLines[i]})
-			i++
-			j++
-		} else if lcs[i+1][j] >= lcs[i][j+1] {
-			seq = append(seq, edit{"-", oldLines[i]})
-			i++
-		} else {
-			seq = append(seq, edit{"+", newLines[j]})
-			j++
-		}
-	}
-	for ; i < n; i++ {
-		seq = append(seq, edit{"-", oldLines[i]})
-	}
-	for ; j < m; j++ {
-		seq = append(seq, edit{"+", newLines[j]})
-	}
-
 	// Diff header like Git.
 	oldHash := shortSHA(oldB)
 	newHash := shortSHA(newB)
@@ -156,9 +128,17 @@ func (t *ChangeTracker) DiffPretty(rel string, oldB, newB []byte) string {
 	// Write header with newlines
 	out.WriteString(fmt.Sprintf("%sdiff --git a/%s b/%s%s\n", colorBold+colorCyan, rel, rel, colorReset))
 	out.WriteString(fmt.Sprintf("index %s..%s 100644\n", oldHash, newHash))
+
+	if limit := maxBytes; limit > 0 && int64(len(oldB)+len(newB)) > limit {
+		out.WriteString(fmt.Sprintf("%sfile too large to diff, %d bytes changed%s\n", colorGray, changedBytes(oldB, newB), colorReset))
+		return out.String()
+	}
+
 	out.WriteString(fmt.Sprintf("%s--- a/%s%s\n", colorCyan, rel, colorReset))
 	out.WriteString(fmt.Sprintf("%s+++ b/%s%s\n", colorCyan, rel, colorReset))
 
+	seq := diffLines(splitLines(oldB), splitLines(newB))
+
 	// Context
 	const ctx = 3
 	var hunk []edit
@@ -225,6 +205,20 @@ func (t *ChangeTracker) DiffPretty(rel string, oldB, newB []byte) string {
 	return out.String()
 }
 
+// changedBytes is the size of the span that differs between a and b once
+// their common prefix and suffix are set aside, on the larger side.
+func changedBytes(a, b []byte) int {
+	p := 0
+	for p < len(a) && p < len(b) && a[p] == b[p] {
+		p++
+	}
+	s := 0
+	for s < len(a)-p && s < len(b)-p && a[len(a)-1-s] == b[len(b)-1-s] {
+		s++
+	}
+	return max(len(a), len(b)) - p - s
+}
+
 // shortSHA returns a short SHA1-like index label for diff headers.
 func shortSHA(b []byte) string {
 	h := sha1.Sum(b)
@@ -8,30 +8,52 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
+
+	"github.com/Protocol-Lattice/lattice-code/src/atomicfile"
 )
 
+// WriteOptions controls how WriteCodeBlocks treats existing files.
+type WriteOptions struct {
+	// Config supplies the limits, backups and path hints for the writes; nil
+	// means DefaultConfig().
+	Config *Config
+	// Force overwrites files the agent did not create without asking, as
+	// does Config.Force.
+	Force bool
+	// OnAction, when set, is called as soon as each fence has been handled,
+	// so callers can report progress before the whole response is written.
+	OnAction func(FileAction)
+}
+
 // WriteCodeBlocks writes fenced code blocks and prints per-prompt diffs.
-func WriteCodeBlocks(root, response string) ([]FileAction, error) {
+// Existing files missing from the workspace manifest are not overwritten unless
+// opts.Force is set, and new files past -max-new-files are not created; both
+// are returned as "pending" actions for ApplyPendingWrites. With -dry-run
+// nothing is written: each change is returned as a "dry-run" action with its
+// diff.
+// Each fence is written, and recorded in the manifest, as soon as it is parsed,
+// so earlier files survive a failure later in the response. A fence holding
+// several path-marked files is written as those files.
+func WriteCodeBlocks(root, response string, opts WriteOptions) ([]FileAction, error) {
+	cfg := opts.Config.orDefault()
 	GlobalChanges.BeginPrompt()
 	var actions []FileAction
-
-	blocks := extractCodeBlocks(response)
-	if len(blocks) == 0 {
-		return []FileAction{{Action: "info", Message: "No code blocks detected."}}, nil
+	emit := func(a FileAction) {
+		actions = append(actions, a)
+		if opts.OnAction != nil {
+			opts.OnAction(a)
+		}
 	}
 
-	for i, b := range blocks {
-		path, body := extractPathAndStrip(b.lang, b.body)
-		if path == "" {
-			ext := strings.TrimPrefix(extFromLang(b.lang), ".")
-			path = fmt.Sprintf("generated/file_%d.%s", i+1, ext)
-		}
+	managed := loadManifest(root)
+	var written []string
+	created, held, previewed := 0, 0, 0
+	writeFile := func(path, body string) {
 		abs := filepath.Join(root, filepath.FromSlash(path))
-		_ = os.MkdirAll(filepath.Dir(abs), 0o755)
-
 		newB := []byte(body)
 		oldB := GlobalChanges.Snapshot(root, path)
-		diff := GlobalChanges.DiffPretty(path, oldB, newB)
+		diff := GlobalChanges.DiffPretty(path, oldB, newB, cfg.MaxDiffBytes)
 		status := "created"
 		if oldB != nil {
 			if bytes.Equal(oldB, newB) {
@@ -40,20 +62,134 @@ func WriteCodeBlocks(root, response string) ([]FileAction, error) {
 				status = "updated"
 			}
 		}
+		if cfg.DryRun && status != "unchanged" {
+			previewed++
+			emit(FileAction{Path: path, Action: "dry-run", Message: "would be " + status, Diff: diff})
+			return
+		}
+		if status == "updated" && !opts.Force && !cfg.Force && !managed[path] {
+			emit(FileAction{Path: path, Action: "pending", Message: "not created by the agent; confirm to overwrite", Diff: diff, body: newB})
+			return
+		}
+		if status == "created" && cfg.MaxNewFiles > 0 && created >= cfg.MaxNewFiles {
+			held++
+			emit(FileAction{Path: path, Action: "pending", Message: fmt.Sprintf("over the limit of %d new files per run; confirm to create", cfg.MaxNewFiles), Diff: diff, body: newB})
+			return
+		}
+		if status == "updated" {
+			if err := backupFile(cfg, root, path, time.Now()); err != nil {
+				emit(FileAction{Path: path, Action: "error", Message: fmt.Sprintf("backup failed, not overwriting: %v", err), Err: err})
+				return
+			}
+		}
 		if status != "unchanged" {
-			if err := os.WriteFile(abs, newB, 0o644); err != nil {
-				actions = append(actions, FileAction{Path: path, Action: "error", Message: err.Error(), Err: err})
-				continue
+			_ = os.MkdirAll(filepath.Dir(abs), 0o755)
+			if err := atomicfile.WriteFile(abs, newB, 0o644); err != nil {
+				emit(FileAction{Path: path, Action: "error", Message: err.Error(), Err: err})
+				return
+			}
+			_ = recordManaged(root, []string{path})
+			managed[path] = true
+			written = append(written, path)
+			if status == "created" {
+				created++
 			}
 		}
 		GlobalChanges.Record(path, newB)
 
-		actions = append(actions, FileAction{Path: path, Action: "saved", Message: status, Diff: diff})
+		emit(FileAction{Path: path, Action: "saved", Message: status, Diff: diff})
+	}
+
+	rest := response
+	for i := 0; ; i++ {
+		b, next, ok := nextCodeBlock(rest)
+		if !ok {
+			break
+		}
+		rest = next
+
+		for _, f := range splitPathHints(cfg, b.body) {
+			if f.path == "" {
+				ext := strings.TrimPrefix(extFromLang(b.lang), ".")
+				f.path = fmt.Sprintf("generated/file_%d.%s", i+1, ext)
+			}
+			writeFile(f.path, f.body)
+		}
+	}
+	if previewed > 0 {
+		emit(FileAction{Action: "info", Message: fmt.Sprintf("Dry run (-dry-run): %d file(s) shown but not written.", previewed)})
+	}
+	if held > 0 {
+		emit(FileAction{Action: "info", Message: fmt.Sprintf("Stopped creating files at the limit of %d per run (-max-new-files); %d more await confirmation.", cfg.MaxNewFiles, held)})
+	}
+	for _, a := range normalizeWritten(cfg, root, written) {
+		emit(a)
+	}
+	if len(actions) == 0 {
+		return []FileAction{{Action: "info", Message: "No code blocks detected."}}, nil
 	}
 
 	return actions, nil
 }
 
+// ApplyPendingWrites writes the confirmed "pending" actions returned by WriteCodeBlocks.
+func ApplyPendingWrites(root string, pending []FileAction, cfg *Config) []FileAction {
+	cfg = cfg.orDefault()
+	var actions []FileAction
+	var written []string
+	for _, p := range pending {
+		if p.Action != "pending" {
+			continue
+		}
+		abs := filepath.Join(root, filepath.FromSlash(p.Path))
+		status := "updated"
+		if _, err := os.Stat(abs); os.IsNotExist(err) {
+			status = "created"
+			_ = os.MkdirAll(filepath.Dir(abs), 0o755)
+		}
+		if err := backupFile(cfg, root, p.Path, time.Now()); err != nil {
+			actions = append(actions, FileAction{Path: p.Path, Action: "error", Message: fmt.Sprintf("backup failed, not overwriting: %v", err), Err: err})
+			continue
+		}
+		if err := atomicfile.WriteFile(abs, p.body, 0o644); err != nil {
+			actions = append(actions, FileAction{Path: p.Path, Action: "error", Message: err.Error(), Err: err})
+			continue
+		}
+		GlobalChanges.Record(p.Path, p.body)
+		written = append(written, p.Path)
+		actions = append(actions, FileAction{Path: p.Path, Action: "saved", Message: status, Diff: p.Diff})
+	}
+	_ = recordManaged(root, written)
+	return append(actions, normalizeWritten(cfg, root, written)...)
+}
+
+// normalizeWritten fixes the imports of the files just written, and only
+// those, so files the user never asked to change are left alone. Each file
+// it rewrote is reported as a "normalized" action carrying the diff, and the
+// change tracker picks up the new content.
+func normalizeWritten(cfg *Config, root string, written []string) []FileAction {
+	if len(written) == 0 {
+		return nil
+	}
+	before := map[string][]byte{}
+	for _, rel := range written {
+		abs := filepath.Join(root, filepath.FromSlash(rel))
+		before[abs], _ = os.ReadFile(abs)
+	}
+	changed, _ := NormalizeImports(root, written)
+	var actions []FileAction
+	for _, rel := range changed {
+		abs := filepath.Join(root, filepath.FromSlash(rel))
+		after, err := os.ReadFile(abs)
+		if err != nil {
+			continue
+		}
+		actions = append(actions, FileAction{Path: rel, Action: "normalized", Message: "imports normalized", Diff: GlobalChanges.DiffPretty(rel, before[abs], after, cfg.MaxDiffBytes)})
+		GlobalChanges.Record(rel, after)
+	}
+	return actions
+}
+
 type codeBlock struct {
 	lang string
 	body string
@@ -63,20 +199,21 @@ var fenceRe = regexp.MustCompile("(?s)```([a-zA-Z0-9_+\\.-]*)\\s*\\n(.*?)\\n```"
 
 func extractCodeBlocks(s string) []codeBlock {
 	var out []codeBlock
-	for _, m := range fenceRe.FindAllStringSubmatch(s, -1) {
-		out = append(out, codeBlock{lang: strings.ToLower(m[1]), body: m[2]})
+	for {
+		b, rest, ok := nextCodeBlock(s)
+		if !ok {
+			return out
+		}
+		out = append(out, b)
+		s = rest
 	}
-	return out
 }
 
-func extractPathAndStrip(lang, code string) (string, string) {
-	lines := strings.Split(code, "\n")
-	if len(lines) == 0 {
-		return "", code
-	}
-	re := regexp.MustCompile(`(?i)^\s*(?:\/\/|#|--|;|@|<!--)\s*path:?\s*([^\s>]+)`)
-	if m := re.FindStringSubmatch(lines[0]); len(m) > 1 {
-		return filepath.ToSlash(strings.TrimSpace(m[1])), strings.Join(lines[1:], "\n")
+// nextCodeBlock parses the first fence in s and returns the text after it.
+func nextCodeBlock(s string) (codeBlock, string, bool) {
+	m := fenceRe.FindStringSubmatchIndex(s)
+	if m == nil {
+		return codeBlock{}, s, false
 	}
-	return "", code
+	return codeBlock{lang: strings.ToLower(s[m[2]:m[3]]), body: s[m[4]:m[5]]}, s[m[1]:], true
 }
@@ -0,0 +1,258 @@
+package src
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func fence(path, body string) string {
+	return "```go\n// path: " + path + "\n" + body + "\n```\n"
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestWriteCodeBlocksGuardsUnmanagedFiles(t *testing.T) {
+	GlobalChanges = NewChangeTracker()
+	root := t.TempDir()
+	writeFixture(t, root, map[string]string{"main.go": "package main // hand-written"})
+
+	actions, err := WriteCodeBlocks(root, fence("main.go", "package main // generated"), WriteOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actions) != 1 || actions[0].Action != "pending" {
+		t.Fatalf("actions = %+v; want one pending action", actions)
+	}
+	if actions[0].Diff == "" {
+		t.Error("pending action should carry a diff for review")
+	}
+	if got := readFile(t, filepath.Join(root, "main.go")); got != "package main // hand-written" {
+		t.Errorf("unmanaged file was overwritten: %q", got)
+	}
+
+	applied := ApplyPendingWrites(root, actions, nil)
+	if len(applied) != 1 || applied[0].Action != "saved" {
+		t.Fatalf("applied = %+v; want one saved action", applied)
+	}
+	if got := readFile(t, filepath.Join(root, "main.go")); got != "package main // generated" {
+		t.Errorf("confirmed write not applied: %q", got)
+	}
+	if !loadManifest(root)["main.go"] {
+		t.Error("confirmed file should be recorded in the manifest")
+	}
+}
+
+func TestWriteCodeBlocksOverwritesManagedAndForced(t *testing.T) {
+	GlobalChanges = NewChangeTracker()
+	root := t.TempDir()
+
+	// Files created by the agent become managed and can be updated freely.
+	if _, err := WriteCodeBlocks(root, fence("app.go", "package app // v1"), WriteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	actions, _ := WriteCodeBlocks(root, fence("app.go", "package app // v2"), WriteOptions{})
+	if actions[0].Action != "saved" || actions[0].Message != "updated" {
+		t.Errorf("managed update = %+v; want saved/updated", actions[0])
+	}
+
+	writeFixture(t, root, map[string]string{"user.go": "package app // mine"})
+	actions, _ = WriteCodeBlocks(root, fence("user.go", "package app // theirs"), WriteOptions{Force: true})
+	if actions[0].Action != "saved" {
+		t.Errorf("forced write = %+v; want saved", actions[0])
+	}
+	if got := readFile(t, filepath.Join(root, "user.go")); got != "package app // theirs" {
+		t.Errorf("forced write not applied: %q", got)
+	}
+}
+
+func TestWriteCodeBlocksWritesIncrementally(t *testing.T) {
+	GlobalChanges = NewChangeTracker()
+	root := t.TempDir()
+	response := fence("a.go", "package a") + "some prose\n" + fence("b.go", "package b")
+
+	var seen []string
+	_, err := WriteCodeBlocks(root, response, WriteOptions{OnAction: func(a FileAction) {
+		seen = append(seen, a.Path)
+		if _, err := os.Stat(filepath.Join(root, a.Path)); err != nil {
+			t.Errorf("%s not on disk when reported: %v", a.Path, err)
+		}
+		if a.Path == "a.go" {
+			if _, err := os.Stat(filepath.Join(root, "b.go")); err == nil {
+				t.Error("b.go written before a.go was reported")
+			}
+			if !loadManifest(root)["a.go"] {
+				t.Error("a.go should be in the manifest as soon as it is written")
+			}
+		}
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != 2 || seen[0] != "a.go" || seen[1] != "b.go" {
+		t.Errorf("callback order = %v", seen)
+	}
+}
+
+func TestWriteCodeBlocksNormalizesOnlyWrittenFiles(t *testing.T) {
+	GlobalChanges = NewChangeTracker()
+	root := t.TempDir()
+	old := "package main\n\nimport \"src/ui\"\n\nfunc main() { ui.Render() }\n"
+	writeFixture(t, root, map[string]string{
+		"go.mod":          "module example.com/app\n",
+		"src/ui/ui.go":    "package ui\n\nfunc Render() {}\n",
+		"cmd/old/main.go": old,
+	})
+
+	resp := fence("cmd/new/main.go", "package main\n\nimport \"src/ui\"\n\nfunc main() { ui.Render() }")
+	actions, err := WriteCodeBlocks(root, resp, WriteOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actions) != 2 || actions[1].Action != "normalized" || actions[1].Path != "cmd/new/main.go" || actions[1].Diff == "" {
+		t.Errorf("actions = %+v; want the save followed by a normalized action with a diff", actions)
+	}
+	if got := readFile(t, filepath.Join(root, "cmd/new/main.go")); !strings.Contains(got, `"example.com/app/src/ui"`) {
+		t.Errorf("written file not normalized:\n%s", got)
+	}
+	if got := readFile(t, filepath.Join(root, "cmd/old/main.go")); got != old {
+		t.Errorf("pre-existing file was rewritten:\n%s", got)
+	}
+}
+
+func TestCustomPathConventions(t *testing.T) {
+	cfg := DefaultConfig()
+	if err := cfg.set("path-keywords", "file, filename"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.set("path-pattern", `^\{"file":\s*"([^"]+)"\}$`); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.set("path-pattern", `no group`); err == nil {
+		t.Error("a pattern without a group should be rejected")
+	}
+
+	GlobalChanges = NewChangeTracker()
+	root := t.TempDir()
+	response := "```go\n// file: notes/todo.go\npackage notes\n```\n" +
+		"```python\n# filename: tools/run.py\nprint(1)\n```\n" +
+		"```json\n{\"file\": \"config/app.json\"}\n{\"debug\": true}\n```\n"
+	if _, err := WriteCodeBlocks(root, response, WriteOptions{Config: cfg}); err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]string{
+		"notes/todo.go":   "package notes",
+		"tools/run.py":    "print(1)",
+		"config/app.json": `{"debug": true}`,
+	} {
+		if got := strings.TrimSpace(readFile(t, filepath.Join(root, path))); got != want {
+			t.Errorf("%s = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestWriteCodeBlocksSplitsMultiFileFence(t *testing.T) {
+	GlobalChanges = NewChangeTracker()
+	root := t.TempDir()
+	response := "```go\n// path: a/a.go\npackage a\n\nfunc A() {\n\t// path: not/a/file.go\n}\n\n// path segments are joined below\n// @path x\n\n// path: b/b.go\npackage b\n```\n"
+	actions, err := WriteCodeBlocks(root, response, WriteOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved []string
+	for _, a := range actions {
+		if a.Action == "saved" {
+			saved = append(saved, a.Path)
+		}
+	}
+	if strings.Join(saved, " ") != "a/a.go b/b.go" {
+		t.Fatalf("saved = %v, actions = %+v", saved, actions)
+	}
+	if got := readFile(t, filepath.Join(root, "a/a.go")); got != "package a\n\nfunc A() {\n\t// path: not/a/file.go\n}\n\n// path segments are joined below\n// @path x" {
+		t.Errorf("a/a.go = %q", got)
+	}
+	if got := readFile(t, filepath.Join(root, "b/b.go")); got != "package b" {
+		t.Errorf("b/b.go = %q", got)
+	}
+}
+
+func TestWriteCodeBlocksHoldsNewFilesPastCap(t *testing.T) {
+	GlobalChanges = NewChangeTracker()
+	root := t.TempDir()
+	cfg := DefaultConfig()
+	cfg.MaxNewFiles = 2
+
+	resp := fence("a.go", "package a") + fence("b.go", "package a") + fence("pkg/c.go", "package pkg")
+	actions, err := WriteCodeBlocks(root, resp, WriteOptions{Config: cfg, Force: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved, pending []FileAction
+	info := false
+	for _, a := range actions {
+		switch a.Action {
+		case "saved":
+			saved = append(saved, a)
+		case "pending":
+			pending = append(pending, a)
+		case "info":
+			info = info || strings.Contains(a.Message, "limit of 2")
+		}
+	}
+	if len(saved) != 2 || len(pending) != 1 || pending[0].Path != "pkg/c.go" {
+		t.Fatalf("actions = %+v; want a.go and b.go saved and pkg/c.go pending", actions)
+	}
+	if !info {
+		t.Errorf("actions = %+v; want a report that the cap was hit", actions)
+	}
+	if _, err := os.Stat(filepath.Join(root, "pkg")); !os.IsNotExist(err) {
+		t.Errorf("held file's directory was created: %v", err)
+	}
+
+	applied := ApplyPendingWrites(root, pending, cfg)
+	if len(applied) != 1 || applied[0].Message != "created" {
+		t.Fatalf("applied = %+v; want pkg/c.go created", applied)
+	}
+	if got := readFile(t, filepath.Join(root, "pkg", "c.go")); got != "package pkg" {
+		t.Errorf("pkg/c.go = %q", got)
+	}
+	if !loadManifest(root)["pkg/c.go"] {
+		t.Error("confirmed file should be recorded in the manifest")
+	}
+}
+
+func TestWriteCodeBlocksDryRunWritesNothing(t *testing.T) {
+	GlobalChanges = NewChangeTracker()
+	root := t.TempDir()
+	writeFixture(t, root, map[string]string{"app.go": "package app // v1\n"})
+	cfg := DefaultConfig()
+	cfg.DryRun = true
+
+	actions, err := WriteCodeBlocks(root, fence("app.go", "package app // v2")+fence("new.go", "package app"), WriteOptions{Config: cfg, Force: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var previewed []string
+	for _, a := range actions {
+		if a.Action == "dry-run" && a.Diff != "" {
+			previewed = append(previewed, a.Path+" "+a.Message)
+		}
+	}
+	if len(previewed) != 2 || previewed[0] != "app.go would be updated" || previewed[1] != "new.go would be created" {
+		t.Errorf("dry-run actions = %v", previewed)
+	}
+	if got := readFile(t, filepath.Join(root, "app.go")); got != "package app // v1\n" {
+		t.Errorf("app.go was rewritten: %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(root, "new.go")); !os.IsNotExist(err) {
+		t.Errorf("new.go was created: %v", err)
+	}
+}
@@ -11,6 +11,8 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+
+	"github.com/Protocol-Lattice/lattice-code/src/atomicfile"
 )
 
 // checksum computes a SHA256 hash of the data for content comparison
@@ -28,116 +30,20 @@ type CodeFence struct {
 // extFromLang maps language identifiers to file extensions
 func extFromLang(lang string) string {
 	lang = strings.ToLower(strings.TrimSpace(lang))
-
-	switch lang {
-	case "go", "golang":
-		return ".go"
-	case "python", "py":
-		return ".py"
-	case "javascript", "js", "node":
-		return ".js"
-	case "typescript", "ts":
-		return ".ts"
-	case "jsx":
-		return ".jsx"
-	case "tsx":
-		return ".tsx"
-	case "rust", "rs":
-		return ".rs"
-	case "java":
-		return ".java"
-	case "c":
-		return ".c"
-	case "cpp", "c++", "cc", "cxx":
-		return ".cpp"
-	case "h", "hpp", "hh", "hxx":
-		return ".h"
-	case "csharp", "c#", "cs":
-		return ".cs"
-	case "kotlin", "kt":
-		return ".kt"
-	case "swift":
-		return ".swift"
-	case "ruby", "rb":
-		return ".rb"
-	case "php":
-		return ".php"
-	case "scala":
-		return ".scala"
-	case "dart":
-		return ".dart"
-	case "lua":
-		return ".lua"
-	case "r":
-		return ".r"
-	case "shell", "bash", "sh", "zsh":
-		return ".sh"
-	case "sql":
-		return ".sql"
-	case "html", "xml", "svg":
-		return "." + lang
-	case "css", "scss", "sass", "less":
-		return ".css"
-	case "json":
-		return ".json"
-	case "yaml", "yml":
-		return ".yaml"
-	case "toml":
-		return ".toml"
-	case "md", "markdown":
-		return ".md"
-	case "dockerfile":
-		return ".dockerfile"
-	case "makefile":
-		return "Makefile"
-	case "":
-		return ".txt"
-	default:
-		// Last resort: treat unknown tag as an extension if it looks valid
-		if len(lang) <= 6 && regexp.MustCompile(`^[a-z0-9.+-]+$`).MatchString(lang) {
-			if !strings.HasPrefix(lang, ".") {
-				return "." + lang
-			}
-			return lang
-		}
-		return ".txt"
+	if ext, ok := extByLang[lang]; ok {
+		return ext
 	}
-}
-
-// pathRe matches path comments in various formats:
-// - // path: some/path.go
-// - # path: some/path.py
-// - -- path: some/path.sql
-// - <!-- path: some/path.html -->
-var pathRe = regexp.MustCompile(`(?m)^(?:(?://|#|--)\s*path:\s*([^\s]+)|<!--\s*path:\s*([^\s]+)\s*-->)\s*$`)
-
-// extractPathFromCode looks for a path comment at the start of the code
-// and returns the path and the remaining code with the comment removed
-func extractPathFromCode(code string) (path string, remainingCode string) {
-	// Trim leading whitespace but preserve structure
-	trimmed := strings.TrimLeft(code, " \t")
-
-	match := pathRe.FindStringSubmatchIndex(trimmed)
-	if match == nil {
-		return "", code
+	if lang == "" {
+		return ".txt"
 	}
-
-	// Extract path from the appropriate capture group
-	if match[2] != -1 && match[3] != -1 {
-		// Single-line comment style (// # --)
-		path = strings.TrimSpace(trimmed[match[2]:match[3]])
-	} else if match[4] != -1 && match[5] != -1 {
-		// HTML comment style (<!-- -->)
-		path = strings.TrimSpace(trimmed[match[4]:match[5]])
+	// Last resort: treat unknown tag as an extension if it looks valid
+	if len(lang) <= 6 && regexp.MustCompile(`^[a-z0-9.+-]+$`).MatchString(lang) {
+		if !strings.HasPrefix(lang, ".") {
+			return "." + lang
+		}
+		return lang
 	}
-
-	// Remove the path comment line and any trailing newline
-	before := code[:len(code)-len(trimmed)] // Preserve original indentation
-	afterMatch := trimmed[match[1]:]
-	afterMatch = strings.TrimPrefix(afterMatch, "\n")
-
-	remainingCode = before + afterMatch
-	return path, remainingCode
+	return ".txt"
 }
 
 // snapshotFiles creates a map of file paths to their checksums
@@ -172,7 +78,7 @@ func snapshotFiles(baseDir string) (map[string]string, error) {
 }
 
 // writeCodeFence writes a single code fence to disk
-func writeCodeFence(baseDir string, index int, fence CodeFence, writtenFiles map[string]string) []FileAction {
+func writeCodeFence(cfg *Config, baseDir string, index int, fence CodeFence, writtenFiles map[string]string) []FileAction {
 	var actions []FileAction
 
 	code := strings.TrimSpace(fence.Code)
@@ -181,7 +87,7 @@ func writeCodeFence(baseDir string, index int, fence CodeFence, writtenFiles map
 	}
 
 	// Extract path from code comment or generate default
-	path, body := extractPathFromCode(code)
+	path, body := extractPathHint(cfg, code)
 	if path == "" {
 		ext := extFromLang(fence.Lang)
 		path = filepath.Join("generated", fmt.Sprintf("file_%d%s", index+1, ext))
@@ -207,7 +113,7 @@ func writeCodeFence(baseDir string, index int, fence CodeFence, writtenFiles map
 		bodyBytes = append(bodyBytes, '\n')
 	}
 
-	if err := os.WriteFile(fullPath, bodyBytes, 0o644); err != nil {
+	if err := atomicfile.WriteFile(fullPath, bodyBytes, 0o644); err != nil {
 		return append(actions, FileAction{
 			Path:    fullPath,
 			Action:  "error",
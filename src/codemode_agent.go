@@ -0,0 +1,144 @@
+package src
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	agent "github.com/Protocol-Lattice/go-agent"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/universal-tool-calling-protocol/go-utcp/src/plugins/codemode"
+
+	"github.com/Protocol-Lattice/lattice-code/src/ui"
+)
+
+// agentModel adapts the agent to the single-prompt model codemode expects.
+type agentModel struct {
+	ag      *agent.Agent
+	session string
+}
+
+func (a agentModel) Generate(ctx context.Context, prompt string) (any, error) {
+	return a.ag.Generate(ctx, a.session, prompt)
+}
+
+// codeModeRefactor returns the session's CodeModeRefactor, building it from
+// the agent's UTCP client on first use.
+func (m *model) codeModeRefactor() (*CodeModeRefactor, error) {
+	if m.codeMode != nil {
+		return m.codeMode, nil
+	}
+	if m.agent == nil || m.agent.UTCPClient == nil {
+		return nil, errors.New("codemode is unavailable: no UTCP client is configured")
+	}
+	m.codeMode = NewCodeModeRefactor(codemode.NewCodeModeUTCP(m.agent.UTCPClient, agentModel{m.agent, m.sessionID}))
+	return m.codeMode, nil
+}
+
+// parseBatchArgs parses "batch <pattern> <find> <replace>". An argument
+// holding spaces is written in double quotes, with Go escapes.
+func parseBatchArgs(input string) (pattern, find, replace string, ok bool) {
+	var fields []string
+	for s := strings.TrimSpace(input); s != ""; s = strings.TrimSpace(s) {
+		if s[0] != '"' {
+			end := strings.IndexFunc(s, unicode.IsSpace)
+			if end < 0 {
+				end = len(s)
+			}
+			fields, s = append(fields, s[:end]), s[end:]
+			continue
+		}
+		quoted, err := strconv.QuotedPrefix(s)
+		if err != nil {
+			return "", "", "", false
+		}
+		field, _ := strconv.Unquote(quoted)
+		fields, s = append(fields, field), s[len(quoted):]
+	}
+	if len(fields) != 4 || fields[0] != "batch" {
+		return "", "", "", false
+	}
+	return fields[1], fields[2], fields[3], true
+}
+
+// batchPlanMsg carries a batch refactor plan awaiting confirmation.
+type batchPlanMsg struct {
+	plan *BatchPlan
+}
+
+// runCodeMode routes a codemode agent prompt through CodeModeRefactor:
+// "batch <pattern> <find> <replace>" plans a BatchRefactor for confirmation,
+// anything else runs RefactorWithPrompt.
+func (m *model) runCodeMode(raw string) (*model, tea.Cmd) {
+	cmr, err := m.codeModeRefactor()
+	if err != nil {
+		m.output += m.style.Error.Render(fmt.Sprintf("❌ %v\n", err))
+		m.renderOutput(true)
+		return m, nil
+	}
+	cmd := func() tea.Msg {
+		var (
+			res string
+			err error
+		)
+		if pattern, find, replace, ok := parseBatchArgs(raw); ok {
+			// Batches are planned first and only run once confirmed.
+			plan, err := cmr.PlanBatchRefactor(m.ctx, pattern, find, replace)
+			if err != nil {
+				return generateMsg{"", err}
+			}
+			return batchPlanMsg{plan}
+		}
+		res, err = cmr.RefactorWithPrompt(m.ctx, raw)
+		if err != nil {
+			return generateMsg{"", err}
+		}
+		return generateMsg{m.style.Accent.Render("codemode:") + "\n\n" + res + "\n", nil}
+	}
+	return m, tea.Batch(cmd, m.startWork("running codemode"))
+}
+
+// showBatchPlan lists the files a batch refactor would modify and asks for
+// confirmation in ModeConfirm.
+func (m *model) showBatchPlan(plan *BatchPlan) {
+	m.endWork()
+	if len(plan.Files) == 0 {
+		m.output += m.style.Subtle.Render(fmt.Sprintf("ℹ️ no files matching %s contain %q; nothing to refactor\n", plan.Pattern, plan.Find))
+		m.renderOutput(true)
+		return
+	}
+	m.pendingBatch = plan
+	m.output += m.style.Accent.Render(fmt.Sprintf("⚠️ batch refactor would replace %q with %q in %d file(s):\n", plan.Find, plan.Replace, len(plan.Files)))
+	for _, f := range plan.Files {
+		m.output += f + "\n"
+	}
+	if m.mode != ui.ModeConfirm {
+		m.prevMode = m.mode
+		m.mode = ui.ModeConfirm
+	}
+	m.renderOutput(true)
+}
+
+// resolvePendingBatch runs or discards the batch plan awaiting confirmation.
+func (m *model) resolvePendingBatch(apply bool) tea.Cmd {
+	plan := m.pendingBatch
+	m.pendingBatch = nil
+	m.mode = ui.ModeChat
+	if !apply {
+		m.output += m.style.Subtle.Render("↩️ batch refactor cancelled\n")
+		m.renderOutput(true)
+		return nil
+	}
+	m.renderOutput(true)
+	cmd := func() tea.Msg {
+		res, err := m.codeMode.BatchRefactor(m.ctx, plan)
+		if err != nil {
+			return generateMsg{"", err}
+		}
+		return generateMsg{m.style.Accent.Render("codemode:") + "\n\n" + res + "\n", nil}
+	}
+	return tea.Batch(cmd, m.startWork("applying batch refactor"))
+}
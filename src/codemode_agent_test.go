@@ -0,0 +1,114 @@
+package src
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/Protocol-Lattice/lattice-code/src/ui"
+)
+
+// stubCodeMode records codemode prompts and replies with a canned result.
+type stubCodeMode struct {
+	prompts []string
+	result  string
+}
+
+func (s *stubCodeMode) CallTool(_ context.Context, prompt string) (bool, any, error) {
+	s.prompts = append(s.prompts, prompt)
+	return true, s.result, nil
+}
+
+func runCodeModeMsg(t *testing.T, m *model, raw string) tea.Msg {
+	t.Helper()
+	_, cmd := m.runCodeMode(raw)
+	if cmd == nil {
+		t.Fatalf("runCodeMode(%q) returned no command; output: %s", raw, m.output)
+	}
+	batch, ok := cmd().(tea.BatchMsg)
+	if !ok || len(batch) == 0 {
+		t.Fatal("expected a batch of commands")
+	}
+	return batch[0]()
+}
+
+func TestRunCodeModeRoutesPrompts(t *testing.T) {
+	stub := &stubCodeMode{result: "Successfully renamed 2 functions"}
+	m := NewModel(context.Background(), nil, t.TempDir(), nil)
+	m.codeMode = &CodeModeRefactor{cm: stub}
+
+	msg, _ := runCodeModeMsg(t, m, "rename oldName to newName everywhere").(generateMsg)
+	if msg.err != nil || !strings.Contains(msg.text, "Successfully renamed") {
+		t.Errorf("prompt result = %+v", msg)
+	}
+	if len(stub.prompts) != 1 || !strings.Contains(stub.prompts[0], "rename oldName to newName everywhere") {
+		t.Fatalf("prompts = %q", stub.prompts)
+	}
+	m.Update(msg)
+
+	stub.result = `["src/a.go", "src/b.go"]`
+	planMsg, ok := runCodeModeMsg(t, m, "batch src/*.go oldName newName").(batchPlanMsg)
+	if !ok || len(stub.prompts) != 2 || !strings.Contains(stub.prompts[1], "matching pattern: src/*.go") {
+		t.Fatalf("batch should only plan first; prompts = %q", stub.prompts)
+	}
+
+	m.Update(planMsg)
+	if m.mode != ui.ModeConfirm || m.pendingBatch == nil {
+		t.Fatalf("plan should await confirmation, mode = %v", m.mode)
+	}
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")})
+	if m.mode != ui.ModeChat || m.pendingBatch != nil || cmd == nil {
+		t.Fatalf("confirming should start the batch, mode = %v", m.mode)
+	}
+	batch, _ := cmd().(tea.BatchMsg)
+	batch[0]()
+	if len(stub.prompts) != 3 || !strings.Contains(stub.prompts[2], "replace 'oldName' with 'newName'") ||
+		!strings.Contains(stub.prompts[2], "src/b.go") {
+		t.Errorf("batch prompt = %q", stub.prompts[len(stub.prompts)-1])
+	}
+}
+
+func TestBatchPlanCancelled(t *testing.T) {
+	stub := &stubCodeMode{}
+	m := NewModel(context.Background(), nil, t.TempDir(), nil)
+	m.codeMode = &CodeModeRefactor{cm: stub}
+
+	m.Update(batchPlanMsg{&BatchPlan{Find: "a", Replace: "b", Files: []string{"x.go"}}})
+	if _, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")}); cmd != nil {
+		t.Error("cancelling should not run the batch")
+	}
+	if m.pendingBatch != nil || len(stub.prompts) != 0 || !strings.Contains(m.output, "cancelled") {
+		t.Errorf("pending = %v, prompts = %q", m.pendingBatch, stub.prompts)
+	}
+}
+
+func TestRunCodeModeUnavailable(t *testing.T) {
+	m := NewModel(context.Background(), nil, t.TempDir(), nil)
+
+	if _, cmd := m.runCodeMode("anything"); cmd != nil {
+		t.Error("codemode without a UTCP client should not start a run")
+	}
+	if m.isThinking || !strings.Contains(m.output, "codemode is unavailable") {
+		t.Errorf("expected an unavailable error, got %q", m.output)
+	}
+}
+
+func TestParseBatchArgs(t *testing.T) {
+	for input, want := range map[string][3]string{
+		"batch *.go modeDir ui.ModeDir":            {"*.go", "modeDir", "ui.ModeDir"},
+		`batch src/*.go "old name" "new\tname"`:    {"src/*.go", "old name", "new\tname"},
+		`  batch   "*.md"  "say \"hi\""   hello  `: {"*.md", `say "hi"`, "hello"},
+	} {
+		pattern, find, replace, ok := parseBatchArgs(input)
+		if !ok || [3]string{pattern, find, replace} != want {
+			t.Errorf("parseBatchArgs(%q) = %q %q %q %v", input, pattern, find, replace, ok)
+		}
+	}
+	for _, input := range []string{"batch *.go old", `batch *.go "unterminated new`, "batch a b c d", "rename a b c"} {
+		if _, _, _, ok := parseBatchArgs(input); ok {
+			t.Errorf("parseBatchArgs(%q) should not parse", input)
+		}
+	}
+}
@@ -2,15 +2,21 @@ package src
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 
 	"github.com/universal-tool-calling-protocol/go-utcp/src/plugins/codemode"
 )
 
+// codeModeCaller is the part of codemode.CodeModeUTCP that CodeModeRefactor uses.
+type codeModeCaller interface {
+	CallTool(ctx context.Context, prompt string) (bool, any, error)
+}
+
 // CodeModeRefactor uses the codemode plugin to refactor files via natural language prompts
 type CodeModeRefactor struct {
-	cm *codemode.CodeModeUTCP
+	cm codeModeCaller
 }
 
 // NewCodeModeRefactor creates a new codemode-based refactoring engine
@@ -174,16 +180,68 @@ func (cmr *CodeModeRefactor) RefactorFile(ctx context.Context, path, find, repla
 	return fmt.Sprintf("%v", result), nil
 }
 
-// BatchRefactor performs batch refactoring across multiple files
-func (cmr *CodeModeRefactor) BatchRefactor(ctx context.Context, pattern, find, replace string) (string, error) {
+// BatchPlan lists the files a batch refactor would modify, so they can be
+// confirmed before anything is changed.
+type BatchPlan struct {
+	Pattern, Find, Replace string
+	Files                  []string
+}
+
+// PlanBatchRefactor finds the files matching pattern that contain find,
+// without modifying anything.
+func (cmr *CodeModeRefactor) PlanBatchRefactor(ctx context.Context, pattern, find, replace string) (*BatchPlan, error) {
 	prompt := fmt.Sprintf(`
-Perform batch refactoring using lattice_mcp_codebase tools:
+Plan a batch refactoring using lattice_mcp_codebase tools. Do NOT modify any files.
 1. Use lattice_mcp_codebase.search_codebase to find files matching pattern: %s
-2. For each file, use lattice_mcp_codebase.refactor_file to replace '%s' with '%s'
+2. Keep only the files that contain '%s'
+3. Return ONLY a JSON array of the matching file paths, e.g. ["src/a.go","src/b.go"]
+`, pattern, find)
+
+	success, result, err := cmr.cm.CallTool(ctx, prompt)
+	if err != nil {
+		return nil, fmt.Errorf("batch refactor plan failed: %w", err)
+	}
+
+	if !success {
+		return nil, fmt.Errorf("batch refactor plan was not successful")
+	}
+
+	return &BatchPlan{Pattern: pattern, Find: find, Replace: replace, Files: parsePlannedFiles(fmt.Sprintf("%v", result))}, nil
+}
+
+// parsePlannedFiles reads the file list from a plan result: a JSON array of
+// paths if one is present, otherwise one path per line.
+func parsePlannedFiles(result string) []string {
+	if i, j := strings.Index(result, "["), strings.LastIndex(result, "]"); i >= 0 && j > i {
+		var files []string
+		if err := json.Unmarshal([]byte(result[i:j+1]), &files); err == nil {
+			return files
+		}
+	}
+	var files []string
+	for _, line := range strings.Split(result, "\n") {
+		line = strings.Trim(strings.TrimSpace(line), "-*` ")
+		if line != "" && !strings.ContainsAny(line, " \t") {
+			files = append(files, line)
+		}
+	}
+	return files
+}
+
+// BatchRefactor applies a confirmed plan, touching only the files it lists.
+func (cmr *CodeModeRefactor) BatchRefactor(ctx context.Context, plan *BatchPlan) (string, error) {
+	if plan == nil || len(plan.Files) == 0 {
+		return "", fmt.Errorf("batch refactor plan has no files")
+	}
+	prompt := fmt.Sprintf(`
+Perform batch refactoring using lattice_mcp_codebase tools:
+1. For each of these files, use lattice_mcp_codebase.refactor_file to replace '%s' with '%s':
+%s
+2. Do not modify any other file
 3. Report which files were modified
 
 Please execute this refactoring and provide a summary.
-`, pattern, find, replace)
+`, plan.Find, plan.Replace, "   - "+strings.Join(plan.Files, "\n   - "))
 
 	success, result, err := cmr.cm.CallTool(ctx, prompt)
 	if err != nil {
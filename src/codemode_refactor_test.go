@@ -0,0 +1,42 @@
+package src
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPlanBatchRefactorListsFilesWithoutModifying(t *testing.T) {
+	root := t.TempDir()
+	writeFixture(t, root, map[string]string{"src/a.go": "package src\n\nfunc oldName() {}\n"})
+	stub := &stubCodeMode{result: "Matches:\n```json\n[\"src/a.go\"]\n```"}
+	cmr := &CodeModeRefactor{cm: stub}
+
+	plan, err := cmr.PlanBatchRefactor(context.Background(), "src/*.go", "oldName", "newName")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(plan.Files, []string{"src/a.go"}) || plan.Find != "oldName" || plan.Replace != "newName" {
+		t.Errorf("plan = %+v", plan)
+	}
+	if len(stub.prompts) != 1 || !strings.Contains(stub.prompts[0], "Do NOT modify") || strings.Contains(stub.prompts[0], "refactor_file") {
+		t.Errorf("plan prompt should only search: %q", stub.prompts)
+	}
+	if b, _ := os.ReadFile(filepath.Join(root, "src", "a.go")); !strings.Contains(string(b), "oldName") {
+		t.Error("planning must not modify files")
+	}
+
+	if _, err := cmr.BatchRefactor(context.Background(), &BatchPlan{Find: "a", Replace: "b"}); err == nil {
+		t.Error("an empty plan should not run")
+	}
+}
+
+func TestParsePlannedFilesFallsBackToLines(t *testing.T) {
+	got := parsePlannedFiles("- src/a.go\n- `src/b.go`\nFound 2 files\n")
+	if want := []string{"src/a.go", "src/b.go"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("parsePlannedFiles = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,299 @@
+package src
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const defaultRunTimeout = 15 * time.Second
+
+// CodeRunRequest mirrors the arguments of the run_code tool the planner calls.
+type CodeRunRequest struct {
+	Language string
+	Dir      string // workspace root; file paths are relative to it
+	Cwd      string // optional subdirectory of Dir to run in
+	Env      map[string]string
+	File     string // file to run; ignored when Code is set
+	Code     string // inline snippet, written to a temporary file
+	Args     []string
+	Timeout  time.Duration
+}
+
+// CodeRunResult captures the outcome of a run for display.
+type CodeRunResult struct {
+	Language string
+	Command  string
+	Stdout   string
+	Stderr   string
+	ExitCode int
+	Duration time.Duration
+	WorkDir  string // resolved directory the program ran in
+	Err      string
+}
+
+// languageExts maps run languages to the extension used for snippets.
+var languageExts = map[string]string{
+	"go": ".go", "python": ".py", "javascript": ".js", "typescript": ".ts",
+	"ruby": ".rb", "php": ".php", "perl": ".pl", "lua": ".lua", "r": ".R",
+	"bash": ".sh", "c": ".c", "cpp": ".cpp", "rust": ".rs", "java": ".java",
+}
+
+// languageAliases lets users type common short names after --lang.
+var languageAliases = map[string]string{
+	"py": "python", "js": "javascript", "node": "javascript", "ts": "typescript",
+	"rb": "ruby", "sh": "bash", "shell": "bash", "c++": "cpp", "rs": "rust", "golang": "go",
+}
+
+func normalizeLanguage(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if alias, ok := languageAliases[lang]; ok {
+		return alias
+	}
+	return lang
+}
+
+// languageForFile infers the run language from a file extension.
+func languageForFile(path string) string {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == ".cc" || ext == ".cxx" {
+		return "cpp"
+	}
+	for lang, e := range languageExts {
+		if strings.ToLower(e) == ext {
+			return lang
+		}
+	}
+	return ""
+}
+
+// interpreters holds the command prefix for languages run directly from source.
+var interpreters = map[string][]string{
+	"go":         {"go", "run"},
+	"python":     {"python3"},
+	"javascript": {"node"},
+	"typescript": {"npx", "tsx"},
+	"ruby":       {"ruby"},
+	"php":        {"php"},
+	"perl":       {"perl"},
+	"lua":        {"lua"},
+	"r":          {"Rscript"},
+	"bash":       {"bash"},
+}
+
+// runCode executes a file or snippet and reports stdout, stderr, exit code and duration.
+func runCode(ctx context.Context, req CodeRunRequest) CodeRunResult {
+	res := CodeRunResult{Language: normalizeLanguage(req.Language), ExitCode: -1}
+	start := time.Now()
+	defer func() { res.Duration = time.Since(start) }()
+
+	timeout := req.Timeout
+	if timeout <= 0 {
+		timeout = defaultRunTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// tmp holds snippets and compiled binaries for the duration of the run.
+	tmp, err := os.MkdirTemp("", "lattice-run-*")
+	if err != nil {
+		res.Err = err.Error()
+		return res
+	}
+	defer os.RemoveAll(tmp)
+
+	dir, file := req.Dir, req.File
+	runDir, err := resolveRunDir(req.Dir, req.Cwd)
+	if err != nil {
+		res.Err = err.Error()
+		return res
+	}
+	if req.Code != "" {
+		if res.Language == "" {
+			res.Err = "a language is required to run a snippet"
+			return res
+		}
+		file = "main" + languageExts[res.Language]
+		if res.Language == "java" {
+			file = "Main.java"
+		}
+		if err := os.WriteFile(filepath.Join(tmp, file), []byte(req.Code), 0o644); err != nil {
+			res.Err = err.Error()
+			return res
+		}
+		dir = tmp
+		if req.Cwd == "" {
+			runDir = tmp
+		}
+	} else if file != "" && !filepath.IsAbs(file) {
+		file = filepath.Join(dir, file)
+	}
+	if file == "" {
+		res.Err = "nothing to run: no file or snippet given"
+		return res
+	}
+	if res.Language == "" {
+		res.Language = languageForFile(file)
+	}
+
+	name, args, err := runCommand(ctx, res.Language, dir, file, tmp, req.Args)
+	if err != nil {
+		res.Err = err.Error()
+		return res
+	}
+	res.Command = strings.Join(append([]string{name}, args...), " ")
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = runDir
+	cmd.Env = mergeEnv(os.Environ(), req.Env)
+	res.WorkDir = runDir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	res.Stdout, res.Stderr = stdout.String(), stderr.String()
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		res.ExitCode = 0
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		res.Err = fmt.Sprintf("timed out after %s", timeout)
+	case errors.As(err, &exitErr):
+		res.ExitCode = exitErr.ExitCode()
+	default:
+		res.Err = err.Error()
+	}
+	return res
+}
+
+// compiler describes how to build and then invoke a compiled language.
+// compile receives the source file, the output binary path and the build
+// directory; run receives the same binary and build directory plus the
+// source file, and returns the program invocation without user args.
+type compiler struct {
+	compile func(file, bin, outDir string) []string
+	run     func(file, bin, outDir string) []string
+}
+
+var compilers = map[string]compiler{
+	"c":    {compile: nativeCompile("gcc"), run: runBinary},
+	"cpp":  {compile: nativeCompile("g++"), run: runBinary},
+	"rust": {compile: nativeCompile("rustc"), run: runBinary},
+	"java": {
+		compile: func(file, _, outDir string) []string { return []string{"javac", "-d", outDir, file} },
+		run: func(file, _, outDir string) []string {
+			class := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+			return []string{"java", "-cp", outDir, class}
+		},
+	},
+}
+
+// nativeCompile builds "<tool> -o <bin> <file>", which gcc, g++ and rustc share.
+func nativeCompile(tool string) func(file, bin, outDir string) []string {
+	return func(file, bin, _ string) []string { return []string{tool, "-o", bin, file} }
+}
+
+func runBinary(_, bin, _ string) []string { return []string{bin} }
+
+// resolveRunDir resolves cwd against root, refusing paths that escape it.
+func resolveRunDir(root, cwd string) (string, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+	dir := root
+	if cwd != "" {
+		if !filepath.IsAbs(cwd) {
+			cwd = filepath.Join(root, cwd)
+		}
+		dir = filepath.Clean(cwd)
+		if rel, err := filepath.Rel(root, dir); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return "", fmt.Errorf("cwd %q is outside the workspace", cwd)
+		}
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return "", fmt.Errorf("cwd: %w", err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("cwd %q is not a directory", dir)
+	}
+	return dir, nil
+}
+
+// mergeEnv overlays extra onto base, replacing existing keys; the result is
+// ordered deterministically for the extra keys.
+func mergeEnv(base []string, extra map[string]string) []string {
+	if len(extra) == 0 {
+		return base
+	}
+	out := make([]string, 0, len(base)+len(extra))
+	for _, kv := range base {
+		k, _, _ := strings.Cut(kv, "=")
+		if _, ok := extra[k]; !ok {
+			out = append(out, kv)
+		}
+	}
+	keys := make([]string, 0, len(extra))
+	for k := range extra {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		out = append(out, k+"="+extra[k])
+	}
+	return out
+}
+
+// runCommand resolves the command for file, compiling it into outDir first
+// when the language has no interpreter.
+func runCommand(ctx context.Context, lang, dir, file, outDir string, args []string) (string, []string, error) {
+	if prefix, ok := interpreters[lang]; ok {
+		return prefix[0], append(append(append([]string{}, prefix[1:]...), file), args...), nil
+	}
+	c, ok := compilers[lang]
+	if !ok {
+		return "", nil, fmt.Errorf("unsupported language %q", lang)
+	}
+
+	bin := filepath.Join(outDir, "prog")
+	compile := c.compile(file, bin, outDir)
+	build := exec.CommandContext(ctx, compile[0], compile[1:]...)
+	build.Dir = dir
+	if out, err := build.CombinedOutput(); err != nil {
+		return "", nil, fmt.Errorf("compile failed: %v\n%s", err, out)
+	}
+	run := c.run(file, bin, outDir)
+	return run[0], append(run[1:], args...), nil
+}
+
+// formatCodeRunResult renders a run for the chat transcript.
+func formatCodeRunResult(r CodeRunResult) string {
+	var b strings.Builder
+	status := fmt.Sprintf("exit %d", r.ExitCode)
+	if r.Err != "" {
+		status = "error: " + r.Err
+	}
+	cmd := r.Command
+	if cmd == "" {
+		cmd = r.Language
+	}
+	fmt.Fprintf(&b, "🏃 %s (%s, %s)\n", cmd, status, r.Duration.Round(time.Millisecond))
+	if r.Stdout != "" {
+		b.WriteString("--- stdout ---\n")
+		b.WriteString(strings.TrimRight(r.Stdout, "\n") + "\n")
+	}
+	if r.Stderr != "" {
+		b.WriteString("--- stderr ---\n")
+		b.WriteString(strings.TrimRight(r.Stderr, "\n") + "\n")
+	}
+	return b.String()
+}
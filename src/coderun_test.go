@@ -0,0 +1,78 @@
+package src
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatCodeRunResult(t *testing.T) {
+	out := formatCodeRunResult(CodeRunResult{
+		Command:  "python3 main.py",
+		Stdout:   "hello\n",
+		Stderr:   "warn\n",
+		ExitCode: 2,
+		Duration: 1500 * time.Microsecond,
+	})
+	want := "🏃 python3 main.py (exit 2, 2ms)\n--- stdout ---\nhello\n--- stderr ---\nwarn\n"
+	if out != want {
+		t.Errorf("got %q; want %q", out, want)
+	}
+
+	out = formatCodeRunResult(CodeRunResult{Language: "cobol", Err: `unsupported language "cobol"`, ExitCode: -1})
+	if !strings.HasPrefix(out, `🏃 cobol (error: unsupported language "cobol", 0s)`) {
+		t.Errorf("error result = %q", out)
+	}
+}
+
+func TestRunCodeSnippet(t *testing.T) {
+	res := runCode(t.Context(), CodeRunRequest{Language: "bash", Code: "echo out; echo err >&2; exit 3\n"})
+	if res.Err != "" || res.ExitCode != 3 || res.Stdout != "out\n" || res.Stderr != "err\n" {
+		t.Errorf("result = %+v", res)
+	}
+}
+
+func TestRunCodeCompiledLanguages(t *testing.T) {
+	programs := map[string]struct{ tool, code string }{
+		"c":    {"gcc", "#include <stdio.h>\nint main(int argc, char **argv) { printf(\"%s\\n\", argv[1]); return 0; }"},
+		"cpp":  {"g++", "#include <iostream>\nint main(int argc, char **argv) { std::cout << argv[1] << std::endl; }"},
+		"rust": {"rustc", "fn main() { println!(\"{}\", std::env::args().nth(1).unwrap()); }"},
+		"java": {"javac", "public class Main { public static void main(String[] a) { System.out.println(a[0]); } }"},
+	}
+	for lang, p := range programs {
+		t.Run(lang, func(t *testing.T) {
+			if _, err := exec.LookPath(p.tool); err != nil {
+				t.Skipf("%s not installed", p.tool)
+			}
+			res := runCode(t.Context(), CodeRunRequest{Language: lang, Code: p.code, Args: []string{"hi"}, Timeout: time.Minute})
+			if res.Err != "" || res.ExitCode != 0 || res.Stdout != "hi\n" {
+				t.Errorf("result = %+v", res)
+			}
+		})
+	}
+}
+
+func TestRunCodePassesEnvAndCwd(t *testing.T) {
+	root := t.TempDir()
+	writeFixture(t, root, map[string]string{"svc/main.sh": "echo \"port=$PORT\"; pwd\n"})
+
+	res := runCode(t.Context(), CodeRunRequest{
+		Dir:  root,
+		File: "svc/main.sh",
+		Cwd:  "svc",
+		Env:  map[string]string{"PORT": "8089"},
+	})
+	if res.Err != "" || res.ExitCode != 0 {
+		t.Fatalf("result = %+v", res)
+	}
+	wantDir := filepath.Join(root, "svc")
+	if res.WorkDir != wantDir || res.Stdout != "port=8089\n"+wantDir+"\n" {
+		t.Errorf("stdout = %q, workdir = %q", res.Stdout, res.WorkDir)
+	}
+
+	if res := runCode(t.Context(), CodeRunRequest{Dir: root, File: "svc/main.sh", Cwd: "../.."}); !strings.Contains(res.Err, "outside the workspace") {
+		t.Errorf("cwd escaping the workspace should be rejected, got %+v", res)
+	}
+}
@@ -0,0 +1,151 @@
+package src
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// slashCommand handles a chat line starting with "/<name>"; args is the rest
+// of the input, including any following lines.
+type slashCommand func(m *model, args string) (*model, tea.Cmd)
+
+var slashCommands = map[string]slashCommand{
+	"config":     configSlashCommand,
+	"diff":       diffSlashCommand,
+	"discard":    discardSlashCommand,
+	"grep":       grepSlashCommand,
+	"init":       initSlashCommand,
+	"lastprompt": lastPromptSlashCommand,
+	"pin":        pinSlashCommand,
+	"providers":  providersSlashCommand,
+	"refactor":   refactorSlashCommand,
+	"run":        runSlashCommand,
+	"save":       saveSlashCommand,
+	"search":     searchSlashCommand,
+	"target":     targetSlashCommand,
+	"unpin":      unpinSlashCommand,
+}
+
+// handleSlashCommand dispatches raw (which starts with "/") to its command.
+func (m *model) handleSlashCommand(raw string) (*model, tea.Cmd) {
+	name, args := strings.TrimPrefix(raw, "/"), ""
+	if i := strings.IndexAny(name, " \t\n"); i >= 0 {
+		name, args = name[:i], name[i:]
+	}
+	cmd, ok := slashCommands[name]
+	if !ok {
+		names := make([]string, 0, len(slashCommands))
+		for n := range slashCommands {
+			names = append(names, "/"+n)
+		}
+		sort.Strings(names)
+		m.output += m.style.Error.Render(fmt.Sprintf("❌ unknown command /%s (available: %s)\n", name, strings.Join(names, ", ")))
+		m.renderOutput(true)
+		return m, nil
+	}
+	// Keep a leading newline: it separates the flag line from a snippet.
+	return cmd(m, strings.TrimRight(strings.TrimLeft(args, " \t"), " \t\n"))
+}
+
+// parseRunArgs parses "/run" arguments:
+//
+//	/run [--lang L] [--timeout SECONDS] [--cwd DIR] [--env K=V]... [file] [-- program args...]
+//
+// Lines after the first (optionally wrapped in a ``` fence whose info string
+// names the language) are run as a snippet instead of a file.
+func parseRunArgs(input string) (CodeRunRequest, error) {
+	var req CodeRunRequest
+	first, rest, _ := strings.Cut(input, "\n")
+
+	fields := strings.Fields(first)
+	for i := 0; i < len(fields); i++ {
+		switch f := fields[i]; {
+		case f == "--":
+			req.Args = append(req.Args, fields[i+1:]...)
+			i = len(fields)
+		case f == "--lang" || f == "--timeout" || f == "--cwd" || f == "--env":
+			if i+1 >= len(fields) {
+				return req, fmt.Errorf("%s needs a value", f)
+			}
+			i++
+			v := fields[i]
+			switch f {
+			case "--lang":
+				req.Language = normalizeLanguage(v)
+			case "--cwd":
+				req.Cwd = v
+			case "--env":
+				key, val, ok := strings.Cut(v, "=")
+				if !ok || key == "" {
+					return req, fmt.Errorf("invalid --env %q, want KEY=VALUE", v)
+				}
+				if req.Env == nil {
+					req.Env = map[string]string{}
+				}
+				req.Env[key] = val
+			case "--timeout":
+				secs, err := strconv.Atoi(v)
+				if err != nil || secs <= 0 {
+					return req, fmt.Errorf("invalid --timeout %q", v)
+				}
+				req.Timeout = time.Duration(secs) * time.Second
+			}
+		case strings.HasPrefix(f, "--"):
+			return req, fmt.Errorf("unknown flag %s", f)
+		case req.File == "":
+			req.File = f
+		default:
+			req.Args = append(req.Args, f)
+		}
+	}
+
+	if code := strings.TrimSpace(rest); code != "" {
+		if strings.HasPrefix(code, "```") {
+			info, body, _ := strings.Cut(strings.TrimPrefix(code, "```"), "\n")
+			code = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(body), "```"))
+			if req.Language == "" && strings.TrimSpace(info) != "" {
+				req.Language = normalizeLanguage(info)
+			}
+		}
+		if req.File != "" {
+			return req, fmt.Errorf("give either a file or a snippet, not both")
+		}
+		req.Code = code + "\n"
+		if req.Language == "" {
+			return req, fmt.Errorf("snippets need --lang or a fenced block with a language")
+		}
+	}
+	return req, nil
+}
+
+func runSlashCommand(m *model, args string) (*model, tea.Cmd) {
+	req, err := parseRunArgs(args)
+	if err != nil {
+		m.output += m.style.Error.Render(fmt.Sprintf("❌ /run: %v\n", err))
+		m.renderOutput(true)
+		return m, nil
+	}
+	req.Dir = m.working
+	if req.File == "" && req.Code == "" {
+		file, lang := findMainFile(m.working, m.cfg.Entrypoints)
+		if file == "" {
+			m.output += m.style.Error.Render("❌ /run: no entry file found; pass a file or a snippet\n")
+			m.renderOutput(true)
+			return m, nil
+		}
+		req.File = file
+		if req.Language == "" {
+			req.Language = lang
+		}
+	}
+
+	cmd := func() tea.Msg {
+		return generateMsg{formatCodeRunResult(runCode(m.ctx, req)), nil}
+	}
+	return m, tea.Batch(cmd, m.startWork("running code"))
+}
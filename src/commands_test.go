@@ -0,0 +1,80 @@
+package src
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Protocol-Lattice/lattice-code/src/ui"
+)
+
+func TestParseRunArgs(t *testing.T) {
+	req, err := parseRunArgs("--lang py --timeout 3 --cwd svc --env PORT=8080 script.py -- --verbose x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Language != "python" || req.File != "script.py" || req.Timeout != 3*time.Second ||
+		req.Cwd != "svc" || req.Env["PORT"] != "8080" || strings.Join(req.Args, " ") != "--verbose x" {
+		t.Errorf("file request = %+v", req)
+	}
+
+	req, err = parseRunArgs("\n```go\npackage main\nfunc main() {}\n```")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Language != "go" || req.File != "" || req.Code != "package main\nfunc main() {}\n" {
+		t.Errorf("snippet request = %+v", req)
+	}
+
+	for _, bad := range []string{"--lang", "--timeout soon", "--nope", "--env PORT", "main.py\nprint(1)", "\nprint(1)"} {
+		if _, err := parseRunArgs(bad); err == nil {
+			t.Errorf("parseRunArgs(%q) should fail", bad)
+		}
+	}
+}
+
+func TestMatchSlashCommands(t *testing.T) {
+	names := func(input string) []string {
+		var out []string
+		for _, c := range matchSlashCommands(input) {
+			out = append(out, c.Name)
+		}
+		return out
+	}
+	if got := names("/"); len(got) != len(slashCommands) {
+		t.Fatalf("/ matched %v, want every command", got)
+	}
+	if got := names("/r"); !reflect.DeepEqual(got, []string{"/refactor", "/run"}) {
+		t.Fatalf("/r matched %v", got)
+	}
+	for _, input := range []string{"", "run", "/run ", "/run main.go", "/zz"} {
+		if got := names(input); got != nil {
+			t.Errorf("%q matched %v, want none", input, got)
+		}
+	}
+	for name := range slashCommands {
+		if slashCommandHelp[name] == "" {
+			t.Errorf("/%s has no description", name)
+		}
+	}
+}
+
+func TestAcceptCompletion(t *testing.T) {
+	m := NewModel(context.Background(), nil, t.TempDir(), nil)
+	m.mode = ui.ModeChat
+	m.textarea.SetValue("/r")
+	m.moveCompletion(1)
+	m.moveCompletion(1) // wraps back to the first match
+	m.moveCompletion(1)
+	if !m.acceptCompletion() {
+		t.Fatal("expected a completion")
+	}
+	if got := m.textarea.Value(); got != "/run " {
+		t.Fatalf("input = %q, want %q", got, "/run ")
+	}
+	if m.acceptCompletion() {
+		t.Fatal("nothing should complete once arguments start")
+	}
+}
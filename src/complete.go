@@ -0,0 +1,72 @@
+package src
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/Protocol-Lattice/lattice-code/src/ui"
+)
+
+// slashCommandHelp describes each entry of slashCommands for the
+// autocomplete popup.
+var slashCommandHelp = map[string]string{
+	"config":     "view or change settings (/config set <name> [value])",
+	"diff":       "show everything changed since the start of the session",
+	"discard":    "revert uncommitted agent changes to git HEAD",
+	"grep":       "search the workspace for a pattern",
+	"init":       "scaffold a minimal project (/init go, node, typescript, python, rust)",
+	"lastprompt": "show the full prompt and files of the last model request",
+	"pin":        "keep a file in every context whatever the caps (no path lists pins)",
+	"providers":  "list UTCP providers and whether they are reachable",
+	"refactor":   "refactor the workspace towards a goal",
+	"run":        "run a file or snippet locally",
+	"save":       "save the last result to a file (--code writes its code blocks)",
+	"search":     "search session memory",
+	"target":     "set the command the planner verifies each step with (off clears it)",
+	"unpin":      "stop pinning a file to the context",
+}
+
+// matchSlashCommands returns the commands completing input, which must be a
+// "/" followed by a partial command name. Once the name is finished (input
+// contains whitespace) there is nothing to complete.
+func matchSlashCommands(input string) []ui.Completion {
+	prefix, ok := strings.CutPrefix(input, "/")
+	if !ok || strings.ContainsAny(prefix, " \t\n") {
+		return nil
+	}
+	var out []ui.Completion
+	for name := range slashCommands {
+		if strings.HasPrefix(name, prefix) {
+			out = append(out, ui.Completion{Name: "/" + name, Description: slashCommandHelp[name]})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// completions returns the popup entries for the current chat input.
+func (m *model) completions() []ui.Completion {
+	if m.mode != ui.ModeChat {
+		return nil
+	}
+	return matchSlashCommands(m.textarea.Value())
+}
+
+// moveCompletion moves the popup selection by delta, wrapping around.
+func (m *model) moveCompletion(delta int) {
+	if n := len(m.completions()); n > 0 {
+		m.completeIdx = ((m.completeIdx+delta)%n + n) % n
+	}
+}
+
+// acceptCompletion replaces the input with the selected command, ready for
+// its arguments.
+func (m *model) acceptCompletion() bool {
+	c := m.completions()
+	if len(c) == 0 {
+		return false
+	}
+	m.textarea.SetValue(c[min(m.completeIdx, len(c)-1)].Name + " ")
+	m.completeIdx = 0
+	return true
+}
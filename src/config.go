@@ -0,0 +1,365 @@
+package src
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/Protocol-Lattice/lattice-code/src/ui"
+)
+
+// Config holds the settings of a session: what the command-line flags set
+// and /config changes while it runs. cmd fills one with RegisterFlags and
+// hands it to NewModel, BuildAgent and RunHeadless. A nil *Config stands for
+// DefaultConfig() wherever one is taken.
+type Config struct {
+	// StateDir holds config.json and recents.json, which NewModel loads and
+	// /config and the directory picker save; "" keeps nothing between
+	// sessions. cmd sets it to DefaultStateDir().
+	StateDir string
+
+	// Writing files.
+	Force       bool // overwrite files not created by the agent without confirmation
+	DryRun      bool
+	Backup      bool
+	BackupKeep  int
+	MaxNewFiles int
+	Format      bool
+	Tidy        bool
+	RunScript   bool
+	GitCommit   bool
+
+	// Context snapshots and requests.
+	Concurrency       int // files read at once for a snapshot
+	ContextMaxFiles   int
+	ContextMaxBytes   int64
+	ContextFileBytes  int64
+	OutlineThreshold  int64
+	LineNumbers       bool
+	TruncateHeadRatio float64
+	Lang              string
+	MaxRequestBytes   int64
+
+	// Reading responses.
+	PathKeywords string
+	PathPattern  *regexp.Regexp
+
+	// Planner.
+	MaxSteps    int
+	Entrypoints string
+	Vet         bool
+	RunTests    bool
+
+	// UI.
+	MaxOutputBytes int
+	MaxDiffBytes   int64
+	Theme          string
+	UTCPTimeout    time.Duration
+
+	// Agent.
+	Models             string
+	Mock               bool
+	SystemPrompt       string
+	SystemPromptAppend bool
+	LogLevel           string
+
+	cmdline  *flag.FlagSet // where RegisterFlags put the flags, for explicit ones
+	settable *flag.FlagSet // c's own flags, for /config
+}
+
+// DefaultConfig returns the settings used when no flag changes them.
+func DefaultConfig() *Config {
+	return &Config{
+		Backup:            true,
+		BackupKeep:        5,
+		MaxNewFiles:       50,
+		Format:            true,
+		RunScript:         true,
+		Concurrency:       min(2*runtime.NumCPU(), 16),
+		ContextMaxFiles:   100,
+		ContextMaxBytes:   1_000_000,
+		ContextFileBytes:  20_000,
+		TruncateHeadRatio: 0.7,
+		MaxRequestBytes:   4_000_000,
+		MaxSteps:          5,
+		MaxOutputBytes:    1 << 20,
+		MaxDiffBytes:      4 << 20,
+		Theme:             ui.DefaultTheme,
+		UTCPTimeout:       2 * time.Minute,
+		Models:            "gemini:gemini-2.5-pro",
+		LogLevel:          "info",
+	}
+}
+
+// orDefault returns c, or DefaultConfig() when c is nil.
+func (c *Config) orDefault() *Config {
+	if c == nil {
+		return DefaultConfig()
+	}
+	return c
+}
+
+// RegisterFlags defines a flag on fs for each setting, bound to c. Flags
+// given on fs's command line later win over values saved by /config.
+func (c *Config) RegisterFlags(fs *flag.FlagSet) {
+	c.cmdline = fs
+	c.bind(fs)
+}
+
+func (c *Config) bind(fs *flag.FlagSet) {
+	fs.BoolVar(&c.Force, "force", c.Force, "overwrite files not created by the agent without confirmation")
+	fs.BoolVar(&c.DryRun, "dry-run", c.DryRun, "show the diffs generated code would make without writing any file")
+	fs.BoolVar(&c.Backup, "backup", c.Backup, "copy a file to .lattice/backups before overwriting it")
+	fs.IntVar(&c.BackupKeep, "backup-keep", c.BackupKeep, "number of backups kept per file; older ones are pruned")
+	fs.IntVar(&c.MaxNewFiles, "max-new-files", c.MaxNewFiles, "new files one response may create before the rest wait for confirmation (0 for no limit)")
+	fs.BoolVar(&c.Format, "format", c.Format, "run gofmt, prettier, black or rustfmt over files written by the agent when installed")
+	fs.BoolVar(&c.Tidy, "tidy", c.Tidy, "run go mod tidy after writing Go files into a module and report the dependencies it adds")
+	fs.BoolVar(&c.RunScript, "run-script", c.RunScript, "write a run.sh for the detected project type after generation when the workspace has none")
+	fs.BoolVar(&c.GitCommit, "git-commit", c.GitCommit, "commit the files each generation run writes when the workspace is a git repository")
+
+	fs.IntVar(&c.Concurrency, "concurrency", c.Concurrency, "files read at once when building a context snapshot")
+	fs.IntVar(&c.ContextMaxFiles, "context-max-files", c.ContextMaxFiles, "most files a context snapshot includes, pinned files aside")
+	fs.Int64Var(&c.ContextMaxBytes, "context-max-bytes", c.ContextMaxBytes, "most file bytes a context snapshot includes")
+	fs.Int64Var(&c.ContextFileBytes, "context-file-bytes", c.ContextFileBytes, "bytes kept of each file in a context snapshot; longer files keep their head and tail")
+	fs.Int64Var(&c.OutlineThreshold, "outline-threshold", c.OutlineThreshold, "files larger than this many bytes appear in the context snapshot as a symbol outline instead of their body (0 disables)")
+	fs.BoolVar(&c.LineNumbers, "line-numbers", c.LineNumbers, "prefix each line of files in the context snapshot with its line number (costs extra tokens)")
+	fs.Float64Var(&c.TruncateHeadRatio, "truncate-head-ratio", c.TruncateHeadRatio, "share of the per-file limit kept from the start of a truncated file; the rest comes from its end")
+	fs.StringVar(&c.Lang, "lang", c.Lang, `languages the context and plan are limited to, comma-separated (e.g. "go,ts"; empty to include every language)`)
+	fs.Int64Var(&c.MaxRequestBytes, "max-request-bytes", c.MaxRequestBytes, "upper bound on prompt plus attachment bytes per generate call; least relevant attachments are dropped to fit")
+
+	fs.StringVar(&c.PathKeywords, "path-keywords", c.PathKeywords, `extra words that name a code block's file in a comment on its first line, like "path" in "// path: main.go" (comma-separated, e.g. "file,filename")`)
+	fs.Var(regexpValue{&c.PathPattern}, "path-pattern", `regular expression matched against a code block's first line whose first group is the file's path (e.g. '^\{"file":\s*"([^"]+)"\}$')`)
+
+	fs.IntVar(&c.MaxSteps, "max-steps", c.MaxSteps, "maximum number of planner steps per run")
+	fs.StringVar(&c.Entrypoints, "entrypoints", c.Entrypoints, `extra entrypoint candidates tried before the built-in ones, as comma-separated lang:pattern pairs (e.g. "python:server.py,javascript:bin/www"); a pattern without "/" matches a file name at any depth`)
+	fs.BoolVar(&c.Vet, "vet", c.Vet, "run go build and go vet after each planner step that writes Go files and feed failures into a corrective step")
+	fs.BoolVar(&c.RunTests, "run-tests", c.RunTests, "verify each planner step by running the project's test suite instead of its entrypoint and feed failures into a corrective step")
+
+	fs.IntVar(&c.MaxOutputBytes, "max-output-bytes", c.MaxOutputBytes, "chat output kept on screen before the oldest lines are trimmed; .lattice/transcript.md in the workspace keeps everything (0 keeps all)")
+	fs.Int64Var(&c.MaxDiffBytes, "max-diff-bytes", c.MaxDiffBytes, "largest old plus new size DiffPretty renders line by line; bigger files only report how much changed (0 diffs everything)")
+	fs.StringVar(&c.Theme, "theme", c.Theme, "color theme: "+strings.Join(ui.ThemeNames(), ", ")+", or a path to a JSON palette file")
+	fs.DurationVar(&c.UTCPTimeout, "utcp-timeout", c.UTCPTimeout, "maximum time a UTCP tool call may take")
+
+	fs.StringVar(&c.Models, "models", c.Models, "comma-separated provider:model list tried in order when a model is unavailable, e.g. gemini:gemini-2.5-pro,openai:gpt-4o,ollama:llama3")
+	fs.BoolVar(&c.Mock, "mock", c.Mock, "use a deterministic offline model that returns canned responses (for demos and tests; no API key needed)")
+	fs.StringVar(&c.SystemPrompt, "system-prompt", c.SystemPrompt, "file holding the agent's system prompt, replacing the built-in one (default "+latticeDir+"/system.md in the workspace, when present)")
+	fs.BoolVar(&c.SystemPromptAppend, "system-prompt-append", c.SystemPromptAppend, "add the system prompt file to the built-in prompt instead of replacing it")
+	fs.StringVar(&c.LogLevel, "log-level", c.LogLevel, "level for .lattice/lattice.log: debug, info, warn or error")
+}
+
+// settingFlags returns a flag set bound to c, through which /config reads and
+// changes settings by name without touching the process's flags.
+func (c *Config) settingFlags() *flag.FlagSet {
+	if c.settable == nil {
+		c.settable = flag.NewFlagSet("config", flag.ContinueOnError)
+		c.bind(c.settable)
+	}
+	return c.settable
+}
+
+// configurable lists the flags /config may change while the TUI is running.
+// Anything read only at startup (models, theme) stays a command-line flag.
+var configurable = []string{
+	"backup",
+	"backup-keep",
+	"concurrency",
+	"context-file-bytes",
+	"context-max-bytes",
+	"context-max-files",
+	"dry-run",
+	"entrypoints",
+	"force",
+	"format",
+	"git-commit",
+	"lang",
+	"line-numbers",
+	"max-diff-bytes",
+	"max-new-files",
+	"max-output-bytes",
+	"max-request-bytes",
+	"max-steps",
+	"outline-threshold",
+	"path-keywords",
+	"path-pattern",
+	"run-script",
+	"run-tests",
+	"tidy",
+	"truncate-head-ratio",
+	"utcp-timeout",
+	"vet",
+}
+
+// settings is the persisted ~/.lattice/config.json.
+type settings struct {
+	Values      map[string]string `json:"settings,omitempty"`     // flag name -> value
+	InputHeight int               `json:"input_height,omitempty"` // preferred textarea height in lines
+}
+
+// Bounds for the resizable input area; defaultInputHeight is used until the
+// user picks another.
+const (
+	defaultInputHeight = 3
+	minInputHeight     = 1
+	maxInputHeight     = 20
+)
+
+// DefaultStateDir returns ~/.lattice, or "" if home is unknown.
+func DefaultStateDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, latticeDir)
+}
+
+// statePath returns name inside cfg's StateDir, or "" when there is none.
+func (c *Config) statePath(name string) string {
+	if c.StateDir == "" {
+		return ""
+	}
+	return filepath.Join(c.StateDir, name)
+}
+
+// loadSettings reads the config file; a missing or invalid file yields no settings.
+func loadSettings(path string) settings {
+	var s settings
+	if path == "" {
+		return s
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return s
+	}
+	_ = json.Unmarshal(data, &s)
+	return s
+}
+
+func (s settings) save(path string) error {
+	if path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// apply sets each stored value on cfg, except settings given explicitly on
+// the command line, which win over the config file.
+func (s settings) apply(cfg *Config) error {
+	explicit := map[string]bool{}
+	if cfg.cmdline != nil {
+		cfg.cmdline.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	}
+	var errs []error
+	for name, value := range s.Values {
+		if explicit[name] {
+			continue
+		}
+		if err := cfg.set(name, value); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// set changes a configurable setting for the rest of the session.
+func (c *Config) set(name, value string) error {
+	if !isConfigurable(name) {
+		return fmt.Errorf("unknown setting %q (available: %s)", name, strings.Join(configurable, ", "))
+	}
+	if err := c.settingFlags().Set(name, value); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// get returns a setting's current value as /config shows and saves it.
+func (c *Config) get(name string) string {
+	if f := c.settingFlags().Lookup(name); f != nil {
+		return f.Value.String()
+	}
+	return ""
+}
+
+func isConfigurable(name string) bool {
+	i := sort.SearchStrings(configurable, name)
+	return i < len(configurable) && configurable[i] == name
+}
+
+type configItem struct{ name, value, usage string }
+
+func (c configItem) Title() string       { return fmt.Sprintf("%s = %s", c.name, c.value) }
+func (c configItem) Description() string { return c.usage }
+func (c configItem) FilterValue() string { return c.name }
+
+func configItems(cfg *Config) []list.Item {
+	items := make([]list.Item, 0, len(configurable))
+	for _, name := range configurable {
+		if f := cfg.settingFlags().Lookup(name); f != nil {
+			items = append(items, configItem{name: name, value: f.Value.String(), usage: f.Usage})
+		}
+	}
+	return items
+}
+
+// configSlashCommand handles "/config" (open the settings view) and
+// "/config set <name> [value]" (change and persist a setting).
+func configSlashCommand(m *model, args string) (*model, tea.Cmd) {
+	fields := strings.Fields(args)
+	switch {
+	case len(fields) == 0:
+		m.list.SetItems(configItems(m.cfg))
+		m.list.Title = "⚙️ Settings"
+		m.prevMode = m.mode
+		m.mode = ui.ModeConfig
+	case fields[0] == "set" && len(fields) >= 2:
+		// The value is the rest of the line, so it may hold spaces or be
+		// empty to clear the setting.
+		name := fields[1]
+		rest := strings.TrimSpace(strings.TrimSpace(args)[len("set"):])
+		value := strings.TrimSpace(rest[len(name):])
+		if err := m.cfg.set(name, value); err != nil {
+			m.output += m.style.Error.Render(fmt.Sprintf("❌ /config: %v\n", err))
+			break
+		}
+		if m.settings.Values == nil {
+			m.settings.Values = map[string]string{}
+		}
+		m.settings.Values[name] = m.cfg.get(name)
+		if err := m.settings.save(m.configPath); err != nil {
+			m.output += m.style.Error.Render(fmt.Sprintf("❌ /config: saving %s: %v\n", m.configPath, err))
+			break
+		}
+		m.output += m.style.Success.Render(fmt.Sprintf("⚙️ %s = %s\n", name, m.settings.Values[name]))
+	default:
+		m.output += m.style.Error.Render("❌ usage: /config or /config set <name> [value]\n")
+	}
+	m.renderOutput(true)
+	return m, nil
+}
+
+// editConfigItem leaves the settings view with a "/config set" line for the
+// selected setting in the input, ready to edit and submit.
+func (m *model) editConfigItem(c configItem) {
+	m.list.SetItems(defaultAgents())
+	m.list.Title = fmt.Sprintf("📁 %s", filepath.Base(m.working))
+	m.mode = ui.ModeChat
+	m.textarea.SetValue(fmt.Sprintf("/config set %s %s", c.name, c.value))
+	m.textarea.Focus()
+}
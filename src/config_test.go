@@ -0,0 +1,126 @@
+package src
+
+import (
+	"context"
+	"flag"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/Protocol-Lattice/lattice-code/src/ui"
+)
+
+func TestSettingsSaveLoadRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".lattice", "config.json")
+	in := settings{Values: map[string]string{"max-steps": "3", "vet": "true"}}
+	if err := in.save(path); err != nil {
+		t.Fatal(err)
+	}
+	out := loadSettings(path)
+	if out.Values["max-steps"] != "3" || out.Values["vet"] != "true" {
+		t.Errorf("loaded %+v", out)
+	}
+	if got := loadSettings(filepath.Join(t.TempDir(), "missing.json")); len(got.Values) != 0 {
+		t.Errorf("missing file should yield no settings, got %+v", got)
+	}
+}
+
+func TestSettingsApply(t *testing.T) {
+	cfg := DefaultConfig()
+	if err := (settings{Values: map[string]string{"max-steps": "2"}}).apply(cfg); err != nil || cfg.MaxSteps != 2 {
+		t.Errorf("max-steps = %d, err = %v", cfg.MaxSteps, err)
+	}
+	if err := (settings{Values: map[string]string{"models": "openai:gpt-4o"}}).apply(cfg); err == nil {
+		t.Error("startup-only flags should not be configurable")
+	}
+	if err := (settings{Values: map[string]string{"concurrency": "3", "dry-run": "true"}}).apply(cfg); err != nil || cfg.Concurrency != 3 || !cfg.DryRun {
+		t.Errorf("concurrency = %d, dry-run = %v, err = %v", cfg.Concurrency, cfg.DryRun, err)
+	}
+	if err := (settings{Values: map[string]string{"max-steps": "lots"}}).apply(cfg); err == nil {
+		t.Error("invalid values should be reported")
+	}
+
+	explicit := DefaultConfig()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	explicit.RegisterFlags(fs)
+	if err := fs.Parse([]string{"-max-steps=7"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := (settings{Values: map[string]string{"max-steps": "2"}}).apply(explicit); err != nil || explicit.MaxSteps != 7 {
+		t.Errorf("max-steps = %d, err = %v; the command line should win", explicit.MaxSteps, err)
+	}
+}
+
+func TestConfigSlashCommand(t *testing.T) {
+	m := NewModel(context.Background(), nil, t.TempDir(), nil)
+	m.configPath = filepath.Join(t.TempDir(), "config.json")
+	m.mode = ui.ModeChat
+
+	m.handleSlashCommand("/config")
+	if m.mode != ui.ModeConfig || len(m.list.Items()) != len(configurable) {
+		t.Fatalf("mode = %v, items = %d", m.mode, len(m.list.Items()))
+	}
+	m.editConfigItem(configItem{name: "max-steps", value: "5"})
+	if m.mode != ui.ModeChat || m.textarea.Value() != "/config set max-steps 5" {
+		t.Errorf("mode = %v, input = %q", m.mode, m.textarea.Value())
+	}
+
+	m.handleSlashCommand("/config set max-steps 2")
+	if m.cfg.MaxSteps != 2 {
+		t.Errorf("max-steps = %d; the change should take effect immediately", m.cfg.MaxSteps)
+	}
+	if got := loadSettings(m.configPath).Values["max-steps"]; got != "2" {
+		t.Errorf("persisted max-steps = %q", got)
+	}
+
+	m.handleSlashCommand(`/config set path-pattern ^# file: (\S+)  $`)
+	if m.cfg.PathPattern == nil || m.cfg.PathPattern.String() != `^# file: (\S+)  $` {
+		t.Errorf("path-pattern = %v; want the whole rest of the line", m.cfg.PathPattern)
+	}
+	m.handleSlashCommand("/config set lang go")
+	m.handleSlashCommand("/config set lang")
+	if m.cfg.Lang != "" || loadSettings(m.configPath).Values["lang"] != "" {
+		t.Errorf("lang = %q; an empty value should clear it", m.cfg.Lang)
+	}
+
+	m.handleSlashCommand("/config set nope 1")
+	if !strings.Contains(m.output, `unknown setting "nope"`) {
+		t.Errorf("expected unknown-setting error, got %q", m.output)
+	}
+}
+
+func TestInputHeightAppliedOnResize(t *testing.T) {
+	m := NewModel(context.Background(), nil, t.TempDir(), nil)
+	m.configPath = filepath.Join(t.TempDir(), "config.json")
+	m.mode = ui.ModeChat
+	m.Update(tea.WindowSizeMsg{Width: 100, Height: 40})
+	base := m.viewport.Height
+
+	m.Update(tea.KeyMsg{Type: tea.KeyCtrlUp})
+	m.Update(tea.KeyMsg{Type: tea.KeyCtrlUp})
+	if m.textarea.Height() != defaultInputHeight+2 || m.viewport.Height != base-2 {
+		t.Fatalf("input height = %d, viewport = %d (was %d)", m.textarea.Height(), m.viewport.Height, base)
+	}
+	if got := loadSettings(m.configPath).InputHeight; got != defaultInputHeight+2 {
+		t.Errorf("persisted input height = %d", got)
+	}
+
+	// A new session restores the stored height and lays out around it.
+	cfg := DefaultConfig()
+	cfg.StateDir = t.TempDir()
+	if err := (settings{InputHeight: defaultInputHeight + 2}).save(filepath.Join(cfg.StateDir, "config.json")); err != nil {
+		t.Fatal(err)
+	}
+	next := NewModel(context.Background(), nil, t.TempDir(), cfg)
+	next.Update(tea.WindowSizeMsg{Width: 100, Height: 40})
+	if next.viewport.Height != base-2 {
+		t.Errorf("restored viewport height = %d, want %d", next.viewport.Height, base-2)
+	}
+
+	m.resizeInput(100)
+	if m.textarea.Height() > 20 {
+		t.Errorf("input height %d should be capped", m.textarea.Height())
+	}
+}
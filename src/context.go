@@ -1,17 +1,33 @@
 package src
 
 import (
+	"bytes"
 	"fmt"
-	"io/fs"
 	"os"
 	"path/filepath"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/Protocol-Lattice/go-agent/src/models"
+
+	"github.com/Protocol-Lattice/lattice-code/src/outline"
 )
 
+// contextLimits bound every snapshot of the workspace: the file tree and
+// attachments of a request and what the UI reports as in context. Models
+// with larger context windows can take more.
+type contextLimits struct {
+	maxFiles      int
+	maxTotalBytes int64
+	perFileLimit  int64
+}
+
+func (c *Config) contextLimits() contextLimits {
+	return contextLimits{maxFiles: c.ContextMaxFiles, maxTotalBytes: c.ContextMaxBytes, perFileLimit: c.ContextFileBytes}
+}
+
 type fileEntry struct {
 	Rel  string
 	Abs  string
@@ -22,6 +38,8 @@ func isIgnoredDir(name string) bool {
 	ignored := map[string]struct{}{
 		".git": {}, "node_modules": {}, "dist": {}, "build": {}, "out": {}, "target": {}, "vendor": {},
 		".venv": {}, "__pycache__": {}, ".idea": {}, ".vscode": {}, ".DS_Store": {},
+		// The tool's own manifest, logs, plans and transcripts must never be fed back to the model.
+		latticeDir: {},
 	}
 	_, ok := ignored[name]
 	return ok
@@ -90,43 +108,10 @@ func buildTree(files []fileEntry) string {
 	return strings.Join(lines, "\n")
 }
 
+// fenceLangFromExt returns the code fence tag for a file extension, or ""
+// when the languages table has none.
 func fenceLangFromExt(ext string) string {
-	switch strings.TrimPrefix(strings.ToLower(ext), ".") {
-	case "go":
-		return "go"
-	case "py":
-		return "python"
-	case "js":
-		return "javascript"
-	case "ts", "tsx":
-		return "ts"
-	case "jsx":
-		return "jsx"
-	case "rs":
-		return "rust"
-	case "rb":
-		return "ruby"
-	case "java":
-		return "java"
-	case "c":
-		return "c"
-	case "cpp", "hpp", "cc", "cxx":
-		return "cpp"
-	case "h":
-		return "c"
-	case "json":
-		return "json"
-	case "yaml", "yml":
-		return "yaml"
-	case "md":
-		return "md"
-	case "sh":
-		return "bash"
-	case "toml":
-		return "toml"
-	default:
-		return ""
-	}
+	return langByExt[strings.TrimPrefix(strings.ToLower(ext), ".")]
 }
 
 func HumanSize(n int64) string {
@@ -148,6 +133,9 @@ func HumanSize(n int64) string {
 }
 
 func mimeForPath(rel string) string {
+	if isEnvFile(rel) {
+		return "text/plain"
+	}
 	ext := strings.ToLower(filepath.Ext(rel))
 	switch ext {
 	case ".md":
@@ -173,44 +161,65 @@ func mimeForPath(rel string) string {
 	}
 }
 
-func trim(s string, n int) string {
-	if len(s) <= n {
-		return s
+// truncateHeadTail caps b at limit bytes by keeping a head and a tail slice,
+// split by headRatio, joined with a marker noting how much was dropped.
+func truncateHeadTail(b []byte, limit int64, headRatio float64) []byte {
+	if int64(len(b)) <= limit {
+		return b
 	}
-	return s[:n] + "…"
+	if headRatio < 0 {
+		headRatio = 0
+	} else if headRatio > 1 {
+		headRatio = 1
+	}
+	head := int64(float64(limit) * headRatio)
+	tail := limit - head
+	dropped := int64(len(b)) - limit
+	marker := fmt.Sprintf("\n… (truncated %d bytes) …\n", dropped)
+
+	out := make([]byte, 0, limit+int64(len(marker)))
+	out = append(out, b[:head]...)
+	out = append(out, marker...)
+	out = append(out, b[int64(len(b))-tail:]...)
+	return out
 }
 
-func collectFiles(root, ext string) []string {
-	var out []string
-	_ = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
-		if err != nil || d.IsDir() {
-			return err
-		}
-		if strings.EqualFold(filepath.Ext(p), ext) {
-			out = append(out, p)
-		}
-		return nil
-	})
-	return out
+// numberLines prefixes every line of b with its 1-based line number, padded
+// to a common width. A trailing newline does not start an extra line.
+func numberLines(b []byte) []byte {
+	if len(b) == 0 {
+		return b
+	}
+	lines := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+	width := len(strconv.Itoa(len(lines)))
+	var out strings.Builder
+	out.Grow(len(b) + len(lines)*(width+3))
+	for i, line := range lines {
+		fmt.Fprintf(&out, "%*d | %s\n", width, i+1, line)
+	}
+	return []byte(out.String())
 }
 
-func collectFilesMany(root string, exts []string) []string {
-	extSet := map[string]struct{}{}
-	for _, e := range exts {
-		extSet[strings.ToLower(e)] = struct{}{}
+// fileMetaLine describes a file's type and how much of it made it into the
+// snapshot, so the model knows when it is looking at a partial view. size is
+// the file's own size; sent is the size of the text sent for it, larger once
+// lines are numbered, and is what the per-file limit is judged against.
+func fileMetaLine(rel string, size, sent, perFileLimit int64) string {
+	meta := fmt.Sprintf("_mime: %s · size: %s", mimeForPath(rel), HumanSize(size))
+	if sent != size {
+		meta += fmt.Sprintf(" · numbered: %s", HumanSize(sent))
 	}
+	if sent <= perFileLimit {
+		return meta + " · complete_"
+	}
+	return meta + fmt.Sprintf(" · included: %s · TRUNCATED (middle omitted)_", HumanSize(perFileLimit))
+}
 
-	var out []string
-	_ = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
-		if err != nil || d.IsDir() {
-			return err
-		}
-		if _, ok := extSet[strings.ToLower(filepath.Ext(p))]; ok {
-			out = append(out, p)
-		}
-		return nil
-	})
-	return out
+func trim(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "…"
 }
 
 func relFromTo(fromDir, absTarget string) string {
@@ -232,41 +241,63 @@ func isUnderRoot(root, target string) bool {
 	return err == nil
 }
 
+// langExts maps the names context filters accept to their extensions.
+var langExts = map[string][]string{
+	"go":         {".go"},
+	"python":     {".py"},
+	"py":         {".py"},
+	"js":         {".js", ".jsx"},
+	"ts":         {".ts", ".tsx"},
+	"typescript": {".ts", ".tsx"},
+	"rust":       {".rs"},
+	"java":       {".java"},
+	"cpp":        {".cpp", ".cc", ".cxx", ".h"},
+	"c":          {".c", ".h"},
+	"rb":         {".rb"},
+	"ruby":       {".rb"},
+	"php":        {".php"},
+	"kotlin":     {".kt"},
+	"swift":      {".swift"},
+	"dart":       {".dart"},
+	"lua":        {".lua"},
+	"r":          {".r"},
+	"scala":      {".scala"},
+}
+
+// allowedFileForLang reports whether path belongs in a context limited to
+// lang, a comma-separated set of languages such as "go,ts" for a Go backend
+// with a TypeScript frontend. Unknown names are ignored; when none is known
+// every supported file type is allowed.
 func allowedFileForLang(path, lang string) bool {
 	ext := strings.ToLower(filepath.Ext(path))
-	langExts := map[string][]string{
-		"go":         {".go"},
-		"python":     {".py"},
-		"py":         {".py"},
-		"js":         {".js", ".jsx"},
-		"ts":         {".ts", ".tsx"},
-		"typescript": {".ts", ".tsx"},
-		"rust":       {".rs"},
-		"java":       {".java"},
-		"cpp":        {".cpp", ".cc", ".cxx", ".h"},
-		"c":          {".c", ".h"},
-		"rb":         {".rb"},
-		"ruby":       {".rb"},
-		"php":        {".php"},
-		"kotlin":     {".kt"},
-		"swift":      {".swift"},
-		"dart":       {".dart"},
-		"lua":        {".lua"},
-		"r":          {".r"},
-		"scala":      {".scala"},
-	}
-
-	exts, ok := langExts[strings.ToLower(lang)]
-	if !ok {
-		return allowedFile(path)
+	known := false
+	for _, l := range strings.Split(lang, ",") {
+		exts, ok := langExts[strings.ToLower(strings.TrimSpace(l))]
+		if !ok {
+			continue
+		}
+		known = true
+		for _, e := range exts {
+			if ext == e {
+				return true
+			}
+		}
 	}
+	return !known && allowedFile(path)
+}
 
-	for _, e := range exts {
-		if ext == e {
-			return true
+// contextLanguage returns the language filter for building context: -lang
+// lowercased with blank entries dropped, or "" for every supported file type.
+// Guessing the languages from the prompt defaults to Go and misses most
+// polyglot prompts, so the context is only narrowed when -lang names them.
+func (c *Config) contextLanguage() string {
+	var langs []string
+	for _, l := range strings.Split(c.Lang, ",") {
+		if l = strings.ToLower(strings.TrimSpace(l)); l != "" {
+			langs = append(langs, l)
 		}
 	}
-	return false
+	return strings.Join(langs, ",")
 }
 
 func detectPromptLanguage(prompt string) string {
@@ -317,7 +348,49 @@ func detectPromptLanguage(prompt string) string {
 	return "go"
 }
 
-func buildCodebaseContext(root string, maxFiles int, maxTotalBytes, perFileLimit int64, langFilter string) (string, int, int64) {
+// conventionsFile, in the workspace's .lattice directory, holds project
+// rules (naming, error handling, the test framework) that every prompt
+// carries, much like an AGENTS.md.
+const conventionsFile = "conventions.md"
+
+// conventionsBlock returns root's conventions file as a prompt section to
+// put before the context, or "" when there is none or it is empty.
+func conventionsBlock(root string) string {
+	b, err := os.ReadFile(filepath.Join(root, latticeDir, conventionsFile))
+	if err != nil || strings.TrimSpace(string(b)) == "" {
+		return ""
+	}
+	return "## PROJECT CONVENTIONS\nFollow these project rules in every change:\n\n" + strings.TrimSpace(string(b)) + "\n\n"
+}
+
+// Files whose lines average more than minifiedLineBytes, like minified JS
+// or CSS, are one unreadable blob to the model; the context carries a note
+// in their place. Files under minifiedMinBytes are not checked.
+const (
+	minifiedMinBytes  = 4 << 10
+	minifiedLineBytes = 500
+)
+
+// minifiedNote returns the note standing in for b when it is minified, and
+// whether it is.
+func minifiedNote(rel string, b []byte) (string, bool) {
+	if len(b) < minifiedMinBytes {
+		return "", false
+	}
+	lines := bytes.Split(bytes.TrimSuffix(b, []byte("\n")), []byte("\n"))
+	longest := 0
+	for _, l := range lines {
+		longest = max(longest, len(l))
+	}
+	if len(b)/len(lines) <= minifiedLineBytes {
+		return "", false
+	}
+	return fmt.Sprintf("_mime: %s · size: %s · MINIFIED (%d line(s), longest %s; body omitted)_",
+		mimeForPath(rel), HumanSize(int64(len(b))), len(lines), HumanSize(int64(longest))), true
+}
+
+func buildCodebaseContext(cfg *Config, root string, maxFiles int, maxTotalBytes, perFileLimit int64, langFilter string) (string, int, int64) {
+	cfg = cfg.orDefault()
 	var entries []fileEntry
 	var total int64
 
@@ -331,7 +404,10 @@ func buildCodebaseContext(root string, maxFiles int, maxTotalBytes, perFileLimit
 			}
 			return nil
 		}
-		if !allowedFileForLang(path, langFilter) {
+		if !allowedFileForLang(path, langFilter) && !isEnvFile(path) {
+			return nil
+		}
+		if isSecretFile(path) {
 			return nil
 		}
 		info, err := d.Info()
@@ -344,17 +420,52 @@ func buildCodebaseContext(root string, maxFiles int, maxTotalBytes, perFileLimit
 	})
 
 	sort.Slice(entries, func(i, j int) bool { return entries[i].Rel < entries[j].Rel })
-
+	entries, pinned := withPins(root, entries)
+
+	// Oversized and minified files are summarized up front so their (much
+	// smaller) outline or note is what counts against the byte budget. Files
+	// are read ahead in batches and scanned here, so ones dropped for the
+	// secrets they hold are left out of the tree and the totals too.
+	outlines := map[string]string{}
+	notes := map[string]string{}
 	var included []fileEntry
-	for _, e := range entries {
-		if len(included) >= maxFiles {
+	var bodies [][]byte
+	var batch [][]byte
+	var batchErrs []error
+	for i, e := range entries {
+		// Pinned files come first and count towards the caps without
+		// being subject to them.
+		if i >= pinned && (len(included) >= maxFiles || total >= maxTotalBytes) {
 			break
 		}
-		if total >= maxTotalBytes {
-			break
+		if i%readBatch == 0 {
+			paths := make([]string, 0, readBatch)
+			for _, e := range entries[i:min(i+readBatch, len(entries))] {
+				paths = append(paths, e.Abs)
+			}
+			batch, batchErrs = readFiles(paths, cfg.Concurrency)
+		}
+		content, err := batch[i%readBatch], batchErrs[i%readBatch]
+		if err != nil {
+			continue
+		}
+		content, ok := sanitizeContextFile(e.Rel, content)
+		if !ok {
+			continue
 		}
-		included = append(included, e)
 		capAdd := e.Size
+		if e.Size >= minifiedMinBytes {
+			if note, ok := minifiedNote(e.Rel, content); ok {
+				notes[e.Rel] = note
+				capAdd = int64(len(note))
+			}
+		}
+		if _, ok := notes[e.Rel]; !ok && cfg.OutlineThreshold > 0 && e.Size > cfg.OutlineThreshold && outline.Supported(e.Rel) {
+			outlines[e.Rel] = outline.Format(outline.Extract(e.Rel, content))
+			capAdd = int64(len(outlines[e.Rel]))
+		}
+		included = append(included, e)
+		bodies = append(bodies, content)
 		if capAdd > perFileLimit {
 			capAdd = perFileLimit
 		}
@@ -364,14 +475,43 @@ func buildCodebaseContext(root string, maxFiles int, maxTotalBytes, perFileLimit
 	tree := buildTree(included)
 
 	var filesSection strings.Builder
-	for _, f := range included {
-		content, _ := os.ReadFile(f.Abs)
-		if int64(len(content)) > perFileLimit {
-			content = content[:perFileLimit]
+	for i, f := range included {
+		if note, ok := notes[f.Rel]; ok {
+			filesSection.WriteString("\n### ")
+			filesSection.WriteString(f.Rel)
+			filesSection.WriteString("\n")
+			filesSection.WriteString(note)
+			filesSection.WriteString("\n")
+			continue
+		}
+		if o, ok := outlines[f.Rel]; ok {
+			filesSection.WriteString("\n### ")
+			filesSection.WriteString(f.Rel)
+			filesSection.WriteString("\n")
+			filesSection.WriteString(fmt.Sprintf("_mime: %s · size: %s · OUTLINE ONLY (body omitted)_", mimeForPath(f.Rel), HumanSize(f.Size)))
+			filesSection.WriteString("\n```\n")
+			filesSection.WriteString(o)
+			filesSection.WriteString("\n```\n")
+			continue
+		}
+		content := bodies[i]
+		// Number before truncating so lines after the omitted middle keep
+		// their real numbers.
+		size := int64(len(content))
+		if cfg.LineNumbers {
+			content = numberLines(content)
 		}
+		sent := int64(len(content))
+		content = truncateHeadTail(content, perFileLimit, cfg.TruncateHeadRatio)
 		lang := fenceLangFromExt(filepath.Ext(f.Rel))
+		meta := fileMetaLine(f.Rel, size, sent, perFileLimit)
+		if cfg.LineNumbers {
+			meta = strings.TrimSuffix(meta, "_") + " · line-numbered, numbers are not part of the file_"
+		}
 		filesSection.WriteString("\n### ")
 		filesSection.WriteString(f.Rel)
+		filesSection.WriteString("\n")
+		filesSection.WriteString(meta)
 		filesSection.WriteString("\n```")
 		filesSection.WriteString(lang)
 		filesSection.WriteString("\n")
@@ -380,6 +520,7 @@ func buildCodebaseContext(root string, maxFiles int, maxTotalBytes, perFileLimit
 	}
 
 	var out strings.Builder
+	out.WriteString(conventionsBlock(root))
 	out.WriteString("## CODEBASE SNAPSHOT\n")
 	out.WriteString(fmt.Sprintf("- Root: `%s`\n", root))
 	out.WriteString(fmt.Sprintf("- Files included: %d (limit %d)\n", len(included), maxFiles))
@@ -392,7 +533,11 @@ func buildCodebaseContext(root string, maxFiles int, maxTotalBytes, perFileLimit
 	return out.String(), len(included), total
 }
 
-func collectAttachmentFiles(root string, maxFiles int, maxTotalBytes, perFileLimit int64, langFilter string) ([]models.File, []fileEntry) {
+// readBatch is how many files collectAttachmentFiles reads ahead at once.
+const readBatch = 64
+
+func collectAttachmentFiles(cfg *Config, root string, maxFiles int, maxTotalBytes, perFileLimit int64, langFilter string) ([]models.File, []fileEntry) {
+	cfg = cfg.orDefault()
 	var entries []fileEntry
 	var total int64
 
@@ -409,7 +554,10 @@ func collectAttachmentFiles(root string, maxFiles int, maxTotalBytes, perFileLim
 			}
 			return nil
 		}
-		if !allowedFileForLang(path, langFilter) {
+		if !allowedFileForLang(path, langFilter) && !isEnvFile(path) {
+			return nil
+		}
+		if isSecretFile(path) {
 			return nil
 		}
 		info, err := d.Info()
@@ -422,27 +570,47 @@ func collectAttachmentFiles(root string, maxFiles int, maxTotalBytes, perFileLim
 	})
 
 	sort.Slice(entries, func(i, j int) bool { return entries[i].Rel < entries[j].Rel })
+	entries, pinned := withPins(root, entries)
 
 	var out []models.File
 	var includedEntries []fileEntry
-	for _, e := range entries {
-		if len(out) >= maxFiles || total >= maxTotalBytes {
+	// Files are read ahead in batches, a batch at most being read in vain
+	// once the caps are reached.
+	var batch [][]byte
+	var batchErrs []error
+	for i, e := range entries {
+		// Pinned files come first and count towards the caps without
+		// being subject to them.
+		if i >= pinned && (len(out) >= maxFiles || total >= maxTotalBytes) {
 			break
 		}
-		b, err := os.ReadFile(e.Abs)
+		if i%readBatch == 0 {
+			paths := make([]string, 0, readBatch)
+			for _, e := range entries[i:min(i+readBatch, len(entries))] {
+				paths = append(paths, e.Abs)
+			}
+			batch, batchErrs = readFiles(paths, cfg.Concurrency)
+		}
+		b, err := batch[i%readBatch], batchErrs[i%readBatch]
 		if err != nil {
 			continue
 		}
-		if int64(len(b)) > perFileLimit {
-			b = b[:perFileLimit]
+		b, ok := sanitizeContextFile(e.Rel, b)
+		if !ok {
+			continue
 		}
+		add := e.Size
+		if note, ok := minifiedNote(e.Rel, b); ok {
+			b = []byte(note + "\n")
+			add = int64(len(b))
+		}
+		b = truncateHeadTail(b, perFileLimit, cfg.TruncateHeadRatio)
 		out = append(out, models.File{
 			Name: e.Rel,
 			MIME: mimeForPath(e.Rel),
 			Data: b,
 		})
 		includedEntries = append(includedEntries, e)
-		add := e.Size
 		if add > perFileLimit {
 			add = perFileLimit
 		}
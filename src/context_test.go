@@ -0,0 +1,261 @@
+package src
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Protocol-Lattice/go-agent/src/models"
+)
+
+func TestTruncateHeadTailKeepsBothEnds(t *testing.T) {
+	in := []byte("HEAD" + strings.Repeat("x", 100) + "TAIL")
+	out := string(truncateHeadTail(in, 20, 0.5))
+
+	if !strings.HasPrefix(out, "HEAD") || !strings.HasSuffix(out, "TAIL") {
+		t.Fatalf("head or tail lost: %q", out)
+	}
+	if !strings.Contains(out, "… (truncated 88 bytes) …") {
+		t.Errorf("missing truncation marker: %q", out)
+	}
+	if short := []byte("small"); string(truncateHeadTail(short, 20, 0.5)) != "small" {
+		t.Errorf("content under the limit should be unchanged")
+	}
+}
+
+func TestContextTruncationPreservesTail(t *testing.T) {
+	root := t.TempDir()
+	writeFixture(t, root, map[string]string{
+		"main.go": "package main\n" + strings.Repeat("// filler\n", 200) + "func main() {}\n",
+	})
+
+	ctxStr, _, _ := buildCodebaseContext(nil, root, 10, 1_000_000, 200, "")
+	if !strings.Contains(ctxStr, "package main") || !strings.Contains(ctxStr, "func main() {}") {
+		t.Errorf("buildCodebaseContext dropped head or tail:\n%s", ctxStr)
+	}
+
+	files, _ := collectAttachmentFiles(nil, root, 10, 1_000_000, 200, "")
+	if len(files) != 1 {
+		t.Fatalf("got %d attachments", len(files))
+	}
+	data := string(files[0].Data)
+	if !strings.HasPrefix(data, "package main") || !strings.HasSuffix(data, "func main() {}\n") {
+		t.Errorf("collectAttachmentFiles dropped head or tail: %q", data)
+	}
+}
+
+func TestFileMetaLine(t *testing.T) {
+	if got, want := fileMetaLine("main.go", 512, 512, 1024), "_mime: text/plain · size: 512 B · complete_"; got != want {
+		t.Errorf("full file: got %q; want %q", got, want)
+	}
+	if got, want := fileMetaLine("app.json", 4096, 4096, 2048), "_mime: application/json · size: 4 KB · included: 2 KB · TRUNCATED (middle omitted)_"; got != want {
+		t.Errorf("truncated file: got %q; want %q", got, want)
+	}
+	if got, want := fileMetaLine("main.go", 1000, 1100, 1024), "_mime: text/plain · size: 1000 B · numbered: 1 KB · included: 1 KB · TRUNCATED (middle omitted)_"; got != want {
+		t.Errorf("numbered past the limit: got %q; want %q", got, want)
+	}
+}
+
+func TestContextSkipsLatticeDir(t *testing.T) {
+	root := t.TempDir()
+	writeFixture(t, root, map[string]string{
+		"main.go":                "package main\n",
+		".lattice/manifest.json": `{"files":["main.go"]}`,
+		".lattice/transcript.md": "# transcript\n",
+		"sub/.lattice/plan.json": "{}",
+	})
+
+	ctxStr, n, _ := buildCodebaseContext(nil, root, 10, 1_000_000, 10_000, "")
+	if n != 1 || strings.Contains(ctxStr, ".lattice") {
+		t.Errorf("buildCodebaseContext walked .lattice (%d files):\n%s", n, ctxStr)
+	}
+	files, _ := collectAttachmentFiles(nil, root, 10, 1_000_000, 10_000, "")
+	for _, f := range files {
+		if strings.Contains(f.Name, ".lattice") {
+			t.Errorf("collectAttachmentFiles included %s", f.Name)
+		}
+	}
+}
+
+func TestContextOutlinesOversizedFiles(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.OutlineThreshold = 200
+
+	root := t.TempDir()
+	writeFixture(t, root, map[string]string{
+		"big.go":   "package big\n\nfunc Exported() {\n" + strings.Repeat("\tx := 1\n", 100) + "}\n",
+		"small.go": "package small\n\nvar answer = 42\n",
+	})
+
+	ctxStr, _, _ := buildCodebaseContext(cfg, root, 10, 1_000_000, 10_000, "")
+	if !strings.Contains(ctxStr, "OUTLINE ONLY") || !strings.Contains(ctxStr, "Line 3: func Exported() {") {
+		t.Errorf("big.go should be outlined:\n%s", ctxStr)
+	}
+	if strings.Contains(ctxStr, "x := 1") {
+		t.Errorf("outlined body leaked into the snapshot")
+	}
+	if !strings.Contains(ctxStr, "var answer = 42") {
+		t.Errorf("small.go should keep its full content")
+	}
+}
+
+func TestNumberLinesMatchesLineCount(t *testing.T) {
+	src := "package main\n\nfunc main() {\n}\n"
+	out := string(numberLines([]byte(src)))
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	if want := strings.Count(src, "\n"); len(lines) != want {
+		t.Fatalf("numbered %d lines, file has %d:\n%s", len(lines), want, out)
+	}
+	if lines[0] != "1 | package main" || lines[3] != "4 | }" {
+		t.Errorf("unexpected numbering:\n%s", out)
+	}
+
+	long := strings.Repeat("x\n", 12)
+	lines = strings.Split(strings.TrimSuffix(string(numberLines([]byte(long))), "\n"), "\n")
+	if len(lines) != 12 || lines[0] != " 1 | x" || lines[11] != "12 | x" {
+		t.Errorf("numbers should be padded to a common width: %q", lines)
+	}
+}
+
+func TestContextLineNumbersToggle(t *testing.T) {
+	root := t.TempDir()
+	writeFixture(t, root, map[string]string{"main.go": "package main\n\nfunc main() {}\n"})
+
+	ctxStr, _, _ := buildCodebaseContext(nil, root, 10, 1_000_000, 10_000, "")
+	if strings.Contains(ctxStr, "1 | package main") {
+		t.Error("line numbers should be off by default")
+	}
+
+	cfg := DefaultConfig()
+	cfg.LineNumbers = true
+	ctxStr, _, _ = buildCodebaseContext(cfg, root, 10, 1_000_000, 10_000, "")
+	for _, want := range []string{"1 | package main", "3 | func main() {}", "size: 29 B · numbered: ", "line-numbered"} {
+		if !strings.Contains(ctxStr, want) {
+			t.Errorf("snapshot missing %q:\n%s", want, ctxStr)
+		}
+	}
+}
+
+func TestFenceLangFromExt(t *testing.T) {
+	cases := map[string]string{
+		".go": "go", ".TSX": "tsx", ".h": "c", ".hpp": "cpp",
+		".ini": "ini", ".cfg": "ini", ".conf": "ini", ".toml": "toml",
+		".proto": "proto", ".graphql": "graphql", ".gql": "graphql",
+		".sql": "sql", ".dockerfile": "dockerfile", ".tf": "hcl", ".mk": "makefile",
+		".txt": "", ".unknown": "",
+	}
+	for ext, want := range cases {
+		if got := fenceLangFromExt(ext); got != want {
+			t.Errorf("fenceLangFromExt(%q) = %q, want %q", ext, got, want)
+		}
+	}
+	// Writing a block under the tag fenceLangFromExt gives it must keep the
+	// file's language.
+	for _, l := range languages {
+		if !strings.HasPrefix(l.ext, ".") {
+			continue
+		}
+		if got := fenceLangFromExt(extFromLang(fenceLangFromExt(l.ext))); got != l.tag {
+			t.Errorf("%s does not round-trip: got tag %q", l.ext, got)
+		}
+	}
+}
+
+func TestContextMultiLanguageFilter(t *testing.T) {
+	root := t.TempDir()
+	writeFixture(t, root, map[string]string{
+		"api/main.go":  "package main\n",
+		"web/app.tsx":  "export {}\n",
+		"tools/gen.py": "print()\n",
+	})
+
+	files, _ := collectAttachmentFiles(nil, root, 10, 1_000_000, 10_000, "go, TS")
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Name)
+	}
+	if strings.Join(names, " ") != "api/main.go web/app.tsx" {
+		t.Errorf("attachments = %v; want the Go and TypeScript files", names)
+	}
+	ctxStr, n, _ := buildCodebaseContext(nil, root, 10, 1_000_000, 10_000, "go,ts")
+	if n != 2 || strings.Contains(ctxStr, "gen.py") {
+		t.Errorf("context has %d files, want api/main.go and web/app.tsx:\n%s", n, ctxStr)
+	}
+	if files, _ := collectAttachmentFiles(nil, root, 10, 1_000_000, 10_000, "cobol"); len(files) != 3 {
+		t.Errorf("unknown language kept %d files; want all 3", len(files))
+	}
+}
+
+func TestContextLimitsApplyToEveryBuilder(t *testing.T) {
+	GlobalChanges = NewChangeTracker()
+	root := t.TempDir()
+	writeFixture(t, root, map[string]string{
+		"a.go": "package a\n" + strings.Repeat("// filler\n", 50),
+		"b.go": "package a\n",
+		"c.go": "package a\n",
+	})
+	cfg := DefaultConfig()
+	cfg.ContextMaxFiles, cfg.ContextFileBytes = 2, 100
+
+	check := func(name string, files []models.File) {
+		t.Helper()
+		seen := map[string]bool{}
+		for _, f := range files {
+			seen[f.Name] = true
+			if len(f.Data) > 200 {
+				t.Errorf("%s: %s kept %d bytes; want it cut to about 100", name, f.Name, len(f.Data))
+			}
+		}
+		if len(seen) != 2 {
+			t.Errorf("%s: %d distinct files; want 2", name, len(seen))
+		}
+	}
+
+	m := NewModel(context.Background(), nil, root, cfg)
+	files, _ := m.refreshContext()
+	check("refreshContext", files)
+	if m.contextFiles != 2 {
+		t.Errorf("contextFiles = %d; want 2", m.contextFiles)
+	}
+
+	llm := &stubLLM{reply: "done"}
+	ag := newStubAgent(t, llm)
+	if _, err := RunHeadless(context.Background(), ag, root, "tweak a.go", HeadlessOptions{Config: cfg}); err != nil {
+		t.Fatal(err)
+	}
+	check("RunHeadless", llm.calls[len(llm.calls)-1])
+	if _, _, err := RunExplain(context.Background(), ag, cfg, "", root, "what is this?", ""); err != nil {
+		t.Fatal(err)
+	}
+	check("RunExplain", llm.calls[len(llm.calls)-1])
+}
+
+func TestContextNotesMinifiedFiles(t *testing.T) {
+	root := t.TempDir()
+	blob := "!function(){" + strings.Repeat("var a=1;", 3_000) + "}();"
+	writeFixture(t, root, map[string]string{
+		"web/app.min.js": blob,
+		"web/app.js":     strings.Repeat("const a = 1;\n", 600),
+	})
+
+	ctxStr, n, total := buildCodebaseContext(nil, root, 10, 1_000_000, 100_000, "")
+	if n != 2 || strings.Contains(ctxStr, "var a=1;var a=1;") {
+		t.Errorf("snapshot has the minified body:\n%s", ctxStr)
+	}
+	if !strings.Contains(ctxStr, "### web/app.min.js\n_mime: ") || !strings.Contains(ctxStr, "MINIFIED (1 line(s)") {
+		t.Errorf("snapshot lacks the minified note:\n%s", ctxStr)
+	}
+	if !strings.Contains(ctxStr, "const a = 1;") {
+		t.Error("readable file should be included in full")
+	}
+	if total >= int64(len(blob)) {
+		t.Errorf("minified file counted %d bytes against the budget", total)
+	}
+
+	files, _ := collectAttachmentFiles(nil, root, 10, 1_000_000, 100_000, "")
+	for _, f := range files {
+		if f.Name == "web/app.min.js" && (len(f.Data) > 200 || !strings.Contains(string(f.Data), "MINIFIED")) {
+			t.Errorf("attachment of the minified file = %q", f.Data)
+		}
+	}
+}
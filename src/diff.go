@@ -0,0 +1,183 @@
+package src
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Limits on what a workspace snapshot keeps in memory.
+const (
+	maxSnapshotFile  = 1 << 20
+	maxSnapshotBytes = 64 << 20
+)
+
+// workspaceSnapshot holds the source files of a workspace, keyed by slash
+// path relative to its root. It is the baseline /diff compares against
+// outside git.
+type workspaceSnapshot map[string][]byte
+
+// walkSnapshotFiles calls fn for each file a snapshot covers: allowed source
+// files outside ignored directories, up to maxSnapshotFile in size.
+func walkSnapshotFiles(root string, fn func(rel, abs string)) {
+	_ = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if p != root && isIgnoredDir(d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !allowedFile(p) {
+			return nil
+		}
+		if info, err := d.Info(); err != nil || info.Size() > maxSnapshotFile {
+			return nil
+		}
+		if rel, err := filepath.Rel(root, p); err == nil {
+			fn(filepath.ToSlash(rel), p)
+		}
+		return nil
+	})
+}
+
+// takeSnapshot records the workspace's current source files, stopping once
+// maxSnapshotBytes have been read.
+func takeSnapshot(root string) workspaceSnapshot {
+	snap := workspaceSnapshot{}
+	total := 0
+	walkSnapshotFiles(root, func(rel, abs string) {
+		if total >= maxSnapshotBytes {
+			return
+		}
+		if b, err := os.ReadFile(abs); err == nil {
+			snap[rel] = b
+			total += len(b)
+		}
+	})
+	return snap
+}
+
+// snapshotDiff renders the changes from snap to the workspace as it is now:
+// edited, new and deleted files, in path order.
+func snapshotDiff(root string, snap workspaceSnapshot, maxBytes int64) string {
+	current := map[string][]byte{}
+	walkSnapshotFiles(root, func(rel, abs string) {
+		if b, err := os.ReadFile(abs); err == nil {
+			current[rel] = b
+		}
+	})
+	paths := map[string]bool{}
+	for p := range snap {
+		paths[p] = true
+	}
+	for p := range current {
+		paths[p] = true
+	}
+	files := make(map[string][2][]byte, len(paths))
+	for p := range paths {
+		files[p] = [2][]byte{snap[p], current[p]}
+	}
+	return renderDiffs(files, maxBytes)
+}
+
+// gitSessionDiff renders git diff rev, plus untracked files, through
+// DiffPretty. Paths are relative to root even when it is a subdirectory of
+// the repository. The tool's own .lattice files are left out.
+func gitSessionDiff(ctx context.Context, root, rev string, maxBytes int64) (string, error) {
+	changed, err := git(ctx, root, "diff", rev, "--name-only", "--relative", "-z", "--", ".")
+	if err != nil {
+		return "", fmt.Errorf("git diff: %s", changed)
+	}
+	untracked, err := git(ctx, root, "ls-files", "--others", "--exclude-standard", "-z", "--", ".")
+	if err != nil {
+		return "", fmt.Errorf("git ls-files: %s", untracked)
+	}
+	files := map[string][2][]byte{}
+	for _, p := range strings.Split(changed+"\x00"+untracked, "\x00") {
+		if p == "" || strings.HasPrefix(p, latticeDir+"/") {
+			continue
+		}
+		old, _ := gitShow(ctx, root, rev, p) // nil for files new since rev
+		var cur []byte
+		if b, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(p))); err == nil {
+			cur = b
+		}
+		files[p] = [2][]byte{old, cur}
+	}
+	return renderDiffs(files, maxBytes), nil
+}
+
+// renderDiffs concatenates the DiffPretty output of each old/new pair in
+// path order, skipping unchanged pairs.
+func renderDiffs(files map[string][2][]byte, maxBytes int64) string {
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	var out strings.Builder
+	for _, p := range paths {
+		out.WriteString(GlobalChanges.DiffPretty(p, files[p][0], files[p][1], maxBytes))
+	}
+	return out.String()
+}
+
+type diffMsg struct {
+	diff, since string
+	err         error
+}
+
+// diffSlashCommand handles "/diff": everything changed since the session
+// started, against the commit that was HEAD then in a git workspace, so
+// changes committed with -git-commit still show. Without a recorded commit
+// it diffs against HEAD. The diff is built in the background, since large
+// files can take a while.
+func diffSlashCommand(m *model, _ string) (*model, tea.Cmd) {
+	ctx, root, baseline, maxBytes := m.ctx, m.working, m.baseline, m.cfg.MaxDiffBytes
+	var cmd tea.Cmd
+	switch {
+	case hasHead(ctx, root):
+		rev, since := m.baseRev, "the start of the session"
+		if rev == "" {
+			rev, since = "HEAD", "HEAD"
+		}
+		cmd = func() tea.Msg {
+			diff, err := gitSessionDiff(ctx, root, rev, maxBytes)
+			return diffMsg{diff: diff, since: since, err: err}
+		}
+	case baseline != nil:
+		cmd = func() tea.Msg {
+			return diffMsg{diff: snapshotDiff(root, baseline, maxBytes), since: "the start of the session"}
+		}
+	default:
+		m.baseline = takeSnapshot(m.working)
+		m.output += m.style.Subtle.Render("ℹ️ no session snapshot yet; later /diff calls compare against the workspace as it is now\n")
+		m.renderOutput(true)
+		return m, nil
+	}
+	return m, tea.Batch(cmd, m.startWork("diffing"))
+}
+
+// showDiff reports a /diff result in the chat.
+func (m *model) showDiff(msg diffMsg) {
+	m.endWork()
+	switch {
+	case msg.err != nil:
+		m.output += m.style.Error.Render(fmt.Sprintf("❌ /diff: %v\n", msg.err))
+	case msg.diff == "":
+		m.output += m.style.Subtle.Render(fmt.Sprintf("ℹ️ no changes since %s\n", msg.since))
+	default:
+		m.output += m.style.Accent.Render(fmt.Sprintf("changes since %s:", msg.since)) + "\n\n" +
+			m.style.Subtle.Render("```diff") + "\n" + msg.diff + m.style.Subtle.Render("```") + "\n"
+	}
+	m.renderOutput(true)
+}
@@ -0,0 +1,120 @@
+package src
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+func TestSnapshotDiffCoversSession(t *testing.T) {
+	root := t.TempDir()
+	writeFixture(t, root, map[string]string{
+		"main.go":                 "package main\n\nfunc main() {}\n",
+		"old.go":                  "package main\n",
+		"same.go":                 "package main\n",
+		"node_modules/dep/dep.js": "module.exports = 1\n",
+	})
+	snap := takeSnapshot(root)
+	if _, ok := snap["node_modules/dep/dep.js"]; ok || len(snap) != 3 {
+		t.Fatalf("snapshot = %v", snap)
+	}
+
+	// Two runs touch main.go; the diff is cumulative.
+	writeFixture(t, root, map[string]string{"main.go": "package main\n\nfunc main() { run() }\n"})
+	writeFixture(t, root, map[string]string{"main.go": "package main\n\nfunc main() { run() }\n\nfunc run() {}\n", "new.go": "package main\n"})
+	if err := os.Remove(filepath.Join(root, "old.go")); err != nil {
+		t.Fatal(err)
+	}
+
+	diff := ansi.Strip(snapshotDiff(root, snap, 0))
+	for _, want := range []string{"--- a/main.go", "-func main() {}", "+func main() { run() }", "+func run() {}", "+++ b/new.go", "--- a/old.go"} {
+		if !strings.Contains(diff, want) {
+			t.Errorf("diff lacks %q:\n%s", want, diff)
+		}
+	}
+	if strings.Contains(diff, "same.go") {
+		t.Errorf("unchanged file in diff:\n%s", diff)
+	}
+	if i, j, k := strings.Index(diff, "main.go"), strings.Index(diff, "new.go"), strings.Index(diff, "old.go"); !(i < j && j < k) {
+		t.Errorf("files out of order:\n%s", diff)
+	}
+}
+
+func TestDiffSlashCommandWithoutGit(t *testing.T) {
+	root := t.TempDir()
+	writeFixture(t, root, map[string]string{"a.go": "package a\n"})
+	m := NewModel(context.Background(), nil, root, nil)
+	m.baseline = takeSnapshot(root)
+	run := func() {
+		t.Helper()
+		before := m.output
+		_, cmd := diffSlashCommand(m, "")
+		if !m.isThinking || m.output != before {
+			t.Fatalf("/diff should be built in the background: thinking=%v output=%q", m.isThinking, m.output)
+		}
+		m.Update(cmd().(tea.BatchMsg)[0]())
+		if m.isThinking {
+			t.Error("the posted result should end the work")
+		}
+	}
+	run()
+	if !strings.Contains(m.output, "no changes since the start of the session") {
+		t.Errorf("output = %q", m.output)
+	}
+	writeFixture(t, root, map[string]string{"a.go": "package a\n\nvar X = 1\n"})
+	run()
+	if !strings.Contains(ansi.Strip(m.output), "+var X = 1") {
+		t.Errorf("output = %q", m.output)
+	}
+}
+
+func TestDiffPrettySkipsFilesOverThreshold(t *testing.T) {
+	before := []byte(strings.Repeat("line\n", 15))
+	after := []byte(strings.Repeat("line\n", 7) + "changed\n" + strings.Repeat("line\n", 7))
+	diff := ansi.Strip(GlobalChanges.DiffPretty("big.txt", before, after, 100))
+	if !strings.Contains(diff, "diff --git a/big.txt b/big.txt") || !strings.Contains(diff, "file too large to diff, 7 bytes changed") {
+		t.Errorf("diff = %q", diff)
+	}
+	if strings.Contains(diff, "@@") {
+		t.Errorf("an oversized file should not get hunks:\n%s", diff)
+	}
+
+	if diff := ansi.Strip(GlobalChanges.DiffPretty("big.txt", before, after, 0)); !strings.Contains(diff, "+changed") {
+		t.Errorf("without a threshold the file should be diffed:\n%s", diff)
+	}
+}
+
+func TestDiffSlashCommandInGitSubdirectory(t *testing.T) {
+	repo := gitRepo(t, map[string]string{"README.md": "hi\n", "app/main.go": "package main\n"})
+	root := filepath.Join(repo, "app")
+	m := NewModel(context.Background(), nil, root, nil)
+	rev, err := git(m.ctx, root, "rev-parse", "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.baseRev = rev
+
+	// A committed run, as with -git-commit, and an untracked file.
+	writeFixture(t, root, map[string]string{"main.go": "package main\n\nvar X = 1\n"})
+	if out, err := git(m.ctx, root, "commit", "-q", "-am", "run"); err != nil {
+		t.Fatalf("git commit: %v\n%s", err, out)
+	}
+	writeFixture(t, root, map[string]string{"new.go": "package main\n"})
+
+	_, cmd := diffSlashCommand(m, "")
+	m.Update(cmd().(tea.BatchMsg)[0]())
+	out := ansi.Strip(m.output)
+	for _, want := range []string{"changes since the start of the session", "--- a/main.go", "+var X = 1", "+++ b/new.go"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "app/") || strings.Contains(out, "README") {
+		t.Errorf("paths should be relative to the workspace:\n%s", out)
+	}
+}
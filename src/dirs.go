@@ -4,11 +4,45 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/charmbracelet/bubbles/list"
+
+	"github.com/Protocol-Lattice/lattice-code/src/ui"
+)
+
+// projectMarkers are well-known files whose presence identifies a project root.
+var projectMarkers = []string{"go.mod", "package.json", "Cargo.toml", "pyproject.toml", "requirements.txt", "pom.xml"}
+
+// dirSort selects the ordering of subdirectories in the ModeDir list.
+type dirSort int
+
+const (
+	sortByName dirSort = iota
+	sortByModTime
 )
 
-func loadDirs(path string) []list.Item {
+func (s dirSort) String() string {
+	if s == sortByModTime {
+		return "modified"
+	}
+	return "name"
+}
+
+// dirOptions controls how loadDirs lists a directory.
+type dirOptions struct {
+	sortBy     dirSort
+	details    bool // include entry counts and modification times
+	showHidden bool // list dot-directories and isIgnoredDir entries
+}
+
+// hiddenDir reports whether a directory is hidden from navigation by default.
+func hiddenDir(name string) bool {
+	return strings.HasPrefix(name, ".") || isIgnoredDir(name)
+}
+
+func loadDirs(path string, opts dirOptions) []list.Item {
 	entries, err := os.ReadDir(path)
 	if path == "" {
 		path, _ = os.Getwd()
@@ -28,10 +62,95 @@ func loadDirs(path string) []list.Item {
 	}
 
 	// 3. Add subdirectories
+	var subdirs []dirItem
 	for _, e := range entries { // Already sorted by ReadDir
-		if e.IsDir() {
-			items = append(items, dirItem{name: "📁 " + e.Name() + "/", path: filepath.Join(path, e.Name())})
+		if !e.IsDir() || (!opts.showHidden && hiddenDir(e.Name())) {
+			continue
+		}
+		item := dirItem{name: "📁 " + e.Name() + "/", path: filepath.Join(path, e.Name()), details: opts.details}
+		if opts.details || opts.sortBy == sortByModTime {
+			if info, err := e.Info(); err == nil {
+				item.modTime = info.ModTime()
+			}
+		}
+		if opts.details {
+			if children, err := os.ReadDir(item.path); err == nil {
+				item.entries = len(children)
+			}
 		}
+		subdirs = append(subdirs, item)
+	}
+	if opts.sortBy == sortByModTime {
+		sort.SliceStable(subdirs, func(i, j int) bool { return subdirs[i].modTime.After(subdirs[j].modTime) })
+	}
+	for _, d := range subdirs {
+		items = append(items, d)
 	}
 	return items
 }
+
+// summarizeDir inspects the immediate contents of path for the ModeDir preview panel.
+func summarizeDir(path string) ui.DirPreview {
+	p := ui.DirPreview{Path: path}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		p.Err = err.Error()
+		return p
+	}
+
+	langs := map[string]struct{}{}
+	for _, e := range entries {
+		if e.IsDir() {
+			p.Dirs++
+			continue
+		}
+		p.Files++
+		if lang := fenceLangFromExt(filepath.Ext(e.Name())); lang != "" {
+			langs[lang] = struct{}{}
+		}
+	}
+	for l := range langs {
+		p.Languages = append(p.Languages, l)
+	}
+	sort.Strings(p.Languages)
+
+	for _, marker := range projectMarkers {
+		if info, err := os.Stat(filepath.Join(path, marker)); err == nil && !info.IsDir() {
+			p.Markers = append(p.Markers, marker)
+		}
+	}
+	return p
+}
+
+// reloadDirs repopulates the directory list for the current working directory.
+// Pinned and recent directories are offered right after the navigation items.
+func (m *model) reloadDirs() {
+	m.dirPreviews = nil
+	items := loadDirs(m.working, m.dirOpts)
+	nav := 1
+	if len(items) > 1 && items[1].(dirItem).name == "⬆️ ../" {
+		nav = 2
+	}
+	if picks := m.recents.quickPicks(m.working); len(picks) > 0 {
+		items = append(items[:nav:nav], append(picks, items[nav:]...)...)
+	}
+	m.dirlist.SetItems(items)
+	m.dirlist.Select(0)
+}
+
+// selectedDirPreview returns the (cached) summary of the highlighted directory.
+func (m *model) selectedDirPreview() *ui.DirPreview {
+	item, ok := m.dirlist.SelectedItem().(dirItem)
+	if !ok {
+		return nil
+	}
+	if m.dirPreviews == nil {
+		m.dirPreviews = map[string]ui.DirPreview{}
+	}
+	p, ok := m.dirPreviews[item.path]
+	if !ok {
+		p = summarizeDir(item.path)
+		m.dirPreviews[item.path] = p
+	}
+	return &p
+}
@@ -0,0 +1,166 @@
+package src
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+)
+
+func writeFixture(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for rel, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSummarizeDirGoProject(t *testing.T) {
+	root := t.TempDir()
+	writeFixture(t, root, map[string]string{
+		"go.mod":          "module example.com/app\n",
+		"main.go":         "package main\n",
+		"README.md":       "# app\n",
+		"web/index.ts":    "export {}\n",
+		"internal/x/x.go": "package x\n",
+	})
+
+	p := summarizeDir(root)
+
+	if p.Files != 3 || p.Dirs != 2 {
+		t.Errorf("got %d files, %d dirs; want 3 files, 2 dirs", p.Files, p.Dirs)
+	}
+	if want := []string{"go", "md"}; !reflect.DeepEqual(p.Languages, want) {
+		t.Errorf("Languages = %v; want %v", p.Languages, want)
+	}
+	if want := []string{"go.mod"}; !reflect.DeepEqual(p.Markers, want) {
+		t.Errorf("Markers = %v; want %v", p.Markers, want)
+	}
+}
+
+func TestSummarizeDirNodeProject(t *testing.T) {
+	root := t.TempDir()
+	writeFixture(t, root, map[string]string{
+		"package.json": "{}\n",
+		"index.js":     "console.log(1)\n",
+	})
+
+	p := summarizeDir(root)
+
+	if want := []string{"javascript", "json"}; !reflect.DeepEqual(p.Languages, want) {
+		t.Errorf("Languages = %v; want %v", p.Languages, want)
+	}
+	if want := []string{"package.json"}; !reflect.DeepEqual(p.Markers, want) {
+		t.Errorf("Markers = %v; want %v", p.Markers, want)
+	}
+}
+
+func TestSummarizeDirMissing(t *testing.T) {
+	p := summarizeDir(filepath.Join(t.TempDir(), "missing"))
+	if p.Err == "" {
+		t.Error("expected an error for a missing directory")
+	}
+}
+
+func dirItemsOf(items []list.Item) []dirItem {
+	var out []dirItem
+	for _, it := range items {
+		out = append(out, it.(dirItem))
+	}
+	return out
+}
+
+func TestLoadDirsDetails(t *testing.T) {
+	root := t.TempDir()
+	writeFixture(t, root, map[string]string{
+		"app/main.go": "package main\n",
+		"app/go.mod":  "module app\n",
+	})
+	mtime := time.Date(2024, 3, 5, 14, 30, 0, 0, time.Local)
+	if err := os.Chtimes(filepath.Join(root, "app"), mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	items := dirItemsOf(loadDirs(root, dirOptions{details: true}))
+
+	if !strings.HasPrefix(items[0].name, "✅") || items[1].name != "⬆️ ../" {
+		t.Fatalf("navigation items must stay on top, got %q, %q", items[0].name, items[1].name)
+	}
+	desc := items[2].Description()
+	for _, want := range []string{"2 entries", "modified 2024-03-05 14:30"} {
+		if !strings.Contains(desc, want) {
+			t.Errorf("Description() = %q; want it to contain %q", desc, want)
+		}
+	}
+
+	plain := dirItemsOf(loadDirs(root, dirOptions{}))
+	if got := plain[2].Description(); got != filepath.Join(root, "app") {
+		t.Errorf("Description() without details = %q; want the path", got)
+	}
+}
+
+func TestLoadDirsSortByModTime(t *testing.T) {
+	root := t.TempDir()
+	for i, name := range []string{"alpha", "beta", "gamma"} {
+		dir := filepath.Join(root, name)
+		if err := os.Mkdir(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		// alpha is the most recently modified.
+		mtime := time.Now().Add(-time.Duration(i) * time.Hour)
+		if err := os.Chtimes(dir, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	byName := dirItemsOf(loadDirs(root, dirOptions{sortBy: sortByName}))
+	byTime := dirItemsOf(loadDirs(root, dirOptions{sortBy: sortByModTime}))
+
+	if byName[2].name != "📁 alpha/" || byName[4].name != "📁 gamma/" {
+		t.Errorf("unexpected name order: %q .. %q", byName[2].name, byName[4].name)
+	}
+	if byTime[2].name != "📁 alpha/" || byTime[4].name != "📁 gamma/" {
+		t.Errorf("unexpected mtime order: %q .. %q", byTime[2].name, byTime[4].name)
+	}
+
+	// Make gamma the newest and check it moves to the top of the subdirectories.
+	now := time.Now().Add(time.Hour)
+	_ = os.Chtimes(filepath.Join(root, "gamma"), now, now)
+	byTime = dirItemsOf(loadDirs(root, dirOptions{sortBy: sortByModTime}))
+	if byTime[2].name != "📁 gamma/" {
+		t.Errorf("expected gamma first when sorted by mtime, got %q", byTime[2].name)
+	}
+}
+
+func TestLoadDirsHidesIgnoredDirs(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{".git", ".cache", "node_modules", "vendor", "src"} {
+		if err := os.Mkdir(filepath.Join(root, name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	names := func(opts dirOptions) []string {
+		var out []string
+		for _, d := range dirItemsOf(loadDirs(root, opts))[2:] {
+			out = append(out, d.name)
+		}
+		return out
+	}
+
+	if got, want := names(dirOptions{}), []string{"📁 src/"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("hidden dirs filtered: got %v; want %v", got, want)
+	}
+	if got := names(dirOptions{showHidden: true}); len(got) != 5 {
+		t.Errorf("showHidden should reveal all 5 dirs, got %v", got)
+	}
+}
@@ -0,0 +1,66 @@
+package src
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	agent "github.com/Protocol-Lattice/go-agent"
+)
+
+// Event is one line of the headless event stream. Type is one of
+// plan-created, step-started, file-saved, run-result and complete; the
+// other fields are set as they apply. A run-result or complete event
+// without Error succeeded.
+type Event struct {
+	Type    string    `json:"type"`
+	Time    time.Time `json:"time"`
+	Step    int       `json:"step,omitempty"` // 1-based
+	Steps   int       `json:"steps,omitempty"`
+	Name    string    `json:"name,omitempty"`
+	Plan    []string  `json:"plan,omitempty"`    // step names, on plan-created
+	Path    string    `json:"path,omitempty"`    // file-saved
+	Status  string    `json:"status,omitempty"`  // file-saved: "created", "updated", ..., or "pending" or "error" when not written
+	Command string    `json:"command,omitempty"` // run-result: what was run
+	Output  string    `json:"output,omitempty"`  // run-result, tail of the output
+	Error   string    `json:"error,omitempty"`
+}
+
+// EventStream writes events to w as JSON lines. A nil *EventStream drops
+// them, so callers need not check.
+type EventStream struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewEventStream returns a stream writing to w, e.g. stdout or a pipe.
+func NewEventStream(w io.Writer) *EventStream {
+	return &EventStream{enc: json.NewEncoder(w)}
+}
+
+// Emit writes e, stamping its time. Write errors are ignored: a reader
+// going away must not stop the run.
+func (s *EventStream) Emit(e Event) {
+	if s == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_ = s.enc.Encode(e)
+}
+
+// RunPlannerHeadless runs userPrompt through the planner without the TUI,
+// streaming its progress to events as JSON lines, and returns once the plan
+// is done. Writes that would need confirmation in the TUI are left pending
+// unless -force is set.
+func RunPlannerHeadless(ctx context.Context, ag *agent.Agent, cfg *Config, workspace, userPrompt string, events io.Writer) error {
+	m := NewModel(ctx, ag, workspace, cfg)
+	m.plannerQueue = nil // no transcript to feed
+	m.events = NewEventStream(events)
+	return runPlan(ctx, ag, workspace, userPrompt, m, m.runTarget)
+}
@@ -0,0 +1,98 @@
+package src
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunPlannerHeadlessEmitsEvents(t *testing.T) {
+	GlobalChanges = NewChangeTracker()
+	root := t.TempDir()
+	llm := &stubLLM{replies: []string{
+		`[{"name":"Step 1: Add main","goal":"Create main.go"}]`,
+		"```go\n// path: main.go\npackage main\n\nfunc main() {}\n```\n",
+	}}
+	var buf bytes.Buffer
+	err := RunPlannerHeadless(context.Background(), newStubAgent(t, llm), nil, root, "write a program", &buf)
+	if err == nil {
+		t.Fatal("a run whose program could not be run should fail")
+	}
+
+	var types []string
+	var events []Event
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var e Event
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("not a JSON line: %q", line)
+		}
+		types = append(types, e.Type)
+		events = append(events, e)
+	}
+	want := "plan-created step-started file-saved run-result complete"
+	if got := strings.Join(types, " "); got != want {
+		t.Fatalf("events = %s, want %s\n%s", got, want, buf.String())
+	}
+	if e := events[0]; e.Steps != 1 || len(e.Plan) != 1 || e.Plan[0] != "Step 1: Add main" {
+		t.Errorf("plan-created = %+v", e)
+	}
+	if e := events[2]; e.Path != "main.go" || e.Step != 1 {
+		t.Errorf("file-saved = %+v", e)
+	}
+	if e := events[3]; e.Command != "main.go" || !strings.Contains(e.Error, "UTCP client not available") {
+		t.Errorf("run-result = %+v", e)
+	}
+	if e := events[4]; e.Error != err.Error() || e.Time.IsZero() {
+		t.Errorf("complete = %+v, run error %v", e, err)
+	}
+}
+
+func TestRunPlannerHeadlessReportsHeldWrites(t *testing.T) {
+	run := func(cfg *Config) ([]Event, string, error) {
+		t.Helper()
+		GlobalChanges = NewChangeTracker()
+		root := t.TempDir()
+		writeFixture(t, root, map[string]string{"README.md": "mine\n"})
+		llm := &stubLLM{replies: []string{
+			`[{"name":"Step 1: Docs","goal":"Rewrite README.md"}]`,
+			"```markdown\n<!-- path: README.md -->\ntheirs\n```\n",
+		}}
+		var buf bytes.Buffer
+		err := RunPlannerHeadless(context.Background(), newStubAgent(t, llm), cfg, root, "rewrite the docs", &buf)
+		var events []Event
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			var e Event
+			if err := json.Unmarshal([]byte(line), &e); err != nil {
+				t.Fatalf("not a JSON line: %q", line)
+			}
+			events = append(events, e)
+		}
+		return events, readFile(t, filepath.Join(root, "README.md")), err
+	}
+
+	events, readme, err := run(nil)
+	if err == nil || !strings.Contains(err.Error(), "README.md") || readme != "mine\n" {
+		t.Fatalf("err = %v, README.md = %q; want the held write reported as a failure", err, readme)
+	}
+	var saved *Event
+	for i := range events {
+		if events[i].Type == "file-saved" {
+			saved = &events[i]
+		}
+	}
+	if saved == nil || saved.Path != "README.md" || saved.Status != "pending" || saved.Error == "" {
+		t.Errorf("file-saved = %+v; want README.md pending with a reason", saved)
+	}
+	if last := events[len(events)-1]; last.Type != "complete" || last.Error != err.Error() {
+		t.Errorf("complete = %+v", last)
+	}
+
+	cfg := DefaultConfig()
+	cfg.Force = true
+	if _, readme, err := run(cfg); err != nil || readme != "theirs" {
+		t.Errorf("with -force: err = %v, README.md = %q", err, readme)
+	}
+}
@@ -4,12 +4,16 @@ package src
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"time"
+
+	"github.com/Protocol-Lattice/lattice-code/src/atomicfile"
 )
 
 // RunProject executes ./run.sh inside dir with a timeout, capturing combined stdout/stderr.
@@ -44,6 +48,149 @@ func RunProject(ctx context.Context, dir string, timeout time.Duration) (ok bool
 	return ok, out, err
 }
 
+// RunTarget runs command with bash inside dir with a timeout, capturing
+// combined stdout/stderr like RunProject.
+func RunTarget(ctx context.Context, dir, command string, timeout time.Duration) (ok bool, out string, err error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "bash", "-c", command)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "CI=1")
+
+	var buf bytes.Buffer
+	cmd.Stdout = &buf
+	cmd.Stderr = &buf
+
+	err = cmd.Run()
+	out = buf.String()
+	ok = err == nil
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) && err != nil {
+		err = fmt.Errorf("run timeout after %s: %w", timeout, err)
+	}
+	return ok, out, err
+}
+
+// detectRunCommand returns the shell commands that build and run the project
+// in root, judged by its manifest (go.mod, package.json, Cargo.toml) and
+// otherwise by its entrypoint as found by findMainFile for entrypoints.
+func detectRunCommand(root, entrypoints string) (string, bool) {
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(root, name))
+		return err == nil
+	}
+	entry, lang := findMainFile(root, entrypoints)
+	entry = filepath.ToSlash(entry)
+	switch {
+	case exists("go.mod"):
+		pkg := "."
+		if lang == "go" && filepath.Dir(entry) != "." {
+			pkg = "./" + filepath.ToSlash(filepath.Dir(entry))
+		}
+		return "go run " + pkg + ` "$@"`, true
+	case exists("package.json"):
+		var pkg struct {
+			Scripts map[string]string `json:"scripts"`
+		}
+		if b, err := os.ReadFile(filepath.Join(root, "package.json")); err == nil {
+			_ = json.Unmarshal(b, &pkg)
+		}
+		install := "[ -d node_modules ] || npm install\n"
+		if pkg.Scripts["start"] != "" {
+			return install + `npm start -- "$@"`, true
+		}
+		if lang == "javascript" {
+			return install + "node " + entry + ` "$@"`, true
+		}
+		if lang == "typescript" {
+			return install + "npx tsx " + entry + ` "$@"`, true
+		}
+	case exists("Cargo.toml"):
+		return `cargo run -- "$@"`, true
+	}
+	runners := map[string]string{
+		"python":     "python3",
+		"javascript": "node",
+		"typescript": "npx tsx",
+		"ruby":       "ruby",
+		"php":        "php",
+		"perl":       "perl",
+		"lua":        "lua",
+		"r":          "Rscript",
+		"dart":       "dart run",
+		"swift":      "swift",
+	}
+	if r, ok := runners[lang]; ok {
+		cmd := r + " " + entry + ` "$@"`
+		if lang == "python" && exists("requirements.txt") {
+			cmd = "pip install -q -r requirements.txt\n" + cmd
+		}
+		return cmd, true
+	}
+	return "", false
+}
+
+// detectTestCommand returns the shell command that runs the test suite of
+// the project in root, judged by its manifest like detectRunCommand: go
+// test, npm test when package.json defines a real test script, cargo test,
+// or pytest for Python projects.
+func detectTestCommand(root, entrypoints string) (string, bool) {
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(root, name))
+		return err == nil
+	}
+	switch {
+	case exists("go.mod"):
+		return "go test ./...", true
+	case exists("package.json"):
+		var pkg struct {
+			Scripts map[string]string `json:"scripts"`
+		}
+		if b, err := os.ReadFile(filepath.Join(root, "package.json")); err == nil {
+			_ = json.Unmarshal(b, &pkg)
+		}
+		// npm init's placeholder fails on purpose; it is not a test suite.
+		if t := pkg.Scripts["test"]; t != "" && !strings.Contains(t, "no test specified") {
+			return "[ -d node_modules ] || npm install\nnpm test", true
+		}
+	case exists("Cargo.toml"):
+		return "cargo test", true
+	}
+	for _, name := range []string{"pytest.ini", "pyproject.toml", "setup.py", "setup.cfg", "tox.ini"} {
+		if exists(name) {
+			return "python3 -m pytest -q", true
+		}
+	}
+	if _, lang := findMainFile(root, entrypoints); lang == "python" {
+		return "python3 -m pytest -q", true
+	}
+	return "", false
+}
+
+// ensureRunScript writes run.sh for the project in root unless it already
+// has one, so RunProject and the planner's run phase have something to run.
+// It reports whether a script was written.
+func ensureRunScript(cfg *Config, root string) (FileAction, bool) {
+	if !cfg.RunScript {
+		return FileAction{}, false
+	}
+	path := filepath.Join(root, "run.sh")
+	if _, err := os.Stat(path); err == nil {
+		return FileAction{}, false
+	}
+	cmd, ok := detectRunCommand(root, cfg.Entrypoints)
+	if !ok {
+		return FileAction{}, false
+	}
+	script := "#!/usr/bin/env bash\nset -euo pipefail\ncd \"$(dirname \"$0\")\"\n\n" + strings.TrimSpace(cmd) + "\n"
+	if err := atomicfile.WriteFile(path, []byte(script), 0o755); err != nil {
+		return FileAction{Path: "run.sh", Action: "error", Message: err.Error(), Err: err}, true
+	}
+	GlobalChanges.Record("run.sh", []byte(script))
+	_ = recordManaged(root, []string{"run.sh"})
+	return FileAction{Path: "run.sh", Action: "saved", Message: "created"}, true
+}
+
 // TailBytes returns the last n bytes of a string (by bytes, not runes), safe for logs.
 func TailBytes(s string, n int) string {
 	if n <= 0 {
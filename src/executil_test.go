@@ -0,0 +1,84 @@
+package src
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEnsureRunScriptRunsGoProject(t *testing.T) {
+	GlobalChanges = NewChangeTracker()
+	root := t.TempDir()
+	writeFixture(t, root, map[string]string{
+		"go.mod":            "module example.com/hello\n\ngo 1.21\n",
+		"cmd/hello/main.go": "package main\n\nimport \"fmt\"\n\nfunc main() { fmt.Println(\"hello from run.sh\") }\n",
+	})
+	a, ok := ensureRunScript(DefaultConfig(), root)
+	if !ok || a.Action != "saved" || a.Path != "run.sh" {
+		t.Fatalf("ensureRunScript = %+v, %v", a, ok)
+	}
+	if got := readFile(t, filepath.Join(root, "run.sh")); !strings.Contains(got, `go run ./cmd/hello "$@"`) {
+		t.Errorf("run.sh = %q", got)
+	}
+	if _, again := ensureRunScript(DefaultConfig(), root); again {
+		t.Error("an existing run.sh must be left alone")
+	}
+
+	for _, tool := range []string{"go", "bash"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skip(tool + " not installed")
+		}
+	}
+	ok, out, err := RunProject(context.Background(), root, time.Minute)
+	if !ok || err != nil || !strings.Contains(out, "hello from run.sh") {
+		t.Errorf("RunProject = %v, %q, %v", ok, out, err)
+	}
+}
+
+func TestDetectRunCommand(t *testing.T) {
+	tests := []struct {
+		files map[string]string
+		want  string
+	}{
+		{map[string]string{"package.json": `{"scripts": {"start": "node server.js"}}`, "server.js": ""}, `npm start -- "$@"`},
+		{map[string]string{"package.json": `{}`, "index.js": ""}, `node index.js "$@"`},
+		{map[string]string{"Cargo.toml": "", "src/main.rs": ""}, `cargo run -- "$@"`},
+		{map[string]string{"requirements.txt": "", "app.py": ""}, "pip install -q -r requirements.txt\npython3 app.py \"$@\""},
+	}
+	for _, tt := range tests {
+		root := t.TempDir()
+		writeFixture(t, root, tt.files)
+		if got, ok := detectRunCommand(root, ""); !ok || !strings.HasSuffix(got, tt.want) {
+			t.Errorf("%v: got %q, want suffix %q", tt.files, got, tt.want)
+		}
+	}
+	if cmd, ok := detectRunCommand(t.TempDir(), ""); ok {
+		t.Errorf("empty workspace detected %q", cmd)
+	}
+}
+
+func TestDetectTestCommand(t *testing.T) {
+	tests := []struct {
+		files map[string]string
+		want  string // "" when there is no suite
+	}{
+		{map[string]string{"go.mod": "module x\n"}, "go test ./..."},
+		{map[string]string{"package.json": `{"scripts": {"test": "jest"}}`}, "npm test"},
+		{map[string]string{"package.json": `{"scripts": {"test": "echo \"Error: no test specified\" && exit 1"}}`}, ""},
+		{map[string]string{"Cargo.toml": ""}, "cargo test"},
+		{map[string]string{"pyproject.toml": ""}, "python3 -m pytest -q"},
+		{map[string]string{"app.py": ""}, "python3 -m pytest -q"},
+		{map[string]string{"index.html": ""}, ""},
+	}
+	for _, tt := range tests {
+		root := t.TempDir()
+		writeFixture(t, root, tt.files)
+		got, ok := detectTestCommand(root, "")
+		if ok != (tt.want != "") || !strings.HasSuffix(got, tt.want) {
+			t.Errorf("%v: got %q, %v, want %q", tt.files, got, ok, tt.want)
+		}
+	}
+}
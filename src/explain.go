@@ -0,0 +1,96 @@
+package src
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	agent "github.com/Protocol-Lattice/go-agent"
+	"github.com/Protocol-Lattice/go-agent/src/models"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// splitExplainScope treats a leading token that names a file in workspace as
+// the explanation's scope, e.g. "src/model.go how is state stored?".
+func splitExplainScope(workspace, input string) (scope, question string) {
+	input = strings.TrimSpace(input)
+	first, rest, _ := strings.Cut(input, " ")
+	first = strings.TrimSuffix(first, ":")
+	if first == "" {
+		return "", input
+	}
+	info, err := os.Stat(filepath.Join(workspace, filepath.FromSlash(first)))
+	if err != nil || info.IsDir() {
+		return "", input
+	}
+	if rest = strings.TrimSpace(rest); rest == "" {
+		rest = "Explain what this file does and how it fits into the project."
+	}
+	return filepath.ToSlash(first), rest
+}
+
+// RunExplain asks the agent a read-only question about the workspace, or just
+// scope when it is set, in the agent session session ("" for a new one).
+// Unlike RunHeadless it never writes code blocks to disk.
+func RunExplain(ctx context.Context, ag *agent.Agent, cfg *Config, session, workspace, question, scope string) (string, Usage, error) {
+	cfg = cfg.orDefault()
+	if ag == nil {
+		return "", Usage{}, errors.New("agent is nil")
+	}
+	if strings.TrimSpace(question) == "" {
+		return "", Usage{}, errors.New("question cannot be empty")
+	}
+	abs, _ := filepath.Abs(workspace)
+
+	var files []models.File
+	var header string
+	if scope != "" {
+		b, err := os.ReadFile(filepath.Join(abs, filepath.FromSlash(scope)))
+		if err != nil {
+			return "", Usage{}, err
+		}
+		b, ok := sanitizeContextFile(scope, b)
+		if !ok {
+			return "", Usage{}, fmt.Errorf("%s looks like it contains secrets; not sending it", scope)
+		}
+		files = []models.File{{Name: scope, MIME: mimeForPath(scope), Data: truncateHeadTail(b, 200_000, cfg.TruncateHeadRatio)}}
+		header = fmt.Sprintf("Focus on the attached file `%s`.", scope)
+	} else {
+		var entries []fileEntry
+		lim := cfg.contextLimits()
+		files, entries = collectAttachmentFiles(cfg, abs, lim.maxFiles, lim.maxTotalBytes, lim.perFileLimit, "")
+		header = "File tree:\n```\n" + buildTree(entries) + "\n```"
+	}
+
+	prompt := conventionsBlock(abs) + fmt.Sprintf(explainPrompt, header, question)
+	files, _ = pruneAttachments(prompt, files, cfg.MaxRequestBytes, pinnedSet(abs))
+	if session == "" {
+		session = randomID()
+	}
+	res, err := ag.GenerateWithFiles(withSession(ctx, session), session, prompt, files)
+	if err != nil {
+		return "", Usage{}, fmt.Errorf("explain failed: %w", err)
+	}
+	return res, estimateUsage(prompt, files, res), nil
+}
+
+// runExplain renders the agent's explanation in the chat without touching files.
+func (m *model) runExplain(raw string) (*model, tea.Cmd) {
+	scope, question := splitExplainScope(m.working, raw)
+	status := "explaining"
+	if scope != "" {
+		status += " " + scope
+	}
+	cmd := func() tea.Msg {
+		text, usage, err := RunExplain(m.ctx, m.agent, m.cfg, m.sessionID, m.working, question, scope)
+		if err != nil {
+			return generateMsg{"", err}
+		}
+		m.usage.add(usage)
+		return generateMsg{m.style.Accent.Render("explain:") + "\n\n" + text + "\n", nil}
+	}
+	return m, tea.Batch(cmd, m.startWork(status))
+}
@@ -0,0 +1,46 @@
+package src
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunExplainWritesNothing(t *testing.T) {
+	root := t.TempDir()
+	writeFixture(t, root, map[string]string{"src/model.go": "package src\n\ntype model struct{}\n"})
+	llm := &stubLLM{reply: "The model struct holds UI state.\n\n```go\n// path: src/new.go\npackage src\n```\n"}
+
+	scope, question := splitExplainScope(root, "src/model.go: what is model for?")
+	if scope != "src/model.go" || question != "what is model for?" {
+		t.Fatalf("scope = %q, question = %q", scope, question)
+	}
+
+	out, usage, err := RunExplain(context.Background(), newStubAgent(t, llm), nil, "", root, question, scope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != llm.reply || usage.CompletionTokens == 0 {
+		t.Errorf("out = %q, usage = %+v", out, usage)
+	}
+	if len(llm.calls) != 1 {
+		t.Fatalf("calls = %d", len(llm.calls))
+	}
+	// The agent may echo this turn's files back as rehydrated session attachments.
+	for _, f := range llm.calls[0] {
+		if f.Name != "src/model.go" {
+			t.Errorf("scoped explain should attach only src/model.go, got %s", f.Name)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "src", "new.go")); !os.IsNotExist(err) {
+		t.Error("explain must not write code blocks to disk")
+	}
+}
+
+func TestSplitExplainScopeWithoutFile(t *testing.T) {
+	scope, question := splitExplainScope(t.TempDir(), "how does routing work?")
+	if scope != "" || question != "how does routing work?" {
+		t.Errorf("scope = %q, question = %q", scope, question)
+	}
+}
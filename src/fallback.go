@@ -0,0 +1,126 @@
+package src
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Protocol-Lattice/go-agent/src/models"
+)
+
+// modelSpec names one entry of the -models chain.
+type modelSpec struct {
+	Provider, Model string
+}
+
+func (s modelSpec) String() string { return s.Provider + ":" + s.Model }
+
+// parseModelChain parses "provider:model[,provider:model...]".
+func parseModelChain(spec string) ([]modelSpec, error) {
+	var specs []modelSpec
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		provider, model, ok := strings.Cut(part, ":")
+		if !ok || provider == "" || model == "" {
+			return nil, fmt.Errorf("invalid model %q, want provider:model", part)
+		}
+		specs = append(specs, modelSpec{Provider: strings.ToLower(provider), Model: model})
+	}
+	if len(specs) == 0 {
+		return nil, errors.New("no models configured")
+	}
+	return specs, nil
+}
+
+// unavailableStatusRe matches the HTTP statuses that mean a model cannot
+// serve requests right now, as whole numbers so ids and sizes that merely
+// contain the digits do not count.
+var unavailableStatusRe = regexp.MustCompile(`\b(?:401|429|502|503)\b`)
+
+// isUnavailable reports whether err means the model could not serve the
+// request at all, as opposed to rejecting this particular request.
+func isUnavailable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	if unavailableStatusRe.MatchString(msg) {
+		return true
+	}
+	for _, marker := range []string{
+		"unavailable", "overloaded", "rate limit", "quota", "resource_exhausted",
+		"connection refused", "no such host", "timeout", "api_key", "api key", "unauthorized",
+	} {
+		if strings.Contains(msg, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// fallbackModel tries each model in order, moving on only when the current
+// one is unavailable.
+type fallbackModel struct {
+	names  []string
+	models []models.Agent
+}
+
+func (f *fallbackModel) Generate(ctx context.Context, prompt string) (any, error) {
+	return f.try(ctx, func(m models.Agent) (any, error) { return m.Generate(ctx, prompt) })
+}
+
+func (f *fallbackModel) GenerateWithFiles(ctx context.Context, prompt string, files []models.File) (any, error) {
+	return f.try(ctx, func(m models.Agent) (any, error) { return m.GenerateWithFiles(ctx, prompt, files) })
+}
+
+func (f *fallbackModel) try(ctx context.Context, call func(models.Agent) (any, error)) (any, error) {
+	var errs []error
+	for i, m := range f.models {
+		res, err := call(m)
+		// Once the caller's own context is done every model would fail the
+		// same way, so that error is returned rather than falling back.
+		if err == nil || ctx.Err() != nil || !isUnavailable(err) {
+			return res, err
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", f.names[i], err))
+		if i+1 < len(f.models) {
+			sessionLog(contextSession(ctx)).Warn("model unavailable, falling back", "model", f.names[i], "next", f.names[i+1], "err", err)
+		}
+	}
+	return nil, errors.Join(errs...)
+}
+
+// buildModelChain constructs the models in specs, skipping any that cannot be
+// created (e.g. a missing API key). newModel is models.NewLLMProvider outside
+// tests.
+func buildModelChain(ctx context.Context, specs []modelSpec, newModel func(ctx context.Context, provider, model, prefix string) (models.Agent, error)) (models.Agent, error) {
+	chain := &fallbackModel{}
+	var errs []error
+	for _, s := range specs {
+		m, err := newModel(ctx, s.Provider, s.Model, "Universal code generator")
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s, err))
+			continue
+		}
+		chain.names = append(chain.names, s.String())
+		chain.models = append(chain.models, m)
+	}
+	switch len(chain.models) {
+	case 0:
+		return nil, fmt.Errorf("no usable model: %w", errors.Join(errs...))
+	case 1:
+		return chain.models[0], nil
+	}
+	if len(errs) > 0 {
+		sessionLog("").Warn("some models could not be created", "err", errors.Join(errs...))
+	}
+	return chain, nil
+}
@@ -0,0 +1,103 @@
+package src
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Protocol-Lattice/go-agent/src/models"
+)
+
+func TestParseModelChain(t *testing.T) {
+	specs, err := parseModelChain("gemini:gemini-2.5-pro, OpenAI:gpt-4o,,ollama:llama3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(specs) != 3 || specs[1] != (modelSpec{"openai", "gpt-4o"}) {
+		t.Errorf("specs = %v", specs)
+	}
+	for _, bad := range []string{"", "gemini", ":model", "openai:"} {
+		if _, err := parseModelChain(bad); err == nil {
+			t.Errorf("parseModelChain(%q) should fail", bad)
+		}
+	}
+}
+
+func TestFallbackModelSkipsUnavailablePrimary(t *testing.T) {
+	primary := &stubLLM{errs: []error{
+		errors.New("googleapi: Error 503: The model is overloaded"),
+		errors.New("googleapi: Error 503: The model is overloaded"),
+	}}
+	secondary := &stubLLM{reply: "from secondary"}
+	m := &fallbackModel{names: []string{"gemini:pro", "openai:gpt-4o"}, models: []models.Agent{primary, secondary}}
+
+	for _, call := range []func() (any, error){
+		func() (any, error) { return m.Generate(context.Background(), "hi") },
+		func() (any, error) { return m.GenerateWithFiles(context.Background(), "hi", nil) },
+	} {
+		res, err := call()
+		if err != nil || res != "from secondary" {
+			t.Errorf("res = %v, err = %v", res, err)
+		}
+	}
+	if len(primary.calls) != 2 || len(secondary.calls) != 2 {
+		t.Errorf("primary calls = %d, secondary calls = %d", len(primary.calls), len(secondary.calls))
+	}
+}
+
+func TestFallbackModelKeepsRequestErrors(t *testing.T) {
+	primary := &stubLLM{errs: []error{errors.New("400: invalid argument")}}
+	secondary := &stubLLM{reply: "unused"}
+	m := &fallbackModel{names: []string{"a:1", "b:2"}, models: []models.Agent{primary, secondary}}
+
+	if _, err := m.Generate(context.Background(), "hi"); err == nil || len(secondary.calls) != 0 {
+		t.Errorf("a rejected request should not fall back (err = %v)", err)
+	}
+
+	down := &fallbackModel{names: []string{"a:1"}, models: []models.Agent{&stubLLM{errs: []error{errors.New("connection refused")}}}}
+	if _, err := down.Generate(context.Background(), "hi"); err == nil {
+		t.Error("expected an error when every model is unavailable")
+	}
+}
+
+func TestFallbackModelStopsWhenCallerIsDone(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	secondary := &stubLLM{reply: "unused"}
+	m := &fallbackModel{names: []string{"a:1", "b:2"}, models: []models.Agent{&stubLLM{errs: []error{context.DeadlineExceeded}}, secondary}}
+	if _, err := m.Generate(ctx, "hi"); !errors.Is(err, context.DeadlineExceeded) || len(secondary.calls) != 0 {
+		t.Errorf("the caller's deadline should end the chain: err = %v, secondary calls = %d", err, len(secondary.calls))
+	}
+}
+
+func TestIsUnavailable(t *testing.T) {
+	for msg, want := range map[string]bool{
+		"googleapi: Error 503: The model is overloaded": true,
+		"status 429 Too Many Requests":                  true,
+		"HTTP 401":                                      true,
+		"invalid request 45029: bad argument":           false,
+		"prompt is 15030 tokens, over the limit":        false,
+	} {
+		if got := isUnavailable(errors.New(msg)); got != want {
+			t.Errorf("isUnavailable(%q) = %v; want %v", msg, got, want)
+		}
+	}
+}
+
+func TestBuildModelChainSkipsModelsThatFailToBuild(t *testing.T) {
+	ok := &stubLLM{reply: "ok"}
+	newModel := func(_ context.Context, provider, _, _ string) (models.Agent, error) {
+		if provider == "gemini" {
+			return nil, errors.New("gemini: missing GOOGLE_API_KEY/GEMINI_API_KEY")
+		}
+		return ok, nil
+	}
+
+	m, err := buildModelChain(context.Background(), []modelSpec{{"gemini", "pro"}, {"ollama", "llama3"}}, newModel)
+	if err != nil || m != ok {
+		t.Errorf("model = %v, err = %v; want the only usable model", m, err)
+	}
+	if _, err := buildModelChain(context.Background(), []modelSpec{{"gemini", "pro"}}, newModel); err == nil {
+		t.Error("expected an error when no model can be built")
+	}
+}
@@ -0,0 +1,102 @@
+package src
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// maxCachedBytes bounds fileCache; past it the cache starts over.
+const maxCachedBytes = 64 << 20
+
+// racyWindow is how recently a file may have been modified for its cached
+// copy to be trusted. A file written again within the same mtime tick keeps
+// its mtime, so like git's racily clean index entries such files are
+// re-read until their mtime is safely in the past.
+const racyWindow = 2 * time.Second
+
+// fileCache holds file contents read for the context snapshot, keyed by
+// path and reused while the file's size and modification time are
+// unchanged, so each planner step does not re-read the whole workspace.
+type fileCache struct {
+	mu    sync.Mutex
+	files map[string]cachedFile
+	bytes int64
+	reads int // contents read from disk, for tests
+}
+
+type cachedFile struct {
+	size    int64
+	modTime time.Time
+	readAt  time.Time
+	data    []byte
+}
+
+var contextCache = &fileCache{}
+
+// readFiles reads paths through contextCache on a pool of at most workers
+// goroutines (-concurrency). The results line up with paths, so callers see
+// the same order a serial loop would; empty paths are skipped.
+func readFiles(paths []string, workers int) ([][]byte, []error) {
+	data, errs := make([][]byte, len(paths)), make([]error, len(paths))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(max(workers, 1), len(paths)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				if paths[i] != "" {
+					data[i], errs[i] = contextCache.read(paths[i])
+				}
+			}
+		}()
+	}
+	for i := range paths {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return data, errs
+}
+
+// read returns path's contents, from the cache when the file is unchanged
+// since it was last read. Callers must not modify the returned slice.
+func (c *fileCache) read(path string) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		c.forget(path)
+		return nil, err
+	}
+	c.mu.Lock()
+	f, ok := c.files[path]
+	c.mu.Unlock()
+	if ok && f.size == info.Size() && f.modTime.Equal(info.ModTime()) && f.readAt.Sub(f.modTime) > racyWindow {
+		return f.data, nil
+	}
+
+	readAt := time.Now()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		c.forget(path)
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reads++
+	if c.files == nil || c.bytes+int64(len(data)) > maxCachedBytes {
+		c.files, c.bytes = map[string]cachedFile{}, 0
+	}
+	c.bytes += int64(len(data)) - int64(len(c.files[path].data))
+	c.files[path] = cachedFile{size: info.Size(), modTime: info.ModTime(), readAt: readAt, data: data}
+	return data, nil
+}
+
+func (c *fileCache) forget(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if f, ok := c.files[path]; ok {
+		c.bytes -= int64(len(f.data))
+		delete(c.files, path)
+	}
+}
@@ -0,0 +1,86 @@
+package src
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Protocol-Lattice/go-agent/src/models"
+)
+
+func TestFileCacheRereadsOnlyChangedFiles(t *testing.T) {
+	root := t.TempDir()
+	writeFixture(t, root, map[string]string{"a.go": "package a\n", "b.go": "package b\n"})
+	a, b := filepath.Join(root, "a.go"), filepath.Join(root, "b.go")
+	age := func(p string, d time.Duration) {
+		if err := os.Chtimes(p, time.Now().Add(-d), time.Now().Add(-d)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	age(a, time.Hour)
+	age(b, time.Hour)
+
+	c := &fileCache{}
+	for i := 0; i < 3; i++ {
+		c.read(a)
+		c.read(b)
+	}
+	if c.reads != 2 {
+		t.Fatalf("unchanged files should be read once each, got %d reads", c.reads)
+	}
+
+	// Same size, so only the modification time tells the change apart.
+	writeFixture(t, root, map[string]string{"a.go": "package z\n"})
+	age(a, 30*time.Minute)
+	if got, _ := c.read(a); string(got) != "package z\n" {
+		t.Errorf("changed file served stale: %q", got)
+	}
+	c.read(b)
+	if c.reads != 3 {
+		t.Errorf("only the changed file should be re-read, got %d reads", c.reads)
+	}
+
+	writeFixture(t, root, map[string]string{"a.go": "package y\n"})
+	c.read(a)
+	c.read(a)
+	if c.reads != 5 {
+		t.Errorf("a file modified just now must not be trusted from the cache, got %d reads", c.reads)
+	}
+
+	os.Remove(b)
+	if _, err := c.read(b); err == nil {
+		t.Error("a deleted file should not be served from the cache")
+	}
+}
+
+func TestParallelReadsMatchSerial(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{}
+	for i := 0; i < 150; i++ {
+		files[fmt.Sprintf("pkg%d/file%03d.go", i%7, i)] = fmt.Sprintf("package pkg%d\n\n// file %d\n%s", i%7, i, strings.Repeat("x", i*13))
+	}
+	writeFixture(t, root, files)
+	oldCache := contextCache
+	t.Cleanup(func() { contextCache = oldCache })
+
+	snapshot := func(workers int) (string, []models.File) {
+		cfg := DefaultConfig()
+		cfg.Concurrency = workers
+		contextCache = &fileCache{}
+		ctx, _, _ := buildCodebaseContext(cfg, root, 1000, 1<<20, 20_000, "")
+		att, _ := collectAttachmentFiles(cfg, root, 100, 1<<20, 20_000, "")
+		return ctx, att
+	}
+	serialCtx, serialAtt := snapshot(1)
+	parallelCtx, parallelAtt := snapshot(8)
+	if serialCtx != parallelCtx {
+		t.Error("parallel context snapshot differs from the serial one")
+	}
+	if !reflect.DeepEqual(serialAtt, parallelAtt) || len(parallelAtt) != 100 {
+		t.Errorf("attachments differ: %d serial, %d parallel", len(serialAtt), len(parallelAtt))
+	}
+}
@@ -0,0 +1,200 @@
+package src
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Protocol-Lattice/go-agent/src/models"
+)
+
+var fileDirectiveRe = regexp.MustCompile(`(?:^|\s)@file\s+(\S+)`)
+
+// parseFileDirectives strips "@file <path>" directives from prompt and returns
+// the named paths in order, without duplicates.
+func parseFileDirectives(prompt string) (string, []string) {
+	var paths []string
+	seen := map[string]bool{}
+	for _, m := range fileDirectiveRe.FindAllStringSubmatch(prompt, -1) {
+		p := filepath.ToSlash(filepath.Clean(m[1]))
+		if !seen[p] {
+			seen[p] = true
+			paths = append(paths, p)
+		}
+	}
+	if len(paths) == 0 {
+		return prompt, nil
+	}
+	return strings.TrimSpace(fileDirectiveRe.ReplaceAllString(prompt, "")), paths
+}
+
+// fileDirectives renders paths back into "@file" directives, e.g. to carry a
+// user's selection into planner steps.
+func fileDirectives(paths []string) string {
+	var b strings.Builder
+	for _, p := range paths {
+		b.WriteString(" @file " + p)
+	}
+	return b.String()
+}
+
+// expandWithDependencies returns selected plus the workspace files each of
+// them imports directly. Paths are relative to root, slash-separated, sorted.
+func expandWithDependencies(root string, selected []string) []string {
+	set := map[string]bool{}
+	for _, rel := range selected {
+		abs := filepath.Join(root, filepath.FromSlash(rel))
+		content, err := os.ReadFile(abs)
+		if err != nil {
+			continue
+		}
+		set[rel] = true
+		for _, dep := range directDependencies(root, rel, content) {
+			set[dep] = true
+		}
+	}
+	out := make([]string, 0, len(set))
+	for p := range set {
+		out = append(out, p)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// directDependencies resolves the local imports of one file.
+func directDependencies(root, rel string, content []byte) []string {
+	switch strings.ToLower(filepath.Ext(rel)) {
+	case ".go":
+		return goDependencies(root, rel, content)
+	case ".js", ".jsx", ".ts", ".tsx":
+		return jsDependencies(root, rel, content)
+	case ".py":
+		return pyDependencies(root, rel, content)
+	}
+	return nil
+}
+
+// goDependencies includes the non-test files of every same-module package imported.
+func goDependencies(root, rel string, content []byte) []string {
+	module := goModulePath(root)
+	if module == "" {
+		return nil
+	}
+	f, err := parser.ParseFile(token.NewFileSet(), rel, content, parser.ImportsOnly)
+	if err != nil {
+		return nil
+	}
+	var deps []string
+	for _, imp := range f.Imports {
+		path, _ := strconv.Unquote(imp.Path.Value)
+		sub, ok := strings.CutPrefix(path, module+"/")
+		if !ok {
+			continue
+		}
+		entries, err := os.ReadDir(filepath.Join(root, filepath.FromSlash(sub)))
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			name := e.Name()
+			if !e.IsDir() && strings.HasSuffix(name, ".go") && !strings.HasSuffix(name, "_test.go") {
+				deps = append(deps, sub+"/"+name)
+			}
+		}
+	}
+	return deps
+}
+
+var jsImportRe = regexp.MustCompile(`(?:from\s+|require\(\s*|import\s*\(?\s*)['"](\.{1,2}/[^'"]+)['"]`)
+
+func jsDependencies(root, rel string, content []byte) []string {
+	var deps []string
+	dir := filepath.Dir(filepath.FromSlash(rel))
+	for _, m := range jsImportRe.FindAllStringSubmatch(string(content), -1) {
+		base := filepath.Join(dir, filepath.FromSlash(m[1]))
+		for _, suffix := range []string{"", ".ts", ".tsx", ".js", ".jsx", "/index.ts", "/index.tsx", "/index.js"} {
+			if dep := existingFile(root, base+suffix); dep != "" {
+				deps = append(deps, dep)
+				break
+			}
+		}
+	}
+	return deps
+}
+
+var (
+	pyFromRe   = regexp.MustCompile(`(?m)^\s*from\s+(\.*)([\w\.]*)\s+import`)
+	pyImportRe = regexp.MustCompile(`(?m)^\s*import\s+([\w\.]+)`)
+)
+
+func pyDependencies(root, rel string, content []byte) []string {
+	var deps []string
+	resolve := func(base, module string) {
+		p := filepath.Join(base, filepath.FromSlash(strings.ReplaceAll(module, ".", "/")))
+		for _, candidate := range []string{p + ".py", filepath.Join(p, "__init__.py")} {
+			if dep := existingFile(root, candidate); dep != "" {
+				deps = append(deps, dep)
+				return
+			}
+		}
+	}
+	for _, m := range pyFromRe.FindAllStringSubmatch(string(content), -1) {
+		base := ""
+		if dots := len(m[1]); dots > 0 {
+			base = filepath.Dir(filepath.FromSlash(rel))
+			for i := 1; i < dots; i++ {
+				base = filepath.Dir(base)
+			}
+		}
+		if m[2] != "" {
+			resolve(base, m[2])
+		}
+	}
+	for _, m := range pyImportRe.FindAllStringSubmatch(string(content), -1) {
+		resolve("", m[1])
+	}
+	return deps
+}
+
+// existingFile returns rel (slash-separated) if it names a regular file under root.
+func existingFile(root, rel string) string {
+	rel = filepath.Clean(rel)
+	if strings.HasPrefix(rel, "..") {
+		return ""
+	}
+	info, err := os.Stat(filepath.Join(root, rel))
+	if err != nil || info.IsDir() {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
+
+// collectSelectedFiles builds attachments for exactly rels, applying the same
+// secret filtering and truncation as collectAttachmentFiles.
+func collectSelectedFiles(cfg *Config, root string, rels []string, perFileLimit int64) ([]models.File, []fileEntry) {
+	cfg = cfg.orDefault()
+	var out []models.File
+	var entries []fileEntry
+	for _, rel := range rels {
+		abs := filepath.Join(root, filepath.FromSlash(rel))
+		if isSecretFile(abs) {
+			continue
+		}
+		b, err := os.ReadFile(abs)
+		if err != nil {
+			continue
+		}
+		b, ok := sanitizeContextFile(rel, b)
+		if !ok {
+			continue
+		}
+		entries = append(entries, fileEntry{Rel: rel, Abs: abs, Size: int64(len(b))})
+		out = append(out, models.File{Name: rel, MIME: mimeForPath(rel), Data: truncateHeadTail(b, perFileLimit, cfg.TruncateHeadRatio)})
+	}
+	return out, entries
+}
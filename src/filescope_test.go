@@ -0,0 +1,71 @@
+package src
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestParseFileDirectives(t *testing.T) {
+	prompt, paths := parseFileDirectives("@file api/handler.go fix the bug in @file ./api/routes.go and @file api/handler.go")
+	if prompt != "fix the bug in and" {
+		t.Errorf("prompt = %q", prompt)
+	}
+	if !slices.Equal(paths, []string{"api/handler.go", "api/routes.go"}) {
+		t.Errorf("paths = %v", paths)
+	}
+	if _, paths := parseFileDirectives("email me@file.com"); paths != nil {
+		t.Errorf("an @ inside a word is not a directive: %v", paths)
+	}
+}
+
+func TestExpandWithDependencies(t *testing.T) {
+	root := t.TempDir()
+	writeFixture(t, root, map[string]string{
+		"go.mod":              "module example.com/app\n",
+		"main.go":             "package main\n\nimport (\n\t\"fmt\"\n\t\"example.com/app/store\"\n)\n",
+		"store/store.go":      "package store\n",
+		"store/store_test.go": "package store\n",
+		"other/other.go":      "package other\n",
+		"web/app.ts":          "import { api } from './api'\nconst x = require('../lib/util.js')\n",
+		"web/api.ts":          "export const api = 1\n",
+		"lib/util.js":         "module.exports = {}\n",
+		"py/main.py":          "from .models import User\nimport helpers\n",
+		"py/models.py":        "class User: pass\n",
+		"helpers.py":          "\n",
+	})
+
+	cases := map[string][]string{
+		"main.go":    {"main.go", "store/store.go"},
+		"web/app.ts": {"lib/util.js", "web/api.ts", "web/app.ts"},
+		"py/main.py": {"helpers.py", "py/main.py", "py/models.py"},
+	}
+	for sel, want := range cases {
+		if got := expandWithDependencies(root, []string{sel}); !slices.Equal(got, want) {
+			t.Errorf("%s: got %v; want %v", sel, got, want)
+		}
+	}
+}
+
+func TestRunHeadlessHonorsFileDirectives(t *testing.T) {
+	GlobalChanges = NewChangeTracker()
+	root := t.TempDir()
+	writeFixture(t, root, map[string]string{
+		"go.mod":         "module example.com/app\n",
+		"main.go":        "package main\n\nimport \"example.com/app/store\"\n",
+		"store/store.go": "package store\n",
+		"other/other.go": "package other\n",
+	})
+	llm := &stubLLM{reply: "ok"}
+
+	if _, err := RunHeadless(context.Background(), newStubAgent(t, llm), root, "@file main.go rename things", HeadlessOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	names := map[string]bool{}
+	for _, f := range llm.calls[0] {
+		names[f.Name] = true
+	}
+	if !names["main.go"] || !names["store/store.go"] || names["other/other.go"] || names["go.mod"] {
+		t.Errorf("attachments = %v", names)
+	}
+}
@@ -0,0 +1,91 @@
+package src
+
+import (
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Protocol-Lattice/lattice-code/src/ui"
+)
+
+// workspaceFiles lists the files the context snapshot draws from, as sorted
+// slash paths relative to root.
+func workspaceFiles(root string) []string {
+	var files []string
+	_ = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if p != root && isIgnoredDir(d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if (!allowedFile(p) && !isEnvFile(p)) || isSecretFile(p) {
+			return nil
+		}
+		if rel, err := filepath.Rel(root, p); err == nil {
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files
+}
+
+// annotatedTree lays files (slash paths) out as a tree in display order,
+// each directory before its contents. Files are marked when inContext or
+// changed has them; a directory is marked changed when anything under it is.
+func annotatedTree(files []string, inContext, changed map[string]bool) []ui.TreeEntry {
+	sorted := append([]string(nil), files...)
+	// Sorting on the path with "/" as the lowest byte keeps a directory's
+	// contents together and ahead of siblings like "a.go" vs "a/b.go".
+	sort.Slice(sorted, func(i, j int) bool {
+		return strings.ReplaceAll(sorted[i], "/", "\x00") < strings.ReplaceAll(sorted[j], "/", "\x00")
+	})
+
+	var entries []ui.TreeEntry
+	dirs := map[string]int{} // directory path -> index in entries
+	for _, f := range sorted {
+		parts := strings.Split(f, "/")
+		for i := range parts[:len(parts)-1] {
+			dir := strings.Join(parts[:i+1], "/")
+			if _, ok := dirs[dir]; !ok {
+				dirs[dir] = len(entries)
+				entries = append(entries, ui.TreeEntry{Name: parts[i], Depth: i, Dir: true})
+			}
+		}
+		entries = append(entries, ui.TreeEntry{
+			Name:      parts[len(parts)-1],
+			Depth:     len(parts) - 1,
+			InContext: inContext[f],
+			Changed:   changed[f],
+		})
+		if changed[f] {
+			for dir := path.Dir(f); dir != "."; dir = path.Dir(dir) {
+				entries[dirs[dir]].Changed = true
+			}
+		}
+	}
+	return entries
+}
+
+// refreshFileTree rebuilds the sidebar tree when the sidebar is shown.
+func (m *model) refreshFileTree() {
+	if !m.showFiles {
+		m.fileTree = nil
+		return
+	}
+	m.fileTree = annotatedTree(workspaceFiles(m.working), m.contextPaths, GlobalChanges.Written())
+}
+
+// fileTreeWidth is the width the file-tree sidebar takes from the chat.
+func (m *model) fileTreeWidth() int {
+	if !m.showFiles {
+		return 0
+	}
+	return ui.FileTreeWidth(m.width)
+}
@@ -0,0 +1,82 @@
+package src
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/Protocol-Lattice/lattice-code/src/ui"
+)
+
+func TestAnnotatedTree(t *testing.T) {
+	files := []string{"go.mod", "cmd/app/main.go", "a.go", "a/b.go", "README.md"}
+	inContext := map[string]bool{"go.mod": true, "cmd/app/main.go": true}
+	changed := map[string]bool{"cmd/app/main.go": true, "a.go": true}
+
+	got := annotatedTree(files, inContext, changed)
+	want := []ui.TreeEntry{
+		{Name: "README.md"},
+		{Name: "a", Dir: true},
+		{Name: "b.go", Depth: 1},
+		{Name: "a.go", Changed: true},
+		{Name: "cmd", Dir: true, Changed: true},
+		{Name: "app", Depth: 1, Dir: true, Changed: true},
+		{Name: "main.go", Depth: 2, InContext: true, Changed: true},
+		{Name: "go.mod", InContext: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("annotatedTree =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestFileTreeSidebar(t *testing.T) {
+	dir := t.TempDir()
+	writeFixture(t, dir, map[string]string{
+		"main.go":                 "package main\n",
+		"node_modules/x/index.js": "x\n",
+	})
+	GlobalChanges = NewChangeTracker()
+	GlobalChanges.Record("main.go", []byte("package main\n"))
+
+	if got := workspaceFiles(dir); !reflect.DeepEqual(got, []string{"main.go"}) {
+		t.Errorf("workspaceFiles = %q", got)
+	}
+
+	m := NewModel(context.Background(), nil, dir, nil)
+	m.mode = ui.ModeChat
+	m.Update(tea.WindowSizeMsg{Width: 140, Height: 40})
+	m.refreshContext()
+	m.Update(tea.KeyMsg{Type: tea.KeyCtrlB})
+	if !m.showFiles || len(m.fileTree) != 1 {
+		t.Fatalf("ctrl+b should show the sidebar; tree = %+v", m.fileTree)
+	}
+	if view := ansi.Strip(m.View()); !strings.Contains(view, "● main.go M") {
+		t.Errorf("sidebar row missing:\n%s", view)
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyCtrlB})
+	if m.showFiles || m.fileTreeWidth() != 0 {
+		t.Error("ctrl+b again should hide the sidebar")
+	}
+}
+
+func TestPromptRunLeavesContextToUIThread(t *testing.T) {
+	GlobalChanges = NewChangeTracker()
+	dir := t.TempDir()
+	writeFixture(t, dir, map[string]string{"main.go": "package main\n"})
+	m := NewModel(context.Background(), newStubAgent(t, &stubLLM{reply: "done"}), dir, nil)
+	m.mode = ui.ModeChat
+
+	_, cmd := m.runPrompt("tweak main.go")
+	msg := cmd().(tea.BatchMsg)[0]()
+	if m.contextPaths != nil {
+		t.Fatal("the background run should not write the context snapshot")
+	}
+	m.Update(msg)
+	if !m.contextPaths["main.go"] {
+		t.Errorf("contextPaths = %v after the result was posted", m.contextPaths)
+	}
+}
@@ -0,0 +1,69 @@
+package src
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// formatters maps extensions to a formatter invocation; the file path is appended.
+var formatters = map[string][]string{
+	".go":   {"gofmt", "-w"},
+	".js":   {"prettier", "--write", "--log-level", "warn"},
+	".jsx":  {"prettier", "--write", "--log-level", "warn"},
+	".ts":   {"prettier", "--write", "--log-level", "warn"},
+	".tsx":  {"prettier", "--write", "--log-level", "warn"},
+	".css":  {"prettier", "--write", "--log-level", "warn"},
+	".json": {"prettier", "--write", "--log-level", "warn"},
+	".py":   {"black", "-q"},
+	".rs":   {"rustfmt"},
+}
+
+// writtenPaths lists the files an action set actually changed on disk.
+func writtenPaths(actions []FileAction) []string {
+	var out []string
+	for _, a := range actions {
+		if a.Action == "saved" && a.Message != "unchanged" || a.Action == "tidied" {
+			out = append(out, a.Path)
+		}
+	}
+	return out
+}
+
+// FormatFiles runs the matching formatter over each path (relative to root).
+// Files are reported as "formatted" or "error"; a formatter that is not
+// installed produces a single "info" action and its files are left as is.
+func FormatFiles(ctx context.Context, root string, paths []string) []FileAction {
+	var actions []FileAction
+	missing := map[string]bool{}
+	for _, rel := range paths {
+		argv, ok := formatters[strings.ToLower(filepath.Ext(rel))]
+		if !ok || missing[argv[0]] {
+			continue
+		}
+		if _, err := exec.LookPath(argv[0]); err != nil {
+			missing[argv[0]] = true
+			actions = append(actions, FileAction{Action: "info", Message: fmt.Sprintf("%s not installed; skipped formatting", argv[0])})
+			continue
+		}
+
+		fctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		cmd := exec.CommandContext(fctx, argv[0], append(argv[1:], filepath.FromSlash(rel))...)
+		cmd.Dir = root
+		out, err := cmd.CombinedOutput()
+		cancel()
+		if err != nil {
+			msg := strings.TrimSpace(string(out))
+			if msg == "" {
+				msg = err.Error()
+			}
+			actions = append(actions, FileAction{Path: rel, Action: "error", Message: fmt.Sprintf("%s: %s", argv[0], msg), Err: err})
+			continue
+		}
+		actions = append(actions, FileAction{Path: rel, Action: "formatted", Message: argv[0]})
+	}
+	return actions
+}
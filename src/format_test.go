@@ -0,0 +1,39 @@
+package src
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestFormatFilesRunsGofmt(t *testing.T) {
+	if _, err := exec.LookPath("gofmt"); err != nil {
+		t.Skip("gofmt not installed")
+	}
+	root := t.TempDir()
+	writeFixture(t, root, map[string]string{"main.go": "package main\nfunc main(){\nprintln(1)}\n"})
+
+	actions := FormatFiles(context.Background(), root, []string{"main.go"})
+	if len(actions) != 1 || actions[0].Action != "formatted" {
+		t.Fatalf("actions = %+v", actions)
+	}
+	if got, want := readFile(t, filepath.Join(root, "main.go")), "package main\n\nfunc main() {\n\tprintln(1)\n}\n"; got != want {
+		t.Errorf("got %q; want %q", got, want)
+	}
+}
+
+func TestFormatFilesSkipsMissingFormatter(t *testing.T) {
+	formatters[".zz"] = []string{"no-such-formatter-installed"}
+	t.Cleanup(func() { delete(formatters, ".zz") })
+	root := t.TempDir()
+	writeFixture(t, root, map[string]string{"a.zz": "x", "b.zz": "y", "notes.txt": "z"})
+
+	actions := FormatFiles(context.Background(), root, []string{"a.zz", "b.zz", "notes.txt"})
+	if len(actions) != 1 || actions[0].Action != "info" {
+		t.Errorf("want one skipped-formatter note, got %+v", actions)
+	}
+	if readFile(t, filepath.Join(root, "a.zz")) != "x" {
+		t.Error("file should be left untouched")
+	}
+}
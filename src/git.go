@@ -0,0 +1,221 @@
+package src
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/Protocol-Lattice/lattice-code/src/ui"
+)
+
+// git runs git in root and returns its trimmed combined output.
+func git(ctx context.Context, root string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = root
+	out, err := cmd.CombinedOutput()
+	return strings.TrimSpace(string(out)), err
+}
+
+// gitOutput runs git in root and returns its standard output untouched,
+// for output where leading whitespace matters.
+func gitOutput(ctx context.Context, root string, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = root
+	return cmd.Output()
+}
+
+// gitShow returns the content of rel, relative to root, at rev, byte for
+// byte.
+func gitShow(ctx context.Context, root, rev, rel string) ([]byte, error) {
+	return gitOutput(ctx, root, "show", rev+":./"+rel)
+}
+
+// isGitRepo reports whether root is inside a git work tree.
+func isGitRepo(ctx context.Context, root string) bool {
+	if _, err := exec.LookPath("git"); err != nil {
+		return false
+	}
+	out, err := git(ctx, root, "rev-parse", "--is-inside-work-tree")
+	return err == nil && out == "true"
+}
+
+// hasHead reports whether root is a git repository with at least one commit.
+func hasHead(ctx context.Context, root string) bool {
+	if !isGitRepo(ctx, root) {
+		return false
+	}
+	_, err := git(ctx, root, "rev-parse", "--verify", "-q", "HEAD")
+	return err == nil
+}
+
+// commitSubject turns a prompt into a commit subject: its first non-empty
+// line, cut at 72 characters.
+func commitSubject(prompt string) string {
+	subject := "Apply generated changes"
+	for _, line := range strings.Split(prompt, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			subject = line
+			break
+		}
+	}
+	if r := []rune(subject); len(r) > 72 {
+		subject = string(r[:71]) + "…"
+	}
+	return subject
+}
+
+// CommitRun commits the files a run wrote, and only those: changes the user
+// already had staged or in other files stay out of the commit. It is a no-op
+// unless -git-commit is set and root is a git repository; a failed commit is
+// reported as "info" and leaves the files as written.
+func CommitRun(ctx context.Context, cfg *Config, root, prompt string, actions []FileAction) []FileAction {
+	paths := writtenPaths(actions)
+	if !cfg.orDefault().GitCommit || len(paths) == 0 || !isGitRepo(ctx, root) {
+		return nil
+	}
+	msg := commitSubject(prompt) + "\n\nFiles:\n  " + strings.Join(paths, "\n  ") + "\n"
+	if out, err := git(ctx, root, append([]string{"add", "--"}, paths...)...); err != nil {
+		return []FileAction{{Action: "info", Message: "git add failed; nothing committed: " + out}}
+	}
+	// With pathspecs, commit takes exactly those paths (--only), whatever
+	// else is staged.
+	if out, err := git(ctx, root, append([]string{"commit", "-q", "-m", msg, "--"}, paths...)...); err != nil {
+		return []FileAction{{Action: "info", Message: "git commit failed; changes left uncommitted: " + out}}
+	}
+	rev, _ := git(ctx, root, "rev-parse", "--short", "HEAD")
+	return []FileAction{{Action: "info", Message: fmt.Sprintf("committed %s: %s", rev, commitSubject(prompt))}}
+}
+
+// discardPlan is what /discard would undo: agent-managed files that differ
+// from HEAD, split by whether HEAD has them.
+type discardPlan struct {
+	Restore []string // in HEAD; checked out from it, index included
+	Remove  []string // created since HEAD (untracked or only staged); deleted
+}
+
+// planDiscard lists the uncommitted changes to files in the workspace
+// manifest. Files the agent never wrote are not considered.
+func planDiscard(ctx context.Context, root string) (*discardPlan, error) {
+	if !isGitRepo(ctx, root) {
+		return nil, fmt.Errorf("%s is not a git repository", root)
+	}
+	managed := loadManifest(root)
+	if len(managed) == 0 {
+		return &discardPlan{}, nil
+	}
+	// Status paths are relative to the top of the repository, which may be
+	// above root; the manifest is relative to root.
+	prefix, err := git(ctx, root, "rev-parse", "--show-prefix")
+	if err != nil {
+		return nil, fmt.Errorf("git rev-parse: %s", prefix)
+	}
+	out, err := gitOutput(ctx, root, "status", "--porcelain", "-z", "--no-renames", "--untracked-files=all", "--", ".")
+	if err != nil {
+		return nil, fmt.Errorf("git status: %v", err)
+	}
+	plan := &discardPlan{}
+	for _, entry := range strings.Split(string(out), "\x00") {
+		if len(entry) < 4 {
+			continue
+		}
+		code, path := entry[:2], strings.TrimPrefix(entry[3:], prefix)
+		if !managed[path] {
+			continue
+		}
+		if code == "??" || code[0] == 'A' {
+			plan.Remove = append(plan.Remove, path)
+		} else {
+			plan.Restore = append(plan.Restore, path)
+		}
+	}
+	sort.Strings(plan.Restore)
+	sort.Strings(plan.Remove)
+	return plan, nil
+}
+
+// applyDiscard restores plan.Restore from HEAD and, with removeNew,
+// deletes plan.Remove. Each file is reported as "removed" or as restored
+// ("saved"), or as "error".
+func applyDiscard(ctx context.Context, root string, plan *discardPlan, removeNew bool) []FileAction {
+	var actions []FileAction
+	if len(plan.Restore) > 0 {
+		if out, err := git(ctx, root, append([]string{"restore", "--source=HEAD", "--staged", "--worktree", "--"}, plan.Restore...)...); err != nil {
+			return []FileAction{{Action: "error", Message: "git restore failed: " + out, Err: err}}
+		}
+		for _, p := range plan.Restore {
+			actions = append(actions, FileAction{Path: p, Action: "saved", Message: "restored from HEAD"})
+		}
+	}
+	if !removeNew || len(plan.Remove) == 0 {
+		return actions
+	}
+	if out, err := git(ctx, root, append([]string{"rm", "-q", "-f", "--cached", "--ignore-unmatch", "--"}, plan.Remove...)...); err != nil {
+		return append(actions, FileAction{Action: "error", Message: "git rm failed: " + out, Err: err})
+	}
+	var removed []string
+	for _, p := range plan.Remove {
+		if err := os.Remove(filepath.Join(root, filepath.FromSlash(p))); err != nil && !os.IsNotExist(err) {
+			actions = append(actions, FileAction{Path: p, Action: "error", Message: err.Error(), Err: err})
+			continue
+		}
+		removed = append(removed, p)
+		actions = append(actions, FileAction{Path: p, Action: "removed"})
+	}
+	if err := forgetManaged(root, removed); err != nil {
+		actions = append(actions, FileAction{Action: "error", Message: "updating the manifest: " + err.Error(), Err: err})
+	}
+	return actions
+}
+
+// discardSlashCommand handles "/discard", which asks before reverting the
+// agent's uncommitted changes to HEAD.
+func discardSlashCommand(m *model, _ string) (*model, tea.Cmd) {
+	plan, err := planDiscard(m.ctx, m.working)
+	switch {
+	case err != nil:
+		m.output += m.style.Error.Render(fmt.Sprintf("❌ /discard: %v\n", err))
+	case len(plan.Restore)+len(plan.Remove) == 0:
+		m.output += m.style.Subtle.Render("ℹ️ no uncommitted changes to agent-written files\n")
+	default:
+		m.pendingDiscard = plan
+		m.output += m.style.Accent.Render(fmt.Sprintf("⚠️ /discard would restore %d file(s) from HEAD and remove %d new file(s)\n", len(plan.Restore), len(plan.Remove)))
+		if m.mode != ui.ModeConfirm {
+			m.prevMode = m.mode
+			m.mode = ui.ModeConfirm
+		}
+	}
+	m.renderOutput(true)
+	return m, nil
+}
+
+// resolvePendingDiscard carries out or cancels the /discard awaiting
+// confirmation.
+func (m *model) resolvePendingDiscard(apply, removeNew bool) {
+	plan := m.pendingDiscard
+	m.pendingDiscard = nil
+	m.mode = ui.ModeChat
+	if apply {
+		m.output += m.formatActions("discard", applyDiscard(m.ctx, m.working, plan, removeNew))
+		for _, p := range plan.Restore {
+			if b, err := os.ReadFile(filepath.Join(m.working, filepath.FromSlash(p))); err == nil {
+				GlobalChanges.Record(p, b)
+			}
+		}
+	} else {
+		m.output += m.style.Subtle.Render("↩️ discard cancelled\n")
+	}
+	m.refreshContext()
+	m.refreshFileTree()
+	m.renderOutput(true)
+}
@@ -0,0 +1,187 @@
+package src
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Protocol-Lattice/lattice-code/src/ui"
+)
+
+// gitRepo creates a repository with one commit holding files.
+func gitRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root := t.TempDir()
+	writeFixture(t, root, files)
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "user.name", "Test"},
+		{"add", "-A"},
+		{"commit", "-q", "-m", "initial"},
+	} {
+		if out, err := git(context.Background(), root, args...); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	return root
+}
+
+func TestCommitRunCommitsOnlyWrittenFiles(t *testing.T) {
+	root := gitRepo(t, map[string]string{"main.go": "package main\n", "notes.txt": "mine\n"})
+	cfg := DefaultConfig()
+	cfg.GitCommit = true
+
+	// The user has unrelated work in progress, some of it staged.
+	writeFixture(t, root, map[string]string{"notes.txt": "mine, edited\n", "draft.txt": "wip\n"})
+	if out, err := git(context.Background(), root, "add", "draft.txt"); err != nil {
+		t.Fatal(out)
+	}
+	writeFixture(t, root, map[string]string{"main.go": "package main\n\nfunc main() {}\n", "util/util.go": "package util\n"})
+	actions := []FileAction{
+		{Path: "main.go", Action: "saved", Message: "updated"},
+		{Path: "util/util.go", Action: "saved", Message: "created"},
+		{Path: "README.md", Action: "saved", Message: "unchanged"},
+	}
+
+	got := CommitRun(context.Background(), cfg, root, "\nAdd a main function\nand a util package", actions)
+	if len(got) != 1 || !strings.Contains(got[0].Message, "committed") {
+		t.Fatalf("CommitRun = %+v", got)
+	}
+	files, _ := git(context.Background(), root, "show", "--name-only", "--format=%s", "HEAD")
+	if files != "Add a main function\n\nmain.go\nutil/util.go" {
+		t.Errorf("HEAD = %q", files)
+	}
+	status, _ := git(context.Background(), root, "status", "--porcelain")
+	if status != "A  draft.txt\n M notes.txt" {
+		t.Errorf("the user's own changes should stay uncommitted, status = %q", status)
+	}
+
+	if got := CommitRun(context.Background(), cfg, t.TempDir(), "x", actions); got != nil {
+		t.Errorf("outside a repository CommitRun = %+v", got)
+	}
+}
+
+func TestCommitRunIncludesTidiedModuleFiles(t *testing.T) {
+	GlobalChanges = NewChangeTracker()
+	root := gitRepo(t, map[string]string{"go.mod": "module example.com/app\n\ngo 1.21\n", "main.go": "package main\n"})
+	cfg := DefaultConfig()
+	cfg.GitCommit = true
+	orig := goModTidy
+	defer func() { goModTidy = orig }()
+	goModTidy = func(_ context.Context, dir string) (string, error) {
+		writeFixture(t, dir, map[string]string{
+			"go.mod": "module example.com/app\n\ngo 1.21\n\nrequire github.com/new/dep v1.2.3\n",
+			"go.sum": "github.com/new/dep v1.2.3 h1:abc=\n",
+		})
+		return "", nil
+	}
+
+	writeFixture(t, root, map[string]string{"main.go": "package main\n\nimport _ \"github.com/new/dep\"\n"})
+	actions := append([]FileAction{{Path: "main.go", Action: "saved", Message: "updated"}}, TidyModule(context.Background(), root)...)
+	if got := CommitRun(context.Background(), cfg, root, "Use the new dep", actions); len(got) != 1 || !strings.Contains(got[0].Message, "committed") {
+		t.Fatalf("CommitRun = %+v", got)
+	}
+	files, _ := git(context.Background(), root, "show", "--name-only", "--format=", "HEAD")
+	if files != "go.mod\ngo.sum\nmain.go" {
+		t.Errorf("committed files = %q; want the code with go.mod and go.sum", files)
+	}
+}
+
+func TestDiscardRevertsAgentChanges(t *testing.T) {
+	GlobalChanges = NewChangeTracker()
+	root := gitRepo(t, map[string]string{"main.go": "package main\n", "README.md": "readme\n"})
+	writeFixture(t, root, map[string]string{
+		"main.go":      "package main // agent edit\n",
+		"README.md":    "readme, edited by hand\n",
+		"util/util.go": "package util\n",
+		"new.go":       "package main // staged\n",
+		"mine.txt":     "the user's own file\n",
+	})
+	if err := recordManaged(root, []string{"main.go", "util/util.go", "new.go"}); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := git(context.Background(), root, "add", "main.go", "new.go"); err != nil {
+		t.Fatal(out)
+	}
+
+	plan, err := planDiscard(context.Background(), root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(plan.Restore, ",") != "main.go" || strings.Join(plan.Remove, ",") != "new.go,util/util.go" {
+		t.Fatalf("plan = %+v", plan)
+	}
+
+	m := NewModel(context.Background(), nil, root, nil)
+	m, _ = discardSlashCommand(m, "")
+	if m.mode != ui.ModeConfirm || m.pendingDiscard == nil {
+		t.Fatalf("/discard should ask first, mode = %v", m.mode)
+	}
+	m.resolvePendingDiscard(true, true)
+
+	if got := readFile(t, filepath.Join(root, "main.go")); got != "package main\n" {
+		t.Errorf("main.go = %q", got)
+	}
+	for _, p := range []string{"new.go", "util/util.go"} {
+		if _, err := os.Stat(filepath.Join(root, p)); !os.IsNotExist(err) {
+			t.Errorf("%s should be removed", p)
+		}
+	}
+	status, _ := git(context.Background(), root, "status", "--porcelain", "--untracked-files=all")
+	if status != "M README.md\n?? .lattice/manifest.json\n?? mine.txt" {
+		t.Errorf("only agent files should be touched, status = %q", status)
+	}
+	if managed := loadManifest(root); managed["new.go"] || managed["util/util.go"] || !managed["main.go"] {
+		t.Errorf("manifest after /discard = %v; want only main.go", managed)
+	}
+
+	// A file the user later creates at a discarded path is theirs.
+	writeFixture(t, root, map[string]string{"new.go": "package main // the user's\n"})
+	actions, err := WriteCodeBlocks(root, fence("new.go", "package main // agent"), WriteOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actions) != 1 || actions[0].Action != "pending" {
+		t.Errorf("actions = %+v; want the overwrite held for confirmation", actions)
+	}
+}
+
+func TestDiscardInRepositorySubdirectory(t *testing.T) {
+	GlobalChanges = NewChangeTracker()
+	repo := gitRepo(t, map[string]string{"app/main.go": "package main\n", "other/main.go": "package other\n"})
+	root := filepath.Join(repo, "app")
+	writeFixture(t, repo, map[string]string{
+		"app/main.go":   "package main // agent edit\n",
+		"app/new.go":    "package main\n",
+		"other/main.go": "package other // edited by hand\n",
+	})
+	// main.go is managed in the workspace; other/main.go is outside it.
+	if err := recordManaged(root, []string{"main.go", "new.go"}); err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := planDiscard(context.Background(), root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(plan.Restore, ",") != "main.go" || strings.Join(plan.Remove, ",") != "new.go" {
+		t.Fatalf("plan = %+v", plan)
+	}
+	applyDiscard(context.Background(), root, plan, true)
+	if got := readFile(t, filepath.Join(root, "main.go")); got != "package main\n" {
+		t.Errorf("main.go = %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(root, "new.go")); !os.IsNotExist(err) {
+		t.Error("new.go should be removed")
+	}
+	if got := readFile(t, filepath.Join(repo, "other", "main.go")); got != "package other // edited by hand\n" {
+		t.Errorf("file outside the workspace was touched: %q", got)
+	}
+}
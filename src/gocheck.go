@@ -0,0 +1,191 @@
+package src
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// touchesGo reports whether any written file is Go source or go.mod.
+func touchesGo(actions []FileAction) bool {
+	for _, p := range writtenPaths(actions) {
+		if strings.HasSuffix(p, ".go") || filepath.Base(p) == "go.mod" {
+			return true
+		}
+	}
+	return false
+}
+
+// goCheck runs go build and go vet over a Go module rooted at root and returns
+// their combined diagnostics, or "" when both pass or root is not a module.
+func goCheck(ctx context.Context, root string) string {
+	if _, err := os.Stat(filepath.Join(root, "go.mod")); err != nil {
+		return ""
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+
+	var diags []string
+	for _, args := range [][]string{{"build", "./..."}, {"vet", "./..."}} {
+		cmd := exec.CommandContext(ctx, "go", args...)
+		cmd.Dir = root
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			diags = append(diags, "$ go "+strings.Join(args, " ")+"\n"+strings.TrimSpace(string(out)))
+			if args[0] == "build" {
+				break // vet would only repeat the compile errors
+			}
+		}
+	}
+	return strings.Join(diags, "\n")
+}
+
+// goDiagRe matches a go build or go vet diagnostic such as
+// "./main.go:12:5: undefined: foo", capturing the file, line and message.
+var goDiagRe = regexp.MustCompile(`^(?:vet: )?([^\s:]+\.go):(\d+)(?::\d+)?: (.+)$`)
+
+// compileFixPrompt turns goCheck's diagnostics into buildFixPrompt: the
+// errors grouped by file in the order they were reported, each with the line
+// it points at quoted from the file. It also returns the failing workspace
+// files, slash-separated, so the fix can be limited to them. Diagnostics that
+// name no file, such as go.mod errors, are passed on as they were printed.
+func compileFixPrompt(root, diag string) (string, []string) {
+	type fileErrs struct {
+		lines []string
+		errs  []string
+	}
+	byFile := map[string]*fileErrs{}
+	var files, other []string
+	for _, line := range strings.Split(diag, "\n") {
+		line = strings.TrimSpace(line)
+		m := goDiagRe.FindStringSubmatch(line)
+		if m == nil {
+			if line != "" && !strings.HasPrefix(line, "$ go ") && !strings.HasPrefix(line, "# ") {
+				other = append(other, line)
+			}
+			continue
+		}
+		rel := filepath.ToSlash(filepath.Clean(m[1]))
+		if filepath.IsAbs(rel) || strings.HasPrefix(rel, "../") {
+			other = append(other, line)
+			continue
+		}
+		f := byFile[rel]
+		if f == nil {
+			f = &fileErrs{}
+			if b, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(rel))); err == nil {
+				f.lines = strings.Split(string(b), "\n")
+			}
+			byFile[rel] = f
+			files = append(files, rel)
+		}
+		n, _ := strconv.Atoi(m[2])
+		e := fmt.Sprintf("- line %d: %s", n, m[3])
+		if n >= 1 && n <= len(f.lines) {
+			e += fmt.Sprintf("\n      %d | %s", n, strings.TrimRight(f.lines[n-1], "\r"))
+		}
+		f.errs = append(f.errs, e)
+	}
+
+	var b strings.Builder
+	for _, rel := range files {
+		fmt.Fprintf(&b, "%s:\n%s\n\n", rel, strings.Join(byFile[rel].errs, "\n"))
+	}
+	if len(other) > 0 {
+		fmt.Fprintf(&b, "Other errors:\n%s\n", strings.Join(other, "\n"))
+	}
+	return fmt.Sprintf(buildFixPrompt, strings.TrimRight(b.String(), "\n")), files
+}
+
+// goModTidy runs go mod tidy in root and returns its combined output. It is a
+// variable so tests need neither the network nor a go toolchain.
+var goModTidy = func(ctx context.Context, root string) (string, error) {
+	if _, err := exec.LookPath("go"); err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "go", "mod", "tidy")
+	cmd.Dir = root
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
+// TidyModule runs go mod tidy over the module rooted at root, so generated
+// code that imports new packages builds. go.mod and go.sum, when tidy
+// changed them, are reported as "tidied", with the requirements it added.
+// Failures, such as being offline, are reported as "info" with the tool's
+// output; go.mod is left as tidy left it.
+func TidyModule(ctx context.Context, root string) []FileAction {
+	before, err := os.ReadFile(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return nil
+	}
+	sumBefore, _ := os.ReadFile(filepath.Join(root, "go.sum"))
+	out, err := goModTidy(ctx, root)
+	if err != nil {
+		msg := strings.TrimSpace(out)
+		if msg == "" {
+			msg = err.Error()
+		}
+		return []FileAction{{Action: "info", Message: "go mod tidy failed; dependencies not updated: " + msg}}
+	}
+	var actions []FileAction
+	if after, err := os.ReadFile(filepath.Join(root, "go.mod")); err == nil && !bytes.Equal(before, after) {
+		old := goModRequires(string(before))
+		var added []string
+		for mod, v := range goModRequires(string(after)) {
+			if _, ok := old[mod]; !ok {
+				added = append(added, mod+" "+v)
+			}
+		}
+		sort.Strings(added)
+		msg := "go mod tidy updated go.mod"
+		if len(added) > 0 {
+			msg = "go mod tidy added " + strings.Join(added, ", ")
+		}
+		GlobalChanges.Record("go.mod", after)
+		actions = append(actions, FileAction{Path: "go.mod", Action: "tidied", Message: msg})
+	}
+	if after, err := os.ReadFile(filepath.Join(root, "go.sum")); err == nil && !bytes.Equal(sumBefore, after) {
+		GlobalChanges.Record("go.sum", after)
+		actions = append(actions, FileAction{Path: "go.sum", Action: "tidied", Message: "go mod tidy updated go.sum"})
+	}
+	return actions
+}
+
+// goModRequires maps each module required by a go.mod to its version,
+// covering both single-line and block require directives.
+func goModRequires(gomod string) map[string]string {
+	reqs := map[string]string{}
+	inBlock := false
+	for _, line := range strings.Split(gomod, "\n") {
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+		case inBlock && fields[0] == ")":
+			inBlock = false
+		case inBlock && len(fields) >= 2:
+			reqs[fields[0]] = fields[1]
+		case fields[0] == "require" && len(fields) == 2 && fields[1] == "(":
+			inBlock = true
+		case fields[0] == "require" && len(fields) >= 3:
+			reqs[fields[1]] = fields[2]
+		}
+	}
+	return reqs
+}
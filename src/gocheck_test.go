@@ -0,0 +1,112 @@
+package src
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestGoCheckReportsBrokenCode(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not installed")
+	}
+	root := t.TempDir()
+	writeFixture(t, root, map[string]string{
+		"go.mod":  "module example.com/broken\n\ngo 1.21\n",
+		"main.go": "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Printf(\"%d\\n\", \"not a number\")\n}\n",
+	})
+
+	diag := goCheck(context.Background(), root)
+	if !strings.Contains(diag, "go vet") || !strings.Contains(diag, "Printf") {
+		t.Errorf("expected vet diagnostics, got:\n%s", diag)
+	}
+
+	writeFixture(t, root, map[string]string{"main.go": "package main\n\nfunc main() { undefinedCall() }\n"})
+	if diag := goCheck(context.Background(), root); !strings.Contains(diag, "go build") || !strings.Contains(diag, "undefined") {
+		t.Errorf("expected build errors, got:\n%s", diag)
+	}
+
+	writeFixture(t, root, map[string]string{"main.go": "package main\n\nfunc main() {}\n"})
+	if diag := goCheck(context.Background(), root); diag != "" {
+		t.Errorf("clean module should pass, got:\n%s", diag)
+	}
+}
+
+func TestTouchesGo(t *testing.T) {
+	if touchesGo([]FileAction{{Path: "README.md", Action: "saved", Message: "created"}, {Path: "a.go", Action: "saved", Message: "unchanged"}}) {
+		t.Error("unchanged Go files should not trigger checks")
+	}
+	if !touchesGo([]FileAction{{Path: "cmd/main.go", Action: "saved", Message: "updated"}}) {
+		t.Error("updated Go files should trigger checks")
+	}
+}
+
+func TestTidyModuleReportsAddedRequirements(t *testing.T) {
+	GlobalChanges = NewChangeTracker()
+	root := t.TempDir()
+	writeFixture(t, root, map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.21\n\nrequire github.com/old/dep v1.0.0\n",
+	})
+	var ran string
+	orig := goModTidy
+	defer func() { goModTidy = orig }()
+	goModTidy = func(_ context.Context, dir string) (string, error) {
+		ran = dir
+		writeFixture(t, dir, map[string]string{
+			"go.mod": "module example.com/app\n\ngo 1.21\n\nrequire (\n\tgithub.com/new/dep v1.2.3\n\tgithub.com/old/dep v1.0.0\n\tgolang.org/x/text v0.3.0 // indirect\n)\n",
+		})
+		return "go: added github.com/new/dep v1.2.3\n", nil
+	}
+
+	actions := TidyModule(context.Background(), root)
+	if ran != root {
+		t.Fatalf("tidy ran in %q; want %q", ran, root)
+	}
+	if len(actions) != 1 || actions[0].Message != "go mod tidy added github.com/new/dep v1.2.3, golang.org/x/text v0.3.0" {
+		t.Errorf("actions = %+v", actions)
+	}
+
+	goModTidy = func(context.Context, string) (string, error) {
+		return "go: github.com/x/y: dial tcp: lookup proxy.golang.org: no such host\n", errors.New("exit status 1")
+	}
+	actions = TidyModule(context.Background(), root)
+	if len(actions) != 1 || actions[0].Action != "info" || !strings.Contains(actions[0].Message, "no such host") {
+		t.Errorf("offline failure should be reported with tidy's output, got %+v", actions)
+	}
+
+	if actions := TidyModule(context.Background(), t.TempDir()); actions != nil {
+		t.Errorf("no go.mod should be a no-op, got %+v", actions)
+	}
+}
+
+func TestCompileFixPromptPinsErrorsToFiles(t *testing.T) {
+	root := t.TempDir()
+	writeFixture(t, root, map[string]string{
+		"main.go":   "package main\n\nfunc main() {\n\tundefinedCall()\n}\n",
+		"util/u.go": "package util\n\nvar X int = \"s\"\n",
+	})
+	diag := "$ go build ./...\n# example.com/x\n./main.go:4:2: undefined: undefinedCall\n" +
+		"# example.com/x/util\nutil/u.go:3:13: cannot use \"s\" (untyped string constant) as int value in variable declaration\n" +
+		"go: updates to go.mod needed"
+
+	prompt, files := compileFixPrompt(root, diag)
+	if strings.Join(files, ",") != "main.go,util/u.go" {
+		t.Errorf("files = %v; want main.go and util/u.go", files)
+	}
+	for _, want := range []string{
+		"main.go:\n- line 4: undefined: undefinedCall\n      4 | \tundefinedCall()",
+		"util/u.go:\n- line 3: cannot use",
+		"3 | var X int = \"s\"",
+		"Other errors:\ngo: updates to go.mod needed",
+		"smallest change",
+	} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt lacks %q:\n%s", want, prompt)
+		}
+	}
+	if strings.Contains(prompt, "$ go build") || strings.Contains(prompt, "# example.com") {
+		t.Errorf("prompt kept command or package headers:\n%s", prompt)
+	}
+}
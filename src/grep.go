@@ -0,0 +1,206 @@
+package src
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/Protocol-Lattice/lattice-code/src/ui"
+)
+
+const maxGrepMatches = 500
+
+// maxGrepLineBytes is the longest line grepWorkspace reads; the rest of a
+// file with a longer line is skipped.
+const maxGrepLineBytes = 8 << 20
+
+type grepOptions struct {
+	ignoreCase bool
+	glob       string // matched against the base name, or the relative path when it contains a /
+}
+
+type grepMatch struct {
+	Path string // relative to the workspace root
+	Line int
+	Text string
+}
+
+// grepItem shows one match in the ModeGrep list.
+type grepItem struct{ grepMatch }
+
+func (g grepItem) Title() string       { return fmt.Sprintf("%s:%d", g.Path, g.Line) }
+func (g grepItem) Description() string { return trim(strings.TrimSpace(g.Text), 160) }
+func (g grepItem) FilterValue() string { return g.Path + " " + g.Text }
+
+type grepResultsMsg struct {
+	pattern string
+	matches []grepMatch
+	err     error
+}
+
+// grepWorkspace runs a regex search over the same files the context walk would
+// consider, stopping after maxGrepMatches hits.
+func grepWorkspace(root, pattern string, opts grepOptions) ([]grepMatch, error) {
+	if opts.ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []grepMatch
+	err = filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path != root && isIgnoredDir(d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !allowedFile(path) || isSecretFile(path) {
+			return nil
+		}
+		rel, _ := filepath.Rel(root, path)
+		if opts.glob != "" {
+			target := filepath.Base(rel)
+			if strings.Contains(opts.glob, "/") {
+				target = filepath.ToSlash(rel)
+			}
+			if ok, _ := filepath.Match(opts.glob, target); !ok {
+				return nil
+			}
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return nil
+		}
+		defer f.Close()
+		sc := bufio.NewScanner(f)
+		sc.Buffer(nil, maxGrepLineBytes)
+		for n := 1; sc.Scan(); n++ {
+			if re.MatchString(sc.Text()) {
+				matches = append(matches, grepMatch{Path: filepath.ToSlash(rel), Line: n, Text: sc.Text()})
+				if len(matches) >= maxGrepMatches {
+					return filepath.SkipAll
+				}
+			}
+		}
+		return nil
+	})
+	return matches, err
+}
+
+// parseGrepArgs parses "[-i] [-g GLOB] <pattern>"; the pattern may contain
+// spaces and is kept as typed after the flags.
+func parseGrepArgs(args string) (string, grepOptions, error) {
+	var opts grepOptions
+	rest := strings.TrimLeft(args, " \t")
+	for {
+		tok, after := grepToken(rest)
+		switch tok {
+		case "-i":
+			opts.ignoreCase = true
+			rest = after
+			continue
+		case "-g":
+			glob, after := grepToken(after)
+			if glob == "" {
+				return "", opts, fmt.Errorf("-g needs a glob")
+			}
+			opts.glob, rest = glob, after
+			continue
+		}
+		break
+	}
+	if rest == "" {
+		return "", opts, fmt.Errorf("usage: /grep [-i] [-g GLOB] <pattern>")
+	}
+	return rest, opts, nil
+}
+
+// grepToken splits the first whitespace-separated word off s, returning it
+// and what follows it with the separating whitespace removed.
+func grepToken(s string) (string, string) {
+	s = strings.TrimLeft(s, " \t")
+	i := strings.IndexAny(s, " \t")
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], strings.TrimLeft(s[i:], " \t")
+}
+
+func grepSlashCommand(m *model, args string) (*model, tea.Cmd) {
+	pattern, opts, err := parseGrepArgs(args)
+	if err != nil {
+		m.output += m.style.Error.Render(fmt.Sprintf("❌ /grep: %v\n", err))
+		m.renderOutput(true)
+		return m, nil
+	}
+	root := m.working
+	cmd := func() tea.Msg {
+		matches, err := grepWorkspace(root, pattern, opts)
+		return grepResultsMsg{pattern: pattern, matches: matches, err: err}
+	}
+	return m, tea.Batch(cmd, m.startWork("searching"))
+}
+
+// showGrepResults lists matches in ModeGrep, or reports an empty search in the chat.
+func (m *model) showGrepResults(msg grepResultsMsg) {
+	m.endWork()
+	switch {
+	case msg.err != nil:
+		m.output += m.style.Error.Render(fmt.Sprintf("❌ /grep: %v\n", msg.err))
+	case len(msg.matches) == 0:
+		m.output += m.style.Subtle.Render(fmt.Sprintf("No matches for %q\n", msg.pattern))
+	default:
+		items := make([]list.Item, len(msg.matches))
+		for i, match := range msg.matches {
+			items[i] = grepItem{match}
+		}
+		m.list.SetItems(items)
+		m.list.SetFilteringEnabled(true)
+		m.list.Title = fmt.Sprintf("🔍 %d match(es) for %q", len(items), msg.pattern)
+		m.prevMode = m.mode
+		m.mode = ui.ModeGrep
+	}
+	m.renderOutput(true)
+}
+
+// openGrepMatch prints a few lines around the selected match and returns to chat.
+func (m *model) openGrepMatch(g grepItem) {
+	m.output += m.style.Accent.Render(fmt.Sprintf("%s:%d", g.Path, g.Line)) + "\n"
+	if b, err := os.ReadFile(filepath.Join(m.working, g.Path)); err == nil {
+		lines := strings.Split(string(b), "\n")
+		from, to := max(g.Line-4, 0), min(g.Line+3, len(lines))
+		for i := from; i < to; i++ {
+			marker := "  "
+			if i+1 == g.Line {
+				marker = "> "
+			}
+			m.output += fmt.Sprintf("%s%4d | %s\n", marker, i+1, lines[i])
+		}
+	}
+	m.output += "\n"
+	m.closeGrepResults()
+	m.renderOutput(true)
+}
+
+// closeGrepResults puts the agent list, unfiltered, back in place of the
+// matches and returns to chat.
+func (m *model) closeGrepResults() {
+	m.list.ResetFilter()
+	m.list.SetFilteringEnabled(false)
+	m.list.SetItems(defaultAgents())
+	m.list.Title = fmt.Sprintf("📁 %s", filepath.Base(m.working))
+	m.mode = ui.ModeChat
+}
@@ -0,0 +1,99 @@
+package src
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/Protocol-Lattice/lattice-code/src/ui"
+)
+
+func grepFixture(t *testing.T) string {
+	root := t.TempDir()
+	writeFixture(t, root, map[string]string{
+		"main.go":                 "package main\n\nfunc LoadConfig() {}\n",
+		"pkg/util.go":             "package pkg\n// loadconfig helper\n",
+		"docs/notes.md":           "LoadConfig is documented here\n",
+		"node_modules/x/index.js": "LoadConfig()\n",
+		"image.png":               "LoadConfig",
+	})
+	return root
+}
+
+func TestGrepWorkspace(t *testing.T) {
+	root := grepFixture(t)
+
+	matches, err := grepWorkspace(root, `LoadConfig\(`, grepOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 || matches[0] != (grepMatch{Path: "main.go", Line: 3, Text: "func LoadConfig() {}"}) {
+		t.Errorf("matches = %+v", matches)
+	}
+
+	matches, _ = grepWorkspace(root, "loadconfig", grepOptions{ignoreCase: true})
+	if len(matches) != 3 {
+		t.Errorf("case-insensitive matches = %+v; want main.go, notes.md and util.go", matches)
+	}
+
+	matches, _ = grepWorkspace(root, "loadconfig", grepOptions{ignoreCase: true, glob: "*.go"})
+	if len(matches) != 2 {
+		t.Errorf("glob-filtered matches = %+v", matches)
+	}
+
+	if _, err := grepWorkspace(root, "(", grepOptions{}); err == nil {
+		t.Error("expected an invalid regex error")
+	}
+
+	// Lines past bufio's default 64KB limit are still searched.
+	writeFixture(t, root, map[string]string{"data.txt": strings.Repeat("x", 100_000) + "\nLoadConfig after a long line\n"})
+	matches, _ = grepWorkspace(root, "after a long line", grepOptions{})
+	if len(matches) != 1 || matches[0].Path != "data.txt" || matches[0].Line != 2 {
+		t.Errorf("matches after a long line = %+v", matches)
+	}
+}
+
+func TestParseGrepArgs(t *testing.T) {
+	pattern, opts, err := parseGrepArgs("-i -g *.go func main")
+	if err != nil || pattern != "func main" || !opts.ignoreCase || opts.glob != "*.go" {
+		t.Errorf("parseGrepArgs = %q %+v %v", pattern, opts, err)
+	}
+	if pattern, _, _ := parseGrepArgs("-g\t*.go  if err  !=  nil"); pattern != "if err  !=  nil" {
+		t.Errorf("pattern = %q; want the spacing kept as typed", pattern)
+	}
+	if _, _, err := parseGrepArgs("-i"); err == nil {
+		t.Error("expected a usage error without a pattern")
+	}
+}
+
+func TestGrepCommandListsMatches(t *testing.T) {
+	m := NewModel(context.Background(), nil, grepFixture(t), nil)
+	m.mode = ui.ModeChat
+
+	_, cmd := m.handleSlashCommand("/grep LoadConfig")
+	m.Update(cmd().(tea.BatchMsg)[0]())
+	if m.mode != ui.ModeGrep || len(m.list.Items()) != 2 {
+		t.Fatalf("mode = %v, items = %d", m.mode, len(m.list.Items()))
+	}
+
+	// "/" filters the matches, as the footer says.
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+	if m.list.FilterState() != list.Filtering {
+		t.Fatalf("/ should start filtering, state = %v", m.list.FilterState())
+	}
+	m.list.SetFilterText("notes") // typing filters in a command; apply it directly
+	if got := m.list.VisibleItems(); len(got) != 1 || got[0].(grepItem).Path != "docs/notes.md" {
+		t.Fatalf("filtered matches = %+v", got)
+	}
+
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.mode != ui.ModeChat {
+		t.Errorf("enter should return to chat, mode = %v", m.mode)
+	}
+	if m.list.FilteringEnabled() || len(m.list.VisibleItems()) != len(defaultAgents()) {
+		t.Error("the agent list should come back unfiltered")
+	}
+}
@@ -12,21 +12,64 @@ import (
 	"strings"
 
 	agent "github.com/Protocol-Lattice/go-agent"
+	"github.com/Protocol-Lattice/go-agent/src/models"
 )
 
 type FileAction struct {
 	Path, Action, Message string
 	Err                   error
 	Diff                  string
+
+	body []byte // proposed content of a "pending" write
 }
 
 type HeadlessResult struct {
 	Response string
 	Actions  []FileAction
+	Usage    Usage
+	Dropped  []string // attachments left out to fit -max-request-bytes
+}
+
+// generator is the blocking call RunHeadless makes; *agent.Agent has it.
+type generator interface {
+	GenerateWithFiles(ctx context.Context, sessionID, prompt string, files []models.File) (string, error)
+}
+
+// tokenStreamer is implemented by generators that can deliver a response
+// while it is being generated. go-agent's Agent does not (as of v0.7.3), so
+// for now the blocking call is what runs.
+type tokenStreamer interface {
+	GenerateWithFilesStream(ctx context.Context, sessionID, prompt string, files []models.File, onChunk func(string)) (string, error)
+}
+
+// generate runs prompt on g, passing the response to onChunk as it arrives
+// when g can stream it. The full response is returned either way.
+func generate(ctx context.Context, g generator, session, prompt string, files []models.File, onChunk func(string)) (string, error) {
+	if s, ok := g.(tokenStreamer); ok && onChunk != nil {
+		return s.GenerateWithFilesStream(ctx, session, prompt, files, onChunk)
+	}
+	return g.GenerateWithFiles(ctx, session, prompt, files)
+}
+
+// HeadlessOptions configures RunHeadless.
+type HeadlessOptions struct {
+	// Config supplies the session's settings; nil means DefaultConfig().
+	Config *Config
+	// Session is the agent session the prompt runs in, which keeps its
+	// memory and tags its log lines; "" starts a new one.
+	Session string
+	// TaskOnly leaves out the request for a docker-compose.yml, for prompts
+	// that must change only what they name, like a planner's fix step.
+	TaskOnly bool
+	// OnAction, if non-nil, is called for each file as soon as it is
+	// written, with "progress" actions as the run moves between phases, and
+	// with "chunk" actions carrying response text when the agent streams it.
+	OnAction func(FileAction)
 }
 
 // RunHeadless runs a prompt, writes code, and prints diffs in terminal.
-func RunHeadless(ctx context.Context, ag *agent.Agent, workspace, userPrompt string) (*HeadlessResult, error) {
+func RunHeadless(ctx context.Context, ag *agent.Agent, workspace, userPrompt string, opts HeadlessOptions) (*HeadlessResult, error) {
+	cfg, onAction := opts.Config.orDefault(), opts.OnAction
 	if ag == nil {
 		return nil, errors.New("agent is nil")
 	}
@@ -36,25 +79,97 @@ func RunHeadless(ctx context.Context, ag *agent.Agent, workspace, userPrompt str
 
 	abs, _ := filepath.Abs(workspace)
 	_ = os.MkdirAll(abs, 0o755)
+	useLogWorkspace(abs, cfg.LogLevel)
+	session := opts.Session
+	if session == "" {
+		session = randomID()
+	}
+	ctx = withSession(ctx, session)
+	log := sessionLog(session)
+	progress := func(format string, args ...any) {
+		if onAction != nil {
+			onAction(FileAction{Action: "progress", Message: fmt.Sprintf(format, args...)})
+		}
+	}
 
-	files, entries := collectAttachmentFiles(abs, 100, 1_000_000, 20_000, "")
-	prompt := fmt.Sprintf(`File tree:
+	// "@file path" directives restrict the attachments to those files and
+	// their direct dependencies.
+	userPrompt, selected := parseFileDirectives(userPrompt)
+	progress("📂 Collecting workspace files…")
+	var files []models.File
+	var entries []fileEntry
+	lim := cfg.contextLimits()
+	if len(selected) > 0 {
+		files, entries = collectSelectedFiles(cfg, abs, withPinnedRels(abs, expandWithDependencies(abs, selected)), lim.perFileLimit)
+	} else {
+		files, entries = collectAttachmentFiles(cfg, abs, lim.maxFiles, lim.maxTotalBytes, lim.perFileLimit, cfg.contextLanguage())
+	}
+	prompt := conventionsBlock(abs) + fmt.Sprintf(`File tree:
 `+"```\n%s\n```"+`
 
 My task:
-%s
+%s`, buildTree(entries), userPrompt)
+	if !opts.TaskOnly {
+		prompt += "\n\nAfter generating the code, also generate a docker-compose.yml file to run the application."
+	}
 
-After generating the code, also generate a docker-compose.yml file to run the application.`, buildTree(entries), userPrompt)
+	pins := pinnedSet(abs)
+	files, dropped := pruneAttachments(prompt, files, cfg.MaxRequestBytes, pins)
+	if len(dropped) > 0 {
+		log.Warn("attachments dropped to fit request limit", "dropped", len(dropped), "limit", cfg.MaxRequestBytes)
+	}
 
-	session := randomID()
-	res, err := ag.GenerateWithFiles(ctx, session, prompt, files)
+	log.Info("generation started", "workspace", abs, "files", len(files))
+	progress("%s", cfg.requestPreview(prompt, files))
+	var onChunk func(string)
+	if onAction != nil {
+		onChunk = func(c string) { onAction(FileAction{Action: "chunk", Message: c}) }
+	}
+	res, err := generate(ctx, ag, session, prompt, files, onChunk)
+	if isRequestTooLarge(err) && len(files) > 0 {
+		// The provider's limit is tighter than ours; halve the request and retry once.
+		var more []string
+		files, more = pruneAttachments(prompt, files, requestSize(prompt, files)/2, pins)
+		dropped = append(dropped, more...)
+		log.Warn("request too large, retrying with fewer attachments", "dropped", len(more), "err", err)
+		progress("📤 Request too large, retrying with %d file(s)…", len(files))
+		res, err = generate(ctx, ag, session, prompt, files, onChunk)
+	}
 	if err != nil {
+		log.Error("generation failed", "err", err)
 		return nil, fmt.Errorf("generation failed: %w", err)
 	}
 
-	actions, _ := WriteCodeBlocks(abs, res)
+	progress("✍️ Writing results…")
+	actions, _ := WriteCodeBlocks(abs, res, WriteOptions{Config: cfg, OnAction: onAction})
+	if cfg.Format {
+		actions = append(actions, FormatFiles(ctx, abs, writtenPaths(actions))...)
+	}
+	if cfg.Tidy && touchesGo(actions) {
+		actions = append(actions, TidyModule(ctx, abs)...)
+	}
+	if len(writtenPaths(actions)) > 0 {
+		if a, ok := ensureRunScript(cfg, abs); ok {
+			actions = append(actions, a)
+		}
+	}
+	if len(dropped) > 0 {
+		note := FileAction{Action: "info", Message: fmt.Sprintf("Left %d file(s) out of the request to fit the size limit: %s", len(dropped), strings.Join(dropped, ", "))}
+		actions = append([]FileAction{note}, actions...)
+	}
+	for _, a := range actions {
+		switch a.Action {
+		case "error":
+			log.Error("write failed", "path", a.Path, "err", a.Message)
+		case "info":
+			log.Info(a.Message)
+		default:
+			log.Debug("file "+a.Action, "path", a.Path, "status", a.Message)
+		}
+	}
+	log.Info("generation finished", "actions", len(actions), "response_bytes", len(res))
 
-	return &HeadlessResult{Response: res, Actions: actions}, nil
+	return &HeadlessResult{Response: res, Actions: actions, Usage: estimateUsage(prompt, files, res), Dropped: dropped}, nil
 }
 
 func randomID() string {
@@ -0,0 +1,266 @@
+package src
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	agent "github.com/Protocol-Lattice/go-agent"
+	"github.com/Protocol-Lattice/go-agent/src/memory"
+	"github.com/Protocol-Lattice/go-agent/src/models"
+)
+
+// stubLLM records what it is asked and replies with a canned response.
+type stubLLM struct {
+	reply   string
+	replies []string // returned by successive calls before reply
+	errs    []error  // returned by successive calls before succeeding
+	calls   [][]models.File
+	prompts []string
+}
+
+func (s *stubLLM) Generate(ctx context.Context, prompt string) (any, error) {
+	return s.GenerateWithFiles(ctx, prompt, nil)
+}
+
+func (s *stubLLM) GenerateWithFiles(_ context.Context, prompt string, files []models.File) (any, error) {
+	s.calls = append(s.calls, files)
+	s.prompts = append(s.prompts, prompt)
+	if len(s.errs) > 0 {
+		err := s.errs[0]
+		s.errs = s.errs[1:]
+		return nil, err
+	}
+	if len(s.replies) > 0 {
+		r := s.replies[0]
+		s.replies = s.replies[1:]
+		return r, nil
+	}
+	return s.reply, nil
+}
+
+func newStubAgent(t *testing.T, llm *stubLLM) *agent.Agent {
+	t.Helper()
+	mem := memory.NewSessionMemory(memory.NewMemoryBankWithStore(memory.NewInMemoryStore()), 8)
+	ag, err := agent.New(agent.Options{Model: llm, Memory: mem})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ag
+}
+
+func TestPruneAttachmentsDropsLeastRelevantFirst(t *testing.T) {
+	files := []models.File{
+		{Name: "config/config.go", Data: make([]byte, 100)},
+		{Name: "docs/big.md", Data: make([]byte, 300)},
+		{Name: "util/small.go", Data: make([]byte, 50)},
+	}
+	kept, dropped := pruneAttachments("fix config.go loading", files, 250, nil)
+	if len(dropped) != 1 || dropped[0] != "docs/big.md" {
+		t.Fatalf("dropped = %v", dropped)
+	}
+	if len(kept) != 2 || kept[0].Name != "config/config.go" || kept[1].Name != "util/small.go" {
+		t.Errorf("kept = %v", kept)
+	}
+	if kept, dropped := pruneAttachments("x", files, 0, nil); len(kept) != 3 || dropped != nil {
+		t.Errorf("a zero limit should disable pruning")
+	}
+}
+
+func TestRunHeadlessPrunesOversizedAttachments(t *testing.T) {
+	GlobalChanges = NewChangeTracker()
+	cfg := DefaultConfig()
+	cfg.MaxRequestBytes = 6_000
+
+	root := t.TempDir()
+	writeFixture(t, root, map[string]string{
+		"main.go":     "package main\n",
+		"data/a.json": strings.Repeat(strings.Repeat("a", 89)+"\n", 100),
+	})
+	llm := &stubLLM{reply: "done"}
+
+	res, err := RunHeadless(context.Background(), newStubAgent(t, llm), root, "tweak main.go", HeadlessOptions{Config: cfg})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Dropped) != 1 || res.Dropped[0] != "data/a.json" {
+		t.Errorf("Dropped = %v", res.Dropped)
+	}
+	for _, f := range llm.calls[0] {
+		if f.Name == "data/a.json" {
+			t.Error("oversized attachment reached the model")
+		}
+	}
+	if res.Actions[0].Action != "info" || !strings.Contains(res.Actions[0].Message, "data/a.json") {
+		t.Errorf("expected a dropped-files note, got %+v", res.Actions[0])
+	}
+}
+
+func TestRunHeadlessRetriesWhenProviderRejectsSize(t *testing.T) {
+	GlobalChanges = NewChangeTracker()
+	root := t.TempDir()
+	writeFixture(t, root, map[string]string{"a.go": "package a\n", "b.go": "package b\n"})
+	llm := &stubLLM{reply: "done", errs: []error{errors.New("400: request payload size exceeds the limit")}}
+
+	res, err := RunHeadless(context.Background(), newStubAgent(t, llm), root, "edit a.go", HeadlessOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(llm.calls) != 2 || len(res.Dropped) == 0 {
+		t.Errorf("calls = %d, dropped = %v", len(llm.calls), res.Dropped)
+	}
+}
+
+func TestRunHeadlessReportsProgress(t *testing.T) {
+	GlobalChanges = NewChangeTracker()
+	root := t.TempDir()
+	writeFixture(t, root, map[string]string{"main.go": "package main\n"})
+	llm := &stubLLM{reply: "```go\n// path: util.go\npackage main\n\nfunc util() {}\n```\n"}
+
+	var events []string
+	_, err := RunHeadless(context.Background(), newStubAgent(t, llm), root, "add util.go", HeadlessOptions{OnAction: func(a FileAction) {
+		switch a.Action {
+		case "progress":
+			events = append(events, a.Message)
+		case "saved":
+			events = append(events, "saved "+a.Path)
+		}
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"Collecting workspace files", "Sending 1 file(s)", "Writing results", "saved util.go"}
+	if len(events) != len(want) {
+		t.Fatalf("events = %q, want phases %q", events, want)
+	}
+	for i, w := range want {
+		if !strings.Contains(events[i], w) {
+			t.Errorf("event %d = %q, want it to mention %q", i, events[i], w)
+		}
+	}
+}
+
+func TestRunHeadlessUsesLanguageOverride(t *testing.T) {
+	GlobalChanges = NewChangeTracker()
+	root := t.TempDir()
+	writeFixture(t, root, map[string]string{
+		"server/main.go": "package main\n",
+		"web/app.ts":     "export {}\n",
+		"tools/gen.py":   "print()\n",
+	})
+	cfg := DefaultConfig()
+	cfg.Lang = "TS"
+
+	prompt := "rewrite the python generator in golang"
+	if got := detectPromptLanguage(prompt); got == "ts" {
+		t.Fatalf("detectPromptLanguage(%q) = ts; the test needs a prompt that detects otherwise", prompt)
+	}
+	llm := &stubLLM{reply: "done"}
+	if _, err := RunHeadless(context.Background(), newStubAgent(t, llm), root, prompt, HeadlessOptions{Config: cfg}); err != nil {
+		t.Fatal(err)
+	}
+	if len(llm.calls[0]) == 0 {
+		t.Fatal("no files attached")
+	}
+	for _, f := range llm.calls[0] {
+		if f.Name != "web/app.ts" {
+			t.Errorf("attached %s; want only web/app.ts", f.Name)
+		}
+	}
+}
+
+func TestConventionsReachEveryPrompt(t *testing.T) {
+	GlobalChanges = NewChangeTracker()
+	root := t.TempDir()
+	rules := "Return errors; never panic in library code."
+	writeFixture(t, root, map[string]string{
+		"main.go":                          "package main\n",
+		latticeDir + "/" + conventionsFile: rules + "\n",
+	})
+
+	llm := &stubLLM{reply: "done"}
+	ag := newStubAgent(t, llm)
+	if _, err := RunHeadless(context.Background(), ag, root, "tweak main.go", HeadlessOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := RunExplain(context.Background(), ag, nil, "", root, "what does main do?", ""); err != nil {
+		t.Fatal(err)
+	}
+	for i, p := range llm.prompts {
+		if !strings.Contains(p, "PROJECT CONVENTIONS") || !strings.Contains(p, rules) {
+			t.Errorf("prompt %d lacks the conventions:\n%s", i, p)
+		}
+	}
+	if snapshot, _, _ := buildCodebaseContext(nil, root, 10, 1_000_000, 10_000, ""); !strings.HasPrefix(snapshot, "## PROJECT CONVENTIONS") {
+		t.Errorf("snapshot does not start with the conventions:\n%s", snapshot)
+	}
+
+	if got := conventionsBlock(t.TempDir()); got != "" {
+		t.Errorf("conventionsBlock without a file = %q", got)
+	}
+}
+
+func TestRunHeadlessPreviewsRequestBeforeSending(t *testing.T) {
+	GlobalChanges = NewChangeTracker()
+	root := t.TempDir()
+	writeFixture(t, root, map[string]string{"a.go": "package a\n", "b.go": "package a\n"})
+	cfg := DefaultConfig()
+
+	preview := func() string {
+		t.Helper()
+		llm := &stubLLM{reply: "done"}
+		var got string
+		_, err := RunHeadless(context.Background(), newStubAgent(t, llm), root, "tweak a.go", HeadlessOptions{Config: cfg, OnAction: func(a FileAction) {
+			if a.Action == "progress" && strings.HasPrefix(a.Message, "📤") {
+				if len(llm.calls) != 0 {
+					t.Error("preview reported after the model was called")
+				}
+				got = a.Message
+			}
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	cfg.ContextMaxFiles = 100
+	got := preview()
+	if !strings.Contains(got, "2 file(s)") || !strings.Contains(got, "tokens") || strings.Contains(got, "near the limits") {
+		t.Errorf("preview = %q; want files, size and tokens without a warning", got)
+	}
+	cfg.ContextMaxFiles = 2
+	if got := preview(); !strings.Contains(got, "near the limits: 2 of 2 files") {
+		t.Errorf("preview at the file cap = %q", got)
+	}
+}
+
+func TestRunHeadlessUsesCallerSession(t *testing.T) {
+	GlobalChanges = NewChangeTracker()
+	root := t.TempDir()
+	writeFixture(t, root, map[string]string{"main.go": "package main\n"})
+	llm := &stubLLM{reply: "done"}
+	ag := newStubAgent(t, llm)
+
+	for _, prompt := range []string{"rename the zebra handler", "now add tests"} {
+		if _, err := RunHeadless(context.Background(), ag, root, prompt, HeadlessOptions{Session: "s1"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !strings.Contains(llm.prompts[1], "zebra") {
+		t.Errorf("the second turn of the session lacks the first:\n%s", llm.prompts[1])
+	}
+	if _, err := RunHeadless(context.Background(), ag, root, "unrelated", HeadlessOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(llm.prompts[2], "zebra") {
+		t.Error("a run without a session should not see another session's turns")
+	}
+	logged := readFile(t, filepath.Join(root, latticeDir, "lattice.log"))
+	if !strings.Contains(logged, `"session":"s1"`) {
+		t.Errorf("log lacks the caller's session:\n%s", logged)
+	}
+}
@@ -0,0 +1,108 @@
+package src
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+const maxPromptHistory = 200
+
+// promptHistory is a shell-like list of submitted prompts. While browsing,
+// pos indexes entries; pos == len(entries) means the user is composing a new
+// prompt, kept in draft so browsing back down restores it.
+type promptHistory struct {
+	entries []string
+	pos     int
+	draft   string
+}
+
+func newPromptHistory(entries []string) promptHistory {
+	return promptHistory{entries: entries, pos: len(entries)}
+}
+
+// browsing reports whether an older entry is currently recalled.
+func (h *promptHistory) browsing() bool { return h.pos < len(h.entries) }
+
+// add records a submitted prompt and stops browsing. Repeating the most
+// recent entry does not add a duplicate.
+func (h *promptHistory) add(p string) {
+	if p != "" && (len(h.entries) == 0 || h.entries[len(h.entries)-1] != p) {
+		h.entries = append(h.entries, p)
+		if len(h.entries) > maxPromptHistory {
+			h.entries = h.entries[len(h.entries)-maxPromptHistory:]
+		}
+	}
+	h.pos, h.draft = len(h.entries), ""
+}
+
+// prev steps to the next older entry. current is saved as the draft when
+// browsing starts.
+func (h *promptHistory) prev(current string) (string, bool) {
+	if h.pos == 0 || len(h.entries) == 0 {
+		return "", false
+	}
+	if !h.browsing() {
+		h.draft = current
+	}
+	h.pos--
+	return h.entries[h.pos], true
+}
+
+// next steps to the next newer entry, ending at the saved draft.
+func (h *promptHistory) next() (string, bool) {
+	if !h.browsing() {
+		return "", false
+	}
+	h.pos++
+	if h.pos == len(h.entries) {
+		return h.draft, true
+	}
+	return h.entries[h.pos], true
+}
+
+// promptHistoryPath keeps history with the workspace, so it survives restarts
+// even though session IDs are random per run.
+func promptHistoryPath(workspace string) string {
+	return filepath.Join(workspace, latticeDir, "history.json")
+}
+
+// loadPromptHistory reads the history file; a missing or invalid file yields an empty history.
+func loadPromptHistory(path string) promptHistory {
+	var entries []string
+	if data, err := os.ReadFile(path); err == nil {
+		_ = json.Unmarshal(data, &entries)
+	}
+	return newPromptHistory(entries)
+}
+
+func (h *promptHistory) save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(h.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// recallPrompt handles up/down in the chat input. It only takes over when the
+// cursor is on the first line (up) or last line (down), so moving within a
+// multi-line prompt still works.
+func (m *model) recallPrompt(up bool) bool {
+	var (
+		text string
+		ok   bool
+	)
+	switch {
+	case up && m.textarea.Line() == 0:
+		text, ok = m.prompts.prev(m.textarea.Value())
+	case !up && m.textarea.Line() == m.textarea.LineCount()-1:
+		text, ok = m.prompts.next()
+	}
+	if ok {
+		m.textarea.SetValue(text)
+	}
+	return ok
+}
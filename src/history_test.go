@@ -0,0 +1,61 @@
+package src
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPromptHistoryNavigation(t *testing.T) {
+	h := newPromptHistory(nil)
+	if _, ok := h.prev("draft"); ok {
+		t.Fatal("prev on empty history should do nothing")
+	}
+	h.add("first")
+	h.add("second")
+	h.add("second") // consecutive duplicate
+	h.add("")
+
+	steps := []struct {
+		up   bool
+		want string
+		ok   bool
+	}{
+		{true, "second", true},
+		{true, "first", true},
+		{true, "", false}, // already at the oldest
+		{false, "second", true},
+		{false, "half-typed", true}, // back to the draft
+		{false, "", false},
+	}
+	for i, s := range steps {
+		var got string
+		var ok bool
+		if s.up {
+			got, ok = h.prev("half-typed")
+		} else {
+			got, ok = h.next()
+		}
+		if got != s.want || ok != s.ok {
+			t.Fatalf("step %d: got (%q, %v), want (%q, %v)", i, got, ok, s.want, s.ok)
+		}
+	}
+}
+
+func TestPromptHistoryPersists(t *testing.T) {
+	path := promptHistoryPath(t.TempDir())
+	h := loadPromptHistory(path)
+	for i := 0; i < maxPromptHistory+5; i++ {
+		h.add(fmt.Sprintf("prompt %d", i))
+	}
+	h.add("last one")
+	if err := h.save(path); err != nil {
+		t.Fatal(err)
+	}
+	got := loadPromptHistory(path)
+	if len(got.entries) != maxPromptHistory {
+		t.Fatalf("got %d entries, want %d", len(got.entries), maxPromptHistory)
+	}
+	if p, _ := got.prev(""); p != "last one" {
+		t.Fatalf("newest entry = %q", p)
+	}
+}
@@ -0,0 +1,74 @@
+package src
+
+import "strings"
+
+// languages is the one table behind fenceLangFromExt, which tags files in
+// the context snapshot, and extFromLang, which names files written from a
+// tagged code block, so the two directions cannot drift apart. tag is the
+// fence tag emitted for the language and ext its canonical extension;
+// aliases are other tags accepted for it and exts other extensions tagged
+// with it.
+var languages = []struct {
+	tag, ext      string
+	aliases, exts []string
+}{
+	{tag: "go", ext: ".go", aliases: []string{"golang"}},
+	{tag: "python", ext: ".py", aliases: []string{"py"}, exts: []string{".pyi"}},
+	{tag: "javascript", ext: ".js", aliases: []string{"js", "node"}, exts: []string{".mjs", ".cjs"}},
+	{tag: "ts", ext: ".ts", aliases: []string{"typescript"}, exts: []string{".mts", ".cts"}},
+	{tag: "tsx", ext: ".tsx"},
+	{tag: "jsx", ext: ".jsx"},
+	{tag: "rust", ext: ".rs", aliases: []string{"rs"}},
+	{tag: "ruby", ext: ".rb", aliases: []string{"rb"}},
+	{tag: "java", ext: ".java"},
+	{tag: "kotlin", ext: ".kt", aliases: []string{"kt"}, exts: []string{".kts"}},
+	{tag: "scala", ext: ".scala"},
+	{tag: "c", ext: ".c", exts: []string{".h"}},
+	{tag: "cpp", ext: ".cpp", aliases: []string{"c++", "cc", "cxx"}, exts: []string{".cc", ".cxx", ".hpp", ".hh", ".hxx"}},
+	{tag: "csharp", ext: ".cs", aliases: []string{"c#", "cs"}},
+	{tag: "swift", ext: ".swift"},
+	{tag: "php", ext: ".php"},
+	{tag: "dart", ext: ".dart"},
+	{tag: "lua", ext: ".lua"},
+	{tag: "r", ext: ".r"},
+	{tag: "bash", ext: ".sh", aliases: []string{"shell", "sh", "zsh"}, exts: []string{".bash", ".zsh"}},
+	{tag: "sql", ext: ".sql"},
+	{tag: "html", ext: ".html", exts: []string{".htm"}},
+	{tag: "xml", ext: ".xml"},
+	{tag: "svg", ext: ".svg"},
+	{tag: "css", ext: ".css"},
+	{tag: "scss", ext: ".scss", aliases: []string{"sass"}, exts: []string{".sass"}},
+	{tag: "less", ext: ".less"},
+	{tag: "json", ext: ".json", exts: []string{".jsonc"}},
+	{tag: "yaml", ext: ".yaml", aliases: []string{"yml"}, exts: []string{".yml"}},
+	{tag: "toml", ext: ".toml"},
+	{tag: "ini", ext: ".ini", exts: []string{".cfg", ".conf"}},
+	{tag: "md", ext: ".md", aliases: []string{"markdown"}, exts: []string{".markdown"}},
+	{tag: "proto", ext: ".proto", aliases: []string{"protobuf"}},
+	{tag: "graphql", ext: ".graphql", aliases: []string{"gql"}, exts: []string{".gql"}},
+	{tag: "hcl", ext: ".tf", aliases: []string{"terraform"}, exts: []string{".hcl"}},
+	{tag: "dockerfile", ext: ".dockerfile", aliases: []string{"docker"}},
+	{tag: "makefile", ext: "Makefile", aliases: []string{"make"}, exts: []string{".mk"}},
+	{tag: "diff", ext: ".diff", aliases: []string{"patch"}, exts: []string{".patch"}},
+}
+
+// langByExt and extByLang index languages by lowercased extension (without
+// the dot) and by tag or alias.
+var langByExt, extByLang = indexLanguages()
+
+func indexLanguages() (map[string]string, map[string]string) {
+	byExt, byLang := map[string]string{}, map[string]string{}
+	for _, l := range languages {
+		if strings.HasPrefix(l.ext, ".") {
+			byExt[l.ext[1:]] = l.tag
+		}
+		for _, e := range l.exts {
+			byExt[e[1:]] = l.tag
+		}
+		byLang[l.tag] = l.ext
+		for _, a := range l.aliases {
+			byLang[a] = l.ext
+		}
+	}
+	return byExt, byLang
+}
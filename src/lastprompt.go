@@ -0,0 +1,96 @@
+package src
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Protocol-Lattice/go-agent/src/models"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// sentPrompt is one request as the model received it: the prompt go-agent
+// assembled from the system prompt, session memory and the user's goal, and
+// the files attached to it.
+type sentPrompt struct {
+	at     time.Time
+	prompt string
+	files  []models.File
+}
+
+// lastPrompt holds the most recent request any agent built by BuildAgent
+// sent, for /lastprompt.
+var lastPrompt promptLog
+
+type promptLog struct {
+	mu   sync.Mutex
+	last *sentPrompt
+}
+
+func (l *promptLog) record(prompt string, files []models.File) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.last = &sentPrompt{at: time.Now(), prompt: prompt, files: files}
+}
+
+// get returns the last request, or nil before the first one.
+func (l *promptLog) get() *sentPrompt {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.last
+}
+
+// recordingModel passes calls through to the model go-agent drives, noting
+// each request in lastPrompt first. It sits below the agent so what it sees
+// is exactly what the provider is sent.
+type recordingModel struct {
+	models.Agent
+}
+
+func (r recordingModel) Generate(ctx context.Context, prompt string) (any, error) {
+	lastPrompt.record(prompt, nil)
+	return r.Agent.Generate(ctx, prompt)
+}
+
+func (r recordingModel) GenerateWithFiles(ctx context.Context, prompt string, files []models.File) (any, error) {
+	lastPrompt.record(prompt, files)
+	return r.Agent.GenerateWithFiles(ctx, prompt, files)
+}
+
+// lastPromptSlashCommand handles "/lastprompt": it shows the full prompt of
+// the most recent model request and lists the files sent with it.
+func lastPromptSlashCommand(m *model, _ string) (*model, tea.Cmd) {
+	p := lastPrompt.get()
+	if p == nil {
+		m.output += m.style.Subtle.Render("ℹ️ no prompt has been sent yet\n")
+		m.renderOutput(true)
+		return m, nil
+	}
+	var b strings.Builder
+	b.WriteString(m.style.Accent.Render(fmt.Sprintf("📨 Last prompt, sent %s (%s, %d file(s)):\n", p.at.Format("15:04:05"), HumanSize(requestSize(p.prompt, p.files)), len(p.files))))
+	fence := longerFence(p.prompt)
+	b.WriteString(fence + "text\n" + strings.TrimRight(p.prompt, "\n") + "\n" + fence + "\n")
+	for _, f := range p.files {
+		b.WriteString(m.style.Subtle.Render(fmt.Sprintf("📎 %s (%s)\n", f.Name, HumanSize(int64(len(f.Data))))))
+	}
+	m.output += b.String()
+	m.renderOutput(true)
+	return m, nil
+}
+
+// longerFence returns a run of backticks longer than any in s, and at least
+// three, so a fence made of it is not closed by fences inside s.
+func longerFence(s string) string {
+	longest, run := 0, 0
+	for _, r := range s {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
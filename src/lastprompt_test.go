@@ -0,0 +1,66 @@
+package src
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	agent "github.com/Protocol-Lattice/go-agent"
+	"github.com/Protocol-Lattice/go-agent/src/memory"
+
+	"github.com/Protocol-Lattice/lattice-code/src/ui"
+)
+
+func TestLastPromptMatchesWhatWasSent(t *testing.T) {
+	GlobalChanges = NewChangeTracker()
+	lastPrompt = promptLog{}
+	root := t.TempDir()
+	writeFixture(t, root, map[string]string{"main.go": "package main\n"})
+	llm := &stubLLM{reply: "done"}
+	mem := memory.NewSessionMemory(memory.NewMemoryBankWithStore(memory.NewInMemoryStore()), 8)
+	ag, err := agent.New(agent.Options{Model: recordingModel{llm}, Memory: mem, SystemPrompt: "SYSTEM RULES"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewModel(context.Background(), ag, root, nil)
+	m.handleSlashCommand("/lastprompt")
+	if !strings.Contains(m.output, "no prompt has been sent yet") {
+		t.Errorf("output before any request = %q", m.output)
+	}
+
+	if _, err := RunHeadless(context.Background(), ag, root, "tweak main.go", HeadlessOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	p := lastPrompt.get()
+	if p == nil || p.prompt != llm.prompts[len(llm.prompts)-1] {
+		t.Fatalf("captured %+v; want the prompt the model received", p)
+	}
+	if len(p.files) != len(llm.calls[len(llm.calls)-1]) {
+		t.Errorf("captured %d files; the model received %d", len(p.files), len(llm.calls[len(llm.calls)-1]))
+	}
+	for _, want := range []string{"SYSTEM RULES", "tweak main.go"} {
+		if !strings.Contains(p.prompt, want) {
+			t.Errorf("captured prompt lacks %q", want)
+		}
+	}
+
+	m.output = ""
+	m.handleSlashCommand("/lastprompt")
+	if !strings.Contains(m.output, p.prompt) || !strings.Contains(m.output, "📎 main.go") {
+		t.Errorf("/lastprompt output = %q", m.output)
+	}
+}
+
+func TestLastPromptFenceHoldsPromptFences(t *testing.T) {
+	lastPrompt = promptLog{}
+	lastPrompt.record("Fix this:\n```go\nfunc main() {}\n```\nand keep ```` as is", nil)
+	m := NewModel(context.Background(), nil, t.TempDir(), nil)
+	m.handleSlashCommand("/lastprompt")
+	if !strings.Contains(m.output, "`````text\nFix this:") || !strings.HasSuffix(strings.TrimRight(m.output, "\n"), "as is\n`````") {
+		t.Errorf("/lastprompt output = %q", m.output)
+	}
+	if blocks := ui.IndexBlocks(ui.FormatOutput(m.output, 0)); len(blocks) != 1 {
+		t.Errorf("blocks = %+v; want the prompt as one block", blocks)
+	}
+}
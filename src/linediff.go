@@ -0,0 +1,154 @@
+package src
+
+// diffLines returns a shortest edit script turning a into b. It uses Myers'
+// divide-and-conquer ("middle snake") algorithm, which needs space linear in
+// the input rather than the len(a)*len(b) table of a plain LCS, so diffs of
+// large generated files stay cheap. Within each run of changes deletions
+// come before insertions.
+func diffLines(a, b []string) []edit {
+	var seq []edit
+	diffInto(&seq, a, b)
+
+	// Order each run of changes deletions first, as the hunks always read.
+	for i := 0; i < len(seq); {
+		if seq[i].tag == " " {
+			i++
+			continue
+		}
+		j := i
+		for j < len(seq) && seq[j].tag != " " {
+			j++
+		}
+		var dels, adds []edit
+		for _, e := range seq[i:j] {
+			if e.tag == "-" {
+				dels = append(dels, e)
+			} else {
+				adds = append(adds, e)
+			}
+		}
+		copy(seq[i:], dels)
+		copy(seq[i+len(dels):], adds)
+		i = j
+	}
+	return seq
+}
+
+func diffInto(seq *[]edit, a, b []string) {
+	// Common prefix and suffix need no search.
+	p := 0
+	for p < len(a) && p < len(b) && a[p] == b[p] {
+		p++
+	}
+	for _, l := range a[:p] {
+		*seq = append(*seq, edit{" ", l})
+	}
+	a, b = a[p:], b[p:]
+	s := 0
+	for s < len(a) && s < len(b) && a[len(a)-1-s] == b[len(b)-1-s] {
+		s++
+	}
+	suffix := a[len(a)-s:]
+	a, b = a[:len(a)-s], b[:len(b)-s]
+
+	switch {
+	case len(a) == 0:
+		for _, l := range b {
+			*seq = append(*seq, edit{"+", l})
+		}
+	case len(b) == 0:
+		for _, l := range a {
+			*seq = append(*seq, edit{"-", l})
+		}
+	default:
+		if x, y, ok := middleSnake(a, b); ok {
+			diffInto(seq, a[:x], b[:y])
+			diffInto(seq, a[x:], b[y:])
+		} else {
+			for _, l := range a {
+				*seq = append(*seq, edit{"-", l})
+			}
+			for _, l := range b {
+				*seq = append(*seq, edit{"+", l})
+			}
+		}
+	}
+
+	for _, l := range suffix {
+		*seq = append(*seq, edit{" ", l})
+	}
+}
+
+// middleSnake searches for a shortest edit path from both ends at once and
+// returns a point (x, y) it passes through, splitting the problem in two.
+// ok is false when a and b have nothing in common.
+func middleSnake(a, b []string) (x, y int, ok bool) {
+	n, m := len(a), len(b)
+	maxD := (n + m + 1) / 2
+	off, size := maxD, 2*maxD+2
+	fwd, rev := make([]int, size), make([]int, size)
+	for i := range fwd {
+		fwd[i], rev[i] = -1, -1
+	}
+	fwd[off+1], rev[off+1] = 0, 0
+	delta := n - m
+	front := delta%2 != 0 // an odd delta means the paths meet going forward
+	var k1start, k1end, k2start, k2end int
+
+	for d := 0; d < maxD; d++ {
+		for k1 := -d + k1start; k1 <= d-k1end; k1 += 2 {
+			i := off + k1
+			var x1 int
+			if k1 == -d || (k1 != d && fwd[i-1] < fwd[i+1]) {
+				x1 = fwd[i+1]
+			} else {
+				x1 = fwd[i-1] + 1
+			}
+			y1 := x1 - k1
+			for x1 < n && y1 < m && a[x1] == b[y1] {
+				x1++
+				y1++
+			}
+			fwd[i] = x1
+			switch {
+			case x1 > n:
+				k1end += 2 // ran off the right
+			case y1 > m:
+				k1start += 2 // ran off the bottom
+			case front:
+				if j := off + delta - k1; j >= 0 && j < size && rev[j] != -1 && x1 >= n-rev[j] {
+					return x1, y1, true
+				}
+			}
+		}
+		for k2 := -d + k2start; k2 <= d-k2end; k2 += 2 {
+			i := off + k2
+			var x2 int
+			if k2 == -d || (k2 != d && rev[i-1] < rev[i+1]) {
+				x2 = rev[i+1]
+			} else {
+				x2 = rev[i-1] + 1
+			}
+			y2 := x2 - k2
+			for x2 < n && y2 < m && a[n-x2-1] == b[m-y2-1] {
+				x2++
+				y2++
+			}
+			rev[i] = x2
+			switch {
+			case x2 > n:
+				k2end += 2
+			case y2 > m:
+				k2start += 2
+			case !front:
+				if j := off + delta - k2; j >= 0 && j < size && fwd[j] != -1 {
+					x1 := fwd[j]
+					if x1 >= n-x2 {
+						return x1, off + x1 - j, true
+					}
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,147 @@
+package src
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// lcsEdits is the full-table LCS diff DiffPretty used before diffLines,
+// kept as the reference the hunks must match.
+func lcsEdits(a, b []string) []edit {
+	n, m := len(a), len(b)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	var seq []edit
+	i, j := 0, 0
+	for i < n && j < m {
+		if a[i] == b[j] {
+			seq = append(seq, edit{" ", a[i]})
+			i, j = i+1, j+1
+		} else if lcs[i+1][j] >= lcs[i][j+1] {
+			seq = append(seq, edit{"-", a[i]})
+			i++
+		} else {
+			seq = append(seq, edit{"+", b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		seq = append(seq, edit{"-", a[i]})
+	}
+	for ; j < m; j++ {
+		seq = append(seq, edit{"+", b[j]})
+	}
+	return seq
+}
+
+func TestDiffLinesMatchesLCS(t *testing.T) {
+	samples := []struct{ old, new string }{
+		{"a\nb\nc\n", "a\nB\nc\n"},
+		{"package main\n\nfunc main() {\n\tprintln(1)\n}\n", "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(1)\n\tfmt.Println(2)\n}\n"},
+		{"", "new\nfile\n"},
+		{"gone\n", ""},
+		{"1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n", "1\n2\nthree\n4\n5\n6\n7\n8\n9\nten\n11\n12\n13\n"},
+		{"x\ny\nz\n", "p\nq\n"},
+	}
+	for _, s := range samples {
+		a, b := splitLines([]byte(s.old)), splitLines([]byte(s.new))
+		if got, want := diffLines(a, b), lcsEdits(a, b); !reflect.DeepEqual(got, want) {
+			t.Errorf("%q -> %q:\n got %v\nwant %v", s.old, s.new, got, want)
+		}
+	}
+}
+
+func TestDiffLinesIsShortestScript(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	lines := func() []string {
+		out := make([]string, r.Intn(30))
+		for i := range out {
+			out[i] = string(rune('a' + r.Intn(4)))
+		}
+		return out
+	}
+	for n := 0; n < 500; n++ {
+		a, b := lines(), lines()
+		got := diffLines(a, b)
+		var from, to []string
+		changes := 0
+		for _, e := range got {
+			if e.tag != "+" {
+				from = append(from, e.txt)
+			}
+			if e.tag != "-" {
+				to = append(to, e.txt)
+			}
+			if e.tag != " " {
+				changes++
+			}
+		}
+		if strings.Join(from, ",") != strings.Join(a, ",") || strings.Join(to, ",") != strings.Join(b, ",") {
+			t.Fatalf("%v -> %v: script %v does not turn one into the other", a, b, got)
+		}
+		want := 0
+		for _, e := range lcsEdits(a, b) {
+			if e.tag != " " {
+				want++
+			}
+		}
+		if changes != want {
+			t.Fatalf("%v -> %v: %d changes, shortest has %d", a, b, changes, want)
+		}
+	}
+}
+
+// generatedFile is a large file like a generated client or fixture.
+func generatedFile(lines int, edit func(i int) string) []byte {
+	var b strings.Builder
+	for i := 0; i < lines; i++ {
+		if s := edit(i); s != "" {
+			b.WriteString(s)
+		} else {
+			fmt.Fprintf(&b, "\tField%d string `json:\"field_%d\"`\n", i, i)
+		}
+	}
+	return []byte(b.String())
+}
+
+func BenchmarkDiffPrettySmallEdit(b *testing.B) {
+	old := generatedFile(200, func(int) string { return "" })
+	edited := generatedFile(200, func(i int) string {
+		if i == 100 {
+			return "\tRenamed string\n"
+		}
+		return ""
+	})
+	for b.Loop() {
+		GlobalChanges.DiffPretty("small.go", old, edited, 0)
+	}
+}
+
+func BenchmarkDiffPrettyLargeFile(b *testing.B) {
+	old := generatedFile(30_000, func(int) string { return "" })
+	edited := generatedFile(30_000, func(i int) string {
+		if i%1000 == 0 {
+			return fmt.Sprintf("\t// regenerated %d\n", i)
+		}
+		return ""
+	})
+	for b.Loop() {
+		GlobalChanges.DiffPretty("large.go", old, edited, 0)
+	}
+}
@@ -0,0 +1,59 @@
+package src
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+
+	"github.com/Protocol-Lattice/lattice-code/src/logging"
+)
+
+var (
+	logMu     sync.Mutex
+	logger    = logging.Discard()
+	logCloser io.Closer
+	logDir    string
+)
+
+// useLogWorkspace points the shared logger at <dir>/.lattice/lattice.log,
+// logging at level (-log-level).
+func useLogWorkspace(dir, level string) {
+	logMu.Lock()
+	defer logMu.Unlock()
+	if dir == logDir {
+		return
+	}
+	l, closer, err := logging.Open(dir, level)
+	if err != nil {
+		return
+	}
+	if logCloser != nil {
+		_ = logCloser.Close()
+	}
+	logger, logCloser, logDir = l, closer, dir
+}
+
+type sessionKey struct{}
+
+// withSession returns ctx carrying session, so code below the agent, such
+// as the model fallback chain, can log under the session that made the call.
+func withSession(ctx context.Context, session string) context.Context {
+	return context.WithValue(ctx, sessionKey{}, session)
+}
+
+// contextSession returns the session withSession put in ctx, or "".
+func contextSession(ctx context.Context) string {
+	s, _ := ctx.Value(sessionKey{}).(string)
+	return s
+}
+
+// sessionLog returns the shared logger, tagged with the session ID when one is given.
+func sessionLog(session string) *slog.Logger {
+	logMu.Lock()
+	defer logMu.Unlock()
+	if session == "" {
+		return logger
+	}
+	return logger.With("session", session)
+}
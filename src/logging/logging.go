@@ -0,0 +1,59 @@
+// Package logging provides the leveled JSON-lines logger shared by the TUI
+// and the MCP servers. Entries are appended to <workspace>/.lattice/lattice.log.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileName is the log file created inside the workspace's .lattice directory.
+const FileName = "lattice.log"
+
+// ParseLevel maps "debug", "info", "warn" or "error" to a slog level.
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return slog.LevelInfo, fmt.Errorf("unknown log level %q", s)
+	}
+}
+
+// New returns a JSON logger writing entries at or above level to w.
+func New(w io.Writer, level slog.Level) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level}))
+}
+
+// Discard returns a logger that drops every entry.
+func Discard() *slog.Logger {
+	return New(io.Discard, slog.LevelError)
+}
+
+// Open returns a logger appending to dir/.lattice/lattice.log at the given level.
+// The returned closer must be closed when the logger is no longer used.
+func Open(dir, level string) (*slog.Logger, io.Closer, error) {
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return nil, nil, err
+	}
+	logDir := filepath.Join(dir, ".lattice")
+	if err := os.MkdirAll(logDir, 0o755); err != nil {
+		return nil, nil, err
+	}
+	f, err := os.OpenFile(filepath.Join(logDir, FileName), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, nil, err
+	}
+	return New(f, lvl), f, nil
+}
@@ -0,0 +1,60 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewFiltersByLevel(t *testing.T) {
+	var buf bytes.Buffer
+	lvl, err := ParseLevel("warn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := New(&buf, lvl).With("session", "abc123")
+
+	log.Debug("dropped")
+	log.Info("dropped too")
+	log.Warn("kept", "step", 2)
+	log.Error("kept too")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d entries; want 2:\n%s", len(lines), buf.String())
+	}
+	var entry map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry["level"] != "WARN" || entry["msg"] != "kept" || entry["session"] != "abc123" {
+		t.Errorf("unexpected entry: %v", entry)
+	}
+}
+
+func TestParseLevelRejectsUnknown(t *testing.T) {
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+}
+
+func TestOpenAppendsToWorkspaceLog(t *testing.T) {
+	dir := t.TempDir()
+	log, closer, err := Open(dir, "debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.Debug("hello")
+	closer.Close()
+
+	data, err := os.ReadFile(filepath.Join(dir, ".lattice", FileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"msg":"hello"`) {
+		t.Errorf("log file missing entry: %s", data)
+	}
+}
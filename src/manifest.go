@@ -0,0 +1,75 @@
+package src
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// latticeDir holds per-workspace artifacts (manifest, logs, backups).
+const latticeDir = ".lattice"
+
+func manifestPath(root string) string {
+	return filepath.Join(root, latticeDir, "manifest.json")
+}
+
+// loadManifest returns the set of workspace-relative paths written by the agent.
+func loadManifest(root string) map[string]bool {
+	files := map[string]bool{}
+	data, err := os.ReadFile(manifestPath(root))
+	if err != nil {
+		return files
+	}
+	var m struct {
+		Files []string `json:"files"`
+	}
+	if json.Unmarshal(data, &m) == nil {
+		for _, f := range m.Files {
+			files[filepath.ToSlash(f)] = true
+		}
+	}
+	return files
+}
+
+// recordManaged adds paths to the workspace manifest.
+func recordManaged(root string, paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	files := loadManifest(root)
+	for _, p := range paths {
+		files[filepath.ToSlash(p)] = true
+	}
+	return saveManifest(root, files)
+}
+
+// forgetManaged removes paths from the workspace manifest, so files later
+// created there by the user are not taken for the agent's.
+func forgetManaged(root string, paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	files := loadManifest(root)
+	for _, p := range paths {
+		delete(files, filepath.ToSlash(p))
+	}
+	return saveManifest(root, files)
+}
+
+func saveManifest(root string, files map[string]bool) error {
+	list := make([]string, 0, len(files))
+	for f := range files {
+		list = append(list, f)
+	}
+	sort.Strings(list)
+
+	data, err := json.MarshalIndent(map[string][]string{"files": list}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Join(root, latticeDir), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(manifestPath(root), data, 0o644)
+}
@@ -0,0 +1,63 @@
+package src
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Protocol-Lattice/go-agent/src/models"
+)
+
+const mockPlan = `[{"name":"Step 1: Create entrypoint","goal":"Create main.go with a main function that prints a greeting."},` +
+	`{"name":"Step 2: Add a helper","goal":"Create greet.go with a Greet(name string) string helper and use it from main."}]`
+
+const mockGoMain = "```go\n// path: main.go\npackage main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(Greet(\"lattice\"))\n}\n```\n"
+
+const mockGoGreet = "```go\n// path: greet.go\npackage main\n\n// Greet returns a greeting for name.\nfunc Greet(name string) string {\n\treturn \"Hello, \" + name + \"!\"\n}\n```\n"
+
+const mockPython = "```python\n# path: app.py\ndef greet(name):\n    return f\"Hello, {name}!\"\n\n\nif __name__ == \"__main__\":\n    print(greet(\"lattice\"))\n```\n"
+
+// mockLLM is an offline models.Agent that picks a canned response by simple
+// prompt matching, so planning, writing and diffing can run without an LLM.
+type mockLLM struct{}
+
+func (mockLLM) Generate(ctx context.Context, prompt string) (any, error) {
+	return mockReply(prompt), nil
+}
+
+func (mockLLM) GenerateWithFiles(ctx context.Context, prompt string, _ []models.File) (any, error) {
+	return mockReply(prompt), nil
+}
+
+// mockReply answers the most recent request in prompt. The agent wraps
+// prompts with its system prompt and session history, so the marker that
+// appears last decides which kind of request this is.
+func mockReply(prompt string) string {
+	kind, at := "", -1
+	for _, marker := range []string{"Break the goal into", "could not be parsed as a plan", "You are explaining existing code", "My task:"} {
+		if i := strings.LastIndex(prompt, marker); i > at {
+			kind, at = marker, i
+		}
+	}
+	switch kind {
+	case "Break the goal into", "could not be parsed as a plan":
+		return mockPlan
+	case "You are explaining existing code":
+		return "This is the offline mock model. The workspace's entrypoint prints a greeting built by a small helper."
+	}
+
+	task := strings.ToLower(prompt)
+	if at >= 0 {
+		task = strings.ToLower(prompt[at:])
+		if i := strings.Index(task, "after generating the code"); i >= 0 {
+			task = task[:i]
+		}
+	}
+	switch {
+	case strings.Contains(task, "python"):
+		return "I will add a small Python script.\n\n" + mockPython
+	case strings.Contains(task, "helper"):
+		return "I will add the greeting helper.\n\n" + mockGoGreet
+	default:
+		return "I will create the entrypoint.\n\n" + mockGoMain + "\n" + mockGoGreet
+	}
+}
@@ -0,0 +1,69 @@
+package src
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	agent "github.com/Protocol-Lattice/go-agent"
+	"github.com/Protocol-Lattice/go-agent/src/memory"
+)
+
+func newMockAgent(t *testing.T) *agent.Agent {
+	t.Helper()
+	mem := memory.NewSessionMemory(memory.NewMemoryBankWithStore(memory.NewInMemoryStore()), 8)
+	ag, err := agent.New(agent.Options{Model: mockLLM{}, Memory: mem, SystemPrompt: VibeSystemPrompt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ag
+}
+
+func TestMockModelDrivesHeadlessRun(t *testing.T) {
+	GlobalChanges = NewChangeTracker()
+	root := t.TempDir()
+	writeFixture(t, root, map[string]string{"go.mod": "module example.com/demo\n\ngo 1.21\n"})
+	ag := newMockAgent(t)
+
+	steps, err := requestPlan(context.Background(), ag, "demo", "Break the goal into steps.\n\nUser goal:\ngreeter", &usageStats{}, nil)
+	if err != nil || len(steps) != 2 {
+		t.Fatalf("mock plan = %+v, err = %v", steps, err)
+	}
+
+	res, err := RunHeadless(context.Background(), ag, root, steps[0].Goal, HeadlessOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := map[string]bool{}
+	for _, a := range res.Actions {
+		if a.Action == "saved" && a.Diff != "" {
+			saved[a.Path] = true
+		}
+	}
+	if !saved["main.go"] || !saved["greet.go"] {
+		t.Fatalf("expected main.go and greet.go with diffs, got %+v", res.Actions)
+	}
+	if _, err := exec.LookPath("go"); err == nil {
+		if diag := goCheck(context.Background(), root); diag != "" {
+			t.Errorf("mock output should build cleanly:\n%s", diag)
+		}
+	}
+
+	res, err = RunHeadless(context.Background(), ag, root, "write a python script", HeadlessOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "app.py")); err != nil {
+		t.Errorf("python task should write app.py: %v (actions %+v)", err, res.Actions)
+	}
+}
+
+func TestMockReplyUsesLatestRequest(t *testing.T) {
+	// History from an earlier planning turn must not hijack a codegen request.
+	prompt := "Break the goal into 2–4 steps\n...\nMy task:\nCreate greet.go with a Greet helper\n\nAfter generating the code, also generate a docker-compose.yml"
+	if got := mockReply(prompt); got != "I will add the greeting helper.\n\n"+mockGoGreet {
+		t.Errorf("mockReply picked the wrong response:\n%s", got)
+	}
+}
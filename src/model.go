@@ -2,10 +2,10 @@ package src
 
 import (
 	"context"
-	"crypto/rand"
-	"encoding/hex"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -40,10 +40,20 @@ func (p plugin) FilterValue() string { return p.name }
 type dirItem struct {
 	name string
 	path string
+
+	// Optional metadata shown when dirOptions.details is enabled.
+	entries int
+	modTime time.Time
+	details bool
 }
 
-func (d dirItem) Title() string       { return d.name }
-func (d dirItem) Description() string { return d.path }
+func (d dirItem) Title() string { return d.name }
+func (d dirItem) Description() string {
+	if !d.details || d.modTime.IsZero() {
+		return d.path
+	}
+	return fmt.Sprintf("%s · %d entries · modified %s", d.path, d.entries, d.modTime.Format("2006-01-02 15:04"))
+}
 func (d dirItem) FilterValue() string { return d.name }
 
 type utcpItem struct {
@@ -51,8 +61,13 @@ type utcpItem struct {
 	stream               bool
 }
 
-func (u utcpItem) Title() string       { return u.name }
-func (u utcpItem) Description() string { return fmt.Sprintf("[%s] %s", u.provider, u.desc) }
+func (u utcpItem) Title() string { return u.name }
+func (u utcpItem) Description() string {
+	if u.provider == "" {
+		return u.desc
+	}
+	return fmt.Sprintf("[%s] %s", u.provider, u.desc)
+}
 func (u utcpItem) FilterValue() string { return u.name }
 
 type generateMsg struct {
@@ -74,12 +89,15 @@ type stepBuildCompleteMsg struct {
 type model struct {
 	ctx        context.Context
 	agent      *agent.Agent
+	agentDir   string  // the directory agent's system prompt was taken for
+	cfg        *Config // session settings; /config edits them
 	working    string
 	history    []string
 	mode       ui.Mode
 	prevMode   ui.Mode
 	selected   plugin
-	isThinking bool
+	isThinking bool // the spinner runs; true while inFlight > 0
+	inFlight   int  // operations started with startWork and not yet ended
 	list       list.Model
 	dirlist    list.Model
 	textarea   textarea.Model
@@ -92,6 +110,7 @@ type model struct {
 	style      ui.Styles
 
 	Program *tea.Program
+	Memory  MemoryRetriever // memory backing /search; NewModel uses the agent's
 	mu      sync.Mutex
 	// Context snapshot stats (set on each run)
 	contextFiles int
@@ -100,17 +119,68 @@ type model struct {
 	sessionID         string
 	sharedSpaces      []string
 	transcriptPath    string
-	lastTranscriptSig string
+	lastTranscriptSig string // hash of output as last written to or read from the transcript
+	trimmedBytes      int64  // output trimmed off the front; the transcript keeps it ahead of output
+	transcriptBase    int64  // where this session starts in the transcript, after earlier sessions
 	syncInterval      time.Duration
 	lockDir           string
 	plannerQueue      chan string // new: queued logs for planner output
 
+	dirPreviews map[string]ui.DirPreview // lazily computed ModeDir previews, keyed by path
+	dirOpts     dirOptions
+	recents     recentDirs
+	recentsPath string
+	settings    settings // persisted /config values
+	configPath  string
+	prompts     promptHistory     // submitted chat prompts, recalled with up/down
+	completeIdx int               // selected slash-command completion
+	lastResult  string            // text of the last generateMsg, for /save
+	baseline    workspaceSnapshot // workspace at session start, for /diff outside git
+	baseRev     string            // HEAD at session start, for /diff in git
+	streamed    string            // response received so far for the run in flight; not persisted
+
+	pendingWrites []FileAction // overwrites awaiting confirmation in ModeConfirm
+	usage         usageStats   // estimated token usage for the session
+
+	codeMode       *CodeModeRefactor // built on first use of the codemode agent
+	pendingBatch   *BatchPlan        // batch refactor awaiting confirmation in ModeConfirm
+	pendingDiscard *discardPlan      // /discard awaiting confirmation in ModeConfirm
+	runTarget      string            // /target command the planner verifies steps with
+	events         *EventStream      // headless progress events; nil in the TUI
+
+	showFiles    bool               // file-tree sidebar toggled with ctrl+b
+	fileTree     []ui.TreeEntry     // sidebar contents, rebuilt by refreshFileTree
+	contextPaths map[string]bool    // slash paths in the last context snapshot
+	showActivity bool               // render the activity panel (NewAgenticModel)
+	activity     []ui.ActivityEntry // timeline of actions and thoughts, oldest first
+
+	scrollLocked bool       // user scrolled away from the bottom; renderOutput stops following
+	unseenOutput bool       // output arrived while scrollLocked
+	blocks       []ui.Block // code blocks and diffs of the rendered output, for ]/[
+	blockLabel   string     // block last jumped to, shown in the status bar
+	blockIdx     int        // index of that block in blocks
+	blockTop     int        // viewport offset the jump left; moved means start afresh
+}
+
+// themeStyles builds the UI styles for -theme, falling back to the default
+// theme when the selection cannot be loaded.
+func themeStyles(theme, session string) ui.Styles {
+	p, err := ui.LoadPalette(theme)
+	if err != nil {
+		sessionLog(session).Warn("falling back to default theme", "err", err)
+		return ui.NewStyles()
+	}
+	return ui.NewStylesFromPalette(p)
 }
 
-func NewModel(ctx context.Context, a *agent.Agent, startDir string) *model {
-	dirItems := loadDirs(startDir)
+// NewModel returns the chat model for an agent built by BuildAgent, starting
+// in startDir with the settings in cfg (nil for the defaults). /config
+// changes cfg for the rest of the session.
+func NewModel(ctx context.Context, a *agent.Agent, startDir string, cfg *Config) *model {
+	cfg = cfg.orDefault()
+	dirOpts := dirOptions{details: true}
 	dirDelegate := list.NewDefaultDelegate()
-	dirList := list.New(dirItems, dirDelegate, 0, 0)
+	dirList := list.New(nil, dirDelegate, 0, 0)
 	dirList.Title = "Choose Working Directory"
 	dirList.SetShowHelp(false)
 	dirList.SetShowStatusBar(false)
@@ -125,11 +195,11 @@ func NewModel(ctx context.Context, a *agent.Agent, startDir string) *model {
 	ta := textarea.New()
 	ta.Placeholder = "Describe your task or goal..."
 	ta.Focus()
-	ta.SetHeight(3)
+	ta.SetHeight(defaultInputHeight)
 
-	ta.SetHeight(3)
-
-	st := ui.NewStyles()
+	// Generate a random session ID for this run.
+	sessionID := randomID()
+	st := themeStyles(cfg.Theme, sessionID)
 
 	vp := viewport.New(0, 0)
 	vp.SetContent("Welcome to Lattice Code! Describe your task to get started.\n")
@@ -138,14 +208,11 @@ func NewModel(ctx context.Context, a *agent.Agent, startDir string) *model {
 	s.Spinner = spinner.Line
 	s.Style = st.Thinking
 
-	// Generate a random session ID for this run.
-	randBytes := make([]byte, 4)
-	_, _ = rand.Read(randBytes) // Best effort, ignore error.
-	sessionID := hex.EncodeToString(randBytes)
-
 	m := &model{
 		ctx:          ctx,
 		agent:        a,
+		cfg:          cfg,
+		agentDir:     startDir,
 		working:      startDir,
 		history:      []string{startDir},
 		mode:         ui.ModeDir,
@@ -158,27 +225,219 @@ func NewModel(ctx context.Context, a *agent.Agent, startDir string) *model {
 		syncInterval: time.Second,
 		sessionID:    sessionID,
 		plannerQueue: make(chan string, 100), // <-- add this
-
+		dirOpts:      dirOpts,
+		recentsPath:  cfg.statePath("recents.json"),
+		configPath:   cfg.statePath("config.json"),
+	}
+	m.Memory = agentMemory(a)
+	m.recents = loadRecents(m.recentsPath)
+	m.settings = loadSettings(m.configPath)
+	if err := m.settings.apply(cfg); err != nil {
+		sessionLog(sessionID).Warn("ignoring invalid settings", "path", m.configPath, "err", err)
+	}
+	if h := m.settings.InputHeight; h >= minInputHeight && h <= maxInputHeight {
+		m.textarea.SetHeight(h)
 	}
+	m.reloadDirs()
 
 	return m
 }
 
+// startWork counts one more operation in flight and shows status as the
+// thinking text. Only the first of overlapping operations starts the
+// spinner; the returned command is nil otherwise and is meant to be batched
+// with the operation's own. Each startWork is paired with one endWork when
+// the operation's result message arrives.
+func (m *model) startWork(status string) tea.Cmd {
+	m.inFlight++
+	m.thinking = status
+	m.AddThought(status)
+	if m.isThinking {
+		return nil
+	}
+	m.isThinking = true
+	return m.spinner.Tick
+}
+
+// setStatus replaces the thinking line shown next to the spinner. Status
+// arriving after all work has ended is dropped, so a late update cannot
+// leave stale text for the next operation.
+func (m *model) setStatus(text string) {
+	if m.isThinking {
+		m.thinking = text
+	}
+}
+
+// endWork counts one operation as finished. The spinner stops with the last.
+func (m *model) endWork() {
+	if m.inFlight > 0 {
+		m.inFlight--
+	}
+	if m.inFlight == 0 {
+		m.isThinking = false
+		m.thinking = ""
+	}
+}
+
 func (m *model) renderOutput(sync bool) {
-	m.viewport.SetContent(m.output)
-	m.viewport.GotoBottom()
+	m.trimOutput()
+	text := m.output + m.streamed
+	if m.trimmedBytes > 0 {
+		note := "✂️ earlier output trimmed"
+		if m.transcriptPath != "" {
+			note += "; the transcript keeps all of it"
+		}
+		text = m.style.Subtle.Render(note) + "\n" + text
+	}
+	content := ui.FormatOutput(text, m.viewport.Width)
+	m.viewport.SetContent(content)
+	m.blocks = ui.IndexBlocks(content)
+	if m.scrollLocked {
+		m.unseenOutput = true
+	} else {
+		m.viewport.GotoBottom()
+	}
 	if sync {
 		m.persistTranscript()
 	}
 }
 
+// syncScrollLock records whether the user has scrolled the chat viewport away
+// from the bottom. Returning to the bottom resumes auto-scrolling.
+func (m *model) syncScrollLock() {
+	m.scrollLocked = !m.viewport.AtBottom()
+	if !m.scrollLocked {
+		m.unseenOutput = false
+		m.blockLabel = ""
+	}
+}
+
+// jumpBlock scrolls the chat viewport to the next (or previous) code block
+// or diff after (before) the top line. It reports false when there is none
+// in that direction.
+func (m *model) jumpBlock(next bool) bool {
+	top := m.viewport.YOffset
+	idx := -1
+	if m.blockLabel != "" && top == m.blockTop {
+		// Continue from the last jump: near the end of the output the
+		// viewport cannot scroll a block to the top, so top alone would
+		// find the same block again.
+		idx = m.blockIdx - 1
+		if next {
+			idx = m.blockIdx + 1
+		}
+	} else {
+		for i, b := range m.blocks {
+			if next && b.Line > top {
+				idx = i
+				break
+			}
+			if !next && b.Line < top {
+				idx = i
+			}
+		}
+	}
+	if idx < 0 || idx >= len(m.blocks) {
+		return false
+	}
+	b := m.blocks[idx]
+	m.viewport.SetYOffset(b.Line)
+	m.syncScrollLock()
+	m.blockIdx, m.blockTop = idx, m.viewport.YOffset
+	m.blockLabel = fmt.Sprintf("%d/%d", idx+1, len(m.blocks))
+	if b.Path != "" {
+		m.blockLabel += " " + b.Path
+	}
+	return true
+}
+
+// scrollsViewport reports whether a chat-mode message should reach the
+// viewport. Printable keys belong to the textarea; without this, typing
+// "k" or a space would scroll the output and engage the scroll lock.
+func scrollsViewport(msg tea.Msg) bool {
+	k, ok := msg.(tea.KeyMsg)
+	return !ok || (k.Type != tea.KeyRunes && k.Type != tea.KeySpace)
+}
+
+// trimOutput drops the oldest whole lines of output once it grows past
+// -max-output-bytes, so rendering stays fast in long sessions. The
+// transcript is brought up to date first, so it still holds what was dropped.
+func (m *model) trimOutput() {
+	limit := m.cfg.MaxOutputBytes
+	if limit <= 0 || len(m.output) <= limit {
+		return
+	}
+	nl := strings.IndexByte(m.output[len(m.output)-limit:], '\n')
+	if nl < 0 {
+		return
+	}
+	m.persistTranscript()
+	cut := len(m.output) - limit + nl + 1
+	m.mu.Lock()
+	m.output = m.output[cut:]
+	m.trimmedBytes += int64(cut)
+	m.lastTranscriptSig = hashString(m.output)
+	m.mu.Unlock()
+}
+
+// transcriptFile is where the chat of a session in root is written; it keeps
+// everything -max-output-bytes trims from the screen.
+func transcriptFile(root string) string {
+	return filepath.Join(root, latticeDir, "transcript.md")
+}
+
+// rebuildAgentCmd rebuilds the agent for the confirmed workspace dir when
+// its system prompt differs from the one the agent was built with, such as
+// when dir has its own .lattice/system.md. It returns nil when there is
+// nothing to rebuild.
+func (m *model) rebuildAgentCmd(dir string) tea.Cmd {
+	if m.agent == nil || dir == m.agentDir {
+		return nil
+	}
+	want, err := systemPrompt(m.cfg, dir)
+	if have, _ := systemPrompt(m.cfg, m.agentDir); err == nil && want == have {
+		m.agentDir = dir
+		return nil
+	}
+	ctx, cfg := m.ctx, m.cfg
+	return func() tea.Msg {
+		a, err := BuildAgentFor(ctx, cfg, dir)
+		return agentBuiltMsg{dir: dir, agent: a, err: err}
+	}
+}
+
+// openTranscript makes path the session's transcript. Earlier sessions in
+// the file are kept; this one is written after them.
+func (m *model) openTranscript(path string) {
+	var base int64
+	if info, err := os.Stat(path); err == nil {
+		base = info.Size()
+	}
+	m.mu.Lock()
+	m.transcriptPath, m.transcriptBase = path, base
+	m.trimmedBytes, m.lastTranscriptSig = 0, ""
+	m.mu.Unlock()
+	m.persistTranscript()
+}
+
+// persistTranscript writes output to the transcript after the earlier
+// sessions and the trimmed part it already holds.
 func (m *model) persistTranscript() {
 	if m.transcriptPath == "" {
 		return
 	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	if err := os.WriteFile(m.transcriptPath, []byte(m.output), 0o644); err != nil {
+	f, err := os.OpenFile(m.transcriptPath, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	at := m.transcriptBase + m.trimmedBytes
+	if err := f.Truncate(at); err != nil {
+		return
+	}
+	if _, err := f.WriteAt([]byte(m.output), at); err != nil {
 		return
 	}
 	m.lastTranscriptSig = hashString(m.output)
@@ -190,6 +449,8 @@ func defaultAgents() []list.Item {
 		plugin{"architect", "High-level design and refactoring"},
 		plugin{"coder", "Feature implementation and tests"},
 		plugin{"reviewer", "Code review and optimization"},
+		plugin{"explain", "Explain code without changing files"},
+		plugin{"codemode", "Refactor through UTCP codemode tools"},
 		plugin{"shell", "Execute terminal commands"},
 		plugin{"utcp", "Explore connected UTCP tools"},
 	}
@@ -0,0 +1,190 @@
+package src
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/Protocol-Lattice/lattice-code/src/ui"
+)
+
+func TestRenderOutputScrollLock(t *testing.T) {
+	m := &model{cfg: DefaultConfig(), viewport: viewport.New(40, 3)}
+	m.output = strings.Repeat("line\n", 10)
+	m.renderOutput(false)
+	if !m.viewport.AtBottom() {
+		t.Fatal("output should follow the bottom by default")
+	}
+
+	m.viewport.LineUp(2)
+	m.syncScrollLock()
+	offset := m.viewport.YOffset
+	m.output += "more\n"
+	m.renderOutput(false)
+	if m.viewport.YOffset != offset {
+		t.Errorf("scrolled-up viewport moved from %d to %d", offset, m.viewport.YOffset)
+	}
+	if !m.unseenOutput {
+		t.Error("new output while locked should be flagged")
+	}
+
+	m.viewport.GotoBottom()
+	m.syncScrollLock()
+	if m.scrollLocked || m.unseenOutput {
+		t.Error("returning to the bottom should release the lock")
+	}
+	m.output += "again\n"
+	m.renderOutput(false)
+	if !m.viewport.AtBottom() {
+		t.Error("output should follow again after the lock is released")
+	}
+}
+
+func TestScrollsViewport(t *testing.T) {
+	if scrollsViewport(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("k")}) {
+		t.Error("typed runes belong to the textarea")
+	}
+	if scrollsViewport(tea.KeyMsg{Type: tea.KeySpace}) {
+		t.Error("space belongs to the textarea")
+	}
+	if !scrollsViewport(tea.KeyMsg{Type: tea.KeyPgUp}) {
+		t.Error("page up should scroll the viewport")
+	}
+}
+
+func TestWorkRefCountGatesSpinner(t *testing.T) {
+	m := &model{}
+	if m.startWork("first") == nil {
+		t.Fatal("the first operation should start the spinner")
+	}
+	if m.startWork("second") != nil {
+		t.Error("an overlapping operation should not start a second tick loop")
+	}
+	if m.thinking != "second" {
+		t.Errorf("thinking = %q, want the latest status", m.thinking)
+	}
+
+	m.endWork()
+	if !m.isThinking {
+		t.Fatal("the spinner stopped while an operation is still in flight")
+	}
+	m.endWork()
+	if m.isThinking || m.thinking != "" {
+		t.Errorf("spinner still running after the last operation: %v %q", m.isThinking, m.thinking)
+	}
+
+	m.endWork() // an unmatched end must not go negative
+	if m.startWork("again") == nil {
+		t.Error("work after an idle period should restart the spinner")
+	}
+}
+
+func TestJumpBlock(t *testing.T) {
+	m := &model{cfg: DefaultConfig(), viewport: viewport.New(40, 4)}
+	m.output = "intro\n💾 a.go\n```go\npackage a\n```\n" + strings.Repeat("prose\n", 10) + "💾 b.go\n```go\npackage b\n```\ntail\n"
+	m.renderOutput(false)
+	m.viewport.GotoTop()
+
+	if !m.jumpBlock(true) || m.viewport.YOffset != 2 || m.blockLabel != "1/2 a.go" {
+		t.Fatalf("first jump: offset %d, label %q", m.viewport.YOffset, m.blockLabel)
+	}
+	// The second block is near the end, so the viewport stops short of it.
+	if !m.jumpBlock(true) || m.blockLabel != "2/2 b.go" {
+		t.Fatalf("second jump: label %q", m.blockLabel)
+	}
+	if m.jumpBlock(true) {
+		t.Error("there is no block after the last")
+	}
+	if !m.jumpBlock(false) || m.viewport.YOffset != 2 || m.blockLabel != "1/2 a.go" {
+		t.Errorf("jump back: offset %d, label %q", m.viewport.YOffset, m.blockLabel)
+	}
+}
+
+func TestOutputTrimmedWhileTranscriptKeepsAll(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.MaxOutputBytes = 200
+	m := &model{cfg: cfg, viewport: viewport.New(60, 5), transcriptPath: filepath.Join(t.TempDir(), "transcript.txt")}
+	var all strings.Builder
+	for i := 0; i < 50; i++ {
+		line := fmt.Sprintf("line %02d of the session\n", i)
+		all.WriteString(line)
+		m.output += line
+		m.renderOutput(true)
+	}
+
+	if len(m.output) > 200 || !strings.HasSuffix(m.output, "line 49 of the session\n") || !strings.HasPrefix(m.output, "line ") {
+		t.Errorf("output not trimmed to whole lines within the cap: %d bytes %q", len(m.output), m.output)
+	}
+	m.viewport.GotoTop()
+	if !strings.Contains(m.viewport.View(), "earlier output trimmed") {
+		t.Errorf("no trim marker:\n%s", m.viewport.View())
+	}
+	if got := readFile(t, m.transcriptPath); got != all.String() {
+		t.Errorf("transcript lost output: got %d bytes, want %d", len(got), all.Len())
+	}
+
+	kept := m.output
+	m.Update(m.readTranscriptCmd()())
+	if m.output != kept {
+		t.Errorf("syncing the transcript back restored trimmed output: %d bytes", len(m.output))
+	}
+}
+
+func TestConfirmingWorkspaceStartsTranscript(t *testing.T) {
+	root := t.TempDir()
+	m := NewModel(context.Background(), nil, root, nil)
+	if _, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter}); cmd == nil || m.mode == ui.ModeDir {
+		t.Fatalf("confirming the workspace should start syncing the transcript; mode = %v", m.mode)
+	}
+	if want := filepath.Join(root, latticeDir, "transcript.md"); m.transcriptPath != want {
+		t.Fatalf("transcriptPath = %q, want %q", m.transcriptPath, want)
+	}
+	m.output += "You: hello\n"
+	m.renderOutput(true)
+	if got := readFile(t, m.transcriptPath); !strings.HasSuffix(got, "You: hello\n") {
+		t.Errorf("transcript = %q", got)
+	}
+}
+
+func TestReopeningWorkspaceKeepsTranscript(t *testing.T) {
+	root := t.TempDir()
+	earlier := "You: from an earlier session\n"
+	writeFixture(t, root, map[string]string{latticeDir + "/transcript.md": earlier})
+	cfg := DefaultConfig()
+	cfg.MaxOutputBytes = 100
+	m := NewModel(context.Background(), nil, root, cfg)
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+
+	var session strings.Builder
+	for i := 0; i < 20; i++ {
+		line := fmt.Sprintf("line %02d of this session\n", i)
+		session.WriteString(line)
+		m.output += line
+		m.renderOutput(true)
+	}
+	got := readFile(t, m.transcriptPath)
+	if !strings.HasPrefix(got, earlier) || !strings.HasSuffix(got, session.String()) {
+		t.Errorf("transcript lost the earlier session or this one:\n%s", got)
+	}
+	kept := m.output
+	m.Update(m.readTranscriptCmd()())
+	if m.output != kept {
+		t.Errorf("syncing the transcript back changed the output: %q", m.output)
+	}
+
+	// Switching workspaces after output was trimmed starts the new
+	// transcript at its own beginning.
+	other := t.TempDir()
+	m.Update(tea.KeyMsg{Type: tea.KeyCtrlD})
+	m.working = other
+	m.reloadDirs()
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if got := readFile(t, filepath.Join(other, latticeDir, "transcript.md")); strings.ContainsRune(got, 0) || got != m.output {
+		t.Errorf("new workspace transcript = %q, want the output %q", got, m.output)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -13,35 +14,93 @@ import (
 	"go/parser"
 	"go/printer"
 	"go/token"
+
+	"github.com/Protocol-Lattice/lattice-code/src/atomicfile"
 )
 
-// NormalizeImports runs all language-specific fixers over the workspace.
-func NormalizeImports(root string) error {
-	_ = normalizeGo(root)
-	_ = normalizePython(root)
-	_ = normalizeJSLike(root)
-	_ = normalizeJavaLike(root)
-	_ = normalizeCppLike(root)
-	_ = normalizePHP(root)
-	return nil
+// NormalizeImports runs the language-specific fixers over files, given
+// relative to root, or over the whole workspace when files is nil. A fixer
+// only runs when there are files of its language to look at. It returns the
+// files it rewrote, relative to root and sorted.
+func NormalizeImports(root string, files []string) ([]string, error) {
+	byExt := normalizeTargets(root, files)
+	of := func(exts ...string) []string {
+		var out []string
+		for _, e := range exts {
+			out = append(out, byExt[e]...)
+		}
+		return out
+	}
+	fixers := []struct {
+		fix  func(root string, files []string) ([]string, error)
+		exts []string
+	}{
+		{normalizeGo, []string{".go"}},
+		{normalizePython, []string{".py"}},
+		{normalizeJSLike, []string{".js", ".mjs", ".cjs", ".ts", ".tsx", ".jsx"}},
+		{normalizeJavaLike, []string{".java", ".kt"}},
+		{normalizeCppLike, []string{".c", ".h", ".hpp", ".hh", ".hxx", ".cpp", ".cc", ".cxx"}},
+		{normalizePHP, []string{".php"}},
+	}
+	var changed []string
+	for _, f := range fixers {
+		if files := of(f.exts...); len(files) > 0 {
+			done, _ := f.fix(root, files)
+			for _, p := range done {
+				if rel, err := filepath.Rel(root, p); err == nil {
+					changed = append(changed, filepath.ToSlash(rel))
+				}
+			}
+		}
+	}
+	sort.Strings(changed)
+	return changed, nil
 }
 
-func normalizeGo(root string) error {
-	mod := goModulePath(root)
-	if mod == "" {
-		return nil
+// normalizeTargets groups the absolute paths of files (or of every file in
+// the workspace outside ignored directories) by lower-cased extension.
+func normalizeTargets(root string, files []string) map[string][]string {
+	byExt := map[string][]string{}
+	add := func(p string) {
+		ext := strings.ToLower(filepath.Ext(p))
+		byExt[ext] = append(byExt[ext], p)
+	}
+	if files != nil {
+		for _, rel := range files {
+			p := filepath.Join(root, filepath.FromSlash(rel))
+			if fi, err := os.Stat(p); err == nil && !fi.IsDir() {
+				add(p)
+			}
+		}
+		return byExt
 	}
-	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
-		if err != nil || d.IsDir() || !strings.HasSuffix(p, ".go") {
-			return err
+	_ = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
 		}
-		if strings.Contains(p, string(filepath.Separator)+"vendor"+string(filepath.Separator)) {
+		if d.IsDir() {
+			if p != root && isIgnoredDir(d.Name()) {
+				return filepath.SkipDir
+			}
 			return nil
 		}
+		add(p)
+		return nil
+	})
+	return byExt
+}
+
+func normalizeGo(root string, files []string) ([]string, error) {
+	var written []string
+	mod := goModulePath(root)
+	if mod == "" {
+		return nil, nil
+	}
+	for _, p := range files {
 		fset := token.NewFileSet()
 		f, err := parser.ParseFile(fset, p, nil, parser.ParseComments)
 		if err != nil {
-			return nil
+			continue
 		}
 		changed := false
 		ast.Inspect(f, func(n ast.Node) bool {
@@ -52,23 +111,32 @@ func normalizeGo(root string) error {
 			path, _ := strconv.Unquote(imp.Path.Value)
 			// If the import path is not a standard library path and corresponds to a directory
 			// within the project, prepend the module path to make it a valid module-relative import.
-			if !isStdLib(path) && isUnderRoot(root, path) {
+			// Paths through a src/ directory look like stdlib paths (no dot) but are project-relative.
+			local := !isStdLib(path) || strings.Contains("/"+path+"/", "/src/")
+			if local && !strings.HasPrefix(path, mod+"/") && isUnderRoot(root, path) {
 				newPath := mod + "/" + path
 				imp.Path.Value = strconv.Quote(newPath)
 				changed = true
 			}
 			return true
 		})
+		if dedupeImports(f) {
+			changed = true
+		}
 		if !changed {
-			return nil
+			continue
 		}
 		var buf bytes.Buffer
 		cfg := &printer.Config{Mode: printer.TabIndent | printer.UseSpaces, Tabwidth: 8}
 		if err := cfg.Fprint(&buf, fset, f); err != nil {
-			return nil
+			continue
 		}
-		return os.WriteFile(p, buf.Bytes(), 0o644)
-	})
+		if err := atomicfile.WriteFile(p, buf.Bytes(), 0o644); err != nil {
+			return written, err
+		}
+		written = append(written, p)
+	}
+	return written, nil
 }
 
 var stdlib map[string]struct{}
@@ -94,12 +162,98 @@ func goModulePath(root string) string {
 	return ""
 }
 
-func normalizePython(root string) error {
-	pyFiles := collectFiles(root, ".py")
-	if len(pyFiles) == 0 {
-		return nil
+// dedupeImports merges import specs that name the same path, which happens
+// when rewriting turns "src/x" into an import that is already present. An
+// aliased duplicate is folded into the kept spec by renaming its uses. It
+// reports whether f changed.
+func dedupeImports(f *ast.File) bool {
+	kept := map[string]*ast.ImportSpec{}
+	drop := map[*ast.ImportSpec]bool{}
+	renames := map[string]string{}
+	for _, imp := range f.Imports {
+		path, err := strconv.Unquote(imp.Path.Value)
+		if err != nil {
+			continue
+		}
+		first, ok := kept[path]
+		if !ok {
+			kept[path] = imp
+			continue
+		}
+		name, firstName := importName(imp, path), importName(first, path)
+		switch {
+		case name == firstName || name == "_":
+			drop[imp] = true
+		case firstName == "_":
+			// Keep the named import; the blank one only forced linking.
+			drop[first] = true
+			kept[path] = imp
+		case name != "." && firstName != "." && token.IsIdentifier(firstName):
+			renames[name] = firstName
+			drop[imp] = true
+		}
+	}
+	if len(drop) == 0 {
+		return false
 	}
 
+	if len(renames) > 0 {
+		ast.Inspect(f, func(n ast.Node) bool {
+			sel, ok := n.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			// Package qualifiers are left unresolved by the parser.
+			if id, ok := sel.X.(*ast.Ident); ok && id.Obj == nil {
+				if to, ok := renames[id.Name]; ok {
+					id.Name = to
+				}
+			}
+			return true
+		})
+	}
+
+	imports := f.Imports[:0]
+	for _, imp := range f.Imports {
+		if !drop[imp] {
+			imports = append(imports, imp)
+		}
+	}
+	f.Imports = imports
+	decls := f.Decls[:0]
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.IMPORT {
+			decls = append(decls, decl)
+			continue
+		}
+		specs := gd.Specs[:0]
+		for _, spec := range gd.Specs {
+			if !drop[spec.(*ast.ImportSpec)] {
+				specs = append(specs, spec)
+			}
+		}
+		gd.Specs = specs
+		if len(specs) > 0 {
+			decls = append(decls, gd)
+		}
+	}
+	f.Decls = decls
+	return true
+}
+
+// importName is the name imp binds in the file. For an unnamed import that
+// is assumed to be the last path element, which holds for generated code.
+func importName(imp *ast.ImportSpec, path string) string {
+	if imp.Name != nil {
+		return imp.Name.Name
+	}
+	return path[strings.LastIndex(path, "/")+1:]
+}
+
+func normalizePython(root string, pyFiles []string) ([]string, error) {
+	var written []string
+
 	reFrom := regexp.MustCompile(`(?m)^\s*from\s+([A-Za-z0-9_\.]+)\s+import\s+`)
 	reImp := regexp.MustCompile(`(?m)^\s*import\s+([A-Za-z0-9_\.]+)`)
 
@@ -145,12 +299,12 @@ func normalizePython(root string) error {
 			return line
 		})
 
-		if changed {
-			_ = os.WriteFile(p, []byte(txt), 0o644)
+		if changed && atomicfile.WriteFile(p, []byte(txt), 0o644) == nil {
+			written = append(written, p)
 		}
 	}
 
-	return nil
+	return written, nil
 }
 
 func moduleNameFromRoot(root string) string {
@@ -167,13 +321,10 @@ func makeReplacer(line, target, newRel string) string {
 	return line // Should not happen with the given regex, but safe to have.
 }
 
-func normalizeJSLike(root string) error {
-	jsExts := []string{".js", ".mjs", ".cjs", ".ts", ".tsx", ".jsx"}
-	files := collectFilesMany(root, jsExts)
-	if len(files) == 0 {
-		return nil
-	}
+func normalizeJSLike(root string, files []string) ([]string, error) {
+	var written []string
 	re := regexp.MustCompile(`(?m)^\s*(?:import|export)\s+(?:[^'"]*?\s+from\s+)?['"]([^'"]+)['"]`)
+	cfg := loadTSConfig(root)
 	for _, p := range files {
 		orig, err := os.ReadFile(p)
 		if err != nil {
@@ -181,6 +332,14 @@ func normalizeJSLike(root string) error {
 		}
 		txt := string(orig)
 		changed := false
+		// spec is how p should import the module at abs: through a
+		// tsconfig alias when one covers it, else relatively.
+		spec := func(abs string) string {
+			if s := cfg.specFor(abs); s != "" {
+				return s
+			}
+			return relFromTo(filepath.Dir(p), abs)
+		}
 
 		txt = re.ReplaceAllStringFunc(txt, func(line string) string {
 			m := re.FindStringSubmatch(line)
@@ -188,12 +347,27 @@ func normalizeJSLike(root string) error {
 				return line
 			}
 			target := m[1]
-			if strings.HasPrefix(target, ".") || strings.HasPrefix(target, "@") {
+			if strings.HasPrefix(target, ".") {
 				return line
 			}
+			if a, rest, ok := cfg.match(target); ok {
+				// Aliased imports are kept, unless a stray src/ is all that
+				// stops one resolving ("@/src/x" with "@/*": ["src/*"]).
+				if fixed, ok := strings.CutPrefix(rest, "src/"); ok && !a.resolves(rest) && a.resolves(fixed) {
+					changed = true
+					return makeReplacer(line, target, a.prefix+fixed+a.suffix)
+				}
+				return line
+			}
+			if strings.HasPrefix(target, "@") {
+				return line // scoped package
+			}
+			if cfg.baseURL != "" && jsModuleExists(filepath.Join(cfg.baseURL, filepath.FromSlash(target))) {
+				return line // resolves through baseUrl
+			}
 			if idx := strings.Index(target, "/src/"); idx >= 0 {
 				suffix := target[idx+len("/src/"):]
-				newRel := relFromTo(filepath.Dir(p), filepath.Join(root, "src", filepath.FromSlash(suffix)))
+				newRel := spec(filepath.Join(root, "src", filepath.FromSlash(suffix)))
 				if newRel != "" && newRel != target {
 					changed = true
 					return makeReplacer(line, target, newRel)
@@ -201,7 +375,7 @@ func normalizeJSLike(root string) error {
 			}
 			if strings.HasPrefix(target, "src/") {
 				suffix := strings.TrimPrefix(target, "src/")
-				newRel := relFromTo(filepath.Dir(p), filepath.Join(root, "src", filepath.FromSlash(suffix)))
+				newRel := spec(filepath.Join(root, "src", filepath.FromSlash(suffix)))
 				if newRel != "" && newRel != target {
 					changed = true
 					return makeReplacer(line, target, newRel)
@@ -209,7 +383,7 @@ func normalizeJSLike(root string) error {
 			}
 			if isUnderRoot(root, target) {
 				abs := filepath.Join(root, filepath.FromSlash(target))
-				newRel := relFromTo(filepath.Dir(p), abs)
+				newRel := spec(abs)
 				if newRel != "" && newRel != target {
 					changed = true
 					return makeReplacer(line, target, newRel)
@@ -218,21 +392,19 @@ func normalizeJSLike(root string) error {
 			return line
 		})
 
-		if changed {
-			_ = os.WriteFile(p, []byte(txt), 0o644)
+		if changed && atomicfile.WriteFile(p, []byte(txt), 0o644) == nil {
+			written = append(written, p)
 		}
 	}
-	return nil
+	return written, nil
 }
 
-func normalizeJavaLike(root string) error {
-	javaExts := []string{".java", ".kt"}
-	files := collectFilesMany(root, javaExts)
-	if len(files) == 0 {
-		return nil
-	}
-	rePkg := regexp.MustCompile(`(?m)^(package\s+)([A-Za-z0-9_.]+)\s*;`)
-	reImp := regexp.MustCompile(`(?m)^(import\s+)([A-Za-z0-9_.]+)\s*;`)
+func normalizeJavaLike(root string, files []string) ([]string, error) {
+	var written []string
+	// Kotlin declarations may omit the trailing semicolon; keep whichever
+	// form the file uses.
+	rePkg := regexp.MustCompile(`(?m)^(package[ \t]+)([A-Za-z0-9_.]+)([ \t]*;?)`)
+	reImp := regexp.MustCompile(`(?m)^(import[ \t]+)([A-Za-z0-9_.]+)([ \t]*;?)`)
 	for _, p := range files {
 		orig, err := os.ReadFile(p)
 		if err != nil {
@@ -246,35 +418,30 @@ func normalizeJavaLike(root string) error {
 			ns = strings.ReplaceAll(ns, "..", ".")
 			return ns, ns != s
 		}
-		txt = rePkg.ReplaceAllStringFunc(txt, func(line string) string {
-			prefix, name := rePkg.FindStringSubmatch(line)[1], rePkg.FindStringSubmatch(line)[2]
-			if nn, ok := fix(name); ok {
-				changed = true
-				return prefix + nn + ";"
-			}
-			return line
-		})
-		txt = reImp.ReplaceAllStringFunc(txt, func(line string) string {
-			prefix, name := reImp.FindStringSubmatch(line)[1], reImp.FindStringSubmatch(line)[2]
-			if nn, ok := fix(name); ok {
-				changed = true
-				return prefix + nn + ";"
+		rewrite := func(re *regexp.Regexp) func(string) string {
+			return func(line string) string {
+				m := re.FindStringSubmatch(line)
+				if len(m) < 4 {
+					return line
+				}
+				if nn, ok := fix(m[2]); ok {
+					changed = true
+					return m[1] + nn + m[3]
+				}
+				return line
 			}
-			return line
-		})
-		if changed {
-			_ = os.WriteFile(p, []byte(txt), 0o644)
+		}
+		txt = rePkg.ReplaceAllStringFunc(txt, rewrite(rePkg))
+		txt = reImp.ReplaceAllStringFunc(txt, rewrite(reImp))
+		if changed && atomicfile.WriteFile(p, []byte(txt), 0o644) == nil {
+			written = append(written, p)
 		}
 	}
-	return nil
+	return written, nil
 }
 
-func normalizeCppLike(root string) error {
-	ccExts := []string{".c", ".h", ".hpp", ".hh", ".hxx", ".cpp", ".cc", ".cxx"}
-	files := collectFilesMany(root, ccExts)
-	if len(files) == 0 {
-		return nil
-	}
+func normalizeCppLike(root string, files []string) ([]string, error) {
+	var written []string
 	re := regexp.MustCompile(`(?m)^\s*#\s*include\s*[<"]([^">]+)[">]`)
 	for _, p := range files {
 		orig, err := os.ReadFile(p)
@@ -314,18 +481,15 @@ func normalizeCppLike(root string) error {
 			return line
 		})
 
-		if changed {
-			_ = os.WriteFile(p, []byte(txt), 0o644)
+		if changed && atomicfile.WriteFile(p, []byte(txt), 0o644) == nil {
+			written = append(written, p)
 		}
 	}
-	return nil
+	return written, nil
 }
 
-func normalizePHP(root string) error {
-	files := collectFiles(root, ".php")
-	if len(files) == 0 {
-		return nil
-	}
+func normalizePHP(root string, files []string) ([]string, error) {
+	var written []string
 	re := regexp.MustCompile(`(?m)^\s*use\s+([A-Za-z0-9_\\]+)\s*;`)
 	for _, p := range files {
 		orig, err := os.ReadFile(p)
@@ -347,9 +511,9 @@ func normalizePHP(root string) error {
 			}
 			return line
 		})
-		if changed {
-			_ = os.WriteFile(p, []byte(txt), 0o644)
+		if changed && atomicfile.WriteFile(p, []byte(txt), 0o644) == nil {
+			written = append(written, p)
 		}
 	}
-	return nil
+	return written, nil
 }
@@ -0,0 +1,186 @@
+package src
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeGoMergesCollapsedImports(t *testing.T) {
+	root := t.TempDir()
+	writeFixture(t, root, map[string]string{
+		"go.mod":       "module example.com/app\n",
+		"src/ui/ui.go": "package ui\n\nfunc Render() {}\n\nfunc Reset() {}\n",
+		"cmd/main.go": `package main
+
+import (
+	"fmt"
+
+	"example.com/app/src/ui"
+	view "src/ui"
+	"src/ui"
+)
+
+func main() {
+	ui.Render()
+	view.Reset()
+	fmt.Println()
+}
+`,
+	})
+	if _, err := NormalizeImports(root, nil); err != nil {
+		t.Fatal(err)
+	}
+	got := readFile(t, root+"/cmd/main.go")
+	if n := strings.Count(got, `"example.com/app/src/ui"`); n != 1 {
+		t.Fatalf("want the import exactly once, got %d:\n%s", n, got)
+	}
+	if strings.Contains(got, "view") || !strings.Contains(got, "ui.Reset()") {
+		t.Fatalf("aliased duplicate was not folded into the kept import:\n%s", got)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "main.go", got, 0); err != nil {
+		t.Fatalf("result does not parse: %v\n%s", err, got)
+	}
+	if !strings.Contains(got, `"fmt"`) {
+		t.Fatalf("unrelated import dropped:\n%s", got)
+	}
+}
+
+func TestNormalizePythonDoesNotCreatePackages(t *testing.T) {
+	root := t.TempDir()
+	writeFixture(t, root, map[string]string{
+		"scripts/run.py":    "import sys\nprint(sys.argv)\n",
+		"tests/test_app.py": "from src.app import main\n",
+		"app/__init__.py":   "",
+		"app/main.py":       "def main():\n    pass\n",
+	})
+	if _, err := NormalizeImports(root, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range []string{"scripts", "tests"} {
+		if _, err := os.Stat(filepath.Join(root, dir, "__init__.py")); err == nil {
+			t.Errorf("%s/__init__.py was created", dir)
+		}
+	}
+	if got := readFile(t, filepath.Join(root, "tests", "test_app.py")); got != "from app import main\n" {
+		t.Errorf("import not normalized: %q", got)
+	}
+}
+
+func TestNormalizeJavaLikePackages(t *testing.T) {
+	root := t.TempDir()
+	writeFixture(t, root, map[string]string{
+		"src/com/app/Main.java": "package src.com.app;\n\nimport com.src.util.Strings;\nimport java.util.List;\n\nclass Main {}\n",
+		"src/com/app/Main.kt":   "package com.src.app\n\nimport src.com.util.Strings\nimport com.src.util.Json as J\nimport kotlin.math.max\n\nfun main() {}\n",
+	})
+	if _, err := NormalizeImports(root, nil); err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]string{
+		"src/com/app/Main.java": "package com.app;\n\nimport com.util.Strings;\nimport java.util.List;\n\nclass Main {}\n",
+		"src/com/app/Main.kt":   "package com.app\n\nimport com.util.Strings\nimport com.util.Json as J\nimport kotlin.math.max\n\nfun main() {}\n",
+	}
+	for rel, want := range tests {
+		if got := readFile(t, filepath.Join(root, rel)); got != want {
+			t.Errorf("%s:\ngot  %q\nwant %q", rel, got, want)
+		}
+	}
+}
+
+func TestNormalizeJSLikeTSConfigPaths(t *testing.T) {
+	root := t.TempDir()
+	writeFixture(t, root, map[string]string{
+		"tsconfig.json": `{
+  // comments and trailing commas are allowed
+  "compilerOptions": {
+    "paths": {
+      "@/*": ["src/*"],
+      "@ui/*": ["src/components/ui/*"],
+    },
+  },
+}
+`,
+		"src/components/Button.tsx":  "export const Button = () => null;\n",
+		"src/components/ui/Card.tsx": "export const Card = () => null;\n",
+		"src/lib/format.ts":          "export const format = (s: string) => s;\n",
+		"src/pages/home/index.tsx":   "",
+		"src/pages/home/Home.tsx": `import { Button } from "@/components/Button";
+import { Card } from "@ui/Card";
+import { format } from "@/src/lib/format";
+import { api } from "src/lib/format";
+import React from "react";
+import { z } from "@scope/zod";
+`,
+	})
+	if _, err := NormalizeImports(root, nil); err != nil {
+		t.Fatal(err)
+	}
+	want := `import { Button } from "@/components/Button";
+import { Card } from "@ui/Card";
+import { format } from "@/lib/format";
+import { api } from "@/lib/format";
+import React from "react";
+import { z } from "@scope/zod";
+`
+	if got := readFile(t, filepath.Join(root, "src/pages/home/Home.tsx")); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	cfg := loadTSConfig(root)
+	if got := cfg.specFor(filepath.Join(root, "src/components/ui/Card")); got != "@ui/Card" {
+		t.Errorf("specFor picks the most specific alias: got %q", got)
+	}
+	if got := cfg.specFor(filepath.Join(root, "lib/x")); got != "" {
+		t.Errorf("specFor outside any alias = %q", got)
+	}
+}
+
+func TestNormalizeImportsReportsRewrittenFiles(t *testing.T) {
+	root := t.TempDir()
+	writeFixture(t, root, map[string]string{
+		"src/app/Main.java": "package src.app;\n",
+		"src/app/Util.java": "package app;\n",
+		"lib/run.py":        "from src.app import main\n",
+		"lib/ok.py":         "import os\n",
+	})
+	changed, err := NormalizeImports(root, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"lib/run.py", "src/app/Main.java"}
+	if strings.Join(changed, ",") != strings.Join(want, ",") {
+		t.Errorf("changed = %v; want %v", changed, want)
+	}
+}
+
+func TestNormalizeImportsOnlyTouchesGivenFiles(t *testing.T) {
+	root := t.TempDir()
+	src := "package main\n\nimport \"src/ui\"\n\nfunc main() { ui.Render() }\n"
+	writeFixture(t, root, map[string]string{
+		"go.mod":       "module example.com/app\n",
+		"src/ui/ui.go": "package ui\n\nfunc Render() {}\n",
+		"cmd/a/a.go":   src,
+		"cmd/b/b.go":   src,
+	})
+	changed, err := NormalizeImports(root, []string{"cmd/a/a.go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changed) != 1 || changed[0] != "cmd/a/a.go" {
+		t.Errorf("changed = %v; want [cmd/a/a.go]", changed)
+	}
+	if got := readFile(t, filepath.Join(root, "cmd/a/a.go")); !strings.Contains(got, `"example.com/app/src/ui"`) {
+		t.Errorf("given file not normalized:\n%s", got)
+	}
+	if got := readFile(t, filepath.Join(root, "cmd/b/b.go")); got != src {
+		t.Errorf("untouched file was rewritten:\n%s", got)
+	}
+
+	targets := normalizeTargets(root, nil)
+	if len(targets[".go"]) != 3 || len(targets[".py"]) != 0 {
+		t.Errorf("workspace targets = %v", targets)
+	}
+}
@@ -0,0 +1,97 @@
+// Package outline extracts a lightweight symbol outline (functions, types,
+// classes) from source files using per-language line prefixes. It is shared
+// by the context snapshot and the MCP get_file_outline tool.
+package outline
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// genericLines is how many leading lines are shown for unsupported languages.
+const genericLines = 20
+
+// Symbol is one outline entry.
+type Symbol struct {
+	Line int // 1-based
+	Text string
+}
+
+func (s Symbol) String() string { return fmt.Sprintf("Line %d: %s", s.Line, s.Text) }
+
+var (
+	goDecl     = regexp.MustCompile(`^(func|type|const|var) `)
+	pyDecl     = regexp.MustCompile(`^(async def|def|class) `)
+	jsDecl     = regexp.MustCompile(`^(export (default )?)?((async )?function\*? |class |interface |type \w+ =|enum |(const|let) \w+ = (async )?(\(|function))`)
+	rustDecl   = regexp.MustCompile(`^(pub(\([a-z]+\))? )?(async )?(fn|struct|enum|trait|impl|mod|type) `)
+	rubyDecl   = regexp.MustCompile(`^(def|class|module) `)
+	javaDecl   = regexp.MustCompile(`^((public|private|protected|static|final|abstract|sealed|open|data|override|suspend|internal)\s+)*(class|interface|enum|record|object|fun)\s`)
+	javaMethod = regexp.MustCompile(`^(public|private|protected)\s+[\w<>\[\], ]+\s+\w+\s*\(`)
+	cDecl      = regexp.MustCompile(`^(struct|enum|typedef|class|namespace)\b|^[A-Za-z_][\w\s\*&:<>,]*\s[\*&]*\w+(::\w+)?\s*\([^;]*$`)
+)
+
+var matchers = map[string][]*regexp.Regexp{
+	".go":    {goDecl},
+	".py":    {pyDecl},
+	".js":    {jsDecl},
+	".jsx":   {jsDecl},
+	".ts":    {jsDecl},
+	".tsx":   {jsDecl},
+	".rs":    {rustDecl},
+	".rb":    {rubyDecl},
+	".java":  {javaDecl, javaMethod},
+	".kt":    {javaDecl},
+	".scala": {javaDecl},
+	".c":     {cDecl},
+	".h":     {cDecl},
+	".cpp":   {cDecl},
+	".cc":    {cDecl},
+	".hpp":   {cDecl},
+}
+
+// Supported reports whether path has a language-specific outline.
+func Supported(path string) bool {
+	_, ok := matchers[strings.ToLower(filepath.Ext(path))]
+	return ok
+}
+
+// Extract returns the declarations in content. Unsupported languages yield
+// their first few lines so callers always get something to show.
+func Extract(path string, content []byte) []Symbol {
+	lines := strings.Split(string(content), "\n")
+	res, ok := matchers[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		n := min(genericLines, len(lines))
+		out := make([]Symbol, 0, n)
+		for i := 0; i < n; i++ {
+			out = append(out, Symbol{Line: i + 1, Text: strings.TrimSpace(lines[i])})
+		}
+		return out
+	}
+
+	var out []Symbol
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "//") || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		for _, re := range res {
+			if re.MatchString(trimmed) {
+				out = append(out, Symbol{Line: i + 1, Text: trimmed})
+				break
+			}
+		}
+	}
+	return out
+}
+
+// Format renders symbols one per line as "Line N: text".
+func Format(symbols []Symbol) string {
+	lines := make([]string, len(symbols))
+	for i, s := range symbols {
+		lines[i] = s.String()
+	}
+	return strings.Join(lines, "\n")
+}
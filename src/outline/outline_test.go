@@ -0,0 +1,53 @@
+package outline
+
+import (
+	"slices"
+	"testing"
+)
+
+func texts(symbols []Symbol) []string {
+	out := make([]string, len(symbols))
+	for i, s := range symbols {
+		out[i] = s.Text
+	}
+	return out
+}
+
+func TestExtract(t *testing.T) {
+	cases := map[string]struct {
+		src  string
+		want []string
+	}{
+		"main.go": {
+			"package main\n\ntype Server struct{}\n\nfunc (s *Server) Run() error {\n\treturn nil\n}\n",
+			[]string{"type Server struct{}", "func (s *Server) Run() error {"},
+		},
+		"app.py": {
+			"import os\n\nclass App:\n    def run(self):\n        pass\n\nasync def main():\n    pass\n",
+			[]string{"class App:", "def run(self):", "async def main():"},
+		},
+		"index.ts": {
+			"import x from 'y'\nexport function start() {}\nexport class Store {}\nconst handler = async (req) => {}\nconst n = 3\n",
+			[]string{"export function start() {}", "export class Store {}", "const handler = async (req) => {}"},
+		},
+		"lib.rs": {
+			"use std::io;\npub struct Conf;\nimpl Conf {\n    pub fn load() {}\n}\n",
+			[]string{"pub struct Conf;", "impl Conf {", "pub fn load() {}"},
+		},
+	}
+	for path, c := range cases {
+		if got := texts(Extract(path, []byte(c.src))); !slices.Equal(got, c.want) {
+			t.Errorf("%s: got %q; want %q", path, got, c.want)
+		}
+	}
+}
+
+func TestExtractUnsupportedShowsLeadingLines(t *testing.T) {
+	got := Extract("notes.txt", []byte("one\ntwo\n"))
+	if Supported("notes.txt") || len(got) != 3 || got[0] != (Symbol{Line: 1, Text: "one"}) {
+		t.Errorf("got %+v", got)
+	}
+	if Format(got[:2]) != "Line 1: one\nLine 2: two" {
+		t.Errorf("Format = %q", Format(got[:2]))
+	}
+}
@@ -0,0 +1,117 @@
+package src
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// regexpValue is a flag holding a regular expression with at least one
+// group, such as -path-pattern: path hints the comment forms cannot express,
+// like a JSON front-matter line.
+type regexpValue struct{ re **regexp.Regexp }
+
+func (v regexpValue) String() string {
+	if v.re == nil || *v.re == nil {
+		return ""
+	}
+	return (*v.re).String()
+}
+
+func (v regexpValue) Set(s string) error {
+	if s == "" {
+		*v.re = nil
+		return nil
+	}
+	re, err := regexp.Compile(s)
+	if err != nil {
+		return err
+	}
+	if re.NumSubexp() == 0 {
+		return errors.New("the pattern needs a group capturing the path")
+	}
+	*v.re = re
+	return nil
+}
+
+// pathKeywordsRe is an alternation of "path" and the -path-keywords words.
+func (c *Config) pathKeywordsRe() string {
+	words := []string{"path"}
+	for _, w := range strings.Split(c.PathKeywords, ",") {
+		if w = strings.TrimSpace(w); w != "" {
+			words = append(words, regexp.QuoteMeta(w))
+		}
+	}
+	return strings.Join(words, "|")
+}
+
+// pathHintRes returns the patterns extractPathHint tries, in order.
+func (c *Config) pathHintRes() []*regexp.Regexp {
+	res := []*regexp.Regexp{regexp.MustCompile(`(?i)^\s*(?:(?://|#|--|;|/\*|<!--)\s*@?|@)(?:` +
+		c.pathKeywordsRe() + `)(?:\s*:\s*|\s+)([^\s>*]+)`)}
+	if c.PathPattern != nil {
+		res = append(res, c.PathPattern)
+	}
+	return res
+}
+
+// extractPathHint is the one reader of path hints, used for every code block
+// written to disk. It looks at the first non-blank line of code and accepts
+// "path" (or a -path-keywords word) after //, #, --, ;, /* or <!--, with or
+// without a colon and an @, a bare "@path", and -path-pattern. It returns
+// the path with slashes for separators and the code without that line, or
+// "" and the code unchanged when there is no hint.
+func extractPathHint(cfg *Config, code string) (string, string) {
+	lines := strings.Split(code, "\n")
+	i := 0
+	for i < len(lines)-1 && strings.TrimSpace(lines[i]) == "" {
+		i++
+	}
+	for _, re := range cfg.orDefault().pathHintRes() {
+		if m := re.FindStringSubmatch(lines[i]); len(m) > 1 && strings.TrimSpace(m[1]) != "" {
+			rest := append(lines[:i:i], lines[i+1:]...)
+			return strings.ReplaceAll(strings.TrimSpace(m[1]), `\`, "/"), strings.Join(rest, "\n")
+		}
+	}
+	return "", code
+}
+
+// hintedFile is one file of a code block; path is "" when the block named
+// none.
+type hintedFile struct{ path, body string }
+
+// splitPathHints splits a code block that starts with a path hint at every
+// later unindented line that is only an explicit one: a comment marker, the
+// keyword, a colon and the path, as in "// path: b.go". The looser forms
+// extractPathHint accepts on the first line, like "# path to the file", are
+// ordinary comments further down. Blank lines before the next hint are left
+// out of the file they end. A block without a leading hint is a single
+// unnamed file.
+func splitPathHints(cfg *Config, code string) []hintedFile {
+	cfg = cfg.orDefault()
+	path, rest := extractPathHint(cfg, code)
+	if path == "" {
+		return []hintedFile{{body: code}}
+	}
+	explicit := regexp.MustCompile(`(?i)^(?://|#|--|;|/\*|<!--)\s*(?:` + cfg.pathKeywordsRe() +
+		`)\s*:\s*[^\s>*]+\s*(?:\*/|-->)?\s*$`)
+
+	var files []hintedFile
+	lines := strings.Split(rest, "\n")
+	start := 0
+	for i := 0; i <= len(lines); i++ {
+		if i < len(lines) && !explicit.MatchString(lines[i]) {
+			continue
+		}
+		body := strings.Join(lines[start:i], "\n")
+		if i < len(lines) {
+			body = strings.TrimRight(body, "\n")
+		}
+		files = append(files, hintedFile{path, body})
+		if i < len(lines) {
+			path, _ = extractPathHint(cfg, lines[i])
+			start = i + 1
+		}
+	}
+	return files
+}
@@ -0,0 +1,35 @@
+package src
+
+import "testing"
+
+func TestExtractPathHint(t *testing.T) {
+	tests := []struct{ code, path, rest string }{
+		// Every comment leader, with and without the colon.
+		{"// path: cmd/app/main.go\npackage main", "cmd/app/main.go", "package main"},
+		{"//path:main.go\npackage main", "main.go", "package main"},
+		{"// path main.go\npackage main", "main.go", "package main"},
+		{"# path: app.py\nprint(1)", "app.py", "print(1)"},
+		{"-- path: schema.sql\ncreate table t;", "schema.sql", "create table t;"},
+		{"; path: config.ini\n[core]", "config.ini", "[core]"},
+		{"/* path: style.css */\nbody{}", "style.css", "body{}"},
+		{"<!-- path: index.html -->\n<p>", "index.html", "<p>"},
+		// @path directives, bare and inside a comment.
+		{"@path main.go\npackage main", "main.go", "package main"},
+		{"// @path main.go\npackage main", "main.go", "package main"},
+		{"# @path: tools/run.py\nprint(1)", "tools/run.py", "print(1)"},
+		// Case, indentation, leading blank lines and Windows separators.
+		{"  // PATH: Main.java\nclass Main {}", "Main.java", "class Main {}"},
+		{"\n\n// path: a.go\npackage a", "a.go", "\n\npackage a"},
+		{`// path: pkg\win.go` + "\npackage pkg", "pkg/win.go", "package pkg"},
+		// Not hints.
+		{"// Pathfinding helpers\npackage grid", "", "// Pathfinding helpers\npackage grid"},
+		{"package main\n// path: late.go", "", "package main\n// path: late.go"},
+		{"// file: notes/todo.go\npackage notes", "", "// file: notes/todo.go\npackage notes"},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		if path, rest := extractPathHint(nil, tt.code); path != tt.path || rest != tt.rest {
+			t.Errorf("%q: got %q, %q; want %q, %q", tt.code, path, rest, tt.path, tt.rest)
+		}
+	}
+}
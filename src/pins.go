@@ -0,0 +1,147 @@
+package src
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// pinsPath is where a workspace's pinned files are kept.
+func pinsPath(root string) string {
+	return filepath.Join(root, latticeDir, "pins.json")
+}
+
+// loadPins returns the workspace's pinned files as sorted slash paths. A
+// missing or invalid file yields none.
+func loadPins(root string) []string {
+	var pins []string
+	if data, err := os.ReadFile(pinsPath(root)); err == nil {
+		_ = json.Unmarshal(data, &pins)
+	}
+	sort.Strings(pins)
+	return pins
+}
+
+func savePins(root string, pins []string) error {
+	sort.Strings(pins)
+	path := pinsPath(root)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(pins, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// pinnedSet returns the pinned files of root, keyed by slash path.
+func pinnedSet(root string) map[string]bool {
+	set := map[string]bool{}
+	for _, p := range loadPins(root) {
+		set[p] = true
+	}
+	return set
+}
+
+// withPins moves the pinned files to the front of entries, adding any the
+// workspace walk left out, and returns how many lead. Pins that no longer
+// exist are skipped, and secret files are never sent, pinned or not.
+func withPins(root string, entries []fileEntry) ([]fileEntry, int) {
+	pins := loadPins(root)
+	if len(pins) == 0 {
+		return entries, 0
+	}
+	pinned := map[string]bool{}
+	var out []fileEntry
+	for _, p := range pins {
+		abs := filepath.Join(root, filepath.FromSlash(p))
+		info, err := os.Stat(abs)
+		if err != nil || info.IsDir() || isSecretFile(abs) {
+			continue
+		}
+		pinned[p] = true
+		out = append(out, fileEntry{Rel: filepath.FromSlash(p), Abs: abs, Size: info.Size()})
+	}
+	n := len(out)
+	for _, e := range entries {
+		if !pinned[filepath.ToSlash(e.Rel)] {
+			out = append(out, e)
+		}
+	}
+	return out, n
+}
+
+// withPinnedRels adds the pinned files missing from rels (slash paths), for
+// runs whose context was narrowed with @file directives.
+func withPinnedRels(root string, rels []string) []string {
+	have := map[string]bool{}
+	for _, r := range rels {
+		have[r] = true
+	}
+	for _, p := range loadPins(root) {
+		if !have[p] {
+			rels = append(rels, p)
+		}
+	}
+	return rels
+}
+
+// pinSlashCommand handles "/pin [path]": without a path it lists the pins.
+func pinSlashCommand(m *model, args string) (*model, tea.Cmd) {
+	rel := strings.TrimSpace(args)
+	pins := loadPins(m.working)
+	switch {
+	case rel == "" && len(pins) == 0:
+		m.output += m.style.Subtle.Render("ℹ️ no pinned files; /pin <path> keeps a file in every context\n")
+	case rel == "":
+		m.output += m.style.Accent.Render("📌 pinned files:") + "\n" + strings.Join(pins, "\n") + "\n"
+	default:
+		if p := existingFile(m.working, rel); p == "" {
+			m.output += m.style.Error.Render(fmt.Sprintf("❌ /pin: %s is not a file in the workspace\n", rel))
+		} else if pinnedSet(m.working)[p] {
+			m.output += m.style.Subtle.Render(fmt.Sprintf("ℹ️ %s is already pinned\n", p))
+		} else if err := savePins(m.working, append(pins, p)); err != nil {
+			m.output += m.style.Error.Render(fmt.Sprintf("❌ /pin: %v\n", err))
+		} else {
+			m.output += m.style.Success.Render(fmt.Sprintf("📌 pinned %s\n", p))
+			m.refreshContext()
+			m.refreshFileTree()
+		}
+	}
+	m.renderOutput(true)
+	return m, nil
+}
+
+// unpinSlashCommand handles "/unpin <path>".
+func unpinSlashCommand(m *model, args string) (*model, tea.Cmd) {
+	rel := filepath.ToSlash(filepath.Clean(strings.TrimSpace(args)))
+	pins := loadPins(m.working)
+	kept := pins[:0]
+	for _, p := range pins {
+		if p != rel {
+			kept = append(kept, p)
+		}
+	}
+	switch {
+	case strings.TrimSpace(args) == "":
+		m.output += m.style.Error.Render("❌ usage: /unpin <path>\n")
+	case len(kept) == len(pins):
+		m.output += m.style.Subtle.Render(fmt.Sprintf("ℹ️ %s is not pinned\n", rel))
+	default:
+		if err := savePins(m.working, kept); err != nil {
+			m.output += m.style.Error.Render(fmt.Sprintf("❌ /unpin: %v\n", err))
+		} else {
+			m.output += m.style.Success.Render(fmt.Sprintf("unpinned %s\n", rel))
+			m.refreshContext()
+			m.refreshFileTree()
+		}
+	}
+	m.renderOutput(true)
+	return m, nil
+}
@@ -0,0 +1,70 @@
+package src
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Protocol-Lattice/go-agent/src/models"
+)
+
+func TestPinnedFilesSurviveCaps(t *testing.T) {
+	dir := t.TempDir()
+	writeFixture(t, dir, map[string]string{
+		"a.go":           "package a\n",
+		"b.go":           "package b\n",
+		"zz/iface.go":    "package zz\n\ntype Store interface{}\n",
+		"api/spec.proto": "syntax = \"proto3\";\n",
+		"deploy.pem":     "-----BEGIN KEY-----\n",
+	})
+	if err := savePins(dir, []string{"zz/iface.go", "api/spec.proto", "deploy.pem"}); err != nil {
+		t.Fatal(err)
+	}
+
+	files, entries := collectAttachmentFiles(nil, dir, 1, 1_000_000, 20_000, "")
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Name)
+	}
+	// Both pins count towards the cap of one file, which leaves no room for
+	// unpinned ones; the secret file stays out whatever the pin.
+	if got := strings.Join(names, ","); got != "api/spec.proto,zz/iface.go" {
+		t.Errorf("attachments = %s", got)
+	}
+	if len(entries) != 2 {
+		t.Errorf("entries = %+v", entries)
+	}
+
+	snapshot, n, _ := buildCodebaseContext(nil, dir, 1, 1, 20_000, "")
+	if n != 2 || !strings.Contains(snapshot, "type Store interface{}") || strings.Contains(snapshot, "package a") {
+		t.Errorf("snapshot included %d files:\n%s", n, snapshot)
+	}
+
+	big := []models.File{
+		{Name: "zz/iface.go", Data: []byte(strings.Repeat("x", 400))},
+		{Name: "other.go", Data: []byte(strings.Repeat("y", 400))},
+	}
+	kept, dropped := pruneAttachments("unrelated", big, 100, pinnedSet(dir))
+	if len(kept) != 1 || kept[0].Name != "zz/iface.go" || strings.Join(dropped, ",") != "other.go" {
+		t.Errorf("kept %v, dropped %v", kept, dropped)
+	}
+}
+
+func TestPinCommands(t *testing.T) {
+	dir := t.TempDir()
+	writeFixture(t, dir, map[string]string{"spec.md": "# spec\n"})
+	m := NewModel(context.Background(), nil, dir, nil)
+
+	m.handleSlashCommand("/pin spec.md")
+	m.handleSlashCommand("/pin missing.md")
+	if got := loadPins(dir); len(got) != 1 || got[0] != "spec.md" {
+		t.Fatalf("pins = %q; output:\n%s", got, m.output)
+	}
+	if !strings.Contains(m.output, "missing.md is not a file") {
+		t.Errorf("missing file not reported:\n%s", m.output)
+	}
+	m.handleSlashCommand("/unpin spec.md")
+	if got := loadPins(dir); len(got) != 0 {
+		t.Errorf("pins after unpin = %q", got)
+	}
+}
@@ -0,0 +1,92 @@
+package src
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// planStepSchema documents the shape the planner asks the model for. Decoding
+// is tolerant but every step must end up with a non-empty goal.
+//
+//	[{"name": string (optional), "goal": string (required)}]
+const planStepSchema = `{"type":"array","items":{"type":"object","required":["goal"],"properties":{"name":{"type":"string"},"goal":{"type":"string"}}}}`
+
+// errNotJSON reports that the response is not JSON at all, so callers may fall
+// back to heuristicSplit.
+var errNotJSON = errors.New("planner response is not JSON")
+
+// parsePlanSteps decodes the planner response. Accepted shapes are handled as
+// explicit cases:
+//
+//   - an array of step objects (the schema above)
+//   - a wrapper object {"steps": [...]} or {"plan": [...]}
+//   - an array of goal strings
+//
+// Steps missing a name are named "Step N"; steps missing a goal are reported
+// together in the returned error rather than silently dropped.
+func parsePlanSteps(resp string) ([]PlanStep, error) {
+	raw := bytes.TrimSpace([]byte(resp))
+	if !json.Valid(raw) {
+		return nil, errNotJSON
+	}
+
+	var items []json.RawMessage
+	switch raw[0] {
+	case '[':
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return nil, err
+		}
+	case '{':
+		var wrapper map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &wrapper); err != nil {
+			return nil, err
+		}
+		inner, ok := wrapper["steps"]
+		if !ok {
+			inner, ok = wrapper["plan"]
+		}
+		if !ok {
+			return nil, fmt.Errorf("plan object has no \"steps\" array (schema: %s)", planStepSchema)
+		}
+		if err := json.Unmarshal(inner, &items); err != nil {
+			return nil, fmt.Errorf("plan \"steps\" is not an array: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("plan must be an array of steps (schema: %s)", planStepSchema)
+	}
+	if len(items) == 0 {
+		return nil, errors.New("plan contains no steps")
+	}
+
+	steps := make([]PlanStep, 0, len(items))
+	var problems []string
+	for i, item := range items {
+		var step PlanStep
+		var goal string
+		switch {
+		case json.Unmarshal(item, &goal) == nil:
+			step.Goal = goal
+		case json.Unmarshal(item, &step) == nil:
+		default:
+			problems = append(problems, fmt.Sprintf("step %d: not an object or string", i+1))
+			continue
+		}
+		step.Name = strings.TrimSpace(step.Name)
+		step.Goal = strings.TrimSpace(step.Goal)
+		if step.Goal == "" {
+			problems = append(problems, fmt.Sprintf("step %d: missing \"goal\"", i+1))
+			continue
+		}
+		if step.Name == "" {
+			step.Name = fmt.Sprintf("Step %d", i+1)
+		}
+		steps = append(steps, step)
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid plan: %s", strings.Join(problems, "; "))
+	}
+	return steps, nil
+}
@@ -0,0 +1,81 @@
+package src
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParsePlanStepsAcceptedShapes(t *testing.T) {
+	cases := map[string]string{
+		"objects": `[{"name":"Add loader","goal":"create config.go"},{"goal":"wire it in"}]`,
+		"wrapper": `{"steps":[{"name":"Add loader","goal":"create config.go"},{"goal":"wire it in"}]}`,
+		"strings": `["create config.go","wire it in"]`,
+	}
+	for name, in := range cases {
+		t.Run(name, func(t *testing.T) {
+			steps, err := parsePlanSteps(in)
+			if err != nil {
+				t.Fatalf("parsePlanSteps: %v", err)
+			}
+			if len(steps) != 2 || steps[0].Goal != "create config.go" || steps[1].Goal != "wire it in" {
+				t.Fatalf("steps = %+v", steps)
+			}
+			if steps[1].Name != "Step 2" {
+				t.Errorf("missing name should default to Step 2, got %q", steps[1].Name)
+			}
+		})
+	}
+}
+
+func TestParsePlanStepsRejectsMalformed(t *testing.T) {
+	_, err := parsePlanSteps(`[{"name":"a","goal":"ok"},{"name":"b"},42]`)
+	if err == nil {
+		t.Fatal("expected an error for steps without goals")
+	}
+	for _, want := range []string{`step 2: missing "goal"`, "step 3: not an object or string"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+
+	if _, err := parsePlanSteps("1. add loader\n2. wire it in"); !errors.Is(err, errNotJSON) {
+		t.Errorf("plain text should report errNotJSON, got %v", err)
+	}
+}
+
+func TestRequestPlanRepromptsOnce(t *testing.T) {
+	llm := &stubLLM{replies: []string{
+		`Here is the plan: [{"name": "Step 1", "goal": "add config"},]`,
+		"```json\n[{\"name\":\"Step 1\",\"goal\":\"add config\"},{\"goal\":\"wire it up\"}]\n```",
+	}}
+	var usage usageStats
+	retries := 0
+
+	steps, err := requestPlan(context.Background(), newStubAgent(t, llm), "s", "plan this", &usage, func(error) { retries++ })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if retries != 1 || len(llm.prompts) != 2 {
+		t.Fatalf("retries = %d, model calls = %d", retries, len(llm.prompts))
+	}
+	if !strings.Contains(llm.prompts[1], `"goal": "add config"},]`) {
+		t.Errorf("re-prompt should show the malformed answer:\n%s", llm.prompts[1])
+	}
+	if len(steps) != 2 || steps[1].Name != "Step 2" || steps[1].Goal != "wire it up" {
+		t.Errorf("steps = %+v", steps)
+	}
+	if _, calls, _ := usage.totals(); calls != 2 {
+		t.Errorf("usage recorded %d calls, want 2", calls)
+	}
+}
+
+func TestRequestPlanGivesUpAfterOneRetry(t *testing.T) {
+	llm := &stubLLM{reply: `{"steps": [{"name": "no goal"}]}`}
+
+	_, err := requestPlan(context.Background(), newStubAgent(t, llm), "s", "plan this", &usageStats{}, nil)
+	if err == nil || len(llm.prompts) != 2 {
+		t.Errorf("err = %v after %d calls; want an error after exactly 2", err, len(llm.prompts))
+	}
+}
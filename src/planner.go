@@ -3,11 +3,12 @@ package src
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -19,6 +20,7 @@ type PlanStep struct {
 	Name           string `json:"name"`
 	Goal           string `json:"goal"`
 	PrevRuntimeErr string `json:"prev_runtime_err,omitempty"`
+	Corrective     bool   `json:"-"` // added by the planner to fix a failed check
 }
 
 func safeSend(m *model, line string) {
@@ -32,66 +34,151 @@ func safeSend(m *model, line string) {
 	}
 }
 
-// findMainFile scans recursively for the most likely entrypoint across languages.
-func findMainFile(root string) (string, string) {
-	candidates := map[string][]string{
-		"go":         {"main.go"},
-		"python":     {"app.py", "main.py"},
-		"javascript": {"index.js", "main.js"},
-		"typescript": {"index.ts", "main.ts", "index.tsx"},
-		"rust":       {"main.rs"},
-		"java":       {"Main.java"},
-		"c":          {"main.c"},
-		"cpp":        {"main.cpp", "main.cc", "main.cxx"},
-		"ruby":       {"main.rb", "app.rb"},
-		"php":        {"index.php", "main.php"},
-		"perl":       {"main.pl"},
-		"r":          {"main.R", "script.R"},
-		"lua":        {"main.lua", "app.lua"},
-		"bash":       {"run.sh", "main.sh"},
-		"shell":      {"run.sh", "main.sh"},
-		"kotlin":     {"Main.kt", "main.kts"},
-		"scala":      {"Main.scala", "App.scala"},
-		"swift":      {"main.swift"},
-		"dart":       {"main.dart"},
+// entrypoint is a candidate for findMainFile: files matching pattern are
+// run as lang.
+type entrypoint struct {
+	lang, pattern string
+}
+
+// defaultEntrypoints are the built-in candidates, most specific first where
+// a language has several.
+var defaultEntrypoints = []entrypoint{
+	{"go", "cmd/*/main.go"}, {"go", "main.go"},
+	{"python", "app.py"}, {"python", "main.py"}, {"python", "server.py"}, {"python", "__main__.py"},
+	{"javascript", "index.js"}, {"javascript", "main.js"}, {"javascript", "server.js"}, {"javascript", "bin/www"},
+	{"typescript", "index.ts"}, {"typescript", "main.ts"}, {"typescript", "index.tsx"},
+	{"rust", "main.rs"},
+	{"java", "Main.java"},
+	{"c", "main.c"},
+	{"cpp", "main.cpp"}, {"cpp", "main.cc"}, {"cpp", "main.cxx"},
+	{"ruby", "main.rb"}, {"ruby", "app.rb"},
+	{"php", "index.php"}, {"php", "main.php"},
+	{"perl", "main.pl"},
+	{"r", "main.R"}, {"r", "script.R"},
+	{"lua", "main.lua"}, {"lua", "app.lua"},
+	{"bash", "run.sh"}, {"bash", "main.sh"},
+	{"kotlin", "Main.kt"}, {"kotlin", "main.kts"},
+	{"scala", "Main.scala"}, {"scala", "App.scala"},
+	{"swift", "main.swift"},
+	{"dart", "main.dart"},
+}
+
+// parseEntrypoints parses an -entrypoints spec, skipping malformed pairs.
+func parseEntrypoints(spec string) []entrypoint {
+	var out []entrypoint
+	for _, pair := range strings.Split(spec, ",") {
+		lang, pattern, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		lang, pattern = strings.TrimSpace(lang), strings.TrimSpace(pattern)
+		if !ok || lang == "" || pattern == "" {
+			if pair = strings.TrimSpace(pair); pair != "" {
+				sessionLog("").Warn("ignoring malformed -entrypoints pair", "pair", pair)
+			}
+			continue
+		}
+		out = append(out, entrypoint{strings.ToLower(lang), filepath.ToSlash(pattern)})
 	}
+	return out
+}
 
-	var foundPath, lang string
-	filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
-		if err != nil || d.IsDir() {
+// matches reports whether rel (a slash path) is matched by the entrypoint's
+// pattern: as a whole path when the pattern has a "/", otherwise by file
+// name. Names compare case-insensitively.
+func (e entrypoint) matches(rel string) bool {
+	target := rel
+	if !strings.Contains(e.pattern, "/") {
+		target = path.Base(rel)
+	}
+	ok, _ := path.Match(strings.ToLower(e.pattern), strings.ToLower(target))
+	return ok
+}
+
+// findMainFile returns the most likely entrypoint of the workspace and its
+// language, or "" when nothing matches. Configured candidates win over the
+// built-in ones; after that shallower files win, then earlier candidates.
+// Ignored directories such as node_modules are not searched. entrypoints is
+// the -entrypoints spec of configured candidates.
+func findMainFile(root, entrypoints string) (string, string) {
+	candidates := append(parseEntrypoints(entrypoints), defaultEntrypoints...)
+	configured := len(candidates) - len(defaultEntrypoints)
+
+	type match struct {
+		rel, lang         string
+		group, depth, idx int
+	}
+	var best *match
+	_ = filepath.WalkDir(root, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
 			return nil
 		}
-		name := strings.ToLower(filepath.Base(path))
-		for l, patterns := range candidates {
-			for _, p := range patterns {
-				if name == p {
-					rel, err := filepath.Rel(root, path)
-					if err != nil {
-						log.Printf("failed to make relative path: %v", err)
-					}
-					path = rel
-					foundPath, lang = path, l
-					return filepath.SkipDir
-				}
+		if d.IsDir() {
+			if p != root && isIgnoredDir(d.Name()) {
+				return filepath.SkipDir
 			}
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		for i, c := range candidates {
+			if !c.matches(rel) {
+				continue
+			}
+			m := match{rel: rel, lang: c.lang, depth: strings.Count(rel, "/"), idx: i}
+			if i >= configured {
+				m.group = 1
+			}
+			if best == nil || m.group < best.group ||
+				m.group == best.group && (m.depth < best.depth || m.depth == best.depth && m.idx < best.idx) {
+				best = &m
+			}
+			break // later candidates rank lower for this file
 		}
 		return nil
 	})
-	return foundPath, lang
+	if best == nil {
+		return "", ""
+	}
+	return filepath.FromSlash(best.rel), best.lang
 }
 
 // RunPlanner executes each planned step sequentially,
-// appending previous runtime errors to subsequent steps.
-// RunPlanner executes each planned step sequentially,
-// appending previous runtime errors to subsequent steps.
+// appending previous runtime errors to subsequent steps. Each step is
+// verified by running the session's /target command when one is set, the
+// test suite with -run-tests, and otherwise the entrypoint found by
+// findMainFile.
 func RunPlanner(ctx context.Context, ag *agent.Agent, workspace, userPrompt string, m *model) {
+	target := m.runTarget
 	go func() {
 		defer close(m.plannerQueue)
+		err := runPlan(ctx, ag, workspace, userPrompt, m, target)
+		if m.Program != nil {
+			m.Program.Send(stepBuildCompleteMsg{err: err})
+		}
+	}()
+}
+
+// runPlan is the body of RunPlanner: it plans userPrompt, runs and verifies
+// each step, and returns the first step's error that remained. Progress
+// goes to the transcript through safeSend and to m.events.
+func runPlan(ctx context.Context, ag *agent.Agent, workspace, userPrompt string, m *model, target string) (finalErr error) {
+	events, cfg := m.events, m.cfg
+	defer func() {
+		e := Event{Type: "complete"}
+		if finalErr != nil {
+			e.Error = finalErr.Error()
+		}
+		events.Emit(e)
+	}()
 
-		start := time.Now()
-		userPrompt = strings.TrimSpace(userPrompt)
+	start := time.Now()
+	userPrompt, selected := parseFileDirectives(strings.TrimSpace(userPrompt))
+	ctx = withSession(ctx, m.sessionID)
+	log := sessionLog(m.sessionID)
+	log.Info("planner started", "workspace", workspace)
 
-		metaPrompt := fmt.Sprintf(`You are a software engineer. The user has a goal that requires code changes.
+	metaPrompt := conventionsBlock(workspace) + fmt.Sprintf(`You are a software engineer. The user has a goal that requires code changes.
 
 Break the goal into 2–4 concrete, immediately executable steps. 
 Respond with ONLY a JSON array of {"name", "goal"} objects — no explanations, no planning meta-text.
@@ -102,140 +189,255 @@ Example:
 
 User goal:
 %s`, userPrompt)
+	if lang := cfg.contextLanguage(); lang != "" {
+		metaPrompt += fmt.Sprintf("\n\nThe project is written in %s; plan the changes in those languages.", strings.ReplaceAll(lang, ",", ", "))
+	}
 
-		resp, err := ag.Generate(ctx, m.sessionID, metaPrompt)
-		if err != nil {
-			safeSend(m, fmt.Sprintf("❌ planner failed: %v\n", err))
-			m.Program.Send(stepBuildCompleteMsg{err: err})
-			return
-		}
-
-		resp = strings.TrimSpace(resp)
-		if strings.HasPrefix(resp, "```") && strings.HasSuffix(resp, "```") {
-			resp = strings.TrimSuffix(resp, "```")
-			resp = resp[strings.Index(resp, "\n")+1:]
-		}
-
-		var steps []PlanStep
-		if err := json.Unmarshal([]byte(resp), &steps); err != nil || len(steps) == 0 {
-			steps = heuristicSplit(resp)
-		}
-		if len(steps) == 0 {
-			safeSend(m, "❌ no valid steps parsed\n")
-			m.Program.Send(stepBuildCompleteMsg{err: fmt.Errorf("no steps parsed")})
-			return
-		}
-
-		if len(steps) > 5 {
-			steps = steps[:5]
-		}
+	steps, err := requestPlan(ctx, ag, m.sessionID, metaPrompt, &m.usage, func(perr error) {
+		log.Warn("plan unparseable, re-prompting", "err", perr)
+		safeSend(m, "🔁 Plan was not valid JSON, asking the model to restate it…\n")
+	})
+	if err != nil {
+		log.Error("planner failed", "err", err)
+		safeSend(m, fmt.Sprintf("❌ planner failed: %v\n", err))
+		return err
+	}
+	if len(steps) == 0 {
+		log.Error("no valid steps parsed")
+		safeSend(m, "❌ no valid steps parsed\n")
+		return fmt.Errorf("no steps parsed")
+	}
 
-		safeSend(m, fmt.Sprintf("🧭 Plan created with %d steps.\n", len(steps)))
+	if cfg.MaxSteps > 0 && len(steps) > cfg.MaxSteps {
+		steps = steps[:cfg.MaxSteps]
+	}
 
-		for i := range steps {
-			step := &steps[i]
+	log.Info("plan created", "steps", len(steps))
+	safeSend(m, fmt.Sprintf("🧭 Plan created with %d steps.\n", len(steps)))
+	plan := make([]string, len(steps))
+	for i, s := range steps {
+		plan[i] = s.Name
+	}
+	events.Emit(Event{Type: "plan-created", Steps: len(steps), Plan: plan})
 
-			if step.PrevRuntimeErr != "" {
-				step.Goal += fmt.Sprintf("\n\n⚠️ Previous runtime error:\n%s\nPlease fix this issue in this step.", step.PrevRuntimeErr)
-			}
+	corrected := false // at most one extra step is added to fix build errors
+	var held []string  // writes awaiting confirmation
+	for i := 0; i < len(steps); i++ {
+		step := &steps[i]
 
-			safeSend(m, fmt.Sprintf("\n⚙️ Step %d/%d — %s\n", i+1, len(steps), step.Goal))
+		if step.PrevRuntimeErr != "" {
+			step.Goal += fmt.Sprintf("\n\n⚠️ Previous runtime error:\n%s\nPlease fix this issue in this step.", step.PrevRuntimeErr)
+		}
 
-			headlessRes, err := RunHeadless(ctx, ag, workspace, step.Goal)
-			if err != nil {
-				step.PrevRuntimeErr = fmt.Sprintf("❌ Step failed to generate: %v", err)
-				safeSend(m, step.PrevRuntimeErr+"\n")
-				continue
+		safeSend(m, fmt.Sprintf("\n⚙️ Step %d/%d — %s\n", i+1, len(steps), step.Goal))
+		m.reportStatus(fmt.Sprintf("step %d/%d: %s", i+1, len(steps), step.Name))
+		log.Info("step started", "step", i+1, "name", step.Name)
+		events.Emit(Event{Type: "step-started", Step: i + 1, Steps: len(steps), Name: step.Name})
+
+		headlessRes, err := RunHeadless(ctx, ag, workspace, step.Goal+fileDirectives(selected), HeadlessOptions{Config: cfg, Session: m.sessionID, TaskOnly: step.Corrective, OnAction: func(a FileAction) {
+			switch a.Action {
+			case "saved":
+				m.reportStatus("writing " + a.Path)
+				safeSend(m, fmt.Sprintf("✍️ %s (%s)\n", a.Path, a.Message))
+				events.Emit(Event{Type: "file-saved", Step: i + 1, Path: a.Path, Status: a.Message})
+			case "pending":
+				held = append(held, a.Path)
+				events.Emit(Event{Type: "file-saved", Step: i + 1, Path: a.Path, Status: "pending", Error: a.Message})
+			case "error":
+				safeSend(m, fmt.Sprintf("❌ %s: %s\n", a.Path, a.Message))
+				events.Emit(Event{Type: "file-saved", Step: i + 1, Path: a.Path, Status: "error", Error: a.Message})
+			case "progress":
+				m.reportStatus(a.Message)
+				safeSend(m, a.Message+"\n")
 			}
+		}})
+		if err != nil {
+			log.Error("step generation failed", "step", i+1, "err", err)
+			step.PrevRuntimeErr = fmt.Sprintf("❌ Step failed to generate: %v", err)
+			safeSend(m, step.PrevRuntimeErr+"\n")
+			continue
+		}
 
-			logStepDiff(m, step.Name, headlessRes.Actions)
+		m.usage.add(headlessRes.Usage)
+		headlessRes.Actions = append(headlessRes.Actions, CommitRun(ctx, cfg, workspace, step.Name+"\n\n"+step.Goal, headlessRes.Actions)...)
+		logStepDiff(m, step.Name, headlessRes.Actions)
+		m.requestConfirmation(headlessRes.Actions)
 
-			// Refresh UI context after file modifications
-			m.refreshContext()
+		// Refresh UI context after file modifications
+		if m.Program != nil {
+			m.Program.Send(contextChangedMsg{})
+		}
 
-			entryPath, lang := findMainFile(workspace)
-			if entryPath == "" {
-				safeSend(m, fmt.Sprintf("ℹ️ No main file found for step %s\n", step.Name))
-				step.PrevRuntimeErr = ""
+		if cfg.Vet && touchesGo(headlessRes.Actions) {
+			m.reportStatus("running go build and vet")
+			if diag := goCheck(ctx, workspace); diag != "" {
+				log.Warn("static checks failed", "step", i+1)
+				step.PrevRuntimeErr = "❌ go build/vet failed:\n" + TailBytes(diag, 4000)
+				safeSend(m, step.PrevRuntimeErr+"\n")
+				events.Emit(Event{Type: "run-result", Step: i + 1, Command: "go build && go vet", Output: TailBytes(diag, 4000), Error: "go build/vet failed"})
+				switch {
+				case !corrected:
+					// Fix the build before anything else is built on it,
+					// with the errors pinned to the files they are in.
+					corrected = true
+					goal, failing := compileFixPrompt(workspace, diag)
+					steps = slices.Insert(steps, i+1, PlanStep{
+						Name:       "Fix build errors",
+						Goal:       goal + fileDirectives(failing),
+						Corrective: true,
+					})
+				case i+1 < len(steps):
+					steps[i+1].PrevRuntimeErr = step.PrevRuntimeErr
+				}
 				continue
 			}
+		}
 
-			args := map[string]any{
-				"language": lang,
-				"path":     workspace,
-				"file":     entryPath,
-				"timeout":  15, // seconds
+		if target != "" {
+			m.reportStatus("running " + target)
+			report, out, failure := runTargetStep(ctx, workspace, target, false)
+			if failure != "" {
+				log.Warn("run target failed", "step", i+1, "target", target)
 			}
-
-			if ag.UTCPClient == nil {
-				msg := "❌ UTCP client not available"
-				safeSend(m, msg+"\n")
-				step.PrevRuntimeErr = msg
-				continue
+			safeSend(m, report)
+			events.Emit(runResult(i+1, target, out, failure))
+			step.PrevRuntimeErr = failure
+			if i+1 < len(steps) {
+				steps[i+1].PrevRuntimeErr = failure
 			}
+			continue
+		}
 
-			tools, err := ag.UTCPClient.SearchTools("", 5)
-			if err != nil {
-				msg := fmt.Sprintf("❌ Tool search error: %v", err)
-				safeSend(m, msg+"\n")
-				step.PrevRuntimeErr = msg
-				continue
+		if testCmd, ok := detectTestCommand(workspace, cfg.Entrypoints); ok && cfg.RunTests {
+			m.reportStatus("running " + testCmd)
+			report, out, failure := runTargetStep(ctx, workspace, testCmd, true)
+			safeSend(m, report)
+			events.Emit(runResult(i+1, testCmd, out, failure))
+			step.PrevRuntimeErr = failure
+			if failure != "" {
+				log.Warn("tests failed", "step", i+1)
 			}
-			if len(tools) == 0 {
-				msg := "❌ No UTCP tools available"
-				safeSend(m, msg+"\n")
-				step.PrevRuntimeErr = msg
-				continue
+			switch {
+			case failure == "":
+			case i+1 < len(steps):
+				steps[i+1].PrevRuntimeErr = failure
+			case !corrected:
+				corrected = true
+				steps = append(steps, PlanStep{
+					Name:           "Fix failing tests",
+					Goal:           "Fix the code so the project's test suite passes.",
+					PrevRuntimeErr: failure,
+					Corrective:     true,
+				})
 			}
+			continue
+		}
 
-			// --- Non-blocking UTCP call with timeout ---
-			callCtx, cancel := context.WithTimeout(ctx, 20*time.Second)
-			resCh := make(chan any, 1)
-			errCh := make(chan error, 1)
-
-			go func() {
-				defer func() { _ = recover() }()
-				res, err := ag.UTCPClient.CallTool(callCtx, tools[0].Name, args)
-				if err != nil {
-					errCh <- err
-					return
-				}
-				resCh <- res
-			}()
-
-			select {
-			case res := <-resCh:
-				out := fmt.Sprintf("🧪 Run result (%s):\n%s\n", filepath.Base(entryPath), res)
-				safeSend(m, out)
-				step.PrevRuntimeErr = ""
-			case err := <-errCh:
-				msg := fmt.Sprintf("❌ Runtime error (%s): %v", filepath.Base(entryPath), err)
-				safeSend(m, msg+"\n")
-				step.PrevRuntimeErr = msg
-			case <-callCtx.Done():
-				safeSend(m, "🧪 Runtime: Program run succesfully"+"\n")
-			}
-			cancel()
+		entryPath, lang := findMainFile(workspace, cfg.Entrypoints)
+		if entryPath == "" {
+			safeSend(m, fmt.Sprintf("ℹ️ No main file found for step %s\n", step.Name))
+			step.PrevRuntimeErr = ""
+			continue
+		}
 
-			if i+1 < len(steps) {
-				steps[i+1].PrevRuntimeErr = step.PrevRuntimeErr
-			}
+		args := map[string]any{
+			"language": lang,
+			"path":     workspace,
+			"file":     entryPath,
+			"timeout":  15, // seconds
+		}
+
+		if ag.UTCPClient == nil {
+			msg := "❌ UTCP client not available"
+			safeSend(m, msg+"\n")
+			step.PrevRuntimeErr = msg
+			events.Emit(runResult(i+1, filepath.Base(entryPath), "", msg))
+			continue
+		}
+
+		tools, err := ag.UTCPClient.SearchTools("", 5)
+		if err != nil {
+			msg := fmt.Sprintf("❌ Tool search error: %v", err)
+			safeSend(m, msg+"\n")
+			step.PrevRuntimeErr = msg
+			events.Emit(runResult(i+1, filepath.Base(entryPath), "", msg))
+			continue
+		}
+		if len(tools) == 0 {
+			msg := "❌ No UTCP tools available"
+			safeSend(m, msg+"\n")
+			step.PrevRuntimeErr = msg
+			events.Emit(runResult(i+1, filepath.Base(entryPath), "", msg))
+			continue
 		}
 
-		var finalErr error
-		for _, step := range steps {
-			if step.PrevRuntimeErr != "" {
-				finalErr = fmt.Errorf("planner completed with errors in step '%s': %s", step.Name, step.PrevRuntimeErr)
-				break
+		m.reportStatus("running " + filepath.Base(entryPath))
+		// --- Non-blocking UTCP call with timeout ---
+		callCtx, cancel := context.WithTimeout(ctx, 20*time.Second)
+		resCh := make(chan any, 1)
+		errCh := make(chan error, 1)
+
+		go func() {
+			defer func() { _ = recover() }()
+			res, err := ag.UTCPClient.CallTool(callCtx, tools[0].Name, args)
+			if err != nil {
+				errCh <- err
+				return
 			}
+			resCh <- res
+		}()
+
+		select {
+		case res := <-resCh:
+			out := fmt.Sprintf("🧪 Run result (%s):\n%s\n", filepath.Base(entryPath), res)
+			safeSend(m, out)
+			step.PrevRuntimeErr = ""
+			events.Emit(runResult(i+1, filepath.Base(entryPath), fmt.Sprint(res), ""))
+		case err := <-errCh:
+			log.Warn("runtime error", "step", i+1, "file", entryPath, "err", err)
+			msg := fmt.Sprintf("❌ Runtime error (%s): %v", filepath.Base(entryPath), err)
+			safeSend(m, msg+"\n")
+			step.PrevRuntimeErr = msg
+			events.Emit(runResult(i+1, filepath.Base(entryPath), "", msg))
+		case <-callCtx.Done():
+			safeSend(m, "🧪 Runtime: Program run succesfully"+"\n")
 		}
+		cancel()
 
-		if m.Program != nil {
-			m.Program.Send(stepBuildCompleteMsg{err: finalErr})
+		if i+1 < len(steps) {
+			steps[i+1].PrevRuntimeErr = step.PrevRuntimeErr
 		}
+	}
 
-		safeSend(m, fmt.Sprintf("\n✅ Planner finished in %s\n", time.Since(start).Round(time.Second)))
-	}()
+	for _, step := range steps {
+		if step.PrevRuntimeErr != "" {
+			finalErr = fmt.Errorf("planner completed with errors in step '%s': %s", step.Name, step.PrevRuntimeErr)
+			break
+		}
+	}
+	// Without the TUI there is no one to confirm held writes, so the run
+	// did not do all it was asked.
+	if finalErr == nil && m.Program == nil && len(held) > 0 && !cfg.Force {
+		finalErr = fmt.Errorf("%d write(s) were held for confirmation and not applied: %s (use -force to write them)", len(held), strings.Join(held, ", "))
+	}
+
+	if finalErr != nil {
+		log.Warn("planner finished with errors", "duration", time.Since(start), "err", finalErr)
+	} else {
+		log.Info("planner finished", "duration", time.Since(start))
+	}
+	safeSend(m, fmt.Sprintf("\n✅ Planner finished in %s\n", time.Since(start).Round(time.Second)))
+	return finalErr
+}
+
+// runResult is the run-result event for a step's verify command; failure
+// is "" when it passed.
+func runResult(step int, command, output, failure string) Event {
+	e := Event{Type: "run-result", Step: step, Command: command, Output: TailBytes(output, 4000)}
+	if failure != "" {
+		e.Output, e.Error = "", failure
+	}
+	return e
 }
 
 // path: src/planner.go
@@ -259,9 +461,21 @@ func logStepDiff(m *model, stepName string, actions []FileAction) {
 		case "deleted", "removed":
 			safeSend(m, fmt.Sprintf("🧹 %s %s\n", strings.Title(act.Action), act.Path))
 
+		case "pending":
+			safeSend(m, fmt.Sprintf("⏸ %s (%s)\n", act.Path, act.Message))
+
 		case "error":
 			safeSend(m, fmt.Sprintf("❌ %s: %s\n", act.Path, act.Message))
 
+		case "formatted":
+			safeSend(m, fmt.Sprintf("🎨 %s (%s)\n", act.Path, act.Message))
+
+		case "normalized":
+			safeSend(m, fmt.Sprintf("🔧 %s (%s)\n", act.Path, act.Message))
+
+		case "tidied":
+			safeSend(m, fmt.Sprintf("📦 %s (%s)\n", act.Path, act.Message))
+
 		case "info":
 			safeSend(m, fmt.Sprintf("ℹ️ %s\n", act.Message))
 
@@ -271,6 +485,48 @@ func logStepDiff(m *model, stepName string, actions []FileAction) {
 	}
 }
 
+// trimFence strips a code fence wrapping the whole response.
+func trimFence(resp string) string {
+	resp = strings.TrimSpace(resp)
+	if strings.HasPrefix(resp, "```") && strings.HasSuffix(resp, "```") {
+		resp = strings.TrimSuffix(resp, "```")
+		resp = resp[strings.Index(resp, "\n")+1:]
+	}
+	return resp
+}
+
+// requestPlan asks the model for a plan. A response that does not parse gets
+// exactly one re-prompt showing the model its output; onRetry, if non-nil, is
+// told why. When neither answer is JSON the first one is split heuristically.
+func requestPlan(ctx context.Context, ag *agent.Agent, session, metaPrompt string, usage *usageStats, onRetry func(error)) ([]PlanStep, error) {
+	resp, err := ag.Generate(ctx, session, metaPrompt)
+	usage.add(estimateUsage(metaPrompt, nil, resp))
+	if err != nil {
+		return nil, err
+	}
+	steps, perr := parsePlanSteps(trimFence(resp))
+	if perr == nil {
+		return steps, nil
+	}
+
+	if onRetry != nil {
+		onRetry(perr)
+	}
+	repair := fmt.Sprintf(planRepairPrompt, perr, resp)
+	fixed, err := ag.Generate(ctx, session, repair)
+	usage.add(estimateUsage(repair, nil, fixed))
+	if err == nil {
+		steps, err = parsePlanSteps(trimFence(fixed))
+		if !errors.Is(err, errNotJSON) {
+			return steps, err
+		}
+	}
+	if errors.Is(perr, errNotJSON) {
+		return heuristicSplit(trimFence(resp)), nil
+	}
+	return nil, perr
+}
+
 // heuristicSplit fallback for non-JSON planner output.
 func heuristicSplit(s string) []PlanStep {
 	lines := strings.Split(s, "\n")
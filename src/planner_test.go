@@ -0,0 +1,153 @@
+package src
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindMainFile(t *testing.T) {
+	cases := []struct {
+		name        string
+		files       []string
+		entrypoints string
+		want, lang  string
+	}{
+		{"cmd layout", []string{"go.mod", "internal/gen/main.go", "cmd/app/main.go"}, "", "cmd/app/main.go", "go"},
+		{"root main wins", []string{"main.go", "cmd/tool/main.go"}, "", "main.go", "go"},
+		{"node_modules ignored", []string{"node_modules/x/index.js", "src/main.ts"}, "", "src/main.ts", "typescript"},
+		{"case-insensitive names", []string{"src/Main.java"}, "", "src/Main.java", "java"},
+		{"configured first", []string{"main.py", "services/api.py"}, "python:services/api.py", "services/api.py", "python"},
+		{"configured by name", []string{"index.js", "bin/serve"}, " javascript : serve , bogus", "bin/serve", "javascript"},
+		{"nothing to run", []string{"README.md"}, "", "", ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			files := map[string]string{}
+			for _, f := range tc.files {
+				files[f] = "x\n"
+			}
+			writeFixture(t, dir, files)
+			got, lang := findMainFile(dir, tc.entrypoints)
+			if filepath.ToSlash(got) != tc.want || lang != tc.lang {
+				t.Errorf("findMainFile = %q (%s), want %q (%s)", got, lang, tc.want, tc.lang)
+			}
+		})
+	}
+}
+
+func TestRunPlannerVerifiesWithRunTarget(t *testing.T) {
+	GlobalChanges = NewChangeTracker()
+	root := t.TempDir()
+	writeFixture(t, root, map[string]string{"main.go": "package main\n\nfunc main() {}\n"})
+	llm := &stubLLM{replies: []string{
+		`[{"name":"Step 1: Add notes","goal":"Create notes.txt"}]`,
+		"```text notes.txt\nhello\n```\n",
+	}}
+	m := NewModel(context.Background(), newStubAgent(t, llm), root, nil)
+	m.plannerQueue = make(chan string, 64)
+	m.runTarget = "echo verified > marker.txt && echo target ran"
+
+	RunPlanner(context.Background(), m.agent, root, "add notes", m)
+	var out strings.Builder
+	for line := range m.plannerQueue {
+		out.WriteString(line)
+	}
+
+	if _, err := os.Stat(filepath.Join(root, "marker.txt")); err != nil {
+		t.Fatalf("run target was not run: %v\n%s", err, out.String())
+	}
+	if !strings.Contains(out.String(), "target ran") || strings.Contains(out.String(), "No main file") {
+		t.Errorf("planner output:\n%s", out.String())
+	}
+
+	m.plannerQueue = make(chan string, 64)
+	m.runTarget = "echo boom >&2; exit 3"
+	llm.replies = []string{`[{"name":"Step 1: Touch","goal":"Touch notes"}]`, "nothing to change"}
+	RunPlanner(context.Background(), m.agent, root, "touch", m)
+	out.Reset()
+	for line := range m.plannerQueue {
+		out.WriteString(line)
+	}
+	if !strings.Contains(out.String(), "Runtime error (echo boom >&2; exit 3)") || !strings.Contains(out.String(), "boom") {
+		t.Errorf("failing target should be reported:\n%s", out.String())
+	}
+}
+
+func TestRunPlannerFeedsFailingTestsIntoCorrectiveStep(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not installed")
+	}
+	GlobalChanges = NewChangeTracker()
+
+	root := t.TempDir()
+	writeFixture(t, root, map[string]string{
+		"go.mod":      "module example.com/calc\n\ngo 1.21\n",
+		"add.go":      "package calc\n\nfunc Add(a, b int) int { return a - b }\n",
+		"add_test.go": "package calc\n\nimport \"testing\"\n\nfunc TestAdd(t *testing.T) {\n\tif got := Add(2, 3); got != 5 {\n\t\tt.Errorf(\"Add(2, 3) = %d, want 5\", got)\n\t}\n}\n",
+	})
+	llm := &stubLLM{replies: []string{`[{"name":"Step 1: Review","goal":"Review calc"}]`}, reply: "nothing to change"}
+	cfg := DefaultConfig()
+	cfg.RunTests = true
+	m := NewModel(context.Background(), newStubAgent(t, llm), root, cfg)
+	m.plannerQueue = make(chan string, 64)
+
+	RunPlanner(context.Background(), m.agent, root, "review calc", m)
+	var out strings.Builder
+	for line := range m.plannerQueue {
+		out.WriteString(line)
+	}
+
+	last := llm.prompts[len(llm.prompts)-1]
+	if !strings.Contains(last, "test suite passes") {
+		t.Fatalf("no corrective step was run; prompts: %d\n%s", len(llm.prompts), out.String())
+	}
+	if !strings.Contains(last, "Tests failed (go test ./...)") || !strings.Contains(last, "Add(2, 3) = -1, want 5") {
+		t.Errorf("failing test output missing from the corrective step:\n%s", last)
+	}
+	if strings.Contains(currentMessage(last), "docker-compose") || !strings.Contains(currentMessage(llm.prompts[1]), "docker-compose") {
+		t.Errorf("only planned steps should ask for a docker-compose.yml; fix step:\n%s", last)
+	}
+}
+
+func TestRunPlannerBuildFixStepAsksOnlyForTheFix(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not installed")
+	}
+	GlobalChanges = NewChangeTracker()
+	root := t.TempDir()
+	writeFixture(t, root, map[string]string{"go.mod": "module example.com/calc\n\ngo 1.21\n"})
+	llm := &stubLLM{replies: []string{
+		`[{"name":"Step 1: Add","goal":"Create add.go"}]`,
+		"```go\n// path: add.go\npackage calc\n\nfunc Add(a, b int) int { return a + c }\n```\n",
+	}, reply: "```go\n// path: add.go\npackage calc\n\nfunc Add(a, b int) int { return a + b }\n```\n"}
+	cfg := DefaultConfig()
+	cfg.Vet = true
+	m := NewModel(context.Background(), newStubAgent(t, llm), root, cfg)
+	m.plannerQueue = make(chan string, 64)
+
+	RunPlanner(context.Background(), m.agent, root, "add numbers", m)
+	for range m.plannerQueue {
+	}
+
+	last := llm.prompts[len(llm.prompts)-1]
+	if !strings.Contains(last, "undefined: c") {
+		t.Fatalf("no build fix step was run; last prompt:\n%s", last)
+	}
+	if strings.Contains(currentMessage(last), "docker-compose") {
+		t.Errorf("the fix step asks for an extra file:\n%s", last)
+	}
+}
+
+// currentMessage is the turn's own message in a prompt the agent built,
+// without the earlier turns it recalls.
+func currentMessage(prompt string) string {
+	if i := strings.LastIndex(prompt, "Current user message:"); i >= 0 {
+		return prompt[i:]
+	}
+	return prompt
+}
@@ -40,3 +40,44 @@ const VibeSystemPrompt = "You are an expert software engineer and a world-class
 	"	// ... test cases ...\n" +
 	"}\n" +
 	"```"
+
+// explainPrompt frames read-only questions for the explain agent. The first
+// %s is the scope header (file tree or focused file), the second the question.
+const explainPrompt = `You are explaining existing code; you must not change it.
+Answer in clear prose with short references to files, functions and line ranges.
+Do not output complete files and do not include "path:" comments; short inline
+snippets are fine when they help the explanation.
+
+%s
+
+Question:
+%s`
+
+// refactorPrompt frames a /refactor goal. %s is the goal.
+const refactorPrompt = `Refactor the code to achieve the goal below without changing its observable behavior.
+Output every file you change in full, each in its own code block starting with a "path:" comment.
+Leave files you do not change out of the response.
+
+Refactoring goal:
+%s`
+
+// planRepairPrompt re-asks for a plan the model returned in an unparseable
+// form. The first %v is the parse error, the second %s the previous answer.
+const planRepairPrompt = `Your previous answer could not be parsed as a plan (%v):
+
+%s
+
+Return the same plan as valid JSON only: an array of {"name", "goal"} objects,
+with no prose and no code fence.`
+
+// buildFixPrompt asks for the smallest change that makes a Go module build
+// and vet cleanly again. %s is the diagnostics, grouped by file with each
+// offending line quoted (see compileFixPrompt).
+const buildFixPrompt = `The Go code no longer builds. Fix exactly these errors and nothing else:
+
+%s
+
+Make the smallest change that fixes them: touch only the lines involved, and keep
+names, structure and behavior as they are. Do not rewrite or reorganize code that
+works. Output only the files you change, each in full in its own code block
+starting with a "path:" comment.`
@@ -0,0 +1,150 @@
+package src
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/universal-tool-calling-protocol/go-utcp/src/tools"
+)
+
+// configuredProviders holds the provider names the last BuildUTCP call was
+// asked to register, whether or not registration succeeded. The client only
+// knows about providers that registered, so this is what lets /providers
+// report the ones that failed.
+var configuredProviders []string
+
+// providerNames lists the providers named in a providers file (an array, a
+// {"providers": ...} object, or a single provider) plus extra.
+func providerNames(path string, extra []Provider) []string {
+	var names []string
+	if path != "" {
+		if data, err := os.ReadFile(path); err == nil {
+			names = append(names, providerNamesJSON(data)...)
+		}
+	}
+	for _, p := range extra {
+		names = append(names, providerName(p))
+	}
+	return names
+}
+
+func providerNamesJSON(data []byte) []string {
+	var root any
+	if json.Unmarshal(data, &root) != nil {
+		return nil
+	}
+	if obj, ok := root.(map[string]any); ok {
+		if provs, ok := obj["providers"]; ok {
+			root = provs
+		}
+	}
+	var list []any
+	switch v := root.(type) {
+	case []any:
+		list = v
+	case map[string]any:
+		list = []any{v}
+	}
+	var names []string
+	for _, item := range list {
+		if obj, ok := item.(map[string]any); ok {
+			if name, ok := obj["name"].(string); ok && name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// providerName returns p's name via its JSON form, which every provider type
+// shares through base.BaseProvider.
+func providerName(p Provider) string {
+	var named struct {
+		Name string `json:"name"`
+	}
+	if data, err := json.Marshal(p); err == nil {
+		_ = json.Unmarshal(data, &named)
+	}
+	return named.Name
+}
+
+// toolSearcher is the part of the UTCP client the provider probe needs.
+type toolSearcher interface {
+	SearchTools(query string, limit int) ([]tools.Tool, error)
+}
+
+// providerHealth is the probe result for one provider.
+type providerHealth struct {
+	Name  string
+	Tools int
+	Err   error
+}
+
+// probeProviders asks client for each provider's tools. Providers that only
+// show up in tool names (e.g. registered by the agent itself) are included
+// too. A configured provider without tools most likely failed to register.
+func probeProviders(client toolSearcher, names []string) []providerHealth {
+	seen := map[string]bool{}
+	all := append([]string(nil), names...)
+	if tools, err := client.SearchTools("", 0); err == nil {
+		for _, t := range tools {
+			if provider, _, ok := strings.Cut(t.Name, "."); ok {
+				all = append(all, provider)
+			}
+		}
+	}
+	var out []providerHealth
+	for _, name := range all {
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		tools, err := client.SearchTools(name, 0)
+		out = append(out, providerHealth{Name: name, Tools: len(tools), Err: err})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// formatProviderHealth renders probe results, one provider per line.
+func formatProviderHealth(hs []providerHealth) string {
+	if len(hs) == 0 {
+		return "No UTCP providers configured.\n"
+	}
+	var b strings.Builder
+	ok := 0
+	for _, h := range hs {
+		switch {
+		case h.Err != nil:
+			fmt.Fprintf(&b, "  ❌ %s: %v\n", h.Name, h.Err)
+		case h.Tools == 0:
+			fmt.Fprintf(&b, "  ⚠️ %s: no tools (unreachable or failed to register)\n", h.Name)
+		default:
+			ok++
+			fmt.Fprintf(&b, "  ✅ %s: %d tool%s\n", h.Name, h.Tools, plural(h.Tools))
+		}
+	}
+	return fmt.Sprintf("UTCP providers (%d of %d healthy):\n", ok, len(hs)) + b.String()
+}
+
+func plural(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
+
+// providersSlashCommand handles "/providers".
+func providersSlashCommand(m *model, _ string) (*model, tea.Cmd) {
+	if m.agent == nil || m.agent.UTCPClient == nil {
+		m.output += m.style.Error.Render("❌ /providers: no UTCP client is configured\n")
+	} else {
+		m.output += formatProviderHealth(probeProviders(m.agent.UTCPClient, configuredProviders)) + "\n"
+	}
+	m.renderOutput(true)
+	return m, nil
+}
@@ -0,0 +1,60 @@
+package src
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/universal-tool-calling-protocol/go-utcp/src/tools"
+)
+
+type stubSearcher struct {
+	tools []tools.Tool
+	errs  map[string]error
+}
+
+func (s stubSearcher) SearchTools(query string, limit int) ([]tools.Tool, error) {
+	if err := s.errs[query]; err != nil {
+		return nil, err
+	}
+	var out []tools.Tool
+	for _, t := range s.tools {
+		if query == "" || strings.HasPrefix(t.Name, query+".") {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
+func TestProviderHealthSummary(t *testing.T) {
+	client := stubSearcher{
+		tools: []tools.Tool{{Name: "files.read"}, {Name: "files.write"}, {Name: "agent.ask"}},
+		errs:  map[string]error{"broken": errors.New("connection refused")},
+	}
+	got := formatProviderHealth(probeProviders(client, []string{"files", "offline", "broken"}))
+	want := "UTCP providers (2 of 4 healthy):\n" +
+		"  ✅ agent: 1 tool\n" +
+		"  ❌ broken: connection refused\n" +
+		"  ✅ files: 2 tools\n" +
+		"  ⚠️ offline: no tools (unreachable or failed to register)\n"
+	if got != want {
+		t.Fatalf("summary:\n%s\nwant:\n%s", got, want)
+	}
+	if got := formatProviderHealth(nil); got != "No UTCP providers configured.\n" {
+		t.Fatalf("empty summary = %q", got)
+	}
+}
+
+func TestProviderNames(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "provider.json")
+	if err := os.WriteFile(file, []byte(`{"providers":[{"name":"filed","provider_type":"text"}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got := providerNames(file, []Provider{textProvider("runtime", "wave")})
+	if want := []string{"filed", "runtime"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("providerNames = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,105 @@
+package src
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/charmbracelet/bubbles/list"
+)
+
+const maxRecentDirs = 8
+
+// recentDirs is the persisted list of pinned and recently confirmed workspaces.
+type recentDirs struct {
+	Pinned []string `json:"pinned,omitempty"`
+	Recent []string `json:"recent,omitempty"`
+}
+
+// loadRecents reads the recents file; a missing or invalid file yields an empty list.
+func loadRecents(path string) recentDirs {
+	var r recentDirs
+	if path == "" {
+		return r
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return r
+	}
+	_ = json.Unmarshal(data, &r)
+	return r
+}
+
+func (r recentDirs) save(path string) error {
+	if path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// record moves dir to the front of the recent list, capping its length.
+func (r *recentDirs) record(dir string) {
+	r.Recent = append([]string{dir}, without(r.Recent, dir)...)
+	if len(r.Recent) > maxRecentDirs {
+		r.Recent = r.Recent[:maxRecentDirs]
+	}
+}
+
+// togglePin pins or unpins dir and reports whether it is now pinned.
+func (r *recentDirs) togglePin(dir string) bool {
+	if r.isPinned(dir) {
+		r.Pinned = without(r.Pinned, dir)
+		return false
+	}
+	r.Pinned = append(r.Pinned, dir)
+	return true
+}
+
+func (r recentDirs) isPinned(dir string) bool {
+	for _, p := range r.Pinned {
+		if p == dir {
+			return true
+		}
+	}
+	return false
+}
+
+// quickPicks returns list items for pinned then recent directories, skipping current.
+func (r recentDirs) quickPicks(current string) []list.Item {
+	var items []list.Item
+	seen := map[string]bool{current: true}
+	add := func(icon, dir string) {
+		if seen[dir] {
+			return
+		}
+		seen[dir] = true
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			return
+		}
+		items = append(items, dirItem{name: icon + " " + filepath.Base(dir), path: dir})
+	}
+	for _, dir := range r.Pinned {
+		add("⭐", dir)
+	}
+	for _, dir := range r.Recent {
+		add("🕘", dir)
+	}
+	return items
+}
+
+func without(list []string, s string) []string {
+	out := make([]string, 0, len(list))
+	for _, v := range list {
+		if v != s {
+			out = append(out, v)
+		}
+	}
+	return out
+}
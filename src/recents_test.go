@@ -0,0 +1,58 @@
+package src
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestRecentsLoadSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".lattice", "recents.json")
+
+	var r recentDirs
+	r.record("/a")
+	r.record("/b")
+	r.record("/a")
+	r.togglePin("/c")
+	if err := r.save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	got := loadRecents(path)
+	if want := []string{"/a", "/b"}; !reflect.DeepEqual(got.Recent, want) {
+		t.Errorf("Recent = %v; want %v", got.Recent, want)
+	}
+	if !got.isPinned("/c") {
+		t.Error("expected /c to stay pinned")
+	}
+	if got.togglePin("/c") || got.isPinned("/c") {
+		t.Error("second toggle should unpin /c")
+	}
+}
+
+func TestRecentsCap(t *testing.T) {
+	var r recentDirs
+	for i := 0; i < maxRecentDirs+3; i++ {
+		r.record(filepath.Join("/p", string(rune('a'+i))))
+	}
+	if len(r.Recent) != maxRecentDirs {
+		t.Errorf("len(Recent) = %d; want %d", len(r.Recent), maxRecentDirs)
+	}
+}
+
+func TestConfirmDirRecordsRecent(t *testing.T) {
+	work := t.TempDir()
+	m := NewModel(context.Background(), nil, work, nil)
+	m.recentsPath = filepath.Join(t.TempDir(), "recents.json")
+	m.recents = recentDirs{}
+	m.reloadDirs()
+
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+
+	if got := loadRecents(m.recentsPath).Recent; len(got) == 0 || got[0] != work {
+		t.Errorf("recents after confirm = %v; want %s first", got, work)
+	}
+}
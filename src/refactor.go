@@ -0,0 +1,35 @@
+package src
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/Protocol-Lattice/lattice-code/src/ui"
+)
+
+// refactorSlashCommand handles "/refactor <goal>". The chat switches to
+// ModeRefactor until the rewritten files have been applied.
+func refactorSlashCommand(m *model, args string) (*model, tea.Cmd) {
+	goal := strings.TrimSpace(args)
+	if goal == "" {
+		m.output += m.style.Error.Render("❌ usage: /refactor <goal>\n")
+		m.renderOutput(true)
+		return m, nil
+	}
+
+	m.prevMode = m.mode
+	m.mode = ui.ModeRefactor
+	cmd := func() tea.Msg {
+		res, err := RunHeadless(m.ctx, m.agent, m.working, fmt.Sprintf(refactorPrompt, goal), HeadlessOptions{Config: m.cfg, Session: m.sessionID, OnAction: m.reportAction})
+		if err != nil {
+			return generateMsg{"", err}
+		}
+		m.usage.add(res.Usage)
+		res.Actions = append(res.Actions, CommitRun(m.ctx, m.cfg, m.working, "Refactor: "+goal, res.Actions)...)
+		m.requestConfirmation(res.Actions)
+		return generateMsg{m.formatActions("refactor", res.Actions), nil}
+	}
+	return m, tea.Batch(cmd, m.startWork("refactoring"))
+}
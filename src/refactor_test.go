@@ -0,0 +1,54 @@
+package src
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/Protocol-Lattice/lattice-code/src/ui"
+)
+
+func TestRefactorSlashCommandEntersModeAndRuns(t *testing.T) {
+	GlobalChanges = NewChangeTracker()
+	root := t.TempDir()
+	llm := &stubLLM{reply: "```go\n// path: util.go\npackage main\n\nfunc helper() {}\n```\n"}
+	m := NewModel(context.Background(), newStubAgent(t, llm), root, nil)
+	m.mode = ui.ModeChat
+
+	_, cmd := m.handleSlashCommand("/refactor extract a helper")
+	if m.mode != ui.ModeRefactor {
+		t.Fatalf("mode = %v, want ModeRefactor", m.mode)
+	}
+	batch, ok := cmd().(tea.BatchMsg)
+	if !ok || len(batch) == 0 {
+		t.Fatalf("expected a batch of commands, got %T", cmd())
+	}
+	msg := batch[0]()
+	if gm, ok := msg.(generateMsg); !ok || gm.err != nil || !strings.Contains(gm.text, "util.go") {
+		t.Fatalf("refactor result = %#v", msg)
+	}
+
+	m.Update(msg)
+	if m.mode != ui.ModeChat {
+		t.Errorf("mode after completion = %v, want ModeChat", m.mode)
+	}
+	if _, err := os.Stat(filepath.Join(root, "util.go")); err != nil {
+		t.Errorf("refactor output was not written: %v", err)
+	}
+}
+
+func TestRefactorSlashCommandRequiresGoal(t *testing.T) {
+	m := NewModel(context.Background(), nil, t.TempDir(), nil)
+	m.mode = ui.ModeChat
+
+	if _, cmd := m.handleSlashCommand("/refactor"); cmd != nil || m.mode != ui.ModeChat {
+		t.Errorf("empty goal should not start a refactor (mode %v)", m.mode)
+	}
+	if !strings.Contains(m.output, "usage: /refactor") {
+		t.Errorf("missing usage hint: %q", m.output)
+	}
+}
@@ -0,0 +1,96 @@
+package src
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/Protocol-Lattice/lattice-code/src/atomicfile"
+)
+
+// saveOutput writes content to rel inside root and returns the absolute path.
+// rel must stay inside root; an existing file is only replaced with force.
+func saveOutput(root, rel, content string, force bool) (string, error) {
+	if rel == "" {
+		return "", errors.New("no file name given")
+	}
+	if filepath.IsAbs(rel) {
+		return "", fmt.Errorf("%s: path must be relative to the workspace", rel)
+	}
+	abs := filepath.Join(root, filepath.FromSlash(rel))
+	if r, err := filepath.Rel(root, abs); err != nil || r == ".." || strings.HasPrefix(r, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s: path escapes the workspace", rel)
+	}
+	if _, err := os.Stat(abs); err == nil && !force {
+		return "", fmt.Errorf("%s already exists (use /save --force to overwrite)", rel)
+	}
+	if err := os.MkdirAll(filepath.Dir(abs), 0o755); err != nil {
+		return "", err
+	}
+	if !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return abs, atomicfile.WriteFile(abs, []byte(content), 0o644)
+}
+
+// saveSlashCommand handles "/save [--force] <file>", which saves the last
+// result (or the whole transcript before there is one), and "/save --code",
+// which writes the last result's code blocks like a generation run would.
+func saveSlashCommand(m *model, args string) (*model, tea.Cmd) {
+	source := m.lastResult
+	if source == "" {
+		source = m.output
+	}
+	source = ansi.Strip(source)
+
+	var (
+		force, code bool
+		cmd         tea.Cmd
+	)
+	var rest []string
+	for _, f := range strings.Fields(args) {
+		switch f {
+		case "--force":
+			force = true
+		case "--code":
+			code = true
+		default:
+			rest = append(rest, f)
+		}
+	}
+
+	switch {
+	case code:
+		actions, err := WriteCodeBlocks(m.working, source, WriteOptions{Config: m.cfg, Force: force})
+		if err != nil {
+			m.output += m.style.Error.Render(fmt.Sprintf("❌ /save: %v\n", err))
+			break
+		}
+		m.output += m.formatActions("save", actions)
+		var pending []FileAction
+		for _, a := range actions {
+			if a.Action == "pending" {
+				pending = append(pending, a)
+			}
+		}
+		if len(pending) > 0 {
+			cmd = func() tea.Msg { return confirmWritesMsg{actions: pending} }
+		}
+	case len(rest) == 1:
+		abs, err := saveOutput(m.working, rest[0], source, force)
+		if err != nil {
+			m.output += m.style.Error.Render(fmt.Sprintf("❌ /save: %v\n", err))
+			break
+		}
+		m.output += m.style.Success.Render(fmt.Sprintf("💾 saved to %s\n", abs))
+	default:
+		m.output += m.style.Error.Render("❌ usage: /save [--force] <file> or /save --code\n")
+	}
+	m.renderOutput(true)
+	return m, cmd
+}
@@ -0,0 +1,55 @@
+package src
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSaveOutput(t *testing.T) {
+	root := t.TempDir()
+	abs, err := saveOutput(root, "notes/tool.json", `{"ok": true}`, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(root, "notes", "tool.json"); abs != want {
+		t.Fatalf("path = %s, want %s", abs, want)
+	}
+	if got, _ := os.ReadFile(abs); string(got) != "{\"ok\": true}\n" {
+		t.Fatalf("content = %q", got)
+	}
+	if _, err := saveOutput(root, "notes/tool.json", "again", false); err == nil {
+		t.Fatal("expected an existing file to be kept without force")
+	}
+	if _, err := saveOutput(root, "notes/tool.json", "again", true); err != nil {
+		t.Fatalf("force: %v", err)
+	}
+	for _, bad := range []string{"", "../outside.txt", "/etc/passwd"} {
+		if _, err := saveOutput(root, bad, "x", false); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
+
+func TestSaveSlashCommandStripsStyling(t *testing.T) {
+	GlobalChanges = NewChangeTracker()
+	root := t.TempDir()
+	m := NewModel(context.Background(), nil, root, nil)
+	m.lastResult = m.style.Accent.Render("Explanation:") + "\nplain text\n"
+	m.handleSlashCommand("/save out.md")
+	got, err := os.ReadFile(filepath.Join(root, "out.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "Explanation:\nplain text\n" {
+		t.Fatalf("content = %q", got)
+	}
+
+	m.lastResult = "```go\n// path: hello.go\npackage main\n```\n"
+	m.handleSlashCommand("/save --code")
+	if got, _ := os.ReadFile(filepath.Join(root, "hello.go")); !strings.Contains(string(got), "package main") {
+		t.Fatalf("code block not written: %q", got)
+	}
+}
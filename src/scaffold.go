@@ -0,0 +1,129 @@
+package src
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/Protocol-Lattice/lattice-code/src/atomicfile"
+)
+
+// scaffolds holds the files /init writes for each language: the smallest
+// project its toolchain will build and run. "{{name}}" is replaced with the
+// project name.
+var scaffolds = map[string]map[string]string{
+	"go": {
+		"go.mod":  "module {{name}}\n\ngo 1.21\n",
+		"main.go": "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hello from {{name}}\")\n}\n",
+	},
+	"node": {
+		"package.json": "{\n  \"name\": \"{{name}}\",\n  \"version\": \"0.1.0\",\n  \"private\": true,\n  \"main\": \"index.js\",\n  \"scripts\": {\n    \"start\": \"node index.js\"\n  }\n}\n",
+		"index.js":     "console.log(\"hello from {{name}}\");\n",
+	},
+	"typescript": {
+		"package.json":  "{\n  \"name\": \"{{name}}\",\n  \"version\": \"0.1.0\",\n  \"private\": true,\n  \"scripts\": {\n    \"build\": \"tsc\",\n    \"start\": \"node dist/index.js\"\n  },\n  \"devDependencies\": {\n    \"typescript\": \"^5.0.0\"\n  }\n}\n",
+		"tsconfig.json": "{\n  \"compilerOptions\": {\n    \"target\": \"ES2020\",\n    \"module\": \"commonjs\",\n    \"strict\": true,\n    \"outDir\": \"dist\",\n    \"rootDir\": \"src\"\n  },\n  \"include\": [\"src\"]\n}\n",
+		"src/index.ts":  "const greeting: string = \"hello from {{name}}\";\nconsole.log(greeting);\n",
+	},
+	"python": {
+		"pyproject.toml": "[project]\nname = \"{{name}}\"\nversion = \"0.1.0\"\nrequires-python = \">=3.9\"\n",
+		"main.py":        "def main() -> None:\n    print(\"hello from {{name}}\")\n\n\nif __name__ == \"__main__\":\n    main()\n",
+	},
+	"rust": {
+		"Cargo.toml":  "[package]\nname = \"{{name}}\"\nversion = \"0.1.0\"\nedition = \"2021\"\n",
+		"src/main.rs": "fn main() {\n    println!(\"hello from {{name}}\");\n}\n",
+	},
+}
+
+var scaffoldAliases = map[string]string{
+	"golang":     "go",
+	"js":         "node",
+	"javascript": "node",
+	"ts":         "typescript",
+	"py":         "python",
+	"rs":         "rust",
+}
+
+var projectNameRe = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+// projectName derives a name every template accepts from the workspace
+// directory.
+func projectName(root string) string {
+	name := strings.Trim(projectNameRe.ReplaceAllString(strings.ToLower(filepath.Base(root)), "-"), "-_")
+	if name == "" {
+		return "app"
+	}
+	return name
+}
+
+// scaffoldProject writes the starter files for lang into root. Files that
+// already exist are left alone and reported as "info"; the rest are recorded
+// as agent-managed so later generations may update them.
+func scaffoldProject(root, lang string) ([]FileAction, error) {
+	lang = strings.ToLower(lang)
+	if alias, ok := scaffoldAliases[lang]; ok {
+		lang = alias
+	}
+	files, ok := scaffolds[lang]
+	if !ok {
+		names := make([]string, 0, len(scaffolds))
+		for n := range scaffolds {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("no template for %q (available: %s)", lang, strings.Join(names, ", "))
+	}
+
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	name := projectName(root)
+	var actions []FileAction
+	var written []string
+	for _, rel := range paths {
+		abs := filepath.Join(root, filepath.FromSlash(rel))
+		if _, err := os.Stat(abs); err == nil {
+			actions = append(actions, FileAction{Path: rel, Action: "info", Message: rel + " already exists; left as is"})
+			continue
+		}
+		body := []byte(strings.ReplaceAll(files[rel], "{{name}}", name))
+		if err := os.MkdirAll(filepath.Dir(abs), 0o755); err != nil {
+			return actions, err
+		}
+		if err := atomicfile.WriteFile(abs, body, 0o644); err != nil {
+			actions = append(actions, FileAction{Path: rel, Action: "error", Message: err.Error(), Err: err})
+			continue
+		}
+		GlobalChanges.Record(rel, body)
+		written = append(written, rel)
+		actions = append(actions, FileAction{Path: rel, Action: "saved", Message: "created"})
+	}
+	return actions, recordManaged(root, written)
+}
+
+// initSlashCommand handles "/init <language>".
+func initSlashCommand(m *model, args string) (*model, tea.Cmd) {
+	lang := strings.TrimSpace(args)
+	if lang == "" || strings.ContainsAny(lang, " \t\n") {
+		m.output += m.style.Error.Render("❌ usage: /init <go|node|typescript|python|rust>\n")
+		m.renderOutput(true)
+		return m, nil
+	}
+	actions, err := scaffoldProject(m.working, lang)
+	if len(actions) > 0 {
+		m.output += m.formatActions("init", actions)
+	}
+	if err != nil {
+		m.output += m.style.Error.Render(fmt.Sprintf("❌ /init: %v\n", err))
+	}
+	m.renderOutput(true)
+	return m, nil
+}
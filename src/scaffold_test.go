@@ -0,0 +1,50 @@
+package src
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScaffoldGoBuilds(t *testing.T) {
+	GlobalChanges = NewChangeTracker()
+	root := filepath.Join(t.TempDir(), "My Service")
+	actions, err := scaffoldProject(root, "golang")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actions) != 2 || actions[0].Path != "go.mod" || actions[1].Path != "main.go" {
+		t.Fatalf("actions = %+v", actions)
+	}
+	if got := readFile(t, filepath.Join(root, "go.mod")); !strings.HasPrefix(got, "module my-service\n") {
+		t.Errorf("go.mod = %q", got)
+	}
+	if !loadManifest(root)["main.go"] {
+		t.Error("scaffolded files should be agent-managed")
+	}
+	if _, err := exec.LookPath("go"); err == nil {
+		if diag := goCheck(context.Background(), root); diag != "" {
+			t.Errorf("scaffolded module does not build:\n%s", diag)
+		}
+	}
+
+	writeFixture(t, root, map[string]string{"main.go": "package main // mine\n"})
+	actions, err = scaffoldProject(root, "go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range actions {
+		if a.Action != "info" {
+			t.Errorf("re-running /init should leave existing files alone, got %+v", a)
+		}
+	}
+	if got := readFile(t, filepath.Join(root, "main.go")); got != "package main // mine\n" {
+		t.Errorf("existing file overwritten: %q", got)
+	}
+
+	if _, err := scaffoldProject(root, "cobol"); err == nil || !strings.Contains(err.Error(), "available: go, node") {
+		t.Errorf("unknown language error = %v", err)
+	}
+}
@@ -0,0 +1,151 @@
+package src
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	agent "github.com/Protocol-Lattice/go-agent"
+	"github.com/Protocol-Lattice/go-agent/src/memory"
+	memmodel "github.com/Protocol-Lattice/go-agent/src/memory/model"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const defaultSearchLimit = 5
+
+// MemoryRetriever is the slice of *memory.Engine that /search needs.
+type MemoryRetriever interface {
+	Retrieve(ctx context.Context, sessionID, query string, limit int) ([]memory.MemoryRecord, error)
+}
+
+// sessionRetriever serves /search from an agent's session memory. The
+// agent keeps a session's turns short-term until they are flushed, where
+// the engine alone does not see them, so both are searched and ranked
+// together by similarity to the query.
+type sessionRetriever struct{ mem *memory.SessionMemory }
+
+// agentMemory returns a retriever over ag's session memory, or nil when ag
+// has none.
+func agentMemory(ag *agent.Agent) MemoryRetriever {
+	if ag == nil || ag.SessionMemory() == nil {
+		return nil
+	}
+	return sessionRetriever{ag.SessionMemory()}
+}
+
+func (s sessionRetriever) Retrieve(ctx context.Context, sessionID, query string, limit int) ([]memory.MemoryRecord, error) {
+	records, err := s.mem.RetrieveContext(ctx, sessionID, query, limit)
+	if err != nil || len(records) == 0 {
+		return nil, err
+	}
+	q, err := s.mem.Embed(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	for i, r := range records {
+		if r.Score == 0 && r.WeightedScore == 0 && len(r.Embedding) > 0 {
+			records[i].Score = memmodel.CosineSimilarity(q, r.Embedding)
+		}
+	}
+	sort.SliceStable(records, func(i, j int) bool { return recordScore(records[i]) > recordScore(records[j]) })
+	return records[:min(limit, len(records))], nil
+}
+
+// recordScore is the score /search ranks and reports a record by.
+func recordScore(r memory.MemoryRecord) float64 {
+	if r.WeightedScore != 0 {
+		return r.WeightedScore
+	}
+	return r.Score
+}
+
+// recordPath pulls the source file of a stored chunk from its metadata,
+// falling back to the record's Source.
+func recordPath(r memory.MemoryRecord) string {
+	var meta map[string]any
+	if json.Unmarshal([]byte(r.Metadata), &meta) == nil {
+		for _, key := range []string{"path", "file", "filename"} {
+			if p, ok := meta[key].(string); ok && p != "" {
+				return p
+			}
+		}
+	}
+	if r.Source != "" {
+		return r.Source
+	}
+	return "(unknown)"
+}
+
+// snippetPreview returns the first few non-blank lines of content.
+func snippetPreview(content string, maxLines int) string {
+	var lines []string
+	for _, line := range strings.Split(content, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		lines = append(lines, "    "+trim(strings.TrimRight(line, " \t"), 120))
+		if len(lines) == maxLines {
+			break
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatMemoryResults renders retrieval hits as numbered path/score headers
+// followed by a short preview.
+func formatMemoryResults(query string, records []memory.MemoryRecord) string {
+	if len(records) == 0 {
+		return fmt.Sprintf("🔎 No semantic matches for %q\n", query)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "🔎 %d semantic match(es) for %q\n", len(records), query)
+	for i, r := range records {
+		fmt.Fprintf(&b, "%d. %s (score %.2f)\n", i+1, recordPath(r), recordScore(r))
+		if preview := snippetPreview(r.Content, 3); preview != "" {
+			b.WriteString(preview + "\n")
+		}
+	}
+	return b.String()
+}
+
+// parseSearchArgs parses "[-n N] <query>".
+func parseSearchArgs(args string) (string, int, error) {
+	limit := defaultSearchLimit
+	fields := strings.Fields(args)
+	if len(fields) >= 2 && fields[0] == "-n" {
+		n, err := strconv.Atoi(fields[1])
+		if err != nil || n <= 0 {
+			return "", 0, fmt.Errorf("invalid -n %q", fields[1])
+		}
+		limit, fields = n, fields[2:]
+	}
+	query := strings.Join(fields, " ")
+	if query == "" {
+		return "", 0, fmt.Errorf("usage: /search [-n N] <query>")
+	}
+	return query, limit, nil
+}
+
+func searchSlashCommand(m *model, args string) (*model, tea.Cmd) {
+	query, limit, err := parseSearchArgs(args)
+	if err == nil && m.Memory == nil {
+		err = fmt.Errorf("semantic search unavailable: no memory engine is configured")
+	}
+	if err != nil {
+		m.output += m.style.Error.Render(fmt.Sprintf("❌ /search: %v\n", err))
+		m.renderOutput(true)
+		return m, nil
+	}
+
+	cmd := func() tea.Msg {
+		records, err := m.Memory.Retrieve(m.ctx, m.sessionID, query, limit)
+		if err != nil {
+			return generateMsg{"", fmt.Errorf("/search: %w", err)}
+		}
+		return generateMsg{formatMemoryResults(query, records), nil}
+	}
+	return m, tea.Batch(cmd, m.startWork("searching memory"))
+}
@@ -0,0 +1,87 @@
+package src
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Protocol-Lattice/go-agent/src/memory"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type stubRetriever struct {
+	records []memory.MemoryRecord
+	query   string
+	limit   int
+}
+
+func (s *stubRetriever) Retrieve(_ context.Context, _ string, query string, limit int) ([]memory.MemoryRecord, error) {
+	s.query, s.limit = query, limit
+	return s.records, nil
+}
+
+func TestSearchCommandFormatsResults(t *testing.T) {
+	stub := &stubRetriever{records: []memory.MemoryRecord{
+		{Content: "\nfunc LoadConfig() {\n\treturn nil\n}\n// trailing\n", Metadata: `{"path":"config/config.go"}`, WeightedScore: 0.91},
+		{Content: "README intro", Source: "README.md", Score: 0.4},
+	}}
+	m := NewModel(context.Background(), nil, t.TempDir(), nil)
+	m.Memory = stub
+
+	_, cmd := searchSlashCommand(m, "-n 2 config loader")
+	if cmd == nil {
+		t.Fatal("expected a command")
+	}
+	msg := cmd().(tea.BatchMsg)[0]().(generateMsg)
+	if msg.err != nil {
+		t.Fatal(msg.err)
+	}
+	if stub.query != "config loader" || stub.limit != 2 {
+		t.Errorf("Retrieve called with %q/%d", stub.query, stub.limit)
+	}
+
+	want := "🔎 2 semantic match(es) for \"config loader\"\n" +
+		"1. config/config.go (score 0.91)\n" +
+		"    func LoadConfig() {\n    \treturn nil\n    }\n" +
+		"2. README.md (score 0.40)\n" +
+		"    README intro\n"
+	if msg.text != want {
+		t.Errorf("got:\n%s\nwant:\n%s", msg.text, want)
+	}
+}
+
+func TestSearchCommandWithoutEngine(t *testing.T) {
+	m := NewModel(context.Background(), nil, t.TempDir(), nil)
+	m.handleSlashCommand("/search anything")
+	if !strings.Contains(m.output, "no memory engine") {
+		t.Errorf("expected an unavailable error, got %q", m.output)
+	}
+}
+
+func TestSearchFindsTurnsOfTheSession(t *testing.T) {
+	GlobalChanges = NewChangeTracker()
+	root := t.TempDir()
+	t.Chdir(root)
+	cfg := DefaultConfig()
+	cfg.Mock = true
+	ag, err := BuildAgent(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewModel(context.Background(), ag, root, cfg)
+	if m.Memory == nil {
+		t.Fatal("NewModel left /search without the agent's memory")
+	}
+	if _, err := RunHeadless(context.Background(), ag, root, "add a zebra counter to the stats page", HeadlessOptions{Config: cfg, Session: m.sessionID}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, cmd := searchSlashCommand(m, "zebra counter")
+	msg := cmd().(tea.BatchMsg)[0]().(generateMsg)
+	if msg.err != nil {
+		t.Fatal(msg.err)
+	}
+	if !strings.Contains(msg.text, "semantic match") || !strings.Contains(msg.text, "zebra counter") {
+		t.Errorf("/search did not find the session's prompt:\n%s", msg.text)
+	}
+}
@@ -0,0 +1,77 @@
+package src
+
+import (
+	"bytes"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// isEnvFile reports whether name looks like a dotenv file (.env, .env.local, prod.env).
+func isEnvFile(name string) bool {
+	base := strings.ToLower(filepath.Base(name))
+	return base == ".env" || strings.HasPrefix(base, ".env.") || strings.HasSuffix(base, ".env")
+}
+
+// isSecretFile reports whether name is a key or credential file that must never be sent.
+func isSecretFile(name string) bool {
+	base := strings.ToLower(filepath.Base(name))
+	switch base {
+	case "id_rsa", "id_dsa", "id_ecdsa", "id_ed25519", ".netrc", ".npmrc", ".pypirc", "credentials", "credentials.json":
+		return true
+	}
+	switch filepath.Ext(base) {
+	case ".pem", ".key", ".p12", ".pfx", ".jks", ".keystore":
+		return true
+	}
+	return false
+}
+
+// secretPatterns match well-known token formats in file contents.
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`AKIA[0-9A-Z]{16}`),                             // AWS access key
+	regexp.MustCompile(`-----BEGIN (?:[A-Z]+ )?PRIVATE KEY-----`),      // PEM private key
+	regexp.MustCompile(`gh[pousr]_[A-Za-z0-9]{36}|github_pat_\w{22,}`), // GitHub tokens
+	regexp.MustCompile(`xox[abprs]-[A-Za-z0-9-]{10,}`),                 // Slack tokens
+	regexp.MustCompile(`sk-[A-Za-z0-9_-]{20,}`),                        // OpenAI/Anthropic style keys
+	regexp.MustCompile(`AIza[0-9A-Za-z_-]{35}`),                        // Google API key
+}
+
+func containsSecret(b []byte) bool {
+	for _, re := range secretPatterns {
+		if re.Match(b) {
+			return true
+		}
+	}
+	return false
+}
+
+var envAssignRe = regexp.MustCompile(`^(\s*(?:export\s+)?[A-Za-z_][A-Za-z0-9_.]*\s*=\s*)(.*)$`)
+
+// redactEnv masks the value of every KEY=value assignment, keeping names and comments.
+func redactEnv(b []byte) []byte {
+	lines := bytes.Split(b, []byte("\n"))
+	for i, line := range lines {
+		m := envAssignRe.FindSubmatch(line)
+		if m == nil || len(bytes.TrimSpace(m[2])) == 0 {
+			continue
+		}
+		lines[i] = append(append([]byte(nil), m[1]...), "***"...)
+	}
+	return bytes.Join(lines, []byte("\n"))
+}
+
+// sanitizeContextFile prepares file contents before they reach the LLM.
+// Dotenv values are masked; any file still containing a secret-looking token is dropped (ok=false).
+func sanitizeContextFile(rel string, b []byte) ([]byte, bool) {
+	if isSecretFile(rel) {
+		return nil, false
+	}
+	if isEnvFile(rel) {
+		b = redactEnv(b)
+	}
+	if containsSecret(b) {
+		return nil, false
+	}
+	return b, true
+}
@@ -0,0 +1,52 @@
+package src
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// targetTimeout bounds one run of the /target command; test suites get
+// longer than the 15 seconds an entrypoint run does.
+const targetTimeout = 2 * time.Minute
+
+// targetSlashCommand handles "/target [command|off]": with a command the
+// planner runs it after each step instead of looking for a main file,
+// without one it shows the current target, and "off" clears it.
+func targetSlashCommand(m *model, args string) (*model, tea.Cmd) {
+	target := strings.TrimSpace(args)
+	switch {
+	case target == "" && m.runTarget == "":
+		m.output += m.style.Subtle.Render("ℹ️ no run target; the planner runs the detected entrypoint (/target <command> sets one)\n")
+	case target == "":
+		m.output += m.style.Accent.Render("🎯 run target: ") + m.runTarget + "\n"
+	case target == "off":
+		m.runTarget = ""
+		m.output += m.style.Success.Render("🎯 run target cleared; the planner runs the detected entrypoint\n")
+	default:
+		m.runTarget = target
+		m.output += m.style.Success.Render(fmt.Sprintf("🎯 the planner now verifies each step with: %s\n", target))
+	}
+	m.renderOutput(true)
+	return m, nil
+}
+
+// runTargetStep runs command in workspace as a planner step's verify phase,
+// tests telling whether it is the project's test suite. It returns the line
+// to show, the command's output and, when it failed, the feedback for the
+// next step ("" when it passed).
+func runTargetStep(ctx context.Context, workspace, command string, tests bool) (report, out, failure string) {
+	passed, failed := "Run result", "Runtime error"
+	if tests {
+		passed, failed = "Test result", "Tests failed"
+	}
+	ok, out, err := RunTarget(ctx, workspace, command, targetTimeout)
+	if ok {
+		return fmt.Sprintf("🧪 %s (%s):\n%s\n", passed, command, TailBytes(out, 4000)), out, ""
+	}
+	failure = fmt.Sprintf("❌ %s (%s): %v\n%s", failed, command, err, TailBytes(out, 4000))
+	return failure + "\n", out, failure
+}
@@ -3,7 +3,7 @@ package src
 import (
 	"crypto/sha256"
 	"encoding/hex"
-	"errors"
+	"io"
 	"os"
 	"time"
 
@@ -12,9 +12,12 @@ import (
 
 type transcriptTickMsg struct{}
 
+// transcriptSyncMsg carries the transcript from offset on, the part that
+// is not trimmed from the output.
 type transcriptSyncMsg struct {
 	content  string
 	checksum string
+	offset   int64
 	err      error
 }
 
@@ -33,20 +36,31 @@ func (m *model) scheduleTranscriptTick() tea.Cmd {
 	})
 }
 
+// readTranscriptCmd reads the transcript from where the output on screen
+// starts, so a long transcript is not read whole on every tick.
 func (m *model) readTranscriptCmd() tea.Cmd {
-	path := m.transcriptPath
+	path, offset := m.transcriptPath, m.transcriptBase+m.trimmedBytes
 	if path == "" {
 		return nil
 	}
 	return func() tea.Msg {
-		data, err := os.ReadFile(path)
+		f, err := os.Open(path)
+		if err != nil {
+			return transcriptSyncMsg{err: err}
+		}
+		defer f.Close()
+		info, err := f.Stat()
+		if err != nil {
+			return transcriptSyncMsg{err: err}
+		}
+		if info.Size() < offset {
+			offset = 0 // cut short outside the TUI: take it as the whole transcript
+		}
+		data, err := io.ReadAll(io.NewSectionReader(f, offset, info.Size()-offset))
 		if err != nil {
-			if errors.Is(err, os.ErrNotExist) {
-				return transcriptSyncMsg{err: err}
-			}
 			return transcriptSyncMsg{err: err}
 		}
 		content := string(data)
-		return transcriptSyncMsg{content: content, checksum: hashString(content)}
+		return transcriptSyncMsg{content: content, checksum: hashString(content), offset: offset}
 	}
 }
@@ -0,0 +1,183 @@
+package src
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// tsConfig is the part of tsconfig.json (or jsconfig.json) that affects how
+// bare import specifiers resolve.
+type tsConfig struct {
+	baseURL string // absolute; "" when unset
+	aliases []tsAlias
+}
+
+// tsAlias is one compilerOptions.paths entry such as "@/*": ["src/*"]. The
+// pattern is split around its "*"; targets are absolute and keep theirs.
+type tsAlias struct {
+	prefix, suffix string
+	wildcard       bool
+	targets        []string
+}
+
+var jsonTrailingComma = regexp.MustCompile(`,(\s*[}\]])`)
+
+// loadTSConfig reads the workspace's tsconfig.json, falling back to
+// jsconfig.json. "extends" is not followed. A missing or unparsable file
+// yields an empty config.
+func loadTSConfig(root string) tsConfig {
+	var raw struct {
+		CompilerOptions struct {
+			BaseURL string              `json:"baseUrl"`
+			Paths   map[string][]string `json:"paths"`
+		} `json:"compilerOptions"`
+	}
+	var data []byte
+	for _, name := range []string{"tsconfig.json", "jsconfig.json"} {
+		if b, err := os.ReadFile(filepath.Join(root, name)); err == nil {
+			data = b
+			break
+		}
+	}
+	if data == nil {
+		return tsConfig{}
+	}
+	data = jsonTrailingComma.ReplaceAll(stripJSONComments(data), []byte("$1"))
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return tsConfig{}
+	}
+
+	var cfg tsConfig
+	// paths resolve against baseUrl, or against the config's directory
+	// when there is none.
+	base := root
+	if b := raw.CompilerOptions.BaseURL; b != "" {
+		base = filepath.Join(root, filepath.FromSlash(b))
+		cfg.baseURL = base
+	}
+	for pattern, targets := range raw.CompilerOptions.Paths {
+		a := tsAlias{prefix: pattern}
+		if i := strings.IndexByte(pattern, '*'); i >= 0 {
+			a.prefix, a.suffix, a.wildcard = pattern[:i], pattern[i+1:], true
+		}
+		for _, t := range targets {
+			a.targets = append(a.targets, filepath.ToSlash(filepath.Join(base, filepath.FromSlash(t))))
+		}
+		cfg.aliases = append(cfg.aliases, a)
+	}
+	// TypeScript picks the pattern with the longest prefix.
+	sort.Slice(cfg.aliases, func(i, j int) bool {
+		return len(cfg.aliases[i].prefix) > len(cfg.aliases[j].prefix)
+	})
+	return cfg
+}
+
+// match returns the alias spec resolves through and the text its "*"
+// stands for.
+func (c tsConfig) match(spec string) (tsAlias, string, bool) {
+	for _, a := range c.aliases {
+		if !a.wildcard {
+			if spec == a.prefix {
+				return a, "", true
+			}
+			continue
+		}
+		if len(spec) >= len(a.prefix)+len(a.suffix) && strings.HasPrefix(spec, a.prefix) && strings.HasSuffix(spec, a.suffix) {
+			return a, spec[len(a.prefix) : len(spec)-len(a.suffix)], true
+		}
+	}
+	return tsAlias{}, "", false
+}
+
+// resolves reports whether any target of a, with rest substituted, names an
+// existing module.
+func (a tsAlias) resolves(rest string) bool {
+	for _, t := range a.targets {
+		if jsModuleExists(filepath.FromSlash(strings.Replace(t, "*", rest, 1))) {
+			return true
+		}
+	}
+	return false
+}
+
+// specFor returns the aliased specifier for the module at abs (without
+// extension), or "" when no alias covers it. The most specific target wins.
+func (c tsConfig) specFor(abs string) string {
+	abs = filepath.ToSlash(abs)
+	best, bestLen := "", -1
+	for _, a := range c.aliases {
+		for _, t := range a.targets {
+			if !a.wildcard {
+				if t == abs && len(t) > bestLen {
+					best, bestLen = a.prefix, len(t)
+				}
+				continue
+			}
+			tp, ts, _ := strings.Cut(t, "*")
+			if len(tp) > bestLen && len(abs) > len(tp)+len(ts) && strings.HasPrefix(abs, tp) && strings.HasSuffix(abs, ts) {
+				best, bestLen = a.prefix+abs[len(tp):len(abs)-len(ts)]+a.suffix, len(tp)
+			}
+		}
+	}
+	return best
+}
+
+// jsModuleExists reports whether an import of abs would find a file, trying
+// the extensions and index files module resolution does.
+func jsModuleExists(abs string) bool {
+	if fi, err := os.Stat(abs); err == nil && !fi.IsDir() {
+		return true
+	}
+	for _, ext := range []string{".ts", ".tsx", ".d.ts", ".js", ".jsx", ".mjs", ".cjs"} {
+		if _, err := os.Stat(abs + ext); err == nil {
+			return true
+		}
+		if _, err := os.Stat(filepath.Join(abs, "index"+ext)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// stripJSONComments removes // and /* */ comments outside of strings, as
+// tsconfig.json allows them.
+func stripJSONComments(b []byte) []byte {
+	out := make([]byte, 0, len(b))
+	inStr := false
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+		switch {
+		case inStr:
+			out = append(out, c)
+			if c == '\\' && i+1 < len(b) {
+				i++
+				out = append(out, b[i])
+			} else if c == '"' {
+				inStr = false
+			}
+		case c == '"':
+			inStr = true
+			out = append(out, c)
+		case c == '/' && i+1 < len(b) && b[i+1] == '/':
+			for i < len(b) && b[i] != '\n' {
+				i++
+			}
+			if i < len(b) {
+				out = append(out, '\n')
+			}
+		case c == '/' && i+1 < len(b) && b[i+1] == '*':
+			end := strings.Index(string(b[i+2:]), "*/")
+			if end < 0 {
+				return out
+			}
+			i += end + 3
+		default:
+			out = append(out, c)
+		}
+	}
+	return out
+}
@@ -0,0 +1,195 @@
+package ui
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters"
+	"github.com/alecthomas/chroma/v2/lexers"
+	chromastyles "github.com/alecthomas/chroma/v2/styles"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// codeTheme is the chroma style used for fenced code blocks.
+const codeTheme = "monokai"
+
+// outputSegment is a run of transcript text, either prose or the body of a
+// fenced code block.
+type outputSegment struct {
+	text string
+	lang string
+	code bool
+}
+
+// splitFences splits s into prose and fenced code segments. Fence lines stay
+// with the surrounding prose; an unterminated fence (e.g. while a response is
+// still streaming) runs to the end of s. As in Markdown, a fence is closed
+// only by a bare run of at least as many backticks as opened it, so a longer
+// fence can hold ``` lines.
+func splitFences(s string) []outputSegment {
+	var (
+		segs  []outputSegment
+		cur   []string
+		fence int // backticks that opened the current fence; 0 outside one
+		lang  string
+	)
+	flush := func(code bool) {
+		if len(cur) > 0 {
+			segs = append(segs, outputSegment{text: strings.Join(cur, "\n"), lang: lang, code: code})
+		}
+		cur = nil
+	}
+	for _, line := range strings.Split(s, "\n") {
+		// Fence lines are often styled, e.g. Subtle.Render("```diff").
+		trimmed := strings.TrimSpace(ansi.Strip(line))
+		n := fenceLen(trimmed)
+		switch {
+		case fence > 0 && closesFence(trimmed, fence):
+			flush(true)
+			cur = append(cur, line)
+			fence, lang = 0, ""
+		case fence == 0 && n > 0:
+			cur = append(cur, line)
+			flush(false)
+			fence = n
+			lang = strings.TrimSpace(trimmed[n:])
+		default:
+			cur = append(cur, line)
+		}
+	}
+	flush(fence > 0)
+	return segs
+}
+
+// fenceLen returns the length of the run of three or more backticks that
+// starts line, or 0 when line is not a fence.
+func fenceLen(line string) int {
+	if n := len(line) - len(strings.TrimLeft(line, "`")); n >= 3 {
+		return n
+	}
+	return 0
+}
+
+// closesFence reports whether line closes a fence opened by open backticks.
+func closesFence(line string, open int) bool {
+	return fenceLen(line) >= open && strings.Trim(line, "`") == ""
+}
+
+var (
+	highlightMu    sync.Mutex
+	highlightCache = map[string]string{}
+)
+
+// highlightCode colourises code for a 256-colour terminal. Results are cached
+// because the whole transcript is re-rendered on every new line of output.
+func highlightCode(code, lang string) string {
+	key := lang + "\x00" + code
+	highlightMu.Lock()
+	if out, ok := highlightCache[key]; ok {
+		highlightMu.Unlock()
+		return out
+	}
+	highlightMu.Unlock()
+
+	lexer := lexers.Get(lang)
+	if lexer == nil {
+		lexer = lexers.Analyse(code)
+	}
+	if lexer == nil {
+		return code
+	}
+	it, err := chroma.Coalesce(lexer).Tokenise(nil, code)
+	if err != nil {
+		return code
+	}
+	var b strings.Builder
+	if err := formatters.TTY256.Format(&b, chromastyles.Get(codeTheme), it); err != nil {
+		return code
+	}
+	out := strings.TrimSuffix(b.String(), "\n")
+
+	highlightMu.Lock()
+	if len(highlightCache) > 256 {
+		highlightCache = map[string]string{}
+	}
+	highlightCache[key] = out
+	highlightMu.Unlock()
+	return out
+}
+
+// FormatOutput prepares transcript text for the viewport: fenced code blocks
+// are syntax highlighted and every line is wrapped to width. Prose wraps at
+// word boundaries; code is hard-wrapped so indentation is preserved. A
+// non-positive width disables wrapping.
+func FormatOutput(s string, width int) string {
+	segs := splitFences(s)
+	parts := make([]string, 0, len(segs))
+	for _, seg := range segs {
+		text := seg.text
+		if seg.code {
+			text = highlightCode(text, seg.lang)
+			if width > 0 {
+				text = ansi.Hardwrap(text, width, true)
+			}
+		} else if width > 0 {
+			text = ansi.Wrap(text, width, "")
+		}
+		parts = append(parts, text)
+	}
+	return strings.Join(parts, "\n")
+}
+
+// Block is a jump target in formatted output: a fenced code block or a
+// file's diff. Line is its first line; Path is the file it belongs to, when
+// the output says.
+type Block struct {
+	Line int
+	Path string
+}
+
+// IndexBlocks finds the blocks of formatted output. A diff right after its
+// fence's opening line is part of that block and names its file; other
+// fences take their path from the line before them, as in "💾 src/main.go".
+func IndexBlocks(formatted string) []Block {
+	var (
+		blocks []Block
+		fence  int
+		prev   string
+	)
+	for i, line := range strings.Split(formatted, "\n") {
+		trimmed := strings.TrimSpace(ansi.Strip(line))
+		switch {
+		case fence > 0 && closesFence(trimmed, fence):
+			fence = 0
+		case fence == 0 && fenceLen(trimmed) > 0:
+			blocks = append(blocks, Block{Line: i, Path: pathHint(prev)})
+			fence = fenceLen(trimmed)
+		case strings.HasPrefix(trimmed, "diff --git "):
+			path := trimmed
+			if j := strings.LastIndex(trimmed, " b/"); j >= 0 {
+				path = trimmed[j+3:]
+			}
+			if n := len(blocks); n > 0 && blocks[n-1].Line == i-1 {
+				blocks[n-1].Path = path
+			} else {
+				blocks = append(blocks, Block{Line: i, Path: path})
+			}
+		}
+		if trimmed != "" {
+			prev = trimmed
+		}
+	}
+	return blocks
+}
+
+// pathHint returns the first word of line that looks like a file path.
+func pathHint(line string) string {
+	for _, f := range strings.Fields(line) {
+		f = strings.Trim(f, "():,")
+		if strings.ContainsAny(f, "./") && !strings.HasSuffix(f, ".") {
+			return f
+		}
+	}
+	return ""
+}
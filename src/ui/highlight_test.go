@@ -0,0 +1,105 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+func TestSplitFences(t *testing.T) {
+	in := "intro\n```go\nfunc main() {}\n```\noutro\n```python\nprint(1)"
+	segs := splitFences(in)
+
+	var code []outputSegment
+	for _, s := range segs {
+		if s.code {
+			code = append(code, s)
+		}
+	}
+	if len(code) != 2 {
+		t.Fatalf("expected 2 code segments, got %d: %+v", len(code), segs)
+	}
+	if code[0].lang != "go" || code[0].text != "func main() {}" {
+		t.Errorf("unexpected go block: %+v", code[0])
+	}
+	if code[1].lang != "python" || code[1].text != "print(1)" {
+		t.Errorf("unterminated block should run to the end: %+v", code[1])
+	}
+}
+
+func TestSplitFencesIgnoresStyling(t *testing.T) {
+	in := "\x1b[38;5;246m```diff\x1b[0m\n+added\n\x1b[38;5;246m```\x1b[0m"
+	segs := splitFences(in)
+	if len(segs) != 3 || !segs[1].code || segs[1].lang != "diff" || segs[1].text != "+added" {
+		t.Errorf("styled fences not detected: %+v", segs)
+	}
+}
+
+func TestSplitFencesLongerFenceHoldsShorter(t *testing.T) {
+	in := "````text\n```go\nx\n```\n````\nafter"
+	segs := splitFences(in)
+	if len(segs) != 3 || !segs[1].code || segs[1].lang != "text" || segs[1].text != "```go\nx\n```" || segs[2].text != "````\nafter" {
+		t.Errorf("segments = %+v", segs)
+	}
+}
+
+func TestFormatOutputHighlightsAndWraps(t *testing.T) {
+	long := strings.Repeat("word ", 30)
+	in := long + "\n```go\nvar x = \"" + strings.Repeat("a", 80) + "\"\n```\n"
+	const width = 40
+
+	out := FormatOutput(in, width)
+
+	if !strings.Contains(out, "\x1b[") {
+		t.Error("expected ANSI colour codes in highlighted output")
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if w := ansi.StringWidth(line); w > width {
+			t.Errorf("line exceeds width %d (%d): %q", width, w, ansi.Strip(line))
+		}
+	}
+	if got := ansi.Strip(out); !strings.Contains(got, "```go") || !strings.Contains(got, "var x") {
+		t.Errorf("visible text lost: %q", got)
+	}
+}
+
+func TestFormatOutputNoWidthLeavesProse(t *testing.T) {
+	in := "plain text only"
+	if got := FormatOutput(in, 0); got != in {
+		t.Errorf("FormatOutput(%q, 0) = %q", in, got)
+	}
+}
+
+func TestIndexBlocks(t *testing.T) {
+	// A formatActions diff, a /diff of two files and an unterminated fence.
+	out := strings.Join([]string{
+		"coder:",
+		"",
+		"💾 src/main.go",
+		"\x1b[38;5;246m```diff\x1b[0m",
+		"\x1b[1;36mdiff --git a/src/main.go b/src/main.go\x1b[0m",
+		"+package main",
+		"```",
+		"changes since HEAD:",
+		"```diff",
+		"diff --git a/a.go b/a.go",
+		"+a",
+		"diff --git a/b.go b/b.go",
+		"+b",
+		"```",
+		"Here is an example.",
+		"```sh",
+		"```python",
+	}, "\n")
+	got := IndexBlocks(FormatOutput(out, 0))
+	want := []Block{{3, "src/main.go"}, {8, "a.go"}, {11, "b.go"}, {15, ""}}
+	if len(got) != len(want) {
+		t.Fatalf("blocks = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("block %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
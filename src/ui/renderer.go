@@ -28,11 +28,8 @@ func Render(s State, styles Styles) string {
 }
 
 func renderHeader(styles Styles) string {
-	logoStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#AD8CFF")).Bold(true).
-		Background(lipgloss.Color("#000000")).UnsetBackground()
 	subtitle := styles.Header.Render("Protocol Lattice")
-	styledLogo := logoStyle.Render(Logo)
+	styledLogo := styles.Logo.Render(Logo)
 
 	return lipgloss.JoinVertical(lipgloss.Left, styledLogo, subtitle)
 }
@@ -40,7 +37,22 @@ func renderHeader(styles Styles) string {
 func renderFooter(s State, styles Styles) string {
 	help := "ctrl+c: quit"
 	if s.Mode == ModeDir {
-		help += " | enter: select | ←/↑/↓/→: navigate"
+		help += " | enter: select | ←/↑/↓/→: navigate | ctrl+o: sort | ctrl+e: details | ctrl+h: hidden | ctrl+p: pin"
+	}
+	if s.Mode == ModeGrep {
+		help += " | enter: show match | /: filter | esc: back"
+	}
+	if s.Mode == ModeChat {
+		if len(s.Completions) > 0 {
+			help += " | tab: complete | ↑/↓: choose"
+		}
+		help += " | ctrl+↑/↓: resize input | ctrl+b: files | ]/[: next/prev block | ctrl+x: save"
+	}
+	if s.Mode == ModeConfig {
+		help += " | enter: edit | esc: back"
+	}
+	if s.Mode == ModeRefactor {
+		help += " | esc: back to chat (refactor keeps running)"
 	}
 	return styles.Footer.Render(help)
 }
@@ -49,9 +61,9 @@ func renderBody(s State, styles Styles) string {
 	switch s.Mode {
 	case ModeDir:
 		return renderDir(s, styles)
-	case ModeList:
+	case ModeList, ModeUTCP, ModeGrep, ModeConfig:
 		return renderList(s, styles)
-	case ModeChat:
+	case ModeChat, ModeRefactor:
 		return renderChat(s, styles)
 	case ModeThinking:
 		return renderThinking(s, styles)
@@ -61,6 +73,8 @@ func renderBody(s State, styles Styles) string {
 		return renderSession(s, styles)
 	case ModeSwarm:
 		return renderSwarm(s, styles)
+	case ModeConfirm:
+		return renderConfirm(s, styles)
 	default:
 		return ""
 	}
@@ -68,7 +82,41 @@ func renderBody(s State, styles Styles) string {
 
 func renderDir(s State, styles Styles) string {
 	pathHeader := styles.Subtitle.Render(fmt.Sprintf("Current: %s", s.WorkingDir))
-	return lipgloss.JoinVertical(lipgloss.Left, pathHeader, s.DirList.View())
+	body := s.DirList.View()
+	if s.DirPreview != nil {
+		body = lipgloss.JoinHorizontal(lipgloss.Top, body, renderDirPreview(*s.DirPreview, s.DirList.Width(), styles))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, pathHeader, body)
+}
+
+// DirPreviewWidth returns the width reserved for the ModeDir preview panel
+// out of the total terminal width.
+func DirPreviewWidth(total int) int {
+	return total / 3
+}
+
+func renderDirPreview(p DirPreview, listWidth int, styles Styles) string {
+	lines := []string{styles.ListHeader.Render(filepath.Base(p.Path))}
+	if p.Err != "" {
+		lines = append(lines, styles.Error.Render(p.Err))
+	} else {
+		lines = append(lines, styles.Subtle.Render(fmt.Sprintf("%d files, %d dirs", p.Files, p.Dirs)))
+		langs := "none"
+		if len(p.Languages) > 0 {
+			langs = strings.Join(p.Languages, ", ")
+		}
+		lines = append(lines, styles.Subtle.Render("Languages: "+langs))
+		for _, marker := range p.Markers {
+			lines = append(lines, styles.Success.Render("✓ "+marker))
+		}
+	}
+
+	panel := styles.Panel
+	// The list takes the remaining two thirds, so the panel is half its width.
+	if w := listWidth/2 - panel.GetHorizontalFrameSize(); w > 0 {
+		panel = panel.Width(w)
+	}
+	return panel.Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
 }
 
 func renderList(s State, styles Styles) string {
@@ -78,10 +126,22 @@ func renderList(s State, styles Styles) string {
 func renderChat(s State, styles Styles) string {
 	var statusItems []string
 	statusItems = append(statusItems, styles.Status.Render(fmt.Sprintf("SESSION: %s", s.SessionID)))
+	if s.Mode == ModeRefactor {
+		statusItems = append(statusItems, styles.Status.Render("REFACTORING"))
+	}
 	if len(s.SharedSpaces) > 0 {
 		statusItems = append(statusItems, styles.Status.Render(fmt.Sprintf("SWARM: %s", strings.Join(s.SharedSpaces, ", "))))
 	}
 	statusItems = append(statusItems, styles.StatusRight.Render(fmt.Sprintf("CTX: %d files (%s)", s.ContextFiles, humanSize(s.ContextBytes))))
+	if s.TokensIn > 0 || s.TokensOut > 0 {
+		statusItems = append(statusItems, styles.Status.Render(fmt.Sprintf("TOK: %s in / %s out (~$%.2f)", HumanCount(s.TokensIn), HumanCount(s.TokensOut), s.Cost)))
+	}
+	if s.BlockLabel != "" {
+		statusItems = append(statusItems, styles.Status.Render("BLOCK: "+s.BlockLabel))
+	}
+	if s.NewOutputBelow {
+		statusItems = append(statusItems, styles.StatusRight.Render("↓ new output below"))
+	}
 
 	status := lipgloss.JoinHorizontal(lipgloss.Top, statusItems...)
 
@@ -96,14 +156,157 @@ func renderChat(s State, styles Styles) string {
 		}
 		metaLines = append(metaLines, styles.Subtle.Render(fmt.Sprintf("Shared chat log: %s", rel)))
 	}
+	// The popup takes its lines from the top of the viewport so the layout
+	// keeps its height.
+	transcript := s.Viewport.View()
+	popup := renderCompletions(s, styles)
+	if popup != "" {
+		lines := strings.Split(transcript, "\n")
+		transcript = strings.Join(lines[min(len(lines), lipgloss.Height(popup)):], "\n")
+	}
 	chatView := lipgloss.JoinVertical(lipgloss.Left,
 		lipgloss.JoinVertical(lipgloss.Left, metaLines...),
-		s.Viewport.View(),
+		transcript,
 		status,
 		renderThinking(s, styles),
+		popup,
 		s.TextArea.View(),
 	)
-	return styles.ChatContainer.Render(chatView)
+	chat := styles.ChatContainer.Render(chatView)
+	if s.FileTreeWidth > 0 {
+		chat = lipgloss.JoinHorizontal(lipgloss.Top, renderFileTree(s.FileTree, s.FileTreeWidth, lipgloss.Height(chat), styles), chat)
+	}
+	if s.ActivityWidth > 0 {
+		chat = lipgloss.JoinHorizontal(lipgloss.Top, chat, renderActivity(s.Activity, s.ActivityWidth, lipgloss.Height(chat), styles))
+	}
+	return chat
+}
+
+// FileTreeWidth returns the width reserved for the file-tree sidebar out of
+// the total terminal width, or 0 when the terminal is too narrow for it.
+func FileTreeWidth(total int) int {
+	if total < 80 {
+		return 0
+	}
+	return min(total/5, 36)
+}
+
+// renderFileTree draws the workspace tree in a panel of the given outer
+// size: "●" marks files in the context snapshot and "M" files changed this
+// session. Rows past the panel's height are summarised.
+func renderFileTree(entries []TreeEntry, width, height int, styles Styles) string {
+	panel := styles.Panel
+	inner := max(1, width-panel.GetHorizontalFrameSize())
+	rows := max(1, height-panel.GetVerticalFrameSize()-1) // -1 for the title
+	line := lipgloss.NewStyle().MaxWidth(inner)
+
+	var lines []string
+	for i, e := range entries {
+		if len(lines) == rows-1 && len(entries)-i > 1 {
+			lines = append(lines, styles.Subtle.Render(fmt.Sprintf("… %d more", len(entries)-i)))
+			break
+		}
+		mark := "  "
+		if e.InContext {
+			mark = styles.Success.Render("●") + " "
+		}
+		name := strings.Repeat("  ", e.Depth) + e.Name
+		if e.Dir {
+			name = styles.Subtle.Render(name + "/")
+		}
+		if e.Changed {
+			name += " " + styles.Accent.Render("M")
+		}
+		lines = append(lines, line.Render(mark+name))
+	}
+	if len(lines) == 0 {
+		lines = append(lines, styles.Subtle.Render("no files"))
+	}
+	body := append([]string{styles.ListHeader.Render("Files")}, lines...)
+	return panel.Width(width - panel.GetHorizontalBorderSize()).
+		Height(height - panel.GetVerticalBorderSize()).
+		Render(strings.Join(body, "\n"))
+}
+
+// ActivityPanelWidth returns the width reserved for the activity panel out
+// of the total terminal width, or 0 when the terminal is too narrow for it.
+func ActivityPanelWidth(total int) int {
+	if total < 80 {
+		return 0
+	}
+	return min(total/4, 48)
+}
+
+// renderActivity draws the activity timeline in a panel of the given outer
+// size. The newest entries are kept when they do not all fit.
+func renderActivity(entries []ActivityEntry, width, height int, styles Styles) string {
+	panel := styles.Panel
+	inner := max(1, width-panel.GetHorizontalFrameSize())
+	rows := max(1, height-panel.GetVerticalFrameSize()-1) // -1 for the title
+	line := lipgloss.NewStyle().MaxWidth(inner)
+
+	var lines []string
+	for _, e := range entries {
+		stamp := ""
+		if !e.At.IsZero() {
+			stamp = e.At.Format("15:04") + " "
+		}
+		if e.Thought {
+			lines = append(lines, line.Render(styles.Subtle.Render(stamp+"💭 "+e.Title)))
+			continue
+		}
+		icon := styles.Accent.Render("•")
+		switch e.Status {
+		case "success":
+			icon = styles.Success.Render("✓")
+		case "error":
+			icon = styles.Error.Render("✗")
+		case "running":
+			icon = styles.Thinking.Render("…")
+		}
+		lines = append(lines, line.Render(stamp+icon+" "+e.Title))
+		detail := e.Kind
+		if e.Detail != "" {
+			detail = strings.TrimPrefix(detail+" · "+e.Detail, " · ")
+		}
+		if detail != "" {
+			lines = append(lines, line.Render(styles.Subtle.Render("  "+detail)))
+		}
+	}
+	if len(lines) > rows {
+		lines = lines[len(lines)-rows:]
+	}
+	if len(lines) == 0 {
+		lines = append(lines, styles.Subtle.Render("no activity yet"))
+	}
+	body := append([]string{styles.ListHeader.Render("Activity")}, lines...)
+	// Width and Height include the padding but not the border.
+	return panel.Width(width - panel.GetHorizontalBorderSize()).
+		Height(height - panel.GetVerticalBorderSize()).
+		Render(strings.Join(body, "\n"))
+}
+
+// maxCompletions caps the number of popup rows shown at once.
+const maxCompletions = 6
+
+// renderCompletions draws the slash-command popup, scrolled so the selected
+// entry is visible.
+func renderCompletions(s State, styles Styles) string {
+	if len(s.Completions) == 0 {
+		return ""
+	}
+	start := max(0, s.CompletionIdx-maxCompletions+1)
+	end := min(len(s.Completions), start+maxCompletions)
+	rows := make([]string, 0, end-start)
+	for i := start; i < end; i++ {
+		c := s.Completions[i]
+		name := "  " + c.Name
+		if i == s.CompletionIdx {
+			name = styles.ListSelected.Render("› " + c.Name)
+		}
+		rows = append(rows, name+"  "+styles.Subtle.Render(c.Description))
+	}
+	return strings.Join(rows, "\n")
 }
 
 func renderThinking(s State, styles Styles) string {
@@ -135,6 +338,62 @@ func renderSwarm(s State, styles Styles) string {
 	)
 }
 
+func renderConfirm(s State, styles Styles) string {
+	if d := s.PendingDiscard; d != nil {
+		lines := []string{
+			styles.ListHeader.Render(fmt.Sprintf("Discard uncommitted agent changes? %d to restore from HEAD, %d new", len(d.Restore), len(d.Remove))),
+		}
+		for _, f := range d.Restore {
+			lines = append(lines, styles.Subtle.Render("  restore "+f))
+		}
+		for _, f := range d.Remove {
+			lines = append(lines, styles.Subtle.Render("  remove  "+f))
+		}
+		help := "y: restore and remove new files | n/esc: cancel"
+		if len(d.Remove) > 0 && len(d.Restore) > 0 {
+			help = "y: restore and remove new files | r: restore only, keep new files | n/esc: cancel"
+		}
+		lines = append(lines, s.Viewport.View(), styles.Help.Render(help))
+		return lipgloss.JoinVertical(lipgloss.Left, lines...)
+	}
+	if b := s.PendingBatch; b != nil {
+		lines := []string{
+			styles.ListHeader.Render(fmt.Sprintf("Replace %q with %q in %d file(s)?", b.Find, b.Replace, len(b.Files))),
+		}
+		for _, f := range b.Files {
+			lines = append(lines, styles.Subtle.Render("  "+f))
+		}
+		lines = append(lines,
+			s.Viewport.View(),
+			styles.Help.Render("y: apply | n/esc: cancel | ↑/↓: scroll"),
+		)
+		return lipgloss.JoinVertical(lipgloss.Left, lines...)
+	}
+	lines := []string{
+		styles.ListHeader.Render(fmt.Sprintf("Write %d file(s) that need confirmation?", len(s.PendingWrites))),
+	}
+	for _, p := range s.PendingWrites {
+		lines = append(lines, styles.Subtle.Render("  "+p))
+	}
+	lines = append(lines,
+		s.Viewport.View(),
+		styles.Help.Render("y: write them | n/esc: skip them | ↑/↓: scroll diff"),
+	)
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
+
+// HumanCount abbreviates large counts, e.g. 12345 -> "12.3k".
+func HumanCount(n int) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(n)/1e6)
+	case n >= 1_000:
+		return fmt.Sprintf("%.1fk", float64(n)/1e3)
+	default:
+		return fmt.Sprintf("%d", n)
+	}
+}
+
 func humanSize(b int64) string {
 	const unit = 1024
 	if b < unit {
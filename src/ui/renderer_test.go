@@ -1,6 +1,8 @@
 package ui
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -8,6 +10,7 @@ import (
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/viewport"
+	"github.com/charmbracelet/lipgloss"
 )
 
 func TestRenderContainsLogo(t *testing.T) {
@@ -201,3 +204,119 @@ func TestNewStyles(t *testing.T) {
 		t.Errorf("Accent style should have a foreground color")
 	}
 }
+
+func TestRenderDirModeShowsPreview(t *testing.T) {
+	styles := NewStyles()
+	state := State{
+		Mode:       ModeDir,
+		WorkingDir: "/home/user",
+		DirList:    list.New([]list.Item{}, list.NewDefaultDelegate(), 60, 10),
+		DirPreview: &DirPreview{
+			Path:      "/home/user/project",
+			Files:     3,
+			Dirs:      1,
+			Languages: []string{"go"},
+			Markers:   []string{"go.mod"},
+		},
+	}
+
+	output := Render(state, styles)
+
+	for _, want := range []string{"project", "3 files, 1 dirs", "Languages: go", "go.mod"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected dir preview to contain %q", want)
+		}
+	}
+}
+
+func TestRenderChatShowsTokenUsage(t *testing.T) {
+	styles := NewStyles()
+	state := State{
+		Mode:      ModeChat,
+		Viewport:  viewport.New(120, 5),
+		TextArea:  textarea.New(),
+		Spinner:   spinner.New(),
+		TokensIn:  12345,
+		TokensOut: 678,
+		Cost:      0.02,
+	}
+
+	output := Render(state, styles)
+
+	if !strings.Contains(output, "TOK: 12.3k in / 678 out (~$0.02)") {
+		t.Errorf("Expected status bar to show token usage")
+	}
+}
+
+func TestRenderChatShowsNewOutputIndicator(t *testing.T) {
+	styles := NewStyles()
+	state := State{
+		Mode:     ModeChat,
+		Viewport: viewport.New(120, 5),
+		TextArea: textarea.New(),
+		Spinner:  spinner.New(),
+	}
+	if strings.Contains(Render(state, styles), "new output below") {
+		t.Errorf("Indicator should be hidden while following output")
+	}
+	state.NewOutputBelow = true
+	if !strings.Contains(Render(state, styles), "new output below") {
+		t.Errorf("Expected status bar to show new output indicator")
+	}
+}
+
+func TestNewStylesFromPalette(t *testing.T) {
+	p := Palette{
+		Primary:   "#112233",
+		Highlight: "#445566",
+		OnPrimary: "#000000",
+		Muted:     "#777777",
+		Dim:       "#888888",
+		Faint:     "#999999",
+		Error:     "#AA0000",
+		Success:   "#00AA00",
+	}
+	styles := NewStylesFromPalette(p)
+
+	checks := map[string]struct {
+		got  lipgloss.TerminalColor
+		want string
+	}{
+		"Accent":       {styles.Accent.GetForeground(), p.Primary},
+		"ListSelected": {styles.ListSelected.GetForeground(), p.Highlight},
+		"Status bg":    {styles.Status.GetBackground(), p.Primary},
+		"Status fg":    {styles.Status.GetForeground(), p.OnPrimary},
+		"Error":        {styles.Error.GetForeground(), p.Error},
+		"Success":      {styles.Success.GetForeground(), p.Success},
+		"Panel border": {styles.Panel.GetBorderTopForeground(), p.Faint},
+		"Logo":         {styles.Logo.GetForeground(), p.Primary},
+	}
+	for name, c := range checks {
+		if c.got != lipgloss.Color(c.want) {
+			t.Errorf("%s color = %v; want %s", name, c.got, c.want)
+		}
+	}
+}
+
+func TestLoadPalette(t *testing.T) {
+	p, err := LoadPalette("High-Contrast")
+	if err != nil || p != Themes["high-contrast"] {
+		t.Fatalf("LoadPalette(builtin) = %+v, %v", p, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "theme.json")
+	if err := os.WriteFile(path, []byte(`{"primary":"#123456"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	p, err = LoadPalette(path)
+	if err != nil {
+		t.Fatalf("LoadPalette(file): %v", err)
+	}
+	if p.Primary != "#123456" || p.Error != Themes[DefaultTheme].Error {
+		t.Errorf("file palette should override primary and keep defaults: %+v", p)
+	}
+
+	if _, err := LoadPalette("no-such-theme"); err == nil {
+		t.Error("expected error for unknown theme")
+	}
+}
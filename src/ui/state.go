@@ -1,6 +1,8 @@
 package ui
 
 import (
+	"time"
+
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textarea"
@@ -23,6 +25,9 @@ const (
 	ModeRefactor
 	ModeSession
 	ModeSwarm
+	ModeConfirm
+	ModeGrep
+	ModeConfig
 )
 
 // State contains all the data required to render the UI.
@@ -39,6 +44,21 @@ type State struct {
 	ThinkingText   string
 	Output         string
 	SelectedAgent  string
+	DirPreview     *DirPreview
+	PendingWrites  []string        // paths awaiting write confirmation
+	PendingBatch   *BatchPreview   // batch refactor awaiting confirmation
+	PendingDiscard *DiscardPreview // /discard awaiting confirmation
+	TokensIn       int             // estimated prompt tokens this session
+	TokensOut      int             // estimated completion tokens this session
+	Cost           float64         // estimated spend in USD
+	NewOutputBelow bool            // output arrived while the user was scrolled up
+	BlockLabel     string          // code block jumped to with ]/[, e.g. "2/5 src/main.go"
+	Completions    []Completion    // slash commands matching the chat input
+	CompletionIdx  int             // selected entry of Completions
+	FileTree       []TreeEntry     // workspace tree for the sidebar
+	FileTreeWidth  int             // width of the file-tree sidebar; 0 hides it
+	Activity       []ActivityEntry // agent timeline, oldest first
+	ActivityWidth  int             // width of the activity panel; 0 hides it
 
 	// Bubble Tea models
 	List     list.Model
@@ -47,3 +67,47 @@ type State struct {
 	Viewport viewport.Model
 	Spinner  spinner.Model
 }
+
+// TreeEntry is one row of the file-tree sidebar.
+type TreeEntry struct {
+	Name      string
+	Depth     int
+	Dir       bool
+	InContext bool // in the current context snapshot
+	Changed   bool // written this session; for directories, anything beneath
+}
+
+// ActivityEntry is one row of the activity panel: something the agent did
+// or, with Thought set, a note on what it is working on.
+type ActivityEntry struct {
+	At                          time.Time
+	Kind, Title, Status, Detail string
+	Thought                     bool
+}
+
+// Completion is one entry of the slash-command autocomplete popup.
+type Completion struct {
+	Name, Description string
+}
+
+// BatchPreview describes a planned batch refactor shown in ModeConfirm.
+type BatchPreview struct {
+	Find, Replace string
+	Files         []string
+}
+
+// DiscardPreview lists what a confirmed /discard would do.
+type DiscardPreview struct {
+	Restore []string // files checked out from HEAD
+	Remove  []string // new files deleted
+}
+
+// DirPreview summarises the directory highlighted in ModeDir.
+type DirPreview struct {
+	Path      string
+	Files     int
+	Dirs      int
+	Languages []string
+	Markers   []string // project files found, e.g. go.mod or package.json
+	Err       string
+}
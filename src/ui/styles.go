@@ -21,25 +21,33 @@ type Styles struct {
 	ChatContainer lipgloss.Style
 	Subtle        lipgloss.Style
 	Center        lipgloss.Style
+	Panel         lipgloss.Style
+	Logo          lipgloss.Style
 }
 
+// NewStyles builds Styles from the default theme.
 func NewStyles() Styles {
+	return NewStylesFromPalette(Themes[DefaultTheme])
+}
+
+// NewStylesFromPalette builds Styles from the colors in p.
+func NewStylesFromPalette(p Palette) Styles {
 	return Styles{
 		Header: lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#555")).
+			Foreground(lipgloss.Color(p.Faint)).
 			Faint(true).
 			Padding(0, 1),
 
 		Subtitle: lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#999999")).
+			Foreground(lipgloss.Color(p.Muted)).
 			Padding(0, 1),
 
 		List: lipgloss.NewStyle().
 			Border(lipgloss.RoundedBorder()).
-			BorderForeground(lipgloss.Color("#AD8CFF")),
+			BorderForeground(lipgloss.Color(p.Primary)),
 
 		ListHeader: lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#AD8CFF")).
+			Foreground(lipgloss.Color(p.Primary)).
 			Bold(true).
 			Padding(0, 1),
 
@@ -47,52 +55,61 @@ func NewStyles() Styles {
 			Padding(0, 1),
 
 		ListSelected: lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#00E6B8")).
+			Foreground(lipgloss.Color(p.Highlight)).
 			Bold(true),
 
 		Textarea: lipgloss.NewStyle().
 			Border(lipgloss.RoundedBorder()).
-			BorderForeground(lipgloss.Color("#AD8CFF")),
+			BorderForeground(lipgloss.Color(p.Primary)),
 
 		Help: lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#777777")),
+			Foreground(lipgloss.Color(p.Dim)),
 
 		Footer: lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#777777")).
+			Foreground(lipgloss.Color(p.Dim)).
 			Faint(true),
 
 		Accent: lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#AD8CFF")),
+			Foreground(lipgloss.Color(p.Primary)),
 
 		Error: lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#FF5C5C")).
+			Foreground(lipgloss.Color(p.Error)).
 			Bold(true),
 
 		Success: lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#3DDC97")).
+			Foreground(lipgloss.Color(p.Success)).
 			Bold(true),
 
 		Thinking: lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#3DDC97")),
+			Foreground(lipgloss.Color(p.Success)),
 
 		Status: lipgloss.NewStyle().
-			Background(lipgloss.Color("#AD8CFF")).
-			Foreground(lipgloss.Color("#FFFFFF")).
+			Background(lipgloss.Color(p.Primary)).
+			Foreground(lipgloss.Color(p.OnPrimary)).
 			Padding(0, 1),
 
 		StatusRight: lipgloss.NewStyle().
 			Inherit(lipgloss.NewStyle().
-				Background(lipgloss.Color("#AD8CFF")).
-				Foreground(lipgloss.Color("#FFFFFF")).
+				Background(lipgloss.Color(p.Primary)).
+				Foreground(lipgloss.Color(p.OnPrimary)).
 				Padding(0, 1)).Align(lipgloss.Right),
 
 		ChatContainer: lipgloss.NewStyle().
-			Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("#AD8CFF")).Padding(0, 1),
+			Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color(p.Primary)).Padding(0, 1),
 
 		Subtle: lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#999999")),
+			Foreground(lipgloss.Color(p.Muted)),
 
 		Center: lipgloss.NewStyle().
 			Align(lipgloss.Center),
+
+		Panel: lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(lipgloss.Color(p.Faint)).
+			Padding(0, 1),
+
+		Logo: lipgloss.NewStyle().
+			Foreground(lipgloss.Color(p.Primary)).
+			Bold(true),
 	}
 }
@@ -0,0 +1,89 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Palette is the set of colors Styles is built from. Values are anything
+// lipgloss.Color accepts, e.g. "#AD8CFF" or an ANSI index like "12".
+type Palette struct {
+	Primary   string `json:"primary"`    // borders, status bar, logo
+	Highlight string `json:"highlight"`  // selected list items
+	OnPrimary string `json:"on_primary"` // text drawn on Primary
+	Muted     string `json:"muted"`      // subtitles, secondary text
+	Dim       string `json:"dim"`        // help and footer text
+	Faint     string `json:"faint"`      // header and panel borders
+	Error     string `json:"error"`
+	Success   string `json:"success"`
+}
+
+// Themes are the built-in palettes selectable by name.
+var Themes = map[string]Palette{
+	"dark": {
+		Primary:   "#AD8CFF",
+		Highlight: "#00E6B8",
+		OnPrimary: "#FFFFFF",
+		Muted:     "#999999",
+		Dim:       "#777777",
+		Faint:     "#555",
+		Error:     "#FF5C5C",
+		Success:   "#3DDC97",
+	},
+	"light": {
+		Primary:   "#6B3FD4",
+		Highlight: "#007A63",
+		OnPrimary: "#FFFFFF",
+		Muted:     "#555555",
+		Dim:       "#666666",
+		Faint:     "#999999",
+		Error:     "#C62828",
+		Success:   "#1B7F4B",
+	},
+	"high-contrast": {
+		Primary:   "#FFFF00",
+		Highlight: "#00FFFF",
+		OnPrimary: "#000000",
+		Muted:     "#FFFFFF",
+		Dim:       "#FFFFFF",
+		Faint:     "#FFFFFF",
+		Error:     "#FF0000",
+		Success:   "#00FF00",
+	},
+}
+
+// DefaultTheme is used when no theme is selected.
+const DefaultTheme = "dark"
+
+// ThemeNames lists the built-in themes in a stable order.
+func ThemeNames() []string {
+	names := make([]string, 0, len(Themes))
+	for name := range Themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadPalette resolves a built-in theme name or reads a JSON palette file.
+// Colors missing from a file fall back to the default theme.
+func LoadPalette(nameOrPath string) (Palette, error) {
+	if nameOrPath == "" {
+		nameOrPath = DefaultTheme
+	}
+	if p, ok := Themes[strings.ToLower(nameOrPath)]; ok {
+		return p, nil
+	}
+	data, err := os.ReadFile(nameOrPath)
+	if err != nil {
+		return Palette{}, fmt.Errorf("unknown theme %q (built-in: %s): %w", nameOrPath, strings.Join(ThemeNames(), ", "), err)
+	}
+	p := Themes[DefaultTheme]
+	if err := json.Unmarshal(data, &p); err != nil {
+		return Palette{}, fmt.Errorf("parse theme %s: %w", nameOrPath, err)
+	}
+	return p, nil
+}
@@ -1,22 +1,46 @@
 package src
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
+	"sync"
+	"text/tabwriter"
 	"time"
 	"unicode"
 
+	agent "github.com/Protocol-Lattice/go-agent"
 	"github.com/Protocol-Lattice/go-agent/src/models"
+	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
 
 	"github.com/Protocol-Lattice/lattice-code/src/ui"
 )
 
+// fileActionMsg reports a code fence written while a response is still being applied.
+type fileActionMsg struct {
+	action FileAction
+}
+
+// tokenChunkMsg carries the next piece of a response that is still being
+// generated.
+type tokenChunkMsg struct {
+	text string
+}
+
+// statusMsg replaces the thinking line of the work in flight. Background
+// work sends it through reportStatus.
+type statusMsg struct {
+	text string
+}
+
 // codegenStatusMsg is sent from the locking mechanism to update the UI.
 type codegenStatusMsg struct {
 	msg string
@@ -25,6 +49,23 @@ type codegenStatusMsg struct {
 
 type plannerTickMsg struct{}
 
+// confirmWritesMsg carries writes that need the user's approval before
+// overwriting files the agent did not create.
+type confirmWritesMsg struct {
+	actions []FileAction
+}
+
+// contextChangedMsg asks the UI thread to refresh the context panel and
+// file tree after background work wrote files.
+type contextChangedMsg struct{}
+
+// agentBuiltMsg carries the agent rebuilt by rebuildAgentCmd for dir.
+type agentBuiltMsg struct {
+	dir   string
+	agent *agent.Agent
+	err   error
+}
+
 func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 
@@ -41,13 +82,18 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case transcriptSyncMsg:
 		if msg.err != nil {
 			if errors.Is(msg.err, os.ErrNotExist) {
+				m.trimmedBytes, m.transcriptBase = 0, 0 // the trimmed part went with the file
 				m.persistTranscript()
 			}
 			return m, nil
 		}
-		if msg.checksum != m.lastTranscriptSig {
+		if msg.checksum != m.lastTranscriptSig || msg.offset != m.transcriptBase+m.trimmedBytes {
 			m.mu.Lock()
 			m.output = msg.content
+			if msg.offset < m.transcriptBase {
+				m.transcriptBase = msg.offset
+			}
+			m.trimmedBytes = msg.offset - m.transcriptBase
 			m.lastTranscriptSig = msg.checksum
 			m.mu.Unlock()
 			m.renderOutput(false)
@@ -55,30 +101,71 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case tea.WindowSizeMsg:
-		// Calculate header height: logo (7 lines) + subtitle (1 line) + padding
-		headerHeight := 8
-		// Footer is a single line with padding
-		footerHeight := 2
-		chatContainerVPadding := m.style.ChatContainer.GetVerticalPadding()
-		chatContainerHPadding := m.style.ChatContainer.GetHorizontalPadding()
 		m.width, m.height = msg.Width, msg.Height
-		m.list.SetSize(m.width-chatContainerHPadding-2, m.height-headerHeight-footerHeight-chatContainerVPadding-2)
-		m.dirlist.SetSize(m.width, m.height-headerHeight-footerHeight-2)                                             // No container padding
-		m.textarea.SetWidth(m.width - chatContainerHPadding - 2)                                                     // -2 for border
-		m.viewport.Width = m.width - chatContainerHPadding - 2                                                       // -2 for border
-		m.viewport.Height = m.height - headerHeight - footerHeight - m.textarea.Height() - chatContainerVPadding - 4 // -4 for subtitle, status, thinking
+		m.layout()
 		return m, nil
 
 	case tea.KeyMsg:
+		// While the grep results are being filtered, keys belong to the filter input.
+		if m.mode == ui.ModeGrep && m.list.FilterState() == list.Filtering && msg.String() != "ctrl+c" {
+			var cmd tea.Cmd
+			m.list, cmd = m.list.Update(msg)
+			return m, cmd
+		}
 		switch msg.String() {
 
 		case "ctrl+c":
 			return m, tea.Quit
 
+		case "ctrl+up", "ctrl+down": // Grow or shrink the input area
+			if m.mode == ui.ModeChat || m.mode == ui.ModePrompt {
+				delta := 1
+				if msg.String() == "ctrl+down" {
+					delta = -1
+				}
+				m.resizeInput(m.textarea.Height() + delta)
+				return m, nil
+			}
+
+		case "up", "down": // Move through completions, or recall earlier prompts
+			if m.mode == ui.ModeChat && !m.prompts.browsing() && len(m.completions()) > 0 {
+				if msg.String() == "up" {
+					m.moveCompletion(-1)
+				} else {
+					m.moveCompletion(1)
+				}
+				return m, nil
+			}
+			if m.mode == ui.ModeChat && m.recallPrompt(msg.String() == "up") {
+				return m, nil
+			}
+
+		case "]", "[": // Jump between code blocks and diffs while the input is empty
+			if m.mode == ui.ModeChat && m.textarea.Value() == "" {
+				m.jumpBlock(msg.String() == "]")
+				return m, nil
+			}
+
+		case "tab": // Complete the selected slash command
+			if m.mode == ui.ModeChat && m.acceptCompletion() {
+				return m, nil
+			}
+
 		case "ctrl+d": // New: shortcut to change directory
 			m.mode = ui.ModeDir
+			m.reloadDirs()
 			return m, nil
 
+		case "ctrl+p": // Pin or unpin the highlighted directory
+			if m.mode == ui.ModeDir {
+				if item, ok := m.dirlist.SelectedItem().(dirItem); ok && item.name != "⬆️ ../" {
+					m.recents.togglePin(item.path)
+					_ = m.recents.save(m.recentsPath)
+					m.reloadDirs()
+				}
+				return m, nil
+			}
+
 		case "ctrl+s": // New: set session ID
 			m.prevMode = m.mode
 			m.mode = ui.ModeSession
@@ -95,14 +182,85 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.textarea.Focus()
 			return m, nil
 
+		case "ctrl+b": // Toggle the file-tree sidebar
+			if m.mode == ui.ModeChat {
+				m.showFiles = !m.showFiles
+				m.refreshFileTree()
+				m.layout()
+				return m, nil
+			}
+
+		case "ctrl+x": // Save the last result; the user types the file name
+			if m.mode == ui.ModeChat {
+				m.textarea.SetValue("/save ")
+				m.textarea.Focus()
+				return m, nil
+			}
+
+		case "ctrl+o": // Toggle directory sort order
+			if m.mode == ui.ModeDir {
+				if m.dirOpts.sortBy == sortByName {
+					m.dirOpts.sortBy = sortByModTime
+				} else {
+					m.dirOpts.sortBy = sortByName
+				}
+				m.dirlist.Title = fmt.Sprintf("Choose Working Directory (sorted by %s)", m.dirOpts.sortBy)
+				m.reloadDirs()
+				return m, nil
+			}
+
+		case "ctrl+e": // Toggle directory details
+			if m.mode == ui.ModeDir {
+				m.dirOpts.details = !m.dirOpts.details
+				m.reloadDirs()
+				return m, nil
+			}
+
+		case "ctrl+h": // Toggle hidden and ignored directories
+			if m.mode == ui.ModeDir {
+				m.dirOpts.showHidden = !m.dirOpts.showHidden
+				m.reloadDirs()
+				return m, nil
+			}
+
+		case "y":
+			if m.mode == ui.ModeConfirm {
+				if m.pendingDiscard != nil {
+					m.resolvePendingDiscard(true, true)
+					return m, nil
+				}
+				if m.pendingBatch != nil {
+					return m, m.resolvePendingBatch(true)
+				}
+				m.resolvePendingWrites(true)
+				return m, nil
+			}
+
+		case "r":
+			if m.mode == ui.ModeConfirm && m.pendingDiscard != nil {
+				m.resolvePendingDiscard(true, false)
+				return m, nil
+			}
+
+		case "n":
+			if m.mode == ui.ModeConfirm {
+				if m.pendingDiscard != nil {
+					m.resolvePendingDiscard(false, false)
+					return m, nil
+				}
+				if m.pendingBatch != nil {
+					return m, m.resolvePendingBatch(false)
+				}
+				m.resolvePendingWrites(false)
+				return m, nil
+			}
+
 		case "left":
 			if m.mode == ui.ModeDir {
 				parent := filepath.Dir(m.working)
 				if parent != m.working { // This check is sufficient and correct
 					m.working = parent
-					items := loadDirs(m.working)
-					m.dirlist.SetItems(items)
-					m.dirlist.Select(0)
+					m.reloadDirs()
 				}
 				return m, nil
 			}
@@ -145,6 +303,23 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.mode = ui.ModeList
 				m.list.Title = "Agents"
 				m.list.SetItems(defaultAgents())
+			case ui.ModeConfirm:
+				if m.pendingDiscard != nil {
+					m.resolvePendingDiscard(false, false)
+					break
+				}
+				if m.pendingBatch != nil {
+					return m, m.resolvePendingBatch(false)
+				}
+				m.resolvePendingWrites(false)
+			case ui.ModeGrep:
+				m.closeGrepResults()
+			case ui.ModeConfig:
+				m.list.SetItems(defaultAgents())
+				m.list.Title = fmt.Sprintf("📁 %s", filepath.Base(m.working))
+				m.mode = ui.ModeChat
+			case ui.ModeRefactor:
+				m.mode = ui.ModeChat // the refactor keeps running in the background
 			}
 			return m, nil
 
@@ -158,6 +333,27 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.mode = ui.ModeChat
 					m.refreshContext() // Refresh context on agent selection
 					m.textarea.Focus()
+					if i.name == "utcp" {
+						m.openUTCPTools()
+					}
+				}
+				return m, nil
+
+			case ui.ModeUTCP:
+				if u, ok := m.list.SelectedItem().(utcpItem); ok {
+					m.pickUTCPTool(u)
+				}
+				return m, nil
+
+			case ui.ModeGrep:
+				if g, ok := m.list.SelectedItem().(grepItem); ok {
+					m.openGrepMatch(g)
+				}
+				return m, nil
+
+			case ui.ModeConfig:
+				if c, ok := m.list.SelectedItem().(configItem); ok {
+					m.editConfigItem(c)
 				}
 				return m, nil
 
@@ -169,11 +365,25 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 				// --- Confirm current directory ---
 				if strings.HasPrefix(item.name, "✅") {
+					m.recents.record(m.working)
+					_ = m.recents.save(m.recentsPath)
+					useLogWorkspace(m.working, m.cfg.LogLevel)
+					m.prompts = loadPromptHistory(promptHistoryPath(m.working))
+					if !isGitRepo(m.ctx, m.working) {
+						m.baseline = takeSnapshot(m.working) // git repos diff against baseRev instead
+					} else if rev, err := git(m.ctx, m.working, "rev-parse", "HEAD"); err == nil {
+						m.baseRev = rev
+					}
+					sessionLog(m.sessionID).Info("workspace selected", "dir", m.working)
 					m.mode = ui.ModeChat // Go to chat after selecting dir
 					m.list.Title = fmt.Sprintf("📁 %s", filepath.Base(m.working))
 					m.list.SetItems(defaultAgents())
 					m.refreshContext() // Refresh context after confirming directory
-					return m, nil
+					m.refreshFileTree()
+					if err := os.MkdirAll(filepath.Join(m.working, latticeDir), 0o755); err == nil {
+						m.openTranscript(transcriptFile(m.working))
+					}
+					return m, tea.Batch(m.scheduleTranscriptTick(), m.rebuildAgentCmd(m.working))
 				}
 
 				// --- Go up one level ---
@@ -181,9 +391,7 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					parent := filepath.Dir(m.working)
 					if parent != m.working {
 						m.working = parent
-						items := loadDirs(m.working)
-						m.dirlist.SetItems(items)
-						m.dirlist.Select(0)
+						m.reloadDirs()
 					}
 					return m, nil
 				}
@@ -192,9 +400,7 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				info, err := os.Stat(item.path)
 				if err == nil && info.IsDir() {
 					m.working = item.path
-					items := loadDirs(m.working)
-					m.dirlist.SetItems(items)
-					m.dirlist.Select(0)
+					m.reloadDirs()
 					return m, nil
 				}
 
@@ -211,16 +417,29 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					return m, nil
 				}
 
+				m.prompts.add(raw)
+				if err := m.prompts.save(promptHistoryPath(m.working)); err != nil {
+					sessionLog(m.sessionID).Warn("saving prompt history", "err", err)
+				}
+
 				// Reset textarea and show user input
 				m.textarea.Reset()
 				m.output += m.style.Accent.Render("You: ") + raw + "\n\n"
 				m.renderOutput(true)
 
-				// 🧠 Always set thinking state on every new prompt
-				m.isThinking = true
-				m.thinking = "thinking"
 				m.plannerQueue = make(chan string, 64)
 
+				if strings.HasPrefix(raw, "/") {
+					return m.handleSlashCommand(raw)
+				}
+
+				if m.selected.name == "explain" {
+					return m.runExplain(raw)
+				}
+				if m.selected.name == "codemode" {
+					return m.runCodeMode(raw)
+				}
+
 				// --- 1️⃣ UTCP command flow ---
 				if strings.HasPrefix(raw, "@utcp ") {
 					jsonStr := strings.TrimSpace(strings.TrimPrefix(raw, "@utcp "))
@@ -248,8 +467,6 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						return m, nil
 					}
 
-					m.thinking = "calling UTCP tool"
-
 					cmd := func() tea.Msg {
 						if payload.Stream {
 							return m.callUTCPStream(payload.Tool, payload.Args)
@@ -257,14 +474,14 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						return m.callUTCP(payload.Tool, payload.Args)
 					}
 
-					return m, tea.Batch(cmd, m.spinner.Tick)
+					return m, tea.Batch(cmd, m.startWork("calling UTCP tool "+payload.Tool))
 				}
 
 				// --- 2️⃣ Default: orchestrator / planner ---
 				RunPlanner(m.ctx, m.agent, m.working, raw, m)
 				return m, tea.Batch(
 					tea.Tick(time.Millisecond*100, func(time.Time) tea.Msg { return plannerTickMsg{} }),
-					m.spinner.Tick,
+					m.startWork("planning"),
 				)
 
 			case ui.ModeSession:
@@ -293,17 +510,32 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		}
 
+	case tokenChunkMsg:
+		m.streamed += msg.text
+		m.renderOutput(false)
+		return m, nil
+
 	case generateMsg:
-		m.isThinking = false
+		m.endWork()
+		m.streamed = "" // the final text below replaces the live preview
+		if m.mode == ui.ModeRefactor {
+			m.mode = ui.ModeChat
+		}
 		if msg.err != nil {
+			m.AddActivity("run", "Run failed", "error", msg.err.Error())
 			m.output += m.style.Error.Render(fmt.Sprintf("❌ %v\n", msg.err))
 		} else {
+			m.AddActivity("run", "Run finished", "success", "")
 			m.output += msg.text
+			if msg.text != "" {
+				m.lastResult = msg.text
+			}
 			if msg.text != "" && !strings.HasSuffix(msg.text, "\n") {
 				m.output += "\n"
 			}
 		}
 		m.refreshContext()
+		m.refreshFileTree()
 		m.renderOutput(true)
 		return m, nil
 
@@ -313,8 +545,7 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case stepBuildCompleteMsg:
-		m.isThinking = false
-		m.thinking = ""
+		// The planner's work ends when its queue closes, below.
 		m.renderOutput(true)
 		return m, nil
 
@@ -328,8 +559,9 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			case line, ok := <-m.plannerQueue:
 				if !ok {
 					// channel closed, stop ticking
-					m.isThinking = false
-					m.thinking = ""
+					m.AddActivity("plan", "Planner finished", "success", "")
+					m.endWork()
+					m.refreshFileTree()
 					m.renderOutput(true)
 					return m, nil
 				}
@@ -345,10 +577,68 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		}
 
+	case statusMsg:
+		if m.isThinking {
+			m.AddThought(msg.text)
+		}
+		m.setStatus(msg.text)
+		return m, nil
+
+	case fileActionMsg:
+		m.setStatus("writing " + msg.action.Path)
+		m.AddActivity("write", msg.action.Path, "success", msg.action.Message)
+		m.output += m.style.Subtle.Render(fmt.Sprintf("✍️ %s (%s)\n", msg.action.Path, msg.action.Message))
+		m.renderOutput(true)
+		return m, nil
+
+	case agentBuiltMsg:
+		if msg.err != nil {
+			m.output += m.style.Error.Render(fmt.Sprintf("⚠️ Keeping the launch directory's system prompt: %v\n", msg.err))
+		} else {
+			m.agent, m.agentDir = msg.agent, msg.dir
+			sessionLog(m.sessionID).Info("agent rebuilt for workspace system prompt", "dir", msg.dir)
+		}
+		m.renderOutput(true)
+		return m, nil
+
+	case contextChangedMsg:
+		m.refreshContext()
+		m.refreshFileTree()
+		return m, nil
+
+	case batchPlanMsg:
+		m.showBatchPlan(msg.plan)
+		return m, nil
+
+	case grepResultsMsg:
+		m.showGrepResults(msg)
+		return m, nil
+
+	case diffMsg:
+		m.showDiff(msg)
+		return m, nil
+
+	case confirmWritesMsg:
+		m.pendingWrites = append(m.pendingWrites, msg.actions...)
+		m.output += m.style.Accent.Render(fmt.Sprintf("⚠️ %d file write(s) need confirmation:\n", len(msg.actions)))
+		for _, a := range msg.actions {
+			m.output += fmt.Sprintf("%s — %s\n", a.Path, a.Message)
+			if strings.TrimSpace(a.Diff) != "" {
+				m.output += m.style.Subtle.Render("```diff") + "\n" + a.Diff + m.style.Subtle.Render("```") + "\n"
+			}
+		}
+		if m.mode != ui.ModeConfirm {
+			m.prevMode = m.mode
+			m.mode = ui.ModeConfirm
+		}
+		m.renderOutput(true)
+		return m, nil
+
 	case codegenStatusMsg:
 		if msg.err != nil {
 			m.output += m.style.Error.Render(fmt.Sprintf("❌ %v\n", msg.err))
 		} else if msg.msg != "" {
+			m.setStatus(msg.msg)
 			m.output += m.style.Subtle.Render(msg.msg + "\n")
 		}
 		m.renderOutput(true)
@@ -362,12 +652,23 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch m.mode {
 	case ui.ModeDir:
 		m.dirlist, newCmd = m.dirlist.Update(msg)
-	case ui.ModeList, ui.ModeUTCP:
+	case ui.ModeList, ui.ModeUTCP, ui.ModeGrep, ui.ModeConfig:
 		m.list, newCmd = m.list.Update(msg)
-	case ui.ModePrompt, ui.ModeUTCPArgs, ui.ModeChat, ui.ModeSession, ui.ModeSwarm:
+	case ui.ModeConfirm:
+		m.viewport, newCmd = m.viewport.Update(msg)
+	case ui.ModePrompt, ui.ModeUTCPArgs, ui.ModeChat, ui.ModeSession, ui.ModeSwarm, ui.ModeRefactor:
 		var textareaCmd, viewportCmd tea.Cmd
+		before := m.textarea.Value()
 		m.textarea, textareaCmd = m.textarea.Update(msg)
-		m.viewport, viewportCmd = m.viewport.Update(msg)
+		if m.textarea.Value() != before {
+			m.completeIdx = 0
+		}
+		if scrollsViewport(msg) {
+			m.viewport, viewportCmd = m.viewport.Update(msg)
+			if _, ok := msg.(tea.KeyMsg); ok {
+				m.syncScrollLock()
+			}
+		}
 		newCmd = tea.Batch(textareaCmd, viewportCmd)
 	}
 	cmd = tea.Batch(cmd, newCmd) // Batch commands from the switch with existing commands
@@ -381,38 +682,152 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m *model) callUTCP(toolName string, args map[string]any) tea.Msg {
-	res, err := m.agent.UTCPClient.CallTool(m.ctx, toolName, args)
+	res, err := runWithTimeout(m.ctx, toolName, m.cfg.UTCPTimeout, func(ctx context.Context) (any, error) {
+		return m.agent.UTCPClient.CallTool(ctx, toolName, args)
+	})
 	if err != nil {
 		return generateMsg{"", err}
 	}
-	return generateMsg{fmt.Sprintf("%v", res), nil}
+	return generateMsg{formatToolResult(res), nil}
 }
 
+// callUTCPStream reads the whole stream under the UTCP timeout; items read
+// before a deadline or stream error are kept.
 func (m *model) callUTCPStream(toolName string, args map[string]any) tea.Msg {
-	stream, err := m.agent.UTCPClient.CallToolStream(m.ctx, toolName, args)
-	if err != nil {
-		return generateMsg{"", err}
-	}
 	var out strings.Builder
 	out.WriteString(m.style.Accent.Render(fmt.Sprintf("UTCP Stream (%s):", toolName)) + "\n")
-	for {
-		item, err := stream.Next()
-		if err == io.EOF {
-			break
-		}
+	var (
+		mu    sync.Mutex // the reader may still be running after a timeout
+		items int
+	)
+	_, err := runWithTimeout(m.ctx, toolName, m.cfg.UTCPTimeout, func(ctx context.Context) (struct{}, error) {
+		stream, err := m.agent.UTCPClient.CallToolStream(ctx, toolName, args)
 		if err != nil {
-			out.WriteString("\n" + m.style.Error.Render(fmt.Sprintf("❌ Stream error: %v", err)))
-			break // Stop on stream error
+			return struct{}{}, err
+		}
+		defer stream.Close()
+		for {
+			item, err := stream.Next()
+			if err == io.EOF {
+				return struct{}{}, nil
+			}
+			mu.Lock()
+			if err != nil {
+				out.WriteString("\n" + m.style.Error.Render(fmt.Sprintf("❌ Stream error: %v", err)))
+				mu.Unlock()
+				return struct{}{}, nil // Stop on stream error
+			}
+			// Items are buffered and returned as one message.
+			out.WriteString(formatToolResult(item) + "\n")
+			items++
+			mu.Unlock()
+			if ctx.Err() != nil {
+				return struct{}{}, ctx.Err()
+			}
 		}
-		// Render each item as it arrives
-		// This part is tricky in a non-streaming UI update model.
-		// For now, we buffer and return one message.
-		// A more advanced implementation would use tea.Cmd to send progress messages.
-		out.WriteString(fmt.Sprintf("%v\n", item))
+	})
+	mu.Lock()
+	defer mu.Unlock()
+	if err != nil && items == 0 {
+		return generateMsg{"", err}
+	}
+	if err != nil {
+		out.WriteString("\n" + m.style.Error.Render(fmt.Sprintf("❌ %v", err)))
 	}
 	return generateMsg{out.String(), nil}
 }
 
+// formatToolResult renders a UTCP result for the transcript. Structured
+// values (including strings holding JSON) become an indented JSON block, or a
+// table when they are a list of objects; anything else prints with %v.
+func formatToolResult(res any) string {
+	v := res
+	switch r := res.(type) {
+	case string:
+		if !json.Valid([]byte(r)) {
+			return r
+		}
+		if err := json.Unmarshal([]byte(r), &v); err != nil {
+			return r
+		}
+	case []byte:
+		if err := json.Unmarshal(r, &v); err != nil {
+			return string(r)
+		}
+	}
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Map, reflect.Struct, reflect.Slice, reflect.Array, reflect.Pointer:
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", res)
+	}
+	var generic any
+	_ = json.Unmarshal(data, &generic)
+	if rows, ok := generic.([]any); ok {
+		if table, ok := formatObjectTable(rows); ok {
+			return table
+		}
+	}
+	indented, err := json.MarshalIndent(generic, "", "  ")
+	if err != nil {
+		return fmt.Sprintf("%v", res)
+	}
+	return "```json\n" + string(indented) + "\n```"
+}
+
+// formatObjectTable lays out a list of JSON objects as columns, one row per
+// object. It declines anything else, including an empty list.
+func formatObjectTable(rows []any) (string, bool) {
+	if len(rows) == 0 {
+		return "", false
+	}
+	seen := map[string]bool{}
+	var cols []string
+	for _, row := range rows {
+		obj, ok := row.(map[string]any)
+		if !ok {
+			return "", false
+		}
+		for k := range obj {
+			if !seen[k] {
+				seen[k] = true
+				cols = append(cols, k)
+			}
+		}
+	}
+	sort.Strings(cols)
+
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(cols, "\t"))
+	for _, row := range rows {
+		obj := row.(map[string]any)
+		cells := make([]string, len(cols))
+		for i, c := range cols {
+			switch cell := obj[c].(type) {
+			case nil:
+			case string:
+				cells[i] = cell
+			case map[string]any, []any:
+				data, _ := json.Marshal(cell)
+				cells[i] = string(data)
+			default:
+				cells[i] = fmt.Sprintf("%v", cell)
+			}
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+	_ = w.Flush()
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(l, " ")
+	}
+	return strings.Join(lines, "\n"), true
+}
+
 // path: src/update.go
 // path: src/update.go
 func (m *model) runPrompt(raw string) (*model, tea.Cmd) {
@@ -420,62 +835,223 @@ func (m *model) runPrompt(raw string) (*model, tea.Cmd) {
 	m.output += m.style.Accent.Render("You: ") + raw + "\n\n"
 	m.renderOutput(true)
 
-	m.isThinking = true
-	m.thinking = "thinking"
+	if m.selected.name == "explain" {
+		return m.runExplain(raw)
+	}
+	if m.selected.name == "codemode" {
+		return m.runCodeMode(raw)
+	}
+	if _, selected := parseFileDirectives(raw); len(selected) > 0 {
+		m.output += m.style.Subtle.Render(fmt.Sprintf("📎 context limited to %s and their direct dependencies\n", strings.Join(selected, ", ")))
+		m.renderOutput(true)
+	}
 
+	orchestrate := strings.EqualFold(m.selected.name, "orchestrator")
+	if orchestrate {
+		m.plannerQueue = make(chan string, 64)
+	}
 	cmd := func() tea.Msg {
-		_, tree := m.refreshContext()
+		// The generateMsg handler refreshes the context panel afterwards.
+		_, entries := m.collectContext()
+		tree := buildTree(entries)
 		prompt := fmt.Sprintf("File tree:\n%s\n\nsubagent:%s %s", tree, m.selected.name, raw)
 
 		// 🧭 If Orchestrator, run the multi-step planner
-		if strings.EqualFold(m.selected.name, "orchestrator") {
+		if orchestrate {
 			RunPlanner(m.ctx, m.agent, m.working, raw, m)
-			return nil // planner streams messages directly
+			return plannerTickMsg{} // drains the queue until the planner closes it
 		}
 
 		// 🧩 Default single-shot codegen
-		result, err := RunHeadless(m.ctx, m.agent, m.working, prompt)
+		result, err := RunHeadless(m.ctx, m.agent, m.working, prompt, HeadlessOptions{Config: m.cfg, Session: m.sessionID, OnAction: m.reportAction})
 		if err != nil {
 			return generateMsg{"", err}
 		}
+		m.usage.add(result.Usage)
+		result.Actions = append(result.Actions, CommitRun(m.ctx, m.cfg, m.working, raw, result.Actions)...)
+		m.requestConfirmation(result.Actions)
+		return generateMsg{m.formatActions(m.selected.name, result.Actions), nil}
+	}
 
-		var out strings.Builder
-		out.WriteString(m.style.Accent.Render(m.selected.name+":") + "\n\n")
-		for _, action := range result.Actions {
-			switch action.Action {
-			case "saved":
-				out.WriteString(m.style.Success.Render(fmt.Sprintf("💾 %s\n", action.Path)))
-				if strings.TrimSpace(action.Diff) != "" {
-					out.WriteString(m.style.Subtle.Render("```diff") + "\n")
-					out.WriteString(action.Diff)
-					out.WriteString(m.style.Subtle.Render("```") + "\n")
-				}
-			case "deleted", "removed":
-				out.WriteString(m.style.Subtle.Render(fmt.Sprintf("🧹 %s %s\n", strings.Title(action.Action), action.Path)))
-			case "error":
-				out.WriteString(m.style.Error.Render(fmt.Sprintf("❌ %s\n", action.Message)))
-			case "info":
-				out.WriteString(m.style.Subtle.Render(fmt.Sprintf("ℹ️ %s\n", action.Message)))
+	status := "thinking"
+	if orchestrate {
+		status = "planning"
+	}
+	return m, tea.Batch(cmd, m.startWork(status))
+}
+
+// reportAction forwards RunHeadless progress and writes to the UI while the
+// run is still in flight. It is safe to call from background goroutines.
+func (m *model) reportAction(a FileAction) {
+	if m.Program == nil {
+		return
+	}
+	switch a.Action {
+	case "saved":
+		m.Program.Send(fileActionMsg{a})
+	case "progress":
+		m.Program.Send(codegenStatusMsg{msg: a.Message})
+	case "chunk":
+		m.Program.Send(tokenChunkMsg{text: a.Message})
+	}
+}
+
+// reportStatus shows text next to the spinner while work is in flight. It is
+// safe to call from background goroutines.
+func (m *model) reportStatus(text string) {
+	if m.Program != nil {
+		m.Program.Send(statusMsg{text})
+	}
+}
+
+// formatActions renders the outcome of a generation run under title.
+func (m *model) formatActions(title string, actions []FileAction) string {
+	var out strings.Builder
+	out.WriteString(m.style.Accent.Render(title+":") + "\n\n")
+	var normalized []FileAction
+	for _, action := range actions {
+		switch action.Action {
+		case "saved":
+			out.WriteString(m.style.Success.Render(fmt.Sprintf("💾 %s\n", action.Path)))
+			if strings.TrimSpace(action.Diff) != "" {
+				out.WriteString(m.style.Subtle.Render("```diff") + "\n")
+				out.WriteString(action.Diff)
+				out.WriteString(m.style.Subtle.Render("```") + "\n")
+			}
+		case "deleted", "removed":
+			out.WriteString(m.style.Subtle.Render(fmt.Sprintf("🧹 %s %s\n", strings.Title(action.Action), action.Path)))
+		case "pending":
+			out.WriteString(m.style.Accent.Render(fmt.Sprintf("⏸ %s (%s)\n", action.Path, action.Message)))
+		case "dry-run":
+			out.WriteString(m.style.Accent.Render(fmt.Sprintf("👁 %s (%s)\n", action.Path, action.Message)))
+			if strings.TrimSpace(action.Diff) != "" {
+				out.WriteString(m.style.Subtle.Render("```diff") + "\n")
+				out.WriteString(action.Diff)
+				out.WriteString(m.style.Subtle.Render("```") + "\n")
+			}
+		case "error":
+			out.WriteString(m.style.Error.Render(fmt.Sprintf("❌ %s\n", action.Message)))
+		case "formatted":
+			out.WriteString(m.style.Subtle.Render(fmt.Sprintf("🎨 %s (%s)\n", action.Path, action.Message)))
+		case "normalized":
+			normalized = append(normalized, action)
+		case "tidied":
+			out.WriteString(m.style.Subtle.Render(fmt.Sprintf("📦 %s (%s)\n", action.Path, action.Message)))
+		case "info":
+			out.WriteString(m.style.Subtle.Render(fmt.Sprintf("ℹ️ %s\n", action.Message)))
+		}
+	}
+	// Import rewrites are summarised once, after the writes they follow,
+	// with their diffs so they can be checked.
+	if len(normalized) > 0 {
+		out.WriteString(m.style.Subtle.Render(fmt.Sprintf("🔧 normalized imports in %d file%s\n", len(normalized), plural(len(normalized)))))
+		for _, action := range normalized {
+			out.WriteString(m.style.Subtle.Render(fmt.Sprintf("   %s\n", action.Path)))
+			if strings.TrimSpace(action.Diff) != "" {
+				out.WriteString(m.style.Subtle.Render("```diff") + "\n")
+				out.WriteString(action.Diff)
+				out.WriteString(m.style.Subtle.Render("```") + "\n")
+			}
+		}
+	}
+	return out.String()
+}
+
+// layout sizes every component for the current window and input height.
+func (m *model) layout() {
+	// Calculate header height: logo (7 lines) + subtitle (1 line) + padding
+	headerHeight := 8
+	// Footer is a single line with padding
+	footerHeight := 2
+	chatContainerVPadding := m.style.ChatContainer.GetVerticalPadding()
+	chatContainerHPadding := m.style.ChatContainer.GetHorizontalPadding()
+	chatWidth := m.width - m.fileTreeWidth() - m.activityWidth() // side panels sit beside the chat
+	m.list.SetSize(m.width-chatContainerHPadding-2, m.height-headerHeight-footerHeight-chatContainerVPadding-2)
+	m.dirlist.SetSize(m.width-ui.DirPreviewWidth(m.width), m.height-headerHeight-footerHeight-2)                 // No container padding
+	m.textarea.SetWidth(chatWidth - chatContainerHPadding - 2)                                                   // -2 for border
+	m.viewport.Width = chatWidth - chatContainerHPadding - 2                                                     // -2 for border
+	m.viewport.Height = m.height - headerHeight - footerHeight - m.textarea.Height() - chatContainerVPadding - 4 // -4 for subtitle, status, thinking
+	m.renderOutput(false)                                                                                        // re-wrap to the new width
+}
+
+// resizeInput sets the textarea height, keeping the viewport usable, and
+// persists it as the preferred height.
+func (m *model) resizeInput(h int) {
+	maxHeight := maxInputHeight
+	if m.height > 0 {
+		// Leave at least a few lines of output visible.
+		maxHeight = min(maxHeight, max(minInputHeight, m.height/2))
+	}
+	h = min(max(h, minInputHeight), maxHeight)
+	if h == m.textarea.Height() {
+		return
+	}
+	m.textarea.SetHeight(h)
+	m.layout()
+	m.settings.InputHeight = h
+	if err := m.settings.save(m.configPath); err != nil {
+		sessionLog(m.sessionID).Warn("failed to save input height", "err", err)
+	}
+}
+
+// requestConfirmation asks the program to confirm any pending overwrites.
+// It is safe to call from background goroutines.
+func (m *model) requestConfirmation(actions []FileAction) {
+	var pending []FileAction
+	for _, a := range actions {
+		if a.Action == "pending" {
+			pending = append(pending, a)
+		}
+	}
+	if len(pending) > 0 && m.Program != nil {
+		m.Program.Send(confirmWritesMsg{actions: pending})
+	}
+}
+
+func (m *model) resolvePendingWrites(apply bool) {
+	if apply {
+		for _, a := range ApplyPendingWrites(m.working, m.pendingWrites, m.cfg) {
+			if a.Action == "error" {
+				m.output += m.style.Error.Render(fmt.Sprintf("❌ %s: %s\n", a.Path, a.Message))
+			} else {
+				m.output += m.style.Success.Render(fmt.Sprintf("💾 %s (%s)\n", a.Path, a.Message))
 			}
 		}
-		return generateMsg{out.String(), nil}
+	} else {
+		for _, a := range m.pendingWrites {
+			m.output += m.style.Subtle.Render(fmt.Sprintf("↩️ skipped %s\n", a.Path))
+		}
 	}
+	m.pendingWrites = nil
+	m.mode = ui.ModeChat
+	m.refreshContext()
+	m.renderOutput(true)
+}
 
-	return m, tea.Batch(cmd, m.spinner.Tick)
+// collectContext gathers the files a request attaches. It leaves the model
+// alone, so runs in the background can call it; refreshContext publishes the
+// result on the UI thread.
+func (m *model) collectContext() ([]models.File, []fileEntry) {
+	// An empty language filter (no -lang) includes all supported file types.
+	lang := m.cfg.contextLanguage()
+	// The same limits as requests, so the tree and the file panel show what
+	// the model is sent.
+	lim := m.cfg.contextLimits()
+	return collectAttachmentFiles(m.cfg, m.working, lim.maxFiles, lim.maxTotalBytes, lim.perFileLimit, lang)
 }
 
 func (m *model) refreshContext() ([]models.File, string) {
-	// An empty string for the language filter will include all supported file types.
-	lang := ""
-	// Increase limits to include a much larger portion of the codebase.
-	// maxFiles: 1000, maxTotalBytes: 10MB, perFileLimit: 100KB
-	files, includedEntries := collectAttachmentFiles(m.working, 1000, 10000000, 100000, lang)
+	files, includedEntries := m.collectContext()
 	var totalBytes int64
 	for _, f := range files {
 		totalBytes += int64(len(f.Data))
 	}
 	m.contextFiles = len(files)
 	m.contextBytes = totalBytes
+	m.contextPaths = make(map[string]bool, len(includedEntries))
+	for _, e := range includedEntries {
+		m.contextPaths[filepath.ToSlash(e.Rel)] = true
+	}
 
 	tree := buildTree(includedEntries)
 	return files, tree
@@ -0,0 +1,110 @@
+package src
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Protocol-Lattice/go-agent/src/models"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/Protocol-Lattice/lattice-code/src/ui"
+)
+
+func TestFormatToolResult(t *testing.T) {
+	cases := []struct {
+		name string
+		in   any
+		want string
+	}{
+		{"map", map[string]any{"b": 2, "a": "x"}, "```json\n{\n  \"a\": \"x\",\n  \"b\": 2\n}\n```"},
+		{"json string", `{"ok":true}`, "```json\n{\n  \"ok\": true\n}\n```"},
+		{"plain string", "hello world", "hello world"},
+		{"scalar", 42, "42"},
+		{"objects", []map[string]any{{"name": "a", "size": 1}, {"name": "bb"}}, "name  size\na     1\nbb"},
+		{"mixed list", []any{1, "two"}, "```json\n[\n  1,\n  \"two\"\n]\n```"},
+	}
+	for _, c := range cases {
+		if got := formatToolResult(c.in); got != c.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", c.name, got, c.want)
+		}
+	}
+}
+
+type streamingStub struct{ chunks []string }
+
+func (s streamingStub) GenerateWithFiles(context.Context, string, string, []models.File) (string, error) {
+	return "blocking", nil
+}
+
+func (s streamingStub) GenerateWithFilesStream(_ context.Context, _, _ string, _ []models.File, onChunk func(string)) (string, error) {
+	for _, c := range s.chunks {
+		onChunk(c)
+	}
+	return strings.Join(s.chunks, ""), nil
+}
+
+func TestGenerateStreamsWhenSupported(t *testing.T) {
+	var got []string
+	res, err := generate(context.Background(), streamingStub{chunks: []string{"Hel", "lo"}}, "s", "p", nil, func(c string) { got = append(got, c) })
+	if err != nil || res != "Hello" || strings.Join(got, "|") != "Hel|lo" {
+		t.Errorf("streamed: res=%q chunks=%v err=%v", res, got, err)
+	}
+	if res, _ := generate(context.Background(), streamingStub{}, "s", "p", nil, nil); res != "blocking" {
+		t.Errorf("without a chunk callback the blocking call should run, got %q", res)
+	}
+}
+
+func TestTokenChunksAccumulateUntilResult(t *testing.T) {
+	m := NewModel(context.Background(), nil, t.TempDir(), nil)
+	m.viewport.Width, m.viewport.Height = 80, 20
+	m.output = "You: hi\n\n"
+	for _, c := range []string{"Hel", "lo, ", "world"} {
+		next, _ := m.Update(tokenChunkMsg{text: c})
+		m = next.(*model)
+	}
+	if m.streamed != "Hello, world" || !strings.Contains(ansi.Strip(m.viewport.View()), "Hello, world") {
+		t.Fatalf("streamed = %q, view:\n%s", m.streamed, m.viewport.View())
+	}
+	if strings.Contains(m.output, "Hello") {
+		t.Error("partial text must not be committed to the transcript")
+	}
+	next, _ := m.Update(generateMsg{text: "Hello, world\n"})
+	m = next.(*model)
+	if m.streamed != "" || strings.Count(m.output, "Hello, world") != 1 {
+		t.Errorf("final result should replace the preview: streamed=%q output=%q", m.streamed, m.output)
+	}
+}
+
+func TestStatusUpdatesThinkingLine(t *testing.T) {
+	m := NewModel(context.Background(), nil, t.TempDir(), nil)
+	m.mode = ui.ModeChat
+	m.width, m.height = 100, 30
+	thinkingLine := func() string {
+		for _, line := range strings.Split(ansi.Strip(m.View()), "\n") {
+			if strings.Contains(line, ansi.Strip(m.spinner.View())) {
+				return line
+			}
+		}
+		return ""
+	}
+
+	m.startWork("planning")
+	if line := thinkingLine(); !strings.Contains(line, "planning") {
+		t.Fatalf("thinking line = %q", line)
+	}
+	m.Update(statusMsg{text: "running tests"})
+	if line := thinkingLine(); !strings.Contains(line, "running tests") {
+		t.Errorf("status update not rendered: %q", line)
+	}
+	m.Update(fileActionMsg{FileAction{Path: "src/server.go", Action: "saved", Message: "created"}})
+	if line := thinkingLine(); !strings.Contains(line, "writing src/server.go") {
+		t.Errorf("file write not rendered: %q", line)
+	}
+
+	m.Update(generateMsg{text: "done"})
+	m.Update(statusMsg{text: "late"})
+	if m.isThinking || m.thinking != "" {
+		t.Errorf("status after the work ended should be dropped: %v %q", m.isThinking, m.thinking)
+	}
+}
@@ -0,0 +1,58 @@
+package src
+
+import (
+	"sync"
+
+	"github.com/Protocol-Lattice/go-agent/src/models"
+)
+
+// Approximate list prices (USD per million tokens) for the default Gemini model.
+const (
+	promptPricePerMTok     = 1.25
+	completionPricePerMTok = 10.0
+)
+
+// Usage reports token counts for a single model call. The go-agent API does
+// not return provider usage metadata, so counts are estimated from text length.
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// estimateTokens applies the common ~4 characters per token heuristic.
+func estimateTokens(s string) int {
+	return (len(s) + 3) / 4
+}
+
+// estimateUsage estimates the tokens consumed by a prompt, its attachments and the response.
+func estimateUsage(prompt string, files []models.File, response string) Usage {
+	in := estimateTokens(prompt)
+	for _, f := range files {
+		in += estimateTokens(string(f.Data)) + estimateTokens(f.Name)
+	}
+	return Usage{PromptTokens: in, CompletionTokens: estimateTokens(response)}
+}
+
+// usageStats accumulates session totals; it is shared with background planner goroutines.
+type usageStats struct {
+	mu    sync.Mutex
+	total Usage
+	calls int
+}
+
+func (u *usageStats) add(x Usage) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.total.PromptTokens += x.PromptTokens
+	u.total.CompletionTokens += x.CompletionTokens
+	u.calls++
+}
+
+// totals returns the accumulated usage, number of calls and estimated cost in USD.
+func (u *usageStats) totals() (Usage, int, float64) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	cost := float64(u.total.PromptTokens)/1e6*promptPricePerMTok +
+		float64(u.total.CompletionTokens)/1e6*completionPricePerMTok
+	return u.total, u.calls, cost
+}
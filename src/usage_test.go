@@ -0,0 +1,30 @@
+package src
+
+import (
+	"math"
+	"testing"
+
+	"github.com/Protocol-Lattice/go-agent/src/models"
+)
+
+func TestUsageAccumulates(t *testing.T) {
+	var u usageStats
+	u.add(Usage{PromptTokens: 600_000, CompletionTokens: 50_000})
+	u.add(Usage{PromptTokens: 400_000, CompletionTokens: 50_000})
+
+	total, calls, cost := u.totals()
+	if calls != 2 || total.PromptTokens != 1_000_000 || total.CompletionTokens != 100_000 {
+		t.Fatalf("totals = %+v over %d calls", total, calls)
+	}
+	if want := promptPricePerMTok + 0.1*completionPricePerMTok; math.Abs(cost-want) > 1e-9 {
+		t.Errorf("cost = %f; want %f", cost, want)
+	}
+}
+
+func TestEstimateUsageCountsAttachments(t *testing.T) {
+	files := []models.File{{Name: "a.go", Data: make([]byte, 400)}}
+	u := estimateUsage("12345678", files, "abcd")
+	if u.PromptTokens != 2+100+1 || u.CompletionTokens != 1 {
+		t.Errorf("estimateUsage = %+v", u)
+	}
+}
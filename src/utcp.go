@@ -2,28 +2,70 @@ package src
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/charmbracelet/bubbles/list"
+
+	"github.com/Protocol-Lattice/lattice-code/src/ui"
 	utcp "github.com/universal-tool-calling-protocol/go-utcp"
+	"github.com/universal-tool-calling-protocol/go-utcp/src/providers/base"
+	"github.com/universal-tool-calling-protocol/go-utcp/src/tools"
 )
 
+// Provider is a UTCP tool provider (HTTP, CLI, MCP, text, ...).
+type Provider = base.Provider
+
+// UTCPOption customizes how BuildUTCP assembles its client.
+type UTCPOption func(*utcpOptions)
+
+type utcpOptions struct {
+	providersFile string
+	extra         []Provider
+}
+
+// WithProvidersFile overrides the default ~/utcp/provider.json location.
+func WithProvidersFile(path string) UTCPOption {
+	return func(o *utcpOptions) { o.providersFile = path }
+}
+
+// WithExtraProviders registers providers programmatically, in addition to
+// those listed in the providers file.
+func WithExtraProviders(provs []Provider) UTCPOption {
+	return func(o *utcpOptions) { o.extra = append(o.extra, provs...) }
+}
+
 // BuildUTCP initializes a UTCP client with a resolved provider.json path.
-func BuildUTCP(ctx context.Context) (utcp.UtcpClientInterface, error) {
-	// Expand home directory
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return nil, fmt.Errorf("failed to resolve home directory: %w", err)
+func BuildUTCP(ctx context.Context, opts ...UTCPOption) (utcp.UtcpClientInterface, error) {
+	var o utcpOptions
+	for _, opt := range opts {
+		opt(&o)
 	}
 
-	providerPath := filepath.Join(home, "utcp", "provider.json")
+	providerPath := o.providersFile
+	if providerPath == "" {
+		// Expand home directory
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve home directory: %w", err)
+		}
+		providerPath = filepath.Join(home, "utcp", "provider.json")
+	}
 
-	// Check that the file exists
+	// Check that the file exists; extra providers alone are enough to proceed.
 	if _, err := os.Stat(providerPath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("UTCP unavailable: providers file missing at %s", providerPath)
+		if len(o.extra) == 0 {
+			return nil, fmt.Errorf("UTCP unavailable: providers file missing at %s", providerPath)
+		}
+		providerPath = ""
 	}
 
+	configuredProviders = providerNames(providerPath, o.extra)
+
 	cfg := &utcp.UtcpClientConfig{
 		ProvidersFilePath: providerPath,
 	}
@@ -32,5 +74,93 @@ func BuildUTCP(ctx context.Context) (utcp.UtcpClientInterface, error) {
 	if err != nil {
 		return nil, fmt.Errorf("UTCP unavailable: %w", err)
 	}
+	for _, p := range o.extra {
+		if _, err := client.RegisterToolProvider(ctx, p); err != nil {
+			return nil, fmt.Errorf("UTCP unavailable: registering %s provider: %w", p.Type(), err)
+		}
+	}
 	return client, nil
 }
+
+// toolProvider returns the provider label for t: the name of the provider it
+// was registered with, or else the namespace of its "provider.tool" name.
+func toolProvider(t tools.Tool) string {
+	if t.Provider != nil {
+		if name := providerName(t.Provider); name != "" {
+			return name
+		}
+	}
+	if provider, _, ok := strings.Cut(t.Name, "."); ok {
+		return provider
+	}
+	return ""
+}
+
+// loadUTCPTools lists the client's tools for the UTCP tool browser.
+func loadUTCPTools(client toolSearcher) ([]list.Item, error) {
+	found, err := client.SearchTools("", 0)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]list.Item, 0, len(found))
+	for _, t := range found {
+		items = append(items, utcpItem{name: t.Name, provider: toolProvider(t), desc: t.Description})
+	}
+	return items, nil
+}
+
+// openUTCPTools shows the connected tools in ModeUTCP.
+func (m *model) openUTCPTools() {
+	if m.agent == nil || m.agent.UTCPClient == nil {
+		m.mode = ui.ModeChat
+		m.output += m.style.Error.Render("❌ UTCP client not available\n")
+		m.renderOutput(true)
+		return
+	}
+	items, err := loadUTCPTools(m.agent.UTCPClient)
+	if err != nil {
+		m.mode = ui.ModeChat
+		m.output += m.style.Error.Render(fmt.Sprintf("❌ listing UTCP tools: %v\n", err))
+		m.renderOutput(true)
+		return
+	}
+	m.list.SetItems(items)
+	m.list.Title = "🔌 UTCP tools"
+	m.mode = ui.ModeUTCP
+}
+
+// pickUTCPTool returns to chat with an "@utcp" call for u in the input.
+func (m *model) pickUTCPTool(u utcpItem) {
+	m.list.SetItems(defaultAgents())
+	m.list.Title = "Agents"
+	m.mode = ui.ModeChat
+	m.textarea.SetValue(fmt.Sprintf(`@utcp {"tool": %q, "args": {}}`, u.name))
+	m.textarea.Focus()
+}
+
+// runWithTimeout runs call under a deadline of d. It returns at the deadline
+// even if call ignores ctx, so a hung tool cannot wedge the session.
+func runWithTimeout[T any](ctx context.Context, tool string, d time.Duration, call func(context.Context) (T, error)) (T, error) {
+	ctx, cancel := context.WithTimeout(ctx, d)
+	defer cancel()
+
+	type result struct {
+		v   T
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		v, err := call(ctx)
+		done <- result{v, err}
+	}()
+	select {
+	case r := <-done:
+		return r.v, r.err
+	case <-ctx.Done():
+		var zero T
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return zero, fmt.Errorf("UTCP tool %s timed out after %s", tool, d)
+		}
+		return zero, ctx.Err()
+	}
+}
@@ -0,0 +1,107 @@
+package src
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/universal-tool-calling-protocol/go-utcp/src/providers/base"
+	"github.com/universal-tool-calling-protocol/go-utcp/src/providers/text"
+	"github.com/universal-tool-calling-protocol/go-utcp/src/tools"
+)
+
+func textProvider(name, tool string) *text.TextProvider {
+	return &text.TextProvider{
+		BaseProvider: base.BaseProvider{Name: name, ProviderType: base.ProviderText},
+		Templates:    map[string]string{tool: "hello {{.who}}"},
+	}
+}
+
+func TestBuildUTCPMergesExtraProviders(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "provider.json")
+	manual := `{"providers":[{"name":"filed","provider_type":"text","templates":{"greet":"hi"}}]}`
+	if err := os.WriteFile(file, []byte(manual), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := BuildUTCP(context.Background(),
+		WithProvidersFile(file),
+		WithExtraProviders([]Provider{textProvider("runtime", "wave")}),
+	)
+	if err != nil {
+		t.Fatalf("BuildUTCP: %v", err)
+	}
+
+	tools, err := client.SearchTools("", 10)
+	if err != nil {
+		t.Fatalf("SearchTools: %v", err)
+	}
+	names := map[string]bool{}
+	for _, tool := range tools {
+		names[tool.Name] = true
+	}
+	if !names["filed.greet"] || !names["runtime.wave"] {
+		t.Errorf("tools = %v; want both filed.greet and runtime.wave", names)
+	}
+}
+
+func TestBuildUTCPExtraProvidersWithoutFile(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "provider.json")
+	if _, err := BuildUTCP(context.Background(), WithProvidersFile(missing)); err == nil {
+		t.Fatal("expected an error when neither a file nor extra providers exist")
+	}
+	if _, err := BuildUTCP(context.Background(), WithProvidersFile(missing),
+		WithExtraProviders([]Provider{textProvider("runtime", "wave")})); err != nil {
+		t.Fatalf("extra providers alone should be enough: %v", err)
+	}
+}
+
+func TestLoadUTCPToolsProviderLabel(t *testing.T) {
+	client := stubSearcher{tools: []tools.Tool{
+		{Name: "files.read", Description: "read a file"},
+		{Name: "wave", Description: "wave", Provider: textProvider("runtime", "wave")},
+		{Name: "bare", Description: "no namespace"},
+	}}
+	items, err := loadUTCPTools(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"[files] read a file", "[runtime] wave", "no namespace"}
+	for i, item := range items {
+		if got := item.(utcpItem).Description(); got != want[i] {
+			t.Errorf("item %d: Description() = %q, want %q", i, got, want[i])
+		}
+	}
+}
+
+func TestRunWithTimeoutCancelsSlowTool(t *testing.T) {
+	cancelled := make(chan struct{})
+	slow := func(ctx context.Context) (any, error) {
+		<-ctx.Done()
+		close(cancelled)
+		time.Sleep(time.Second) // a tool that is slow to notice
+		return nil, ctx.Err()
+	}
+	start := time.Now()
+	_, err := runWithTimeout(context.Background(), "slow.tool", 20*time.Millisecond, slow)
+	if err == nil || !strings.Contains(err.Error(), "slow.tool timed out after 20ms") {
+		t.Fatalf("err = %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("returned after %s, want the deadline", elapsed)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("tool context was not cancelled")
+	}
+
+	got, err := runWithTimeout(context.Background(), "fast.tool", time.Second, func(context.Context) (string, error) { return "ok", nil })
+	if err != nil || got != "ok" {
+		t.Fatalf("fast tool = (%q, %v)", got, err)
+	}
+}
@@ -24,6 +24,29 @@ func (m *model) View() string {
 		TextArea:       m.textarea,
 		Viewport:       m.viewport,
 		Spinner:        m.spinner,
+		NewOutputBelow: m.unseenOutput,
+		BlockLabel:     m.blockLabel,
+		Completions:    m.completions(),
+		CompletionIdx:  m.completeIdx,
+		FileTree:       m.fileTree,
+		FileTreeWidth:  m.fileTreeWidth(),
+		Activity:       m.activity,
+		ActivityWidth:  m.activityWidth(),
+	}
+	if total, calls, cost := m.usage.totals(); calls > 0 {
+		state.TokensIn, state.TokensOut, state.Cost = total.PromptTokens, total.CompletionTokens, cost
+	}
+	if m.mode == ui.ModeDir {
+		state.DirPreview = m.selectedDirPreview()
+	}
+	if b := m.pendingBatch; b != nil {
+		state.PendingBatch = &ui.BatchPreview{Find: b.Find, Replace: b.Replace, Files: b.Files}
+	}
+	if d := m.pendingDiscard; d != nil {
+		state.PendingDiscard = &ui.DiscardPreview{Restore: d.Restore, Remove: d.Remove}
+	}
+	for _, a := range m.pendingWrites {
+		state.PendingWrites = append(state.PendingWrites, a.Path)
 	}
 
 	return ui.Render(state, m.style)